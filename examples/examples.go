@@ -1,11 +1,15 @@
 package main
 
 import (
+	"log"
+
 	"github.com/kopwei/goof"
 )
 
 func main() {
 	// Launch a openflow controller instance and start listen
 	ctrler, _ := goof.NewOfpController()
-	ctrler.StartListen(6633)
+	if err := ctrler.StartListen(6633); err != nil {
+		log.Fatal(err)
+	}
 }
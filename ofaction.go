@@ -0,0 +1,301 @@
+package goof
+
+import (
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Action is an action applied to the packets regardless of the openflow
+// version, it is translated to the actions of the version negotiated with
+// the switch. A version-neutral action may need several actions of the
+// version, e.g. the enqueue action since openflow 1.1.
+type Action interface {
+	// encodeV10 translates the action to the openflow 1.0 actions
+	encodeV10() ([]ofp10.OfpActionMsg, error)
+	// encode translates the action to the actions of openflow 1.1 and
+	// the later versions
+	encode(version uint8) ([]ofp13.OfpAction, error)
+}
+
+// OutputAction sends the packets out of the port, MaxLen is the number of
+// bytes sent when the port is OFPP_CONTROLLER
+type OutputAction struct {
+	Port   uint32
+	MaxLen uint16
+}
+
+// NewOutputAction creates the action sending the packets out of the port,
+// the whole packets are sent to the controller
+func NewOutputAction(port uint32) *OutputAction {
+	return &OutputAction{Port: port, MaxLen: ofp13.OfpControllerMaxLenNoBuf}
+}
+
+func (a *OutputAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	port, err := portV10(a.Port)
+	if err != nil {
+		return nil, err
+	}
+	return []ofp10.OfpActionMsg{*ofp10.NewActionOutput(port, a.MaxLen)}, nil
+}
+
+func (a *OutputAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	action := ofp13.NewActionOutput(a.Port)
+	action.MaxLen = a.MaxLen
+	return []ofp13.OfpAction{action}, nil
+}
+
+// SetFieldAction rewrites header fields of the packets. The fields are set
+// by the accessors of ofpgeneral.OxmFields and each one is rewritten by its
+// own action, the masks are supported since openflow 1.5.
+type SetFieldAction struct {
+	ofpgeneral.OxmFields
+}
+
+// NewSetFieldAction creates the action rewriting no field
+func NewSetFieldAction() *SetFieldAction {
+	return &SetFieldAction{}
+}
+
+func (a *SetFieldAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	actions := make([]ofp10.OfpActionMsg, 0, len(a.OxmFields))
+	for idx := range a.OxmFields {
+		field := &a.OxmFields[idx]
+		if field.Class != ofpgeneral.OxmClassOpenflowBasic || field.HasMask {
+			return nil, notExpressible(ofp10.Version, "set_field of %s", field.Name())
+		}
+		var action *ofp10.OfpActionMsg
+		switch field.Field {
+		case ofpgeneral.OxmFieldEthSrc:
+			addr, _, _ := a.GetEthSrc()
+			action = ofp10.NewActionSetDLAddr(ofp10.OfpActionSetDLSrc, addr)
+		case ofpgeneral.OxmFieldEthDst:
+			addr, _, _ := a.GetEthDst()
+			action = ofp10.NewActionSetDLAddr(ofp10.OfpActionSetDLDst, addr)
+		case ofpgeneral.OxmFieldVlanVID:
+			vid, _, _ := a.GetVlanVID()
+			action = ofp10.NewActionSetVlanVID(vid &^ ofpgeneral.OfpVIDPresent)
+		case ofpgeneral.OxmFieldVlanPCP:
+			pcp, _ := a.GetVlanPCP()
+			action = ofp10.NewActionSetVlanPCP(pcp)
+		case ofpgeneral.OxmFieldIPDSCP:
+			dscp, _ := a.GetIPDSCP()
+			action = ofp10.NewActionSetNWToS(dscp << 2)
+		case ofpgeneral.OxmFieldIPv4Src:
+			addr, _, _ := a.GetIPv4Src()
+			action = ofp10.NewActionSetNWAddr(ofp10.OfpActionSetNWSrc, addr)
+		case ofpgeneral.OxmFieldIPv4Dst:
+			addr, _, _ := a.GetIPv4Dst()
+			action = ofp10.NewActionSetNWAddr(ofp10.OfpActionSetNWDst, addr)
+		case ofpgeneral.OxmFieldTCPSrc, ofpgeneral.OxmFieldUDPSrc:
+			action = ofp10.NewActionSetTPPort(ofp10.OfpActionSetTPSrc, a.getTPPort(field.Field))
+		case ofpgeneral.OxmFieldTCPDst, ofpgeneral.OxmFieldUDPDst:
+			action = ofp10.NewActionSetTPPort(ofp10.OfpActionSetTPDst, a.getTPPort(field.Field))
+		default:
+			return nil, notExpressible(ofp10.Version, "set_field of %s", field.Name())
+		}
+		actions = append(actions, *action)
+	}
+	return actions, nil
+}
+
+func (a *SetFieldAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	if version == ofp11.Version {
+		return a.encodeV11()
+	}
+	actions := make([]ofp13.OfpAction, 0, len(a.OxmFields))
+	for idx := range a.OxmFields {
+		field := &a.OxmFields[idx]
+		if field.HasMask && version < ofp15.Version {
+			return nil, notExpressible(version, "masked set_field of %s", field.Name())
+		}
+		if err := checkOxmField(version, field, "set_field of"); err != nil {
+			return nil, err
+		}
+		actions = append(actions, ofp13.NewActionSetField(*field))
+	}
+	return actions, nil
+}
+
+// encodeV11 translates the fields to the set actions of openflow 1.1,
+// which precedes the set field action
+func (a *SetFieldAction) encodeV11() ([]ofp13.OfpAction, error) {
+	actions := make([]ofp13.OfpAction, 0, len(a.OxmFields))
+	for idx := range a.OxmFields {
+		field := &a.OxmFields[idx]
+		if field.Class != ofpgeneral.OxmClassOpenflowBasic || field.HasMask {
+			return nil, notExpressible(ofp11.Version, "set_field of %s", field.Name())
+		}
+		var action ofp13.OfpAction
+		switch field.Field {
+		case ofpgeneral.OxmFieldEthSrc:
+			addr, _, _ := a.GetEthSrc()
+			action = ofp11.NewActionSetDLSrc(addr)
+		case ofpgeneral.OxmFieldEthDst:
+			addr, _, _ := a.GetEthDst()
+			action = ofp11.NewActionSetDLDst(addr)
+		case ofpgeneral.OxmFieldVlanVID:
+			vid, _, _ := a.GetVlanVID()
+			action = ofp11.NewActionSetVlanVID(vid &^ ofpgeneral.OfpVIDPresent)
+		case ofpgeneral.OxmFieldVlanPCP:
+			pcp, _ := a.GetVlanPCP()
+			action = ofp11.NewActionSetVlanPCP(pcp)
+		case ofpgeneral.OxmFieldIPDSCP:
+			dscp, _ := a.GetIPDSCP()
+			action = ofp11.NewActionSetNWToS(dscp << 2)
+		case ofpgeneral.OxmFieldIPECN:
+			ecn, _ := a.GetIPECN()
+			action = ofp11.NewActionSetNWEcn(ecn)
+		case ofpgeneral.OxmFieldIPv4Src:
+			addr, _, _ := a.GetIPv4Src()
+			action = ofp11.NewActionSetNWSrc(addr)
+		case ofpgeneral.OxmFieldIPv4Dst:
+			addr, _, _ := a.GetIPv4Dst()
+			action = ofp11.NewActionSetNWDst(addr)
+		case ofpgeneral.OxmFieldTCPSrc, ofpgeneral.OxmFieldUDPSrc, ofpgeneral.OxmFieldSCTPSrc:
+			action = ofp11.NewActionSetTPSrc(a.getTPPort(field.Field))
+		case ofpgeneral.OxmFieldTCPDst, ofpgeneral.OxmFieldUDPDst, ofpgeneral.OxmFieldSCTPDst:
+			action = ofp11.NewActionSetTPDst(a.getTPPort(field.Field))
+		case ofpgeneral.OxmFieldMplsLabel:
+			label, _ := a.GetMplsLabel()
+			action = ofp11.NewActionSetMplsLabel(label)
+		case ofpgeneral.OxmFieldMplsTC:
+			tc, _ := a.GetMplsTC()
+			action = ofp11.NewActionSetMplsTC(tc)
+		default:
+			return nil, notExpressible(ofp11.Version, "set_field of %s", field.Name())
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// getTPPort returns the transport port of the field
+func (a *SetFieldAction) getTPPort(field uint8) uint16 {
+	match := Match{OxmFields: a.OxmFields}
+	return match.getTPValue(field)
+}
+
+// PushVlanAction pushes a new vlan tag of the ethertype, which is 0x8100
+// or 0x88a8
+type PushVlanAction struct {
+	EtherType uint16
+}
+
+// NewPushVlanAction creates the action pushing the vlan tag of the ethertype
+func NewPushVlanAction(etherType uint16) *PushVlanAction {
+	return &PushVlanAction{EtherType: etherType}
+}
+
+func (a *PushVlanAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	return nil, notExpressible(ofp10.Version, "push_vlan action")
+}
+
+func (a *PushVlanAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	return []ofp13.OfpAction{ofp13.NewActionPush(ofp13.OfpActionPushVlan, a.EtherType)}, nil
+}
+
+// PopVlanAction pops the outer vlan tag, which is the strip vlan action of
+// openflow 1.0
+type PopVlanAction struct{}
+
+// NewPopVlanAction creates the action popping the outer vlan tag
+func NewPopVlanAction() *PopVlanAction {
+	return &PopVlanAction{}
+}
+
+func (a *PopVlanAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	return []ofp10.OfpActionMsg{*ofp10.NewActionStripVlan()}, nil
+}
+
+func (a *PopVlanAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	return []ofp13.OfpAction{ofp13.NewActionHeader(ofp13.OfpActionPopVlan)}, nil
+}
+
+// GroupAction applies the group to the packets
+type GroupAction struct {
+	GroupID uint32
+}
+
+// NewGroupAction creates the action applying the group
+func NewGroupAction(groupID uint32) *GroupAction {
+	return &GroupAction{GroupID: groupID}
+}
+
+func (a *GroupAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	return nil, notExpressible(ofp10.Version, "group action")
+}
+
+func (a *GroupAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	return []ofp13.OfpAction{ofp13.NewActionGroup(a.GroupID)}, nil
+}
+
+// EnqueueAction sends the packets to the queue of the port, it's made of
+// the set queue and the output actions since openflow 1.1
+type EnqueueAction struct {
+	Port    uint32
+	QueueID uint32
+}
+
+// NewEnqueueAction creates the action sending the packets to the queue of
+// the port
+func NewEnqueueAction(port, queueID uint32) *EnqueueAction {
+	return &EnqueueAction{Port: port, QueueID: queueID}
+}
+
+func (a *EnqueueAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	port, err := portV10(a.Port)
+	if err != nil {
+		return nil, err
+	}
+	return []ofp10.OfpActionMsg{*ofp10.NewActionEnqueue(port, a.QueueID)}, nil
+}
+
+func (a *EnqueueAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	return []ofp13.OfpAction{ofp13.NewActionSetQueue(a.QueueID), ofp13.NewActionOutput(a.Port)}, nil
+}
+
+// DecNWTTLAction decrements the ip ttl of the packets
+type DecNWTTLAction struct{}
+
+// NewDecNWTTLAction creates the action decrementing the ip ttl
+func NewDecNWTTLAction() *DecNWTTLAction {
+	return &DecNWTTLAction{}
+}
+
+func (a *DecNWTTLAction) encodeV10() ([]ofp10.OfpActionMsg, error) {
+	return nil, notExpressible(ofp10.Version, "dec_nw_ttl action")
+}
+
+func (a *DecNWTTLAction) encode(version uint8) ([]ofp13.OfpAction, error) {
+	return []ofp13.OfpAction{ofp13.NewActionHeader(ofp13.OfpActionDecNWTTL)}, nil
+}
+
+// encodeActionsV10 translates the actions to the openflow 1.0 actions
+func encodeActionsV10(actions []Action) ([]ofp10.OfpActionMsg, error) {
+	result := make([]ofp10.OfpActionMsg, 0, len(actions))
+	for _, action := range actions {
+		encoded, err := action.encodeV10()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, encoded...)
+	}
+	return result, nil
+}
+
+// encodeActions translates the actions to the actions of the openflow
+// version, which is 1.1 or later
+func encodeActions(version uint8, actions []Action) ([]ofp13.OfpAction, error) {
+	result := make([]ofp13.OfpAction, 0, len(actions))
+	for _, action := range actions {
+		encoded, err := action.encode(version)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, encoded...)
+	}
+	return result, nil
+}
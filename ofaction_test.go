@@ -0,0 +1,48 @@
+package goof
+
+import (
+	"net"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+func TestSetFieldActionMasked(t *testing.T) {
+	action := NewSetFieldAction()
+	action.SetIPv4SrcMasked(net.IPv4(10, 0, 0, 1), net.CIDRMask(24, 32))
+	for _, version := range []uint8{ofp11.Version, ofp12.Version, ofp13.Version, ofp14.Version} {
+		_, err := action.encode(version)
+		checkNotExpressible(t, err, version)
+	}
+	_, err := action.encodeV10()
+	checkNotExpressible(t, err, ofp10.Version)
+
+	// The masks are set since openflow 1.5
+	actions, err := action.encode(ofp15.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := actions[0].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ofp15.ParseActions(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setField, ok := decoded[0].(*ofp13.OfpActionSetFieldInfo)
+	if !ok {
+		t.Fatalf("Unexpected action %+v", decoded[0])
+	}
+	checkRoundTrip(t, data, setField)
+	fields := ofpgeneral.OxmFields{setField.Field}
+	if addr, mask, ok := fields.GetIPv4Src(); !ok || !addr.Equal(net.IPv4(10, 0, 0, 1)) || mask.String() != "ffffff00" {
+		t.Errorf("Unexpected set field action %+v", setField)
+	}
+}
@@ -0,0 +1,110 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// AsyncMasks holds the bitmasks of the reasons of the asynchronous messages
+// sent to the controller in one role, the bit (1 << reason) enables the
+// messages of the reason. The role status, table status and request forward
+// masks are only supported by openflow 1.4 and later, the controller status
+// mask by openflow 1.5 and later.
+type AsyncMasks struct {
+	PacketIn         uint32 /* Bitmask of ofp13.OfpPacketInReason* values. */
+	PortStatus       uint32 /* Bitmask of ofp13.OfpPortReason* values. */
+	FlowRemoved      uint32 /* Bitmask of ofp13.OfpFlowRemoveReason* values. */
+	RoleStatus       uint32 /* Bitmask of ofp14.OfpCRReason* values. */
+	TableStatus      uint32 /* Bitmask of the table status reasons. */
+	RequestForward   uint32 /* Bitmask of the request forward reasons. */
+	ControllerStatus uint32 /* Bitmask of ofp15.OfpCSReason* values. */
+}
+
+// AsyncConfig is the asynchronous message configuration of the switch, the
+// master masks also apply to the equal role
+type AsyncConfig struct {
+	Master AsyncMasks
+	Slave  AsyncMasks
+}
+
+// asyncProps lists the property types of the masks and the first openflow
+// version supporting them, the slave type is followed by the master type
+var asyncProps = []struct {
+	slaveType uint16
+	version   uint8
+	mask      func(masks *AsyncMasks) *uint32
+}{
+	{ofp14.OfpAsyncConfigPropTypePacketInSlave, ofp14.Version, func(m *AsyncMasks) *uint32 { return &m.PacketIn }},
+	{ofp14.OfpAsyncConfigPropTypePortStatusSlave, ofp14.Version, func(m *AsyncMasks) *uint32 { return &m.PortStatus }},
+	{ofp14.OfpAsyncConfigPropTypeFlowRemovedSlave, ofp14.Version, func(m *AsyncMasks) *uint32 { return &m.FlowRemoved }},
+	{ofp14.OfpAsyncConfigPropTypeRoleStatusSlave, ofp14.Version, func(m *AsyncMasks) *uint32 { return &m.RoleStatus }},
+	{ofp14.OfpAsyncConfigPropTypeTableStatusSlave, ofp14.Version, func(m *AsyncMasks) *uint32 { return &m.TableStatus }},
+	{ofp14.OfpAsyncConfigPropTypeRequestForwardSlave, ofp14.Version, func(m *AsyncMasks) *uint32 { return &m.RequestForward }},
+	{ofp15.OfpAsyncConfigPropTypeContStatusSlave, ofp15.Version, func(m *AsyncMasks) *uint32 { return &m.ControllerStatus }},
+}
+
+// SetAsyncConfig replaces the asynchronous message configuration of the
+// switch. The masks introduced by a later openflow version than the one of
+// the switch are ignored. The configuration is reset by the switch once the connection closes.
+func (sw *openflowSwitchImpl) SetAsyncConfig(ctx context.Context, config *AsyncConfig) error {
+	if sw.version < ofp13.Version {
+		return fmt.Errorf("The asynchronous configuration isn't supported by openflow version %d", sw.version)
+	}
+	if sw.version == ofp13.Version {
+		msg := ofp13.NewSetAsyncMsg()
+		msg.PacketInMask = [2]uint32{config.Master.PacketIn, config.Slave.PacketIn}
+		msg.PortStatusMask = [2]uint32{config.Master.PortStatus, config.Slave.PortStatus}
+		msg.FlowRemovedMask = [2]uint32{config.Master.FlowRemoved, config.Slave.FlowRemoved}
+		return sw.modify(ctx, msg)
+	}
+	msg := ofp14.NewSetAsyncMsg()
+	msg.Header.Version = sw.version
+	master, slave := config.Master, config.Slave
+	for _, prop := range asyncProps {
+		if prop.version > sw.version {
+			continue
+		}
+		msg.Properties = append(msg.Properties,
+			ofp14.NewAsyncConfigPropReasons(prop.slaveType, *prop.mask(&slave)),
+			ofp14.NewAsyncConfigPropReasons(prop.slaveType+1, *prop.mask(&master)))
+	}
+	return sw.modify(ctx, msg)
+}
+
+// GetAsyncConfig queries the asynchronous message configuration of the switch
+func (sw *openflowSwitchImpl) GetAsyncConfig(ctx context.Context) (*AsyncConfig, error) {
+	if sw.version < ofp13.Version {
+		return nil, fmt.Errorf("The asynchronous configuration isn't supported by openflow version %d", sw.version)
+	}
+	var request *ofpgeneral.OfpHeader
+	if sw.version == ofp13.Version {
+		request = ofp13.NewGetAsyncRequestMsg()
+	} else {
+		request = ofp14.NewGetAsyncRequestMsg()
+		request.Version = sw.version
+	}
+	reply, err := sw.Request(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	config := &AsyncConfig{}
+	switch r := reply.(type) {
+	case *ofp13.OfpAsyncConfigMsg:
+		config.Master.PacketIn, config.Slave.PacketIn = r.PacketInMask[0], r.PacketInMask[1]
+		config.Master.PortStatus, config.Slave.PortStatus = r.PortStatusMask[0], r.PortStatusMask[1]
+		config.Master.FlowRemoved, config.Slave.FlowRemoved = r.FlowRemovedMask[0], r.FlowRemovedMask[1]
+	case *ofp14.OfpAsyncConfigMsg:
+		for _, prop := range asyncProps {
+			*prop.mask(&config.Slave), _ = r.GetReasonsMask(prop.slaveType)
+			*prop.mask(&config.Master), _ = r.GetReasonsMask(prop.slaveType + 1)
+		}
+	default:
+		return nil, fmt.Errorf("Unexpected reply %T to the get async request", reply)
+	}
+	return config, nil
+}
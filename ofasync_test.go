@@ -0,0 +1,101 @@
+package goof
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+var testAsyncConfig = AsyncConfig{
+	Master: AsyncMasks{PacketIn: 7, PortStatus: 7, FlowRemoved: 0xf, RoleStatus: 1, ControllerStatus: 3},
+	Slave:  AsyncMasks{PortStatus: 7},
+}
+
+func TestSetAsyncConfigWireFormat(t *testing.T) {
+	props14 := "0000 0008 00000000 0001 0008 00000007 0002 0008 00000007 0003 0008 00000007" +
+		"0004 0008 00000000 0005 0008 0000000f 0006 0008 00000000 0007 0008 00000001" +
+		"0008 0008 00000000 0009 0008 00000000 000a 0008 00000000 000b 0008 00000000"
+	tests := []struct {
+		name    string
+		version uint8
+		wire    string
+	}{
+		{"openflow 1.3", ofp13.Version, "041c0020 00000007 00000000 00000007 00000007 0000000f 00000000"},
+		{"openflow 1.4", ofp14.Version, "051c0068" + props14},
+		{"openflow 1.5", ofp15.Version, "061c0078" + props14 + "000c 0008 00000000 000d 0008 00000003"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sw, received := newTestSwitch(t, tc.version, nil)
+			defer sw.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := sw.SetAsyncConfig(ctx, &testAsyncConfig); err != nil {
+				t.Fatal(err)
+			}
+			msg := <-received
+			// The xid is skipped
+			encoded := hex.EncodeToString(msg[:4]) + hex.EncodeToString(msg[8:])
+			if wire := strings.Replace(tc.wire, " ", "", -1); encoded != wire {
+				t.Fatalf("Sent %s, expected %s", encoded, wire)
+			}
+		})
+	}
+}
+
+func TestGetAsyncConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  uint8
+		reply    func(xid uint32) ofpgeneral.OfpMessage
+		expected AsyncConfig
+	}{
+		{"openflow 1.3", ofp13.Version, func(xid uint32) ofpgeneral.OfpMessage {
+			header := ofpgeneral.NewOfpHeader(ofp13.Version)
+			header.Type = ofp13.OfpTypeGetAsyncReply
+			header.Xid = xid
+			return &ofp13.OfpAsyncConfigMsg{Header: *header, PacketInMask: [2]uint32{7, 0},
+				PortStatusMask: [2]uint32{7, 7}, FlowRemovedMask: [2]uint32{0xf, 0}}
+		}, AsyncConfig{Master: AsyncMasks{PacketIn: 7, PortStatus: 7, FlowRemoved: 0xf},
+			Slave: AsyncMasks{PortStatus: 7}}},
+		{"openflow 1.5", ofp15.Version, func(xid uint32) ofpgeneral.OfpMessage {
+			header := ofpgeneral.NewOfpHeader(ofp15.Version)
+			header.Type = ofp14.OfpTypeGetAsyncReply
+			header.Xid = xid
+			return &ofp14.OfpAsyncConfigMsg{Header: *header, Properties: []ofpgeneral.OfpMessage{
+				ofp14.NewAsyncConfigPropReasons(ofp14.OfpAsyncConfigPropTypePacketInMaster, 7),
+				ofp14.NewAsyncConfigPropReasons(ofp14.OfpAsyncConfigPropTypePortStatusSlave, 7),
+				ofp14.NewAsyncConfigPropReasons(ofp14.OfpAsyncConfigPropTypePortStatusMaster, 7),
+				ofp14.NewAsyncConfigPropReasons(ofp14.OfpAsyncConfigPropTypeFlowRemovedMaster, 0xf),
+				ofp14.NewAsyncConfigPropReasons(ofp14.OfpAsyncConfigPropTypeRoleStatusMaster, 1),
+				&ofp14.OfpAsyncConfigPropExperimenter{Experimenter: 0x2320, Data: []byte{1}},
+				ofp14.NewAsyncConfigPropReasons(ofp15.OfpAsyncConfigPropTypeContStatusMaster, 3),
+			}}
+		}, testAsyncConfig},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sw, _ := newTestSwitch(t, tc.version, func(msg []byte) []ofpgeneral.OfpMessage {
+				xid, _ := ofpgeneral.GetMessageXid(msg)
+				return []ofpgeneral.OfpMessage{tc.reply(xid)}
+			})
+			defer sw.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			config, err := sw.GetAsyncConfig(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *config != tc.expected {
+				t.Fatalf("Got %+v, expected %+v", *config, tc.expected)
+			}
+		})
+	}
+}
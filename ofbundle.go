@@ -0,0 +1,117 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Bundle collects the messages which are applied by the switch as a single
+// operation, see the Bundle method of the switch
+type Bundle struct {
+	msgs []ofpgeneral.OfpMessage
+}
+
+// Add appends the message to the bundle, e.g. the flow mod, group mod or
+// meter mod messages
+func (b *Bundle) Add(msg ofpgeneral.OfpMessage) {
+	b.msgs = append(b.msgs, msg)
+}
+
+// Bundle applies the messages added by fill as a single operation. The
+// flags are the ofp14.OfpBundleFlag* bits, with ofp14.OfpBundleFlagAtomic
+// either all the messages are applied or none of them. The bundle is
+// discarded if any message is rejected while added, and the error of the
+// rejected message is returned. The bundles are introduced by openflow 1.4.
+func (sw *openflowSwitchImpl) Bundle(ctx context.Context, flags uint16, fill func(b *Bundle)) error {
+	if sw.version < ofp14.Version {
+		return fmt.Errorf("The bundles aren't supported by openflow version %d", sw.version)
+	}
+	bundle := &Bundle{}
+	fill(bundle)
+	if len(bundle.msgs) == 0 {
+		return nil
+	}
+	bundleID := sw.nextBundleID()
+	if err := sw.controlBundle(ctx, bundleID, ofp14.OfpBundleCtrlTypeOpenRequest, flags); err != nil {
+		return err
+	}
+	if err := sw.addBundleMsgs(ctx, bundleID, flags, bundle.msgs); err != nil {
+		sw.discardBundle(bundleID, flags)
+		return err
+	}
+	// The switch discards the bundle once it is committed, whether the
+	// commit succeeds or not
+	return sw.controlBundle(ctx, bundleID, ofp14.OfpBundleCtrlTypeCommitRequest, flags)
+}
+
+// addBundleMsgs adds the messages to the bundle. The switch answers the
+// bundle add messages only if they fail, so they are followed by a barrier
+// like the modifications.
+func (sw *openflowSwitchImpl) addBundleMsgs(ctx context.Context, bundleID uint32, flags uint16, msgs []ofpgeneral.OfpMessage) error {
+	errChan := make(chan error, len(msgs))
+	xids := make([]uint32, 0, len(msgs))
+	defer func() {
+		for _, xid := range xids {
+			sw.tunnel.transactions.remove(xid)
+		}
+	}()
+	for _, msg := range msgs {
+		addMsg, err := ofp14.NewBundleAddMsg(bundleID, flags, msg)
+		if err != nil {
+			return err
+		}
+		addMsg.Header.Version = sw.version
+		err = sw.RequestAsync(addMsg, 0, func(reply ofpgeneral.OfpMessage, err error) {
+			errChan <- err
+		})
+		if err != nil {
+			return err
+		}
+		xids = append(xids, addMsg.Header.Xid)
+	}
+	barrierErr := sw.Barrier(ctx)
+	select {
+	case err := <-errChan:
+		return err
+	default:
+	}
+	return barrierErr
+}
+
+// controlBundle sends the bundle control request and checks the reply
+func (sw *openflowSwitchImpl) controlBundle(ctx context.Context, bundleID uint32, ctrlType uint16, flags uint16) error {
+	msg := ofp14.NewBundleCtrlMsg(bundleID, ctrlType, flags)
+	msg.Header.Version = sw.version
+	reply, err := sw.Request(ctx, msg)
+	if err != nil {
+		return err
+	}
+	ctrlReply, ok := reply.(*ofp14.OfpBundleCtrlMsg)
+	// Each control request is answered by the reply of the next type
+	if !ok || ctrlReply.BundleID != bundleID || ctrlReply.Type != ctrlType+1 {
+		return fmt.Errorf("Unexpected reply %T to the bundle control request %d", reply, ctrlType)
+	}
+	return nil
+}
+
+// discardBundle discards the bundle which failed, the request isn't bound to
+// the context of the bundle as it may be already done
+func (sw *openflowSwitchImpl) discardBundle(bundleID uint32, flags uint16) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHandshakeTimeout)
+	defer cancel()
+	if err := sw.controlBundle(ctx, bundleID, ofp14.OfpBundleCtrlTypeDiscardRequest, flags); err != nil {
+		log.Warnf("Failed to discard the bundle %d on switch %s: %v", bundleID, sw.GetDatapathID().GetHwAddr(), err)
+	}
+}
+
+func (sw *openflowSwitchImpl) nextBundleID() uint32 {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.lastBundleID++
+	return sw.lastBundleID
+}
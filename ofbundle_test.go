@@ -0,0 +1,128 @@
+package goof
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// newTestBundleSwitch creates the switch answering the bundle control
+// requests, the bundle add messages are rejected if reject tells so
+func newTestBundleSwitch(t *testing.T, reject func(count int) bool) (*openflowSwitchImpl, <-chan []byte) {
+	adds := 0
+	return newTestSwitch(t, ofp14.Version, func(msg []byte) []ofpgeneral.OfpMessage {
+		xid, _ := ofpgeneral.GetMessageXid(msg)
+		switch msg[1] {
+		case ofp14.OfpTypeBundleControl:
+			request := &ofp14.OfpBundleCtrlMsg{}
+			if err := request.UnmarshalBinary(msg); err != nil {
+				t.Error(err)
+				return nil
+			}
+			reply := ofp14.NewBundleCtrlMsg(request.BundleID, request.Type+1, request.Flags)
+			reply.Header.Xid = xid
+			return []ofpgeneral.OfpMessage{reply}
+		case ofp14.OfpTypeBundleAddMessage:
+			adds++
+			if reject(adds) {
+				errMsg := ofpgeneral.NewErrMsg(ofp14.Version, ofp14.OfpErrTypeBundleFailed,
+					ofp14.OfpBundleFailedCodeMsgFailed, nil)
+				errMsg.Header.Xid = xid
+				return []ofpgeneral.OfpMessage{errMsg}
+			}
+		}
+		return nil
+	})
+}
+
+// checkBundleCtrl checks the message is the bundle control request of the
+// type
+func checkBundleCtrl(t *testing.T, msg []byte, ctrlType uint16) *ofp14.OfpBundleCtrlMsg {
+	ctrl := &ofp14.OfpBundleCtrlMsg{}
+	if err := ctrl.UnmarshalBinary(msg); err != nil {
+		t.Fatal(err)
+	}
+	if ctrl.Type != ctrlType {
+		t.Fatalf("Expected the bundle control type %d, got %+v", ctrlType, ctrl)
+	}
+	return ctrl
+}
+
+func TestBundle(t *testing.T) {
+	sw, received := newTestBundleSwitch(t, func(count int) bool { return false })
+	defer sw.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := sw.Bundle(ctx, ofp14.OfpBundleFlagAtomic, func(b *Bundle) {
+		b.Add(ofp13.NewFlowModMsg(ofp13.OfpFlowModCmdAdd, 0))
+		b.Add(ofp13.NewFlowModMsg(ofp13.OfpFlowModCmdDelete, 1))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := checkBundleCtrl(t, <-received, ofp14.OfpBundleCtrlTypeOpenRequest)
+	if open.Flags != ofp14.OfpBundleFlagAtomic {
+		t.Errorf("Unexpected bundle open %+v", open)
+	}
+	for i, command := range []uint8{ofp13.OfpFlowModCmdAdd, ofp13.OfpFlowModCmdDelete} {
+		add := &ofp14.OfpBundleAddMsg{}
+		if err := add.UnmarshalBinary(<-received); err != nil {
+			t.Fatal(err)
+		}
+		flowMod := &ofp13.OfpFlowModMsg{}
+		if err := flowMod.UnmarshalBinary(add.Message); err != nil {
+			t.Fatal(err)
+		}
+		if add.BundleID != open.BundleID || add.Flags != ofp14.OfpBundleFlagAtomic ||
+			flowMod.Header.Version != ofp14.Version || flowMod.Header.Xid != add.Header.Xid ||
+			flowMod.Command != command {
+			t.Errorf("Unexpected bundled message %d: %+v %+v", i, add, flowMod)
+		}
+	}
+	if commit := checkBundleCtrl(t, <-received, ofp14.OfpBundleCtrlTypeCommitRequest); commit.BundleID != open.BundleID {
+		t.Errorf("Unexpected bundle commit %+v", commit)
+	}
+}
+
+func TestBundleAddRejected(t *testing.T) {
+	sw, received := newTestBundleSwitch(t, func(count int) bool { return count == 2 })
+	defer sw.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := sw.Bundle(ctx, 0, func(b *Bundle) {
+		for i := 0; i < 3; i++ {
+			b.Add(ofp13.NewFlowModMsg(ofp13.OfpFlowModCmdAdd, 0))
+		}
+	})
+	requestErr, ok := err.(*OfpRequestError)
+	if !ok || requestErr.Msg.Type != ofp14.OfpErrTypeBundleFailed ||
+		requestErr.Msg.Code != ofp14.OfpBundleFailedCodeMsgFailed {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	open := checkBundleCtrl(t, <-received, ofp14.OfpBundleCtrlTypeOpenRequest)
+	for i := 0; i < 3; i++ {
+		if msg := <-received; msg[1] != ofp14.OfpTypeBundleAddMessage {
+			t.Fatalf("Expected the bundle add %d, got the type %d", i, msg[1])
+		}
+	}
+	// The bundle is discarded instead of committed
+	if discard := checkBundleCtrl(t, <-received, ofp14.OfpBundleCtrlTypeDiscardRequest); discard.BundleID != open.BundleID {
+		t.Errorf("Unexpected bundle discard %+v", discard)
+	}
+}
+
+func TestBundleUnsupported(t *testing.T) {
+	sw := &openflowSwitchImpl{version: ofp13.Version}
+	err := sw.Bundle(context.Background(), 0, func(b *Bundle) {
+		b.Add(ofp13.NewFlowModMsg(ofp13.OfpFlowModCmdAdd, 0))
+	})
+	if err == nil {
+		t.Fatal("The bundle is accepted by openflow 1.3")
+	}
+}
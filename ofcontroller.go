@@ -1,14 +1,21 @@
 package goof
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
-	"strings"
+	"sort"
+	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 
 	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
 	"github.com/kopwei/goof/protocols/ofp15"
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
@@ -19,111 +26,680 @@ type OfpPacketInMsg interface{}
 // OFApplication defines the openflow application interface
 type OFApplication interface {
 	// A Switch connected to the controller
-	Connected(sw *OpenflowSwitch)
+	Connected(sw OpenflowSwitch)
 
 	// Switch disconnected from the controller
-	Disconnected(sw *OpenflowSwitch)
+	Disconnected(sw OpenflowSwitch)
 
 	// Controller received a message packet from the switch
-	PacketRcvd(sw *OpenflowSwitch, msg OfpPacketInMsg)
+	PacketRcvd(sw OpenflowSwitch, msg OfpPacketInMsg)
 }
 
 // OfpController represents the openflow controller structure
 type OfpController interface {
-	StartListen(portNo int)
+	RegisterApp(app OFApplication)
+	// SetSupportedVersions configures the openflow versions offered
+	// by the controller during the version negotiation
+	SetSupportedVersions(versions ...uint8) error
+	// SetEchoInterval configures the interval of the echo requests sent on
+	// idle connections and the number of unanswered echo requests after
+	// which the switch is disconnected. Zero interval disables the probing.
+	SetEchoInterval(interval time.Duration, maxMisses int)
+	// StartListen listens on the tcp port and serves the switches until
+	// the controller is stopped
+	StartListen(portNo int) error
+	// Start listens on the tcp port and serves the switches until the
+	// context is done or the controller is stopped
+	Start(ctx context.Context, portNo int) error
+	// StartTLS listens on the tcp port for tls connections and serves
+	// the switches until the context is done or the controller is stopped
+	StartTLS(ctx context.Context, portNo int, config *tls.Config) error
+	// Serve listens on all the targets, which are ptcp:[port][:ip],
+	// pssl:[port][:ip] or punix:path, and serves the switches until the
+	// context is done or the controller is stopped
+	Serve(ctx context.Context, targets ...string) error
+	// SetTLSConfig configures the tls configuration used by the ssl
+	// and pssl targets
+	SetTLSConfig(config *tls.Config)
+	// Connect connects to the switch listening on the target, which is
+	// tcp:host[:port], ssl:host[:port] or unix:path. The connection is
+	// retried with backoff, and reestablished once it is lost, until
+	// the context is done or the controller is stopped.
+	Connect(ctx context.Context, target string) error
+	// SetRole declares the role requested on every switch once it connects,
+	// which is one of the ofp13.OfpControllerRole* constants
+	SetRole(role uint32)
+	// SetSwitchRole declares the role requested on the switch of the
+	// datapath id, it overrides the role declared by SetRole
+	SetSwitchRole(dpid uint64, role uint32)
+	// Stop closes the listeners and the switch connections, and waits
+	// until all of them are released. A stopped controller can't be
+	// started again.
+	Stop()
 }
 
+const (
+	defaultEchoInterval  = 5 * time.Second
+	defaultEchoMaxMisses = 3
+	// defaultHandshakeTimeout is the time given to the switch to
+	// complete the tls and the openflow handshakes
+	defaultHandshakeTimeout = 3 * time.Second
+	// The backoff between the attempts of connecting to a switch, it
+	// is doubled after each attempt until the max backoff
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 8 * time.Second
+)
+
+// codecVersions are the openflow versions which the controller
+// is able to encode and decode
+var codecVersions = []uint8{ofp10.Version, ofp11.Version, ofp12.Version, ofp13.Version, ofp14.Version, ofp15.Version}
+
+// defaultVersions are the openflow versions offered unless configured
+// by SetSupportedVersions
+var defaultVersions = []uint8{ofp10.Version, ofp13.Version}
+
 type ofpControllerImpl struct {
-	bridges []OpenflowSwitch
+	lock     sync.RWMutex
+	apps     []OFApplication
+	versions []uint8
+	switches map[uint64]OpenflowSwitch
+
+	// The roles requested on the switches
+	defaultRole uint32
+	switchRoles map[uint64]uint32
+
+	echoInterval  time.Duration
+	echoMaxMisses int
+	tlsConfig     *tls.Config
+
+	// The backoff between the attempts of connecting to a switch
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// The connections which are not closed yet
+	tunnels  map[*OfpMessageTunnel]bool
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	// The context of the background requests, cancelled by Stop
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewOfpController creates a new openflow controller
 func NewOfpController() (OfpController, error) {
 	ctrler := &ofpControllerImpl{}
-	ctrler.bridges = make([]OpenflowSwitch, 0)
+	ctrler.apps = make([]OFApplication, 0)
+	ctrler.versions = append([]uint8{}, defaultVersions...)
+	ctrler.switches = make(map[uint64]OpenflowSwitch)
+	ctrler.switchRoles = make(map[uint64]uint32)
+	ctrler.echoInterval = defaultEchoInterval
+	ctrler.echoMaxMisses = defaultEchoMaxMisses
+	ctrler.minBackoff = defaultMinBackoff
+	ctrler.maxBackoff = defaultMaxBackoff
+	ctrler.tunnels = make(map[*OfpMessageTunnel]bool)
+	ctrler.stopChan = make(chan struct{})
+	ctrler.ctx, ctrler.cancel = context.WithCancel(context.Background())
 	return ctrler, nil
 }
 
-// StartListen will start a tcp listener
-func (oc *ofpControllerImpl) StartListen(portNo int) {
-	portNoStr := fmt.Sprintf(":%d", portNo)
-	addr, err := net.ResolveTCPAddr("tcp", portNoStr)
-	if err != nil {
-		log.Fatal(err)
+// SetSupportedVersions configures the openflow versions offered
+// by the controller during the version negotiation
+func (oc *ofpControllerImpl) SetSupportedVersions(versions ...uint8) error {
+	if len(versions) == 0 {
+		return fmt.Errorf("At least one openflow version should be supported")
+	}
+	supported := make([]uint8, 0, len(versions))
+	for _, version := range versions {
+		if !isVersionValid(version) {
+			return fmt.Errorf("Unsupported version %d", version)
+		}
+		if !containsVersion(supported, version) {
+			supported = append(supported, version)
+		}
+	}
+	sort.Slice(supported, func(i, j int) bool { return supported[i] < supported[j] })
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.versions = supported
+	return nil
+}
+
+// SetEchoInterval configures the interval of the echo requests sent on
+// idle connections and the number of unanswered echo requests after
+// which the switch is disconnected. Zero interval disables the probing.
+func (oc *ofpControllerImpl) SetEchoInterval(interval time.Duration, maxMisses int) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.echoInterval = interval
+	oc.echoMaxMisses = maxMisses
+}
+
+func (oc *ofpControllerImpl) getEchoInterval() (time.Duration, int) {
+	oc.lock.RLock()
+	defer oc.lock.RUnlock()
+	return oc.echoInterval, oc.echoMaxMisses
+}
+
+func (oc *ofpControllerImpl) getVersions() []uint8 {
+	oc.lock.RLock()
+	defer oc.lock.RUnlock()
+	return oc.versions
+}
+
+// RegisterApp adds an application which will be notified about
+// the switch events received by the controller
+func (oc *ofpControllerImpl) RegisterApp(app OFApplication) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.apps = append(oc.apps, app)
+}
+
+// StartListen listens on the tcp port and serves the switches until
+// the controller is stopped
+func (oc *ofpControllerImpl) StartListen(portNo int) error {
+	return oc.Start(context.Background(), portNo)
+}
+
+// Start listens on the tcp port and serves the switches until the
+// context is done or the controller is stopped
+func (oc *ofpControllerImpl) Start(ctx context.Context, portNo int) error {
+	return oc.Serve(ctx, fmt.Sprintf("ptcp:%d", portNo))
+}
+
+// StartTLS listens on the tcp port for tls connections and serves
+// the switches until the context is done or the controller is stopped
+func (oc *ofpControllerImpl) StartTLS(ctx context.Context, portNo int, config *tls.Config) error {
+	if config == nil {
+		return fmt.Errorf("The tls configuration is required")
 	}
-	listener, err := net.ListenTCP("tcp", addr)
+	listener, err := listenTarget(schemeSSL, fmt.Sprintf(":%d", portNo), config)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	log.Println("Listening for tls connections on", listener.Addr())
+	return oc.serveListeners(ctx, []net.Listener{listener})
+}
+
+// Serve listens on all the targets, which are ptcp:[port][:ip],
+// pssl:[port][:ip] or punix:path, and serves the switches until the
+// context is done or the controller is stopped. Nothing is served if
+// any of the targets can't be listened on. Once the context is done the
+// controller is stopped, Serve returns when the switch connections are
+// released.
+func (oc *ofpControllerImpl) Serve(ctx context.Context, targets ...string) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("At least one listen target should be given")
+	}
+	tlsConfig := oc.getTLSConfig()
+	listeners := make([]net.Listener, 0, len(targets))
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+	for _, target := range targets {
+		scheme, address, err := parseListenTarget(target)
+		if err == nil && scheme == schemeSSL && tlsConfig == nil {
+			err = fmt.Errorf("The tls configuration is required to listen on %s", target)
+		}
+		var listener net.Listener
+		if err == nil {
+			listener, err = listenTarget(scheme, address, tlsConfig)
+		}
+		if err != nil {
+			closeAll()
+			return err
+		}
+		log.Printf("Listening for connections on %s (%s)", listener.Addr(), target)
+		listeners = append(listeners, listener)
 	}
+	return oc.serveListeners(ctx, listeners)
+}
 
+// serveListeners serves the listeners until the context is done or the
+// controller is stopped, the controller is stopped once the context is
+// done
+func (oc *ofpControllerImpl) serveListeners(ctx context.Context, listeners []net.Listener) error {
+	// Every listener is served until the controller is stopped, the
+	// first error is returned
+	errChan := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func(listener net.Listener) {
+			errChan <- oc.serve(ctx, listener)
+		}(listener)
+	}
+	var err error
+	for range listeners {
+		if serveErr := <-errChan; serveErr != nil && err == nil {
+			err = serveErr
+		}
+	}
+	// Stop waits for the workers, it mustn't be called by one of them
+	if ctx.Err() != nil {
+		oc.Stop()
+	}
+	return err
+}
+
+// serve accepts the connections on the listener until the context is
+// done or the controller is stopped
+func (oc *ofpControllerImpl) serve(ctx context.Context, listener net.Listener) error {
 	defer listener.Close()
+	// The worker is added under the lock, so that Stop either waits for
+	// it or it sees the controller stopped
+	oc.lock.Lock()
+	select {
+	case <-oc.stopChan:
+		oc.lock.Unlock()
+		return nil
+	default:
+	}
+	oc.wg.Add(1)
+	oc.lock.Unlock()
+	defer oc.wg.Done()
+
+	// Close the listener to interrupt Accept once the context is done or
+	// the controller is stopped
+	released := make(chan struct{})
+	defer close(released)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-oc.stopChan:
+		case <-released:
+		}
+		listener.Close()
+	}()
 
-	log.Println("Listening for connections on", addr)
 	for {
-		conn, err := listener.AcceptTCP()
+		conn, err := listener.Accept()
 		if err != nil {
-			if strings.Contains(err.Error(), "use of closed network connection") {
-				return
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-oc.stopChan:
+				return nil
+			default:
 			}
-			log.Fatal(err)
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				log.Warnln("Failed to accept connection:", err)
+				continue
+			}
+			return err
 		}
-		go handleConnection(conn)
+		oc.wg.Add(1)
+		go func() {
+			defer oc.wg.Done()
+			oc.handleConnection(ctx, conn)
+		}()
 	}
 }
 
-func handleConnection(conn *net.TCPConn) {
+// SetTLSConfig configures the tls configuration used by the ssl
+// and pssl targets
+func (oc *ofpControllerImpl) SetTLSConfig(config *tls.Config) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.tlsConfig = config
+}
+
+func (oc *ofpControllerImpl) getTLSConfig() *tls.Config {
+	oc.lock.RLock()
+	defer oc.lock.RUnlock()
+	return oc.tlsConfig
+}
+
+// Connect connects to the switch listening on the target, which is
+// tcp:host[:port], ssl:host[:port] or unix:path. The connection is
+// retried with backoff, and reestablished once it is lost, until
+// the context is done or the controller is stopped.
+func (oc *ofpControllerImpl) Connect(ctx context.Context, target string) error {
+	scheme, address, err := parseTarget(target)
+	if err != nil {
+		return err
+	}
+	tlsConfig := oc.getTLSConfig()
+	if scheme == schemeSSL && tlsConfig == nil {
+		return fmt.Errorf("The tls configuration is required to connect to %s", target)
+	}
+	// The worker is added under the lock, so that Stop either waits for
+	// it or it sees the controller stopped
+	oc.lock.Lock()
+	select {
+	case <-oc.stopChan:
+		oc.lock.Unlock()
+		return nil
+	default:
+	}
+	oc.wg.Add(1)
+	oc.lock.Unlock()
+	defer oc.wg.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-oc.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := oc.minBackoff
+	for {
+		conn, err := dialTarget(ctx, scheme, address, tlsConfig)
+		if err != nil {
+			log.Warnf("Failed to connect to %s: %v", target, err)
+		} else if oc.handleConnection(ctx, conn) {
+			// The switch was connected, retry promptly
+			backoff = oc.minBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > oc.maxBackoff {
+			backoff = oc.maxBackoff
+		}
+	}
+}
+
+// Stop closes the listeners and the switch connections, and waits
+// until all of them are released
+func (oc *ofpControllerImpl) Stop() {
+	oc.stopOnce.Do(func() {
+		// The stop channel is closed under the lock, the workers added
+		// under the lock afterwards see it closed and aren't waited for
+		oc.lock.Lock()
+		close(oc.stopChan)
+		oc.lock.Unlock()
+		oc.cancel()
+	})
+	oc.lock.RLock()
+	tunnels := make([]*OfpMessageTunnel, 0, len(oc.tunnels))
+	for tunnel := range oc.tunnels {
+		tunnels = append(tunnels, tunnel)
+	}
+	oc.lock.RUnlock()
+	for _, tunnel := range tunnels {
+		tunnel.Close()
+	}
+	oc.wg.Wait()
+}
+
+// addTunnel tracks the tunnel until it is closed, false is returned
+// if the controller is already stopped
+func (oc *ofpControllerImpl) addTunnel(tunnel *OfpMessageTunnel) bool {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	select {
+	case <-oc.stopChan:
+		return false
+	default:
+	}
+	oc.tunnels[tunnel] = true
+	return true
+}
+
+func (oc *ofpControllerImpl) removeTunnel(tunnel *OfpMessageTunnel) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	delete(oc.tunnels, tunnel)
+}
+
+// handleConnection performs the handshake with the switch on the
+// connection and serves the switch until the connection is lost or the
+// context is done. It returns false if the handshake failed.
+func (oc *ofpControllerImpl) handleConnection(ctx context.Context, conn net.Conn) bool {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// Complete the tls handshake before any message is exchanged,
+		// so that the peer certificate is verified in time
+		tlsConn.SetDeadline(time.Now().Add(defaultHandshakeTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			log.Warnf("TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			return false
+		}
+		tlsConn.SetDeadline(time.Time{})
+	}
 	msgStream := NewOfpMsgTunnel(conn)
-	hello := ofpgeneral.NewHelloMsg(4)
-	msgStream.Outgoing <- hello
+	defer msgStream.Close()
+	if !oc.addTunnel(msgStream) {
+		return false
+	}
+	defer oc.removeTunnel(msgStream)
+
+	// Close the connection once the context is done
+	released := make(chan struct{})
+	defer close(released)
+	go func() {
+		select {
+		case <-ctx.Done():
+			msgStream.Close()
+		case <-released:
+		}
+	}()
+
+	versions := oc.getVersions()
+	hello := ofpgeneral.NewHelloMsgWithVersions(versions...)
+	msgStream.Send(hello)
 	for {
 		select {
 		case msg := <-msgStream.Incomming:
 			switch m := msg.(type) {
 			case *ofpgeneral.OfpHelloMsg:
 				log.Debugf("Hello message %+v is received", m)
-				version, _ := ofpgeneral.GetOfpMsgVersion(m)
-				if isVersionValid(version) {
-					msgStream.Version = version
-					msgStream.SendFeatureRequest()
-				} else {
+				version, peerVersions, err := negotiateVersion(versions, m)
+				if err != nil {
 					// Connection should be severed if controller
 					// doesn't support switch version.
-					log.Println("Received unsupported ofp version", version)
-					msgStream.Shutdown <- true
+					log.Println(err)
+					msgStream.Send(ofpgeneral.NewErrMsg(m.Header.Version,
+						ofpgeneral.OfpErrTypeHelloFailed, ofpgeneral.OfpHelloFailedCodeIncompatible,
+						[]byte(err.Error())))
+					return false
 				}
+				if err := msgStream.SetVersion(version); err != nil {
+					log.Println(err)
+					return false
+				}
+				msgStream.peerVersions = peerVersions
+				msgStream.SendFeatureRequest()
 
 			// After a vaild FeaturesReply has been received we
 			// have all the information we need. Create a new
 			// switch object and notify applications.
-			case *ofp10.OfpSwitchFeatureMsg:
-				log.Printf("Received ofp1.0 Switch feature response: %+v", *m)
+			case *ofp10.OfpSwitchFeatureMsg, *ofp11.OfpSwitchFeatureMsg, *ofp13.OfpSwitchFeatureMsg:
+				log.Printf("Received Switch feature response: %+v", m)
 
 				// Create a new switch and handover the stream
-				NewSwitch(msgStream, m)
+				sw, err := NewSwitch(msgStream, m)
+				if err != nil {
+					log.Warnln(err)
+					return false
+				}
 
 				// Let switch instance handle all future messages..
-				return
+				oc.serveSwitch(sw.(*openflowSwitchImpl))
+				return true
 
 			// An error message may indicate a version mismatch. We
 			// disconnect if an error occurs this early.
 			case *ofpgeneral.OfpErrMsg:
 				log.Warnf("Received  error msg: %+v", *m)
-				msgStream.Shutdown <- true
+				return false
+
+			case *ofpgeneral.OfpEchoMsg:
+				if m.Header.Type == ofpgeneral.OfpTypeEchoRequest {
+					msgStream.Send(ofpgeneral.NewEchoReplyMsg(m))
+				}
 			}
 		case err := <-msgStream.Error:
 			// The connection has been shutdown.
 			log.Println(err)
-			return
-		case <-time.After(time.Second * 3):
+			return false
+		case <-time.After(defaultHandshakeTimeout):
 			// This shouldn't happen. If it does, both the controller
-			// and switch are no longer communicating. The TCPConn is
+			// and switch are no longer communicating. The connection is
 			// still established though.
 			log.Warnln("Connection timed out.")
+			return false
+		}
+	}
+}
+
+// serveSwitch is the dispatch loop of a connected switch. It takes over
+// the message tunnel after the handshake and delivers the switch events
+// to the registered applications until the connection is lost.
+func (oc *ofpControllerImpl) serveSwitch(sw *openflowSwitchImpl) {
+	msgStream := sw.tunnel
+	dpid := sw.GetDatapathID().GetRawValue()
+	oc.lock.Lock()
+	oc.switches[dpid] = sw
+	oc.lock.Unlock()
+
+	if sw.version >= ofp13.Version {
+		if err := sw.requestPortDesc(); err != nil {
+			log.Warnf("Failed to request the ports of switch %s: %v", sw.GetDatapathID().GetHwAddr(), err)
+		}
+	}
+	oc.applyRole(sw)
+	oc.notifyConnected(sw)
+	defer func() {
+		oc.lock.Lock()
+		if oc.switches[dpid] == sw {
+			delete(oc.switches, dpid)
+		}
+		oc.lock.Unlock()
+		sw.disconnected()
+		oc.notifyDisconnected(sw)
+	}()
+
+	var probeTimer <-chan time.Time
+	echoInterval, echoMaxMisses := oc.getEchoInterval()
+	if echoInterval > 0 {
+		ticker := time.NewTicker(echoInterval)
+		defer ticker.Stop()
+		probeTimer = ticker.C
+	}
+
+	for {
+		select {
+		case msg := <-msgStream.Incomming:
+			sw.msgReceived()
+			switch m := msg.(type) {
+			case *ofpgeneral.OfpEchoMsg:
+				sw.handleEcho(m)
+			case *ofp10.OfpPacketInMsg:
+				oc.notifyPacketRcvd(sw, m)
+			case *ofp10.OfpPortStatusMsg:
+				sw.updatePort(m.Reason, newSwitchPortV10(&m.Desc))
+			case *ofp11.OfpPacketInMsg:
+				oc.notifyPacketRcvd(sw, m)
+			case *ofp12.OfpPacketInMsg:
+				oc.notifyPacketRcvd(sw, m)
+			case *ofp13.OfpPacketInMsg:
+				oc.notifyPacketRcvd(sw, m)
+			case *ofp13.OfpPortStatusMsg:
+				sw.updatePort(m.Reason, newSwitchPortV13(&m.Desc))
+			case *ofp14.OfpPortStatusMsg:
+				sw.updatePort(m.Reason, newSwitchPortV14(&m.Desc))
+			case *ofp14.OfpRoleStatusMsg:
+				sw.updateRole(m.Role, m.GenerationID)
+				oc.notifyRoleStatus(sw, m.Role, m.GenerationID)
+			case *ofp15.OfpPortStatusMsg:
+				sw.updatePort(m.Reason, newSwitchPortV15(&m.Desc))
+			case *ofp15.OfpControllerStatusMsg:
+				oc.notifyControllerStatus(sw, &m.Status)
+			case *ofp13.OfpMultipartReplyMsg:
+				if sw.version >= ofp14.Version && m.Type == ofp14.OfpMultipartTypeFlowMonitor {
+					sw.handleFlowUpdates(m)
+				} else {
+					log.Debugf("Received message %+v from switch %s", m, sw.GetDatapathID().GetHwAddr())
+				}
+			default:
+				log.Debugf("Received message %+v from switch %s", m, sw.GetDatapathID().GetHwAddr())
+			}
+		case <-probeTimer:
+			if !sw.probe(echoInterval, echoMaxMisses) {
+				log.Warnf("Switch %s doesn't respond to %d echo requests, disconnecting",
+					sw.GetDatapathID().GetHwAddr(), echoMaxMisses)
+				sw.Close()
+			}
+		case err := <-msgStream.Error:
+			log.Infof("Switch %s disconnected: %v", sw.GetDatapathID().GetHwAddr(), err)
 			return
 		}
 	}
 }
 
+// getApps returns a snapshot of the registered applications
+func (oc *ofpControllerImpl) getApps() []OFApplication {
+	oc.lock.RLock()
+	defer oc.lock.RUnlock()
+	apps := make([]OFApplication, len(oc.apps))
+	copy(apps, oc.apps)
+	return apps
+}
+
+func (oc *ofpControllerImpl) notifyConnected(sw OpenflowSwitch) {
+	for _, app := range oc.getApps() {
+		app.Connected(sw)
+	}
+}
+
+func (oc *ofpControllerImpl) notifyDisconnected(sw OpenflowSwitch) {
+	for _, app := range oc.getApps() {
+		app.Disconnected(sw)
+	}
+}
+
+func (oc *ofpControllerImpl) notifyPacketRcvd(sw OpenflowSwitch, msg OfpPacketInMsg) {
+	for _, app := range oc.getApps() {
+		app.PacketRcvd(sw, msg)
+	}
+}
+
+// negotiateVersion picks the openflow version used on the connection
+// from the versions offered by the controller and the peer hello. It
+// returns the negotiated version and the versions supported by the peer.
+func negotiateVersion(versions []uint8, hello *ofpgeneral.OfpHelloMsg) (uint8, []uint8, error) {
+	if peerVersions, ok := hello.GetVersions(); ok {
+		// Both sides sent the version bitmap, the highest version
+		// in both bitmaps is used
+		for i := len(versions) - 1; i >= 0; i-- {
+			if containsVersion(peerVersions, versions[i]) {
+				return versions[i], peerVersions, nil
+			}
+		}
+		return 0, nil, fmt.Errorf("No common version in the bitmaps, controller: %v, switch: %v",
+			versions, peerVersions)
+	}
+	// The peer doesn't send the bitmap, the smaller version
+	// in the header of both hello messages is used
+	version := versions[len(versions)-1]
+	if hello.Header.Version < version {
+		version = hello.Header.Version
+	}
+	if !containsVersion(versions, version) {
+		return 0, nil, fmt.Errorf("Received unsupported ofp version %d", hello.Header.Version)
+	}
+	return version, []uint8{version}, nil
+}
+
+func containsVersion(versions []uint8, version uint8) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 func isVersionValid(v uint8) bool {
-	return v > 0 && v < ofp15.Version
+	return containsVersion(codecVersions, v)
 }
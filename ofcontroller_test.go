@@ -0,0 +1,501 @@
+package goof
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// readTestMsg reads the next openflow message on behalf of the switch
+func readTestMsg(r io.Reader) ([]byte, error) {
+	msg := make([]byte, ofpHeaderLen)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	msg = append(msg, make([]byte, length-ofpHeaderLen)...)
+	_, err := io.ReadFull(r, msg[ofpHeaderLen:])
+	return msg, err
+}
+
+// writeTestMsg writes the message to the controller on behalf of the switch
+func writeTestMsg(t testing.TB, w io.Writer, msg ofpgeneral.OfpMessage) {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Error(err)
+	}
+}
+
+// newTestController creates the controller whose background work is
+// checked for leaks once the test is over
+func newTestController(t *testing.T) *ofpControllerImpl {
+	goroutines := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		checkGoroutines(t, goroutines)
+	})
+	ctrler, err := NewOfpController()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctrler.(*ofpControllerImpl)
+}
+
+// checkGoroutines waits for the goroutines started since there were the
+// given number of them to exit
+func checkGoroutines(t *testing.T, goroutines int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines are leaked:\n%s", runtime.NumGoroutine()-goroutines, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// freeTestPort returns a local tcp port which isn't listened on
+func freeTestPort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// runTestServe runs the serve function in the background, the returned
+// channel receives its result
+func runTestServe(serve func() error) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- serve()
+	}()
+	return result
+}
+
+// checkServeReturned checks the serve function run by runTestServe
+// returned without error
+func checkServeReturned(t *testing.T, result <-chan error) {
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The controller doesn't return")
+	}
+}
+
+// dialTestSwitch connects to the controller listening on the address and
+// waits for its hello, the connection plays the switch
+func dialTestSwitch(t *testing.T, address string) net.Conn {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 100; i++ {
+		if conn, err = net.Dial("tcp", address); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if msg, err := readTestMsg(conn); err != nil || msg[1] != ofpgeneral.OfpTypeHello {
+		t.Fatalf("Expected the hello, got %x %v", msg, err)
+	}
+	return conn
+}
+
+// checkClosed checks the peer closed the connection
+func checkClosed(t *testing.T, conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, err := readTestMsg(conn); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				t.Fatal("The connection isn't closed")
+			}
+			return
+		}
+	}
+}
+
+func TestStartContextCancel(t *testing.T) {
+	oc := newTestController(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runTestServe(func() error { return oc.Start(ctx, 0) })
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	checkServeReturned(t, result)
+}
+
+func TestServeContextCancel(t *testing.T) {
+	oc := newTestController(t)
+	address := fmt.Sprintf("127.0.0.1:%d", freeTestPort(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runTestServe(func() error { return oc.Serve(ctx, "ptcp:"+address[len("127.0.0.1:"):]+":127.0.0.1") })
+	conn := dialTestSwitch(t, address)
+	defer conn.Close()
+
+	// The connection in handshake is closed and the controller is stopped
+	cancel()
+	checkServeReturned(t, result)
+	checkClosed(t, conn)
+	select {
+	case <-oc.stopChan:
+	default:
+		t.Error("The controller isn't stopped")
+	}
+
+	// The listener is released, the address can be listened on again
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+}
+
+func TestStop(t *testing.T) {
+	oc := newTestController(t)
+	port := freeTestPort(t)
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+	unixPath := fmt.Sprintf("%s/goof.sock", t.TempDir())
+	result := runTestServe(func() error {
+		return oc.Serve(context.Background(), fmt.Sprintf("ptcp:%d:127.0.0.1", port), "punix:"+unixPath)
+	})
+	conn := dialTestSwitch(t, address)
+	defer conn.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		oc.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop doesn't return")
+	}
+	checkServeReturned(t, result)
+	checkClosed(t, conn)
+	// Stop can be called again
+	oc.Stop()
+
+	// A stopped controller returns at once and releases the listener
+	checkServeReturned(t, runTestServe(func() error { return oc.Start(context.Background(), port) }))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	if _, err := net.Dial("unix", unixPath); err == nil {
+		t.Error("The unix socket is still listened on")
+	}
+}
+
+// TestStopWhileStarting checks the controller stopped while it starts
+// serving returns in both orders
+func TestStopWhileStarting(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		oc := newTestController(t)
+		result := runTestServe(func() error { return oc.Start(context.Background(), 0) })
+		oc.Stop()
+		checkServeReturned(t, result)
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		versions     []uint8
+		hello        *ofpgeneral.OfpHelloMsg
+		version      uint8
+		peerVersions []uint8
+	}{
+		{"highest common version of the bitmaps", []uint8{ofp10.Version, ofp13.Version, ofp14.Version},
+			ofpgeneral.NewHelloMsgWithVersions(ofp10.Version, ofp13.Version, ofp15.Version), ofp13.Version,
+			[]uint8{ofp10.Version, ofp13.Version, ofp15.Version}},
+		{"bitmap below the header version", []uint8{ofp10.Version, ofp14.Version},
+			ofpgeneral.NewHelloMsgWithVersions(ofp10.Version, ofp15.Version), ofp10.Version,
+			[]uint8{ofp10.Version, ofp15.Version}},
+		{"lower header version without bitmap", []uint8{ofp10.Version, ofp13.Version, ofp14.Version},
+			ofpgeneral.NewHelloMsg(ofp13.Version), ofp13.Version, []uint8{ofp13.Version}},
+		{"higher header version without bitmap", []uint8{ofp10.Version, ofp13.Version},
+			ofpgeneral.NewHelloMsg(ofp15.Version), ofp13.Version, []uint8{ofp13.Version}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			version, peerVersions, err := negotiateVersion(tc.versions, tc.hello)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != tc.version || fmt.Sprint(peerVersions) != fmt.Sprint(tc.peerVersions) {
+				t.Errorf("Negotiated the version %d with %v, expected %d with %v", version, peerVersions,
+					tc.version, tc.peerVersions)
+			}
+		})
+	}
+
+	failures := []struct {
+		name     string
+		versions []uint8
+		hello    *ofpgeneral.OfpHelloMsg
+	}{
+		{"no common version in the bitmaps", []uint8{ofp13.Version, ofp14.Version},
+			ofpgeneral.NewHelloMsgWithVersions(ofp11.Version, ofp12.Version)},
+		{"unsupported header version", []uint8{ofp10.Version, ofp13.Version}, ofpgeneral.NewHelloMsg(ofp12.Version)},
+		{"header version below the versions", []uint8{ofp13.Version, ofp14.Version}, ofpgeneral.NewHelloMsg(ofp10.Version)},
+	}
+	for _, tc := range failures {
+		if version, _, err := negotiateVersion(tc.versions, tc.hello); err == nil {
+			t.Errorf("%s: the version %d is negotiated", tc.name, version)
+		}
+	}
+}
+
+// runTestConnection handles the connection of the controller end of a
+// pipe in the background, the other end plays the switch and has
+// received the hello of the controller
+func runTestConnection(t *testing.T, oc *ofpControllerImpl) (net.Conn, <-chan bool) {
+	controllerConn, switchConn := net.Pipe()
+	t.Cleanup(func() { switchConn.Close() })
+	result := make(chan bool, 1)
+	go func() {
+		result <- oc.handleConnection(context.Background(), controllerConn)
+	}()
+	switchConn.SetDeadline(time.Now().Add(2 * time.Second))
+	msg, err := readTestMsg(switchConn)
+	if err != nil || msg[1] != ofpgeneral.OfpTypeHello {
+		t.Fatalf("Expected the hello, got %x %v", msg, err)
+	}
+	hello := &ofpgeneral.OfpHelloMsg{}
+	if err := hello.UnmarshalBinary(msg); err != nil {
+		t.Fatal(err)
+	}
+	if versions, ok := hello.GetVersions(); !ok || fmt.Sprint(versions) != fmt.Sprint(oc.getVersions()) {
+		t.Fatalf("The hello advertises the versions %v, expected %v", versions, oc.getVersions())
+	}
+	return switchConn, result
+}
+
+// checkConnectionResult checks the connection handled by
+// runTestConnection is released with the expected result
+func checkConnectionResult(t *testing.T, result <-chan bool, expected bool) {
+	select {
+	case handled := <-result:
+		if handled != expected {
+			t.Errorf("The connection is handled with %t, expected %t", handled, expected)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The connection isn't released")
+	}
+}
+
+func TestHandleConnectionVersionBitmap(t *testing.T) {
+	oc := newTestController(t)
+	if err := oc.SetSupportedVersions(ofp10.Version, ofp13.Version); err != nil {
+		t.Fatal(err)
+	}
+	conn, result := runTestConnection(t, oc)
+	writeTestMsg(t, conn, ofpgeneral.NewHelloMsgWithVersions(ofp10.Version, ofp13.Version, ofp14.Version))
+
+	// The features request is sent in the negotiated version
+	msg, err := readTestMsg(conn)
+	if err != nil || msg[0] != ofp13.Version || msg[1] != ofp13.OfpTypeFeaturesRequest {
+		t.Fatalf("Expected the openflow 1.3 features request, got %x %v", msg, err)
+	}
+	conn.Close()
+	checkConnectionResult(t, result, false)
+}
+
+func TestHandleConnectionHelloFailed(t *testing.T) {
+	oc := newTestController(t)
+	if err := oc.SetSupportedVersions(ofp13.Version); err != nil {
+		t.Fatal(err)
+	}
+	conn, result := runTestConnection(t, oc)
+	writeTestMsg(t, conn, ofpgeneral.NewHelloMsgWithVersions(ofp10.Version))
+
+	// The switch is told there is no common version before the
+	// connection is closed
+	msg, err := readTestMsg(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errMsg := &ofpgeneral.OfpErrMsg{}
+	if err := errMsg.UnmarshalBinary(msg); err != nil {
+		t.Fatal(err)
+	}
+	if errMsg.Header.Type != ofpgeneral.OfpTypeError || errMsg.Header.Version != ofp10.Version ||
+		errMsg.Type != ofpgeneral.OfpErrTypeHelloFailed || errMsg.Code != ofpgeneral.OfpHelloFailedCodeIncompatible {
+		t.Fatalf("Unexpected hello failed error %+v", errMsg)
+	}
+	checkConnectionResult(t, result, false)
+	checkClosed(t, conn)
+}
+
+// testApp records the switch events delivered by the controller
+type testApp struct {
+	connected    chan OpenflowSwitch
+	disconnected chan OpenflowSwitch
+}
+
+func newTestApp() *testApp {
+	return &testApp{connected: make(chan OpenflowSwitch, 4), disconnected: make(chan OpenflowSwitch, 4)}
+}
+
+func (app *testApp) Connected(sw OpenflowSwitch) {
+	app.connected <- sw
+}
+
+func (app *testApp) Disconnected(sw OpenflowSwitch) {
+	app.disconnected <- sw
+}
+
+func (app *testApp) PacketRcvd(sw OpenflowSwitch, msg OfpPacketInMsg) {}
+
+// waitTestEvent waits for the switch event of the channel
+func waitTestEvent(t *testing.T, events <-chan OpenflowSwitch, name string) OpenflowSwitch {
+	select {
+	case sw := <-events:
+		return sw
+	case <-time.After(2 * time.Second):
+		t.Fatalf("The switch isn't %s", name)
+	}
+	return nil
+}
+
+// handshakeTestSwitch completes the openflow 1.0 handshake on behalf of
+// the switch which received the hello of the controller
+func handshakeTestSwitch(t *testing.T, conn net.Conn) {
+	writeTestMsg(t, conn, ofpgeneral.NewHelloMsg(ofp10.Version))
+	msg, err := readTestMsg(conn)
+	if err != nil || msg[1] != ofp10.OfpTypeFeaturesRequest {
+		t.Fatalf("Expected the features request, got %x %v", msg, err)
+	}
+	features := &ofp10.OfpSwitchFeatureMsg{Header: *ofpgeneral.NewOfpHeader(ofp10.Version), DatapathID: 1}
+	features.Header.Type = ofp10.OfpTypeFeaturesReply
+	features.Header.Length = 32
+	features.Header.Xid = binary.BigEndian.Uint32(msg[4:8])
+	writeTestMsg(t, conn, features)
+}
+
+// TestStopConnectedSwitch checks Stop closes the connection of a switch
+// served by the dispatch loop and waits for its disconnection
+func TestStopConnectedSwitch(t *testing.T) {
+	oc := newTestController(t)
+	oc.SetEchoInterval(0, 0)
+	app := newTestApp()
+	oc.RegisterApp(app)
+	port := freeTestPort(t)
+	result := runTestServe(func() error { return oc.Start(context.Background(), port) })
+	conn := dialTestSwitch(t, fmt.Sprintf("127.0.0.1:%d", port))
+	defer conn.Close()
+	handshakeTestSwitch(t, conn)
+	waitTestEvent(t, app.connected, "connected")
+
+	oc.Stop()
+	// The disconnection is notified before Stop returns
+	select {
+	case <-app.disconnected:
+	default:
+		t.Error("The switch isn't disconnected")
+	}
+	checkServeReturned(t, result)
+	checkClosed(t, conn)
+}
+
+// runTestRefusingSwitch accepts the connections and closes them at once,
+// so that the tls handshakes of the controller fail. The time of each
+// connection attempt is sent to the returned channel.
+func runTestRefusingSwitch(t *testing.T) (string, <-chan time.Time) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	attempts := make(chan time.Time, 16)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			attempts <- time.Now()
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), attempts
+}
+
+// waitTestAttempt waits for the next connection attempt
+func waitTestAttempt(t *testing.T, attempts <-chan time.Time) time.Time {
+	select {
+	case attempt := <-attempts:
+		return attempt
+	case <-time.After(2 * time.Second):
+		t.Fatal("The connection isn't retried")
+	}
+	return time.Time{}
+}
+
+func TestConnectBackoff(t *testing.T) {
+	for _, stopBy := range []string{"context", "stop"} {
+		t.Run(stopBy, func(t *testing.T) {
+			oc := newTestController(t)
+			oc.SetTLSConfig(&tls.Config{})
+			oc.minBackoff, oc.maxBackoff = 20*time.Millisecond, 80*time.Millisecond
+			address, attempts := runTestRefusingSwitch(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			result := runTestServe(func() error { return oc.Connect(ctx, "ssl:"+address) })
+
+			// The failed dials are retried after the doubled backoff,
+			// until the max backoff
+			last := waitTestAttempt(t, attempts)
+			for _, backoff := range []time.Duration{20, 40, 80, 80} {
+				backoff *= time.Millisecond
+				attempt := waitTestAttempt(t, attempts)
+				if delay := attempt.Sub(last); delay < backoff || delay > backoff+time.Second {
+					t.Fatalf("The connection is retried after %v, expected %v", delay, backoff)
+				}
+				last = attempt
+			}
+
+			if stopBy == "context" {
+				cancel()
+			} else {
+				oc.Stop()
+			}
+			checkServeReturned(t, result)
+			// Drain the attempt which may be in progress
+			select {
+			case <-attempts:
+			case <-time.After(200 * time.Millisecond):
+			}
+			select {
+			case <-attempts:
+				t.Error("The connection is retried once the controller is stopped")
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package goof
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultOfpPort is the port assigned to openflow by IANA, it is
+	// used when the port is omitted in the target
+	defaultOfpPort = "6653"
+
+	schemeTCP  = "tcp"
+	schemeSSL  = "ssl"
+	schemeUnix = "unix"
+)
+
+// parseTarget splits the target in the form used by OVS, tcp:host[:port],
+// ssl:host[:port] or unix:path, into the scheme and the address
+func parseTarget(target string) (string, string, error) {
+	fields := strings.SplitN(target, ":", 2)
+	if len(fields) != 2 || fields[1] == "" {
+		return "", "", fmt.Errorf("Invalid target %s", target)
+	}
+	scheme, address := fields[0], fields[1]
+	switch scheme {
+	case schemeTCP, schemeSSL:
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			host, port = strings.Trim(address, "[]"), defaultOfpPort
+			address = net.JoinHostPort(host, port)
+		}
+		if host == "" || !isPortValid(port) {
+			return "", "", fmt.Errorf("Invalid target %s", target)
+		}
+	case schemeUnix:
+	default:
+		return "", "", fmt.Errorf("Unsupported scheme %s in target %s", scheme, target)
+	}
+	return scheme, address, nil
+}
+
+// parseListenTarget splits the passive target in the form used by OVS,
+// ptcp:[port][:ip], pssl:[port][:ip] or punix:path, into the scheme of
+// the connections and the listening address
+func parseListenTarget(target string) (string, string, error) {
+	fields := strings.SplitN(target, ":", 2)
+	if len(fields) != 2 || !strings.HasPrefix(fields[0], "p") {
+		return "", "", fmt.Errorf("Invalid listen target %s", target)
+	}
+	scheme, address := strings.TrimPrefix(fields[0], "p"), fields[1]
+	switch scheme {
+	case schemeTCP, schemeSSL:
+		portAndIP := strings.SplitN(address, ":", 2)
+		port, ip := portAndIP[0], ""
+		if port == "" {
+			port = defaultOfpPort
+		}
+		if len(portAndIP) == 2 {
+			ip = strings.Trim(portAndIP[1], "[]")
+		}
+		if !isPortValid(port) {
+			return "", "", fmt.Errorf("Invalid listen target %s", target)
+		}
+		address = net.JoinHostPort(ip, port)
+	case schemeUnix:
+		if address == "" {
+			return "", "", fmt.Errorf("Invalid listen target %s", target)
+		}
+	default:
+		return "", "", fmt.Errorf("Unsupported scheme %s in listen target %s", fields[0], target)
+	}
+	return scheme, address, nil
+}
+
+// isPortValid checks the port is a tcp port number
+func isPortValid(port string) bool {
+	_, err := strconv.ParseUint(port, 10, 16)
+	return err == nil
+}
+
+// listenTarget listens on the address, the accepted connections
+// are secured by tls for the ssl scheme
+func listenTarget(scheme, address string, config *tls.Config) (net.Listener, error) {
+	switch scheme {
+	case schemeSSL:
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		return tls.NewListener(listener, config), nil
+	case schemeUnix:
+		return net.Listen("unix", address)
+	default:
+		return net.Listen("tcp", address)
+	}
+}
+
+// dialTarget connects to the address, the tls handshake is completed
+// before it returns for the ssl scheme
+func dialTarget(ctx context.Context, scheme, address string, config *tls.Config) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: defaultHandshakeTimeout}
+	switch scheme {
+	case schemeSSL:
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: switchTLSConfig(config)}
+		return tlsDialer.DialContext(ctx, "tcp", address)
+	case schemeUnix:
+		return dialer.DialContext(ctx, "unix", address)
+	default:
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+}
+
+// switchTLSConfig returns the configuration verifying the certificate of
+// the switch connected to. The certificates of the switches usually don't
+// carry their addresses, like the ones of OVS, so unless the server name
+// is configured only the chain of the certificate is verified against
+// the root CAs, the same way as the switches verify the controller.
+func switchTLSConfig(config *tls.Config) *tls.Config {
+	if config.ServerName != "" || config.InsecureSkipVerify {
+		return config
+	}
+	config = config.Clone()
+	// The chain is verified below without the host name
+	config.InsecureSkipVerify = true
+	verify := config.VerifyPeerCertificate
+	roots := config.RootCAs
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, rawCert := range rawCerts {
+			cert, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+		if len(certs) == 0 {
+			return fmt.Errorf("The switch presents no certificate")
+		}
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		chains, err := certs[0].Verify(opts)
+		if err != nil {
+			return err
+		}
+		if verify != nil {
+			return verify(rawCerts, chains)
+		}
+		return nil
+	}
+	return config
+}
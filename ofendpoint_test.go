@@ -0,0 +1,87 @@
+package goof
+
+import "testing"
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		scheme  string
+		address string
+	}{
+		{"tcp:192.168.0.1:6633", schemeTCP, "192.168.0.1:6633"},
+		{"tcp:192.168.0.1", schemeTCP, "192.168.0.1:6653"},
+		{"ssl:switch.example.com:6653", schemeSSL, "switch.example.com:6653"},
+		{"ssl:switch.example.com", schemeSSL, "switch.example.com:6653"},
+		{"tcp:[fe80::1]:6633", schemeTCP, "[fe80::1]:6633"},
+		{"tcp:[fe80::1]", schemeTCP, "[fe80::1]:6653"},
+		{"ssl:fe80::1", schemeSSL, "[fe80::1]:6653"},
+		{"unix:/var/run/openvswitch/br0.mgmt", schemeUnix, "/var/run/openvswitch/br0.mgmt"},
+	}
+	for _, tc := range tests {
+		scheme, address, err := parseTarget(tc.target)
+		if err != nil {
+			t.Errorf("%s: %v", tc.target, err)
+			continue
+		}
+		if scheme != tc.scheme || address != tc.address {
+			t.Errorf("%s is parsed into %s %s, expected %s %s", tc.target, scheme, address, tc.scheme, tc.address)
+		}
+	}
+
+	for _, target := range []string{
+		"",
+		"tcp",
+		"tcp:",
+		"unix:",
+		"tcp::6653",
+		"tcp:192.168.0.1:openflow",
+		"tcp:192.168.0.1:65536",
+		"ptcp:6653",
+		"udp:192.168.0.1:6653",
+	} {
+		if scheme, address, err := parseTarget(target); err == nil {
+			t.Errorf("%q is parsed into %s %s", target, scheme, address)
+		}
+	}
+}
+
+func TestParseListenTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		scheme  string
+		address string
+	}{
+		{"ptcp:", schemeTCP, ":6653"},
+		{"ptcp:6633", schemeTCP, ":6633"},
+		{"ptcp:6633:127.0.0.1", schemeTCP, "127.0.0.1:6633"},
+		{"ptcp::127.0.0.1", schemeTCP, "127.0.0.1:6653"},
+		{"pssl:6653:[::1]", schemeSSL, "[::1]:6653"},
+		{"pssl:0:::1", schemeSSL, "[::1]:0"},
+		{"punix:/var/run/goof.sock", schemeUnix, "/var/run/goof.sock"},
+	}
+	for _, tc := range tests {
+		scheme, address, err := parseListenTarget(tc.target)
+		if err != nil {
+			t.Errorf("%s: %v", tc.target, err)
+			continue
+		}
+		if scheme != tc.scheme || address != tc.address {
+			t.Errorf("%s is parsed into %s %s, expected %s %s", tc.target, scheme, address, tc.scheme, tc.address)
+		}
+	}
+
+	for _, target := range []string{
+		"",
+		"ptcp",
+		"punix:",
+		"tcp:6653",
+		"ptcp:openflow",
+		"ptcp:65536:127.0.0.1",
+		"pudp:6653",
+		"p:6653",
+	} {
+		if scheme, address, err := parseListenTarget(target); err == nil {
+			t.Errorf("%q is parsed into %s %s", target, scheme, address)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package goof
+
+import (
+	"context"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// FlowMod describes the modification of the flows regardless of the
+// openflow version, it is translated by Encode to the flow mod of the
+// version negotiated with the switch
+type FlowMod struct {
+	Command     uint8  /* One of ofp13.OfpFlowModCmd*. */
+	TableID     uint8  /* Must be zero, or OFPTT_ALL to delete, for openflow 1.0. */
+	Cookie      uint64 /* Opaque controller-issued identifier. */
+	CookieMask  uint64 /* Mask restricting the cookie bits of the modified flows. */
+	IdleTimeout uint16 /* Idle time before discarding (seconds). */
+	HardTimeout uint16 /* Max time before discarding (seconds). */
+	Priority    uint16 /* Priority level of flow entry. */
+	BufferID    uint32 /* Buffered packet to apply to, or OFP_NO_BUFFER. */
+	OutPort     uint32 /* Output port restriction of the delete commands, or OFPP_ANY. */
+	OutGroup    uint32 /* Output group restriction of the delete commands, or OFPG_ANY. */
+	Flags       uint16 /* Bitmap of ofp13.OfpFlowFlag* flags. */
+	Match       Match  /* Fields to match. */
+
+	// Actions are applied to the matched packets immediately
+	Actions []Action
+	// WriteActions are merged into the action set of the packets
+	WriteActions []Action
+	// GotoTable moves the packets to the next table unless it's zero
+	GotoTable uint8
+	// MeterID applies the meter to the packets unless it's zero
+	MeterID uint32
+}
+
+// NewFlowMod creates the flow mod of the command in the table, it matches
+// all the packets and applies no buffered packet, out port and out group
+// restriction
+func NewFlowMod(command uint8, tableID uint8) *FlowMod {
+	return &FlowMod{
+		Command:  command,
+		TableID:  tableID,
+		BufferID: ofp13.OfpNoBuffer,
+		OutPort:  ofp13.OfpPortAny,
+		OutGroup: ofp13.OfpGroupAny,
+	}
+}
+
+// flowModFlags holds the flow mod flags known by the openflow versions
+// before 1.3, which knows all of them
+var flowModFlags = map[uint8]uint16{
+	ofp10.Version: ofp13.OfpFlowFlagSendFlowRemove | ofp13.OfpFlowFlagCheckOverlap,
+	ofp11.Version: ofp13.OfpFlowFlagSendFlowRemove | ofp13.OfpFlowFlagCheckOverlap,
+	ofp12.Version: ofp13.OfpFlowFlagSendFlowRemove | ofp13.OfpFlowFlagCheckOverlap | ofp13.OfpFlowFlagResetCounts,
+}
+
+// Encode translates the flow mod to the flow mod of the openflow version,
+// a NotExpressibleError is returned if the version lacks a feature used
+func (fm *FlowMod) Encode(version uint8) (ofpgeneral.OfpMessage, error) {
+	if known, ok := flowModFlags[version]; ok && fm.Flags&^known != 0 {
+		return nil, notExpressible(version, "flow mod flags %#x", fm.Flags&^known)
+	}
+	switch version {
+	case ofp10.Version:
+		return fm.encodeV10()
+	case ofp11.Version:
+		match, err := fm.Match.encodeV11()
+		if err != nil {
+			return nil, err
+		}
+		instructions, err := fm.encodeInstructions(version)
+		if err != nil {
+			return nil, err
+		}
+		msg := ofp11.NewFlowModMsg(fm.Command, fm.TableID)
+		msg.Cookie, msg.CookieMask = fm.Cookie, fm.CookieMask
+		msg.IdleTimeout, msg.HardTimeout, msg.Priority = fm.IdleTimeout, fm.HardTimeout, fm.Priority
+		msg.BufferID, msg.OutPort, msg.OutGroup, msg.Flags = fm.BufferID, fm.OutPort, fm.OutGroup, fm.Flags
+		msg.Match = *match
+		msg.Instructions = instructions
+		return msg, nil
+	}
+	// The flow mod is unchanged since openflow 1.2
+	match, err := fm.Match.encodeOxm(version)
+	if err != nil {
+		return nil, err
+	}
+	instructions, err := fm.encodeInstructions(version)
+	if err != nil {
+		return nil, err
+	}
+	msg := ofp13.NewFlowModMsg(fm.Command, fm.TableID)
+	msg.Header.Version = version
+	msg.Cookie, msg.CookieMask = fm.Cookie, fm.CookieMask
+	msg.IdleTimeout, msg.HardTimeout, msg.Priority = fm.IdleTimeout, fm.HardTimeout, fm.Priority
+	msg.BufferID, msg.OutPort, msg.OutGroup, msg.Flags = fm.BufferID, fm.OutPort, fm.OutGroup, fm.Flags
+	msg.Match = *match
+	msg.Instructions = instructions
+	return msg, nil
+}
+
+// encodeV10 translates the flow mod to openflow 1.0, which has a single
+// table and no instruction
+func (fm *FlowMod) encodeV10() (ofpgeneral.OfpMessage, error) {
+	isDelete := fm.Command == ofp13.OfpFlowModCmdDelete || fm.Command == ofp13.OfpFlowModCmdDeleteStrict
+	switch {
+	case fm.TableID != 0 && !(isDelete && fm.TableID == ofp13.OfpTableAll):
+		return nil, notExpressible(ofp10.Version, "flow table %d", fm.TableID)
+	case fm.CookieMask != 0:
+		return nil, notExpressible(ofp10.Version, "cookie mask")
+	case fm.OutGroup != ofp13.OfpGroupAny:
+		return nil, notExpressible(ofp10.Version, "out group restriction")
+	case len(fm.WriteActions) > 0:
+		return nil, notExpressible(ofp10.Version, "write actions instruction")
+	case fm.GotoTable != 0:
+		return nil, notExpressible(ofp10.Version, "goto table instruction")
+	case fm.MeterID != 0:
+		return nil, notExpressible(ofp10.Version, "meter instruction")
+	}
+	match, err := fm.Match.encodeV10()
+	if err != nil {
+		return nil, err
+	}
+	actions, err := encodeActionsV10(fm.Actions)
+	if err != nil {
+		return nil, err
+	}
+	outPort, err := portV10(fm.OutPort)
+	if err != nil {
+		return nil, err
+	}
+	msg := ofp10.NewFlowModMsg(uint16(fm.Command))
+	msg.Match = *match
+	msg.Cookie = fm.Cookie
+	msg.IdleTimeout, msg.HardTimeout, msg.Priority = fm.IdleTimeout, fm.HardTimeout, fm.Priority
+	msg.BufferID, msg.OutPort, msg.Flags = fm.BufferID, outPort, fm.Flags
+	msg.Actions = actions
+	return msg, nil
+}
+
+// encodeInstructions translates the actions, the goto table and the meter
+// to the instructions of openflow 1.1 and the later versions
+func (fm *FlowMod) encodeInstructions(version uint8) ([]ofp13.OfpInstruction, error) {
+	instructions := make([]ofp13.OfpInstruction, 0)
+	if fm.MeterID != 0 {
+		if version < ofp13.Version {
+			return nil, notExpressible(version, "meter instruction")
+		}
+		instructions = append(instructions, ofp13.NewInstructionMeter(fm.MeterID))
+	}
+	if len(fm.Actions) > 0 {
+		actions, err := encodeActions(version, fm.Actions)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, ofp13.NewInstructionActions(ofp13.OfpInstructionTypeApplyActions, actions...))
+	}
+	if len(fm.WriteActions) > 0 {
+		actions, err := encodeActions(version, fm.WriteActions)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, ofp13.NewInstructionActions(ofp13.OfpInstructionTypeWriteActions, actions...))
+	}
+	if fm.GotoTable != 0 {
+		instructions = append(instructions, ofp13.NewInstructionGotoTable(fm.GotoTable))
+	}
+	return instructions, nil
+}
+
+// PacketOut describes the packet sent by the controller regardless of the
+// openflow version, it is translated by Encode to the packet out of the
+// version negotiated with the switch
+type PacketOut struct {
+	BufferID uint32 /* ID assigned by datapath, or OFP_NO_BUFFER. */
+	InPort   uint32 /* Packet's input port, or OFPP_CONTROLLER. */
+	Actions  []Action
+	Data     []byte /* Packet data, only meaningful without buffer id. */
+}
+
+// NewPacketOut creates the packet out of the unbuffered packet data
+// received on the port
+func NewPacketOut(inPort uint32, data []byte, actions ...Action) *PacketOut {
+	return &PacketOut{BufferID: ofp13.OfpNoBuffer, InPort: inPort, Actions: actions, Data: data}
+}
+
+// Encode translates the packet out to the packet out of the openflow
+// version, a NotExpressibleError is returned if the version lacks a
+// feature used
+func (po *PacketOut) Encode(version uint8) (ofpgeneral.OfpMessage, error) {
+	if version == ofp10.Version {
+		inPort, err := portV10(po.InPort)
+		if err != nil {
+			return nil, err
+		}
+		actions, err := encodeActionsV10(po.Actions)
+		if err != nil {
+			return nil, err
+		}
+		msg := ofp10.NewPacketOutMsg(inPort, actions, po.Data)
+		msg.BufferID = po.BufferID
+		return msg, nil
+	}
+	actions, err := encodeActions(version, po.Actions)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case version == ofp11.Version:
+		msg := ofp11.NewPacketOutMsg(po.InPort, actions, po.Data)
+		msg.BufferID = po.BufferID
+		return msg, nil
+	case version >= ofp15.Version:
+		// The input port is a pipeline field of the match since
+		// openflow 1.5
+		match := ofp13.NewOfpMatch()
+		match.OXMFields.SetInPort(po.InPort)
+		msg := ofp15.NewPacketOutMsg(match, actions, po.Data)
+		msg.Header.Version = version
+		msg.BufferID = po.BufferID
+		return msg, nil
+	}
+	msg := ofp13.NewPacketOutMsg(po.InPort, actions, po.Data)
+	msg.Header.Version = version
+	msg.BufferID = po.BufferID
+	return msg, nil
+}
+
+// ModifyFlows sends the flow mod translated to the negotiated version, the
+// error sent by the switch to reject it is returned
+func (sw *openflowSwitchImpl) ModifyFlows(ctx context.Context, fm *FlowMod) error {
+	msg, err := fm.Encode(sw.version)
+	if err != nil {
+		return err
+	}
+	return sw.modify(ctx, msg)
+}
+
+// SendPacketOut sends the packet out translated to the negotiated version
+func (sw *openflowSwitchImpl) SendPacketOut(po *PacketOut) error {
+	msg, err := po.Encode(sw.version)
+	if err != nil {
+		return err
+	}
+	return sw.Send(msg)
+}
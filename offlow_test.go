@@ -0,0 +1,208 @@
+package goof
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// newTestFlowMod creates the flow mod adding the flow of the table 0 which
+// outputs the ipv4 packets received on the port 3 to the port 2
+func newTestFlowMod() *FlowMod {
+	fm := NewFlowMod(ofp13.OfpFlowModCmdAdd, 0)
+	fm.Cookie, fm.Priority = 1, 0x8000
+	fm.Match.SetInPort(3)
+	fm.Match.SetEthType(0x0800)
+	fm.Actions = []Action{NewOutputAction(2)}
+	return fm
+}
+
+// checkWire checks the message is encoded to the wire, which may contain
+// spaces, the xid is skipped
+func checkWire(t *testing.T, msg ofpgeneral.OfpMessage, wire string) []byte {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := hex.EncodeToString(data[:4]) + hex.EncodeToString(data[8:])
+	if expected := strings.Replace(wire, " ", "", -1); encoded != expected {
+		t.Fatalf("%T is encoded as %s, expected %s", msg, encoded, expected)
+	}
+	return data
+}
+
+// checkRoundTrip decodes the data into the message of the version and
+// checks it is encoded back to the same bytes
+func checkRoundTrip(t *testing.T, data []byte, decoded ofpgeneral.OfpMessage) {
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != hex.EncodeToString(data) {
+		t.Fatalf("%T is encoded back as %x, expected %x", decoded, encoded, data)
+	}
+}
+
+// checkNotExpressible checks the error is the NotExpressibleError of the
+// version
+func checkNotExpressible(t *testing.T, err error, version uint8) {
+	if err == nil {
+		t.Fatal("The feature is expressed")
+	}
+	if notExpressibleErr, ok := err.(*NotExpressibleError); !ok || notExpressibleErr.Version != version {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
+func TestFlowModEncode(t *testing.T) {
+	// The oxm match of the in_port 3 and the eth_type 0x0800, followed by
+	// the apply actions instruction outputting to the port 2
+	oxm := "0001 0012 80000004 00000003 80000a02 0800 000000000000" +
+		"0004 0018 00000000 0000 0010 00000002 ffff 000000000000"
+	tests := []struct {
+		name    string
+		version uint8
+		wire    string
+		decoded ofpgeneral.OfpMessage
+	}{
+		{"openflow 1.0", ofp10.Version, "010e0050 003fffee 0003 000000000000 000000000000 0000 00 00 0800 00 00 0000" +
+			"00000000 00000000 0000 0000 0000000000000001 0000 0000 0000 8000 ffffffff ffff 0000" +
+			"0000 0008 0002 ffff", nil},
+		{"openflow 1.1", ofp11.Version, "020e00a0 0000000000000001 0000000000000000 00 00 0000 0000 8000" +
+			"ffffffff ffffffff ffffffff 0000 0000" +
+			"0000 0058 00000003 000003f6 000000000000 ffffffffffff 000000000000 ffffffffffff" +
+			"0000 00 00 0800 00 00 00000000 ffffffff 00000000 ffffffff 0000 0000 00000000 00 000000" +
+			"0000000000000000 ffffffffffffffff" +
+			"0004 0018 00000000 0000 0010 00000002 ffff 000000000000", &ofp11.OfpFlowModMsg{}},
+		{"openflow 1.2", ofp12.Version, "030e0060 0000000000000001 0000000000000000 00 00 0000 0000 8000" +
+			"ffffffff ffffffff ffffffff 0000 0000" + oxm, &ofp13.OfpFlowModMsg{}},
+		{"openflow 1.3", ofp13.Version, "040e0060 0000000000000001 0000000000000000 00 00 0000 0000 8000" +
+			"ffffffff ffffffff ffffffff 0000 0000" + oxm, &ofp13.OfpFlowModMsg{}},
+		{"openflow 1.4", ofp14.Version, "050e0060 0000000000000001 0000000000000000 00 00 0000 0000 8000" +
+			"ffffffff ffffffff ffffffff 0000 0000" + oxm, &ofp13.OfpFlowModMsg{}},
+		{"openflow 1.5", ofp15.Version, "060e0060 0000000000000001 0000000000000000 00 00 0000 0000 8000" +
+			"ffffffff ffffffff ffffffff 0000 0000" + oxm, &ofp13.OfpFlowModMsg{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := newTestFlowMod().Encode(tc.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := checkWire(t, msg, tc.wire)
+			// The openflow 1.0 flow mod is only decoded by the switches
+			if tc.decoded != nil {
+				checkRoundTrip(t, data, tc.decoded)
+			}
+		})
+	}
+}
+
+func TestFlowModEncodeNotExpressible(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint8
+		modify  func(fm *FlowMod)
+	}{
+		{"table on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.TableID = 1 }},
+		{"cookie mask on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.CookieMask = 1 }},
+		{"out group on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.OutGroup = 1 }},
+		{"write actions on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.WriteActions = fm.Actions }},
+		{"goto table on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.GotoTable = 1 }},
+		{"meter on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.MeterID = 1 }},
+		{"out port on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.OutPort = 0xffffff00 }},
+		{"group action on openflow 1.0", ofp10.Version, func(fm *FlowMod) { fm.Actions = []Action{NewGroupAction(1)} }},
+		{"reset counts on openflow 1.1", ofp11.Version, func(fm *FlowMod) { fm.Flags = ofp13.OfpFlowFlagResetCounts }},
+		{"meter on openflow 1.1", ofp11.Version, func(fm *FlowMod) { fm.MeterID = 1 }},
+		{"meter on openflow 1.2", ofp12.Version, func(fm *FlowMod) { fm.MeterID = 1 }},
+		{"no byte counts on openflow 1.2", ofp12.Version, func(fm *FlowMod) { fm.Flags = ofp13.OfpFlowFlagNoBytCounts }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fm := newTestFlowMod()
+			tc.modify(fm)
+			_, err := fm.Encode(tc.version)
+			checkNotExpressible(t, err, tc.version)
+		})
+	}
+
+	// The flows of all the tables are deleted by openflow 1.0
+	fm := NewFlowMod(ofp13.OfpFlowModCmdDelete, ofp13.OfpTableAll)
+	if _, err := fm.Encode(ofp10.Version); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPacketOutEncode(t *testing.T) {
+	output := "0000 0010 fffffffb ffff 000000000000"
+	tests := []struct {
+		name    string
+		version uint8
+		wire    string
+		decoded ofpgeneral.OfpMessage
+	}{
+		{"openflow 1.0", ofp10.Version, "010d001b ffffffff fffd 0008 0000 0008 fffb ffff aabbcc", &ofp10.OfpPacketOutMsg{}},
+		{"openflow 1.1", ofp11.Version, "020d002b ffffffff fffffffd 0010 000000000000" + output + "aabbcc", &ofp11.OfpPacketOutMsg{}},
+		{"openflow 1.2", ofp12.Version, "030d002b ffffffff fffffffd 0010 000000000000" + output + "aabbcc", &ofp13.OfpPacketOutMsg{}},
+		{"openflow 1.3", ofp13.Version, "040d002b ffffffff fffffffd 0010 000000000000" + output + "aabbcc", &ofp13.OfpPacketOutMsg{}},
+		{"openflow 1.4", ofp14.Version, "050d002b ffffffff fffffffd 0010 000000000000" + output + "aabbcc", &ofp14.OfpPacketOutMsg{}},
+		// The input port is in the match since openflow 1.5
+		{"openflow 1.5", ofp15.Version, "060d0033 ffffffff 0010 0000 0001 000c 80000004 fffffffd 00000000" + output + "aabbcc",
+			&ofp15.OfpPacketOutMsg{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			po := NewPacketOut(ofp13.OfpPortController, []byte{0xaa, 0xbb, 0xcc}, NewOutputAction(ofp13.OfpPortFlood))
+			msg, err := po.Encode(tc.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRoundTrip(t, checkWire(t, msg, tc.wire), tc.decoded)
+		})
+	}
+}
+
+func TestPacketOutEncodeV15(t *testing.T) {
+	msg, err := NewPacketOut(3, nil).Encode(ofp15.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &ofp15.OfpPacketOutMsg{}
+	checkRoundTrip(t, data, decoded)
+	if inPort, ok := decoded.Match.OXMFields.GetInPort(); !ok || inPort != 3 || len(decoded.Match.OXMFields) != 1 {
+		t.Errorf("Unexpected packet out match %+v", decoded.Match)
+	}
+}
+
+func TestPacketOutEncodeNotExpressible(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint8
+		po      *PacketOut
+	}{
+		{"in port on openflow 1.0", ofp10.Version, NewPacketOut(0xffffff00, nil)},
+		{"push vlan on openflow 1.0", ofp10.Version, NewPacketOut(1, nil, NewPushVlanAction(0x8100))},
+		{"dec nw ttl on openflow 1.0", ofp10.Version, NewPacketOut(1, nil, NewDecNWTTLAction())},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.po.Encode(tc.version)
+			checkNotExpressible(t, err, tc.version)
+		})
+	}
+}
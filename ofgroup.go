@@ -0,0 +1,48 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp15"
+)
+
+// AddGroup installs the group of the type with the buckets
+func (sw *openflowSwitchImpl) AddGroup(ctx context.Context, groupType uint8, groupID uint32, buckets []ofp13.OfpBucket) error {
+	return sw.modifyGroup(ctx, ofp13.OfpGroupModCmdAdd, groupType, groupID, buckets)
+}
+
+// ModifyGroup replaces the type and the buckets of the group
+func (sw *openflowSwitchImpl) ModifyGroup(ctx context.Context, groupType uint8, groupID uint32, buckets []ofp13.OfpBucket) error {
+	return sw.modifyGroup(ctx, ofp13.OfpGroupModCmdModify, groupType, groupID, buckets)
+}
+
+// DeleteGroup removes the group, ofp13.OfpGroupAll removes all the groups
+func (sw *openflowSwitchImpl) DeleteGroup(ctx context.Context, groupID uint32) error {
+	return sw.modifyGroup(ctx, ofp13.OfpGroupModCmdDelete, ofp13.OfpGroupTypeAll, groupID, nil)
+}
+
+func (sw *openflowSwitchImpl) modifyGroup(ctx context.Context, command uint16, groupType uint8, groupID uint32, buckets []ofp13.OfpBucket) error {
+	// The group mod of openflow 1.5 carries the bucket ids and properties
+	// which aren't encoded
+	if sw.version == ofp10.Version || sw.version >= ofp15.Version {
+		return fmt.Errorf("The groups aren't supported by openflow version %d", sw.version)
+	}
+	switch sw.version {
+	case ofp11.Version:
+		if err := ofp11.CheckBuckets(buckets); err != nil {
+			return err
+		}
+	case ofp12.Version:
+		if err := ofp12.CheckBuckets(buckets); err != nil {
+			return err
+		}
+	}
+	msg := ofp13.NewGroupModMsg(command, groupType, groupID, buckets)
+	msg.Header.Version = sw.version
+	return sw.modify(ctx, msg)
+}
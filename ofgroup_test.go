@@ -0,0 +1,40 @@
+package goof
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// TestAddGroupUnsupported checks the group mods the switch can't decode
+// are rejected before being sent
+func TestAddGroupUnsupported(t *testing.T) {
+	vid := ofpgeneral.OxmFields{}
+	vid.SetVlanVID(ofpgeneral.OfpVIDPresent | 5)
+	tests := []struct {
+		name    string
+		version uint8
+		action  ofp13.OfpAction
+	}{
+		{"openflow 1.0", ofp10.Version, ofp13.NewActionOutput(2)},
+		{"set field on openflow 1.1", ofp11.Version, ofp13.NewActionSetField(vid[0])},
+		{"push pbb on openflow 1.2", ofp12.Version, ofp13.NewActionPush(ofp13.OfpActionPushPBB, 0x88e7)},
+		{"openflow 1.5", ofp15.Version, ofp13.NewActionOutput(2)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// The switch has no tunnel, sending the group mod would panic
+			sw := &openflowSwitchImpl{version: tc.version}
+			buckets := []ofp13.OfpBucket{*ofp13.NewBucket(1, tc.action)}
+			if err := sw.AddGroup(context.Background(), ofp13.OfpGroupTypeSelect, 1, buckets); err == nil {
+				t.Fatal("The group mod is accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package goof
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// NotExpressibleError is returned when a version-neutral message uses a
+// feature which the openflow version negotiated with the switch lacks
+type NotExpressibleError struct {
+	Feature string
+	Version uint8
+}
+
+func (e *NotExpressibleError) Error() string {
+	return fmt.Sprintf("The %s is not expressible in OF1.%d", e.Feature, e.Version-1)
+}
+
+func notExpressible(version uint8, format string, args ...interface{}) error {
+	return &NotExpressibleError{Feature: fmt.Sprintf(format, args...), Version: version}
+}
+
+// Match describes the packets matched by a flow regardless of the openflow
+// version. The fields are set by the accessors of ofpgeneral.OxmFields and
+// the fields which aren't set are wildcarded.
+type Match struct {
+	ofpgeneral.OxmFields
+}
+
+// NewMatch creates the match which matches all the packets
+func NewMatch() *Match {
+	return &Match{}
+}
+
+// oxmLastBasicField holds the last openflow basic field known by the
+// openflow versions before 1.5, which knows all of them
+var oxmLastBasicField = map[uint8]uint8{
+	ofp12.Version: ofpgeneral.OxmFieldMplsTC,
+	ofp13.Version: ofpgeneral.OxmFieldIPv6ExtHdr,
+	ofp14.Version: ofpgeneral.OxmFieldPbbUCA,
+}
+
+// checkOxmField returns an error if the field is unknown to the openflow
+// version, the usage describes the field in the error
+func checkOxmField(version uint8, field *ofpgeneral.OxmField, usage string) error {
+	switch field.Class {
+	case ofpgeneral.OxmClassOpenflowBasic:
+		if last, ok := oxmLastBasicField[version]; ok && field.Field > last {
+			return notExpressible(version, "%s %s", usage, field.Name())
+		}
+	case ofpgeneral.OxmClassPacketRegs:
+		if version < ofp15.Version {
+			return notExpressible(version, "%s %s", usage, field.Name())
+		}
+	}
+	return nil
+}
+
+// encodeOxm translates the match to the OXM match of openflow 1.2 and the
+// later versions
+func (m *Match) encodeOxm(version uint8) (*ofp13.OfpMatch, error) {
+	for idx := range m.OxmFields {
+		if err := checkOxmField(version, &m.OxmFields[idx], "match on"); err != nil {
+			return nil, err
+		}
+	}
+	match := ofp13.NewOfpMatch()
+	match.OXMFields = append(ofpgeneral.OxmFields{}, m.OxmFields...)
+	return match, nil
+}
+
+// encodeV10 translates the match to the openflow 1.0 match, only the exact
+// values and the ip prefixes can be matched
+func (m *Match) encodeV10() (*ofp10.OfpMatch, error) {
+	match := ofp10.NewOfpMatch()
+	for idx := range m.OxmFields {
+		field := &m.OxmFields[idx]
+		if field.Class != ofpgeneral.OxmClassOpenflowBasic {
+			return nil, notExpressible(ofp10.Version, "match on %s", field.Name())
+		}
+		if field.HasMask && field.Field != ofpgeneral.OxmFieldIPv4Src && field.Field != ofpgeneral.OxmFieldIPv4Dst &&
+			field.Field != ofpgeneral.OxmFieldARPSpa && field.Field != ofpgeneral.OxmFieldARPTpa {
+			return nil, notExpressible(ofp10.Version, "masked match on %s", field.Name())
+		}
+		switch field.Field {
+		case ofpgeneral.OxmFieldInPort:
+			inPort, _ := m.GetInPort()
+			port, err := portV10(inPort)
+			if err != nil {
+				return nil, err
+			}
+			match.InPort = port
+			match.Wildcards &^= ofp10.OfpFlowWildCardsInPort
+		case ofpgeneral.OxmFieldEthSrc:
+			match.DLSrc, _, _ = m.GetEthSrc()
+			match.Wildcards &^= ofp10.OfpFlowWildCardsDLSrc
+		case ofpgeneral.OxmFieldEthDst:
+			match.DLDst, _, _ = m.GetEthDst()
+			match.Wildcards &^= ofp10.OfpFlowWildCardsDLDst
+		case ofpgeneral.OxmFieldEthType:
+			match.DLType, _ = m.GetEthType()
+			match.Wildcards &^= ofp10.OfpFlowWildCardsDLType
+		case ofpgeneral.OxmFieldVlanVID:
+			vid, _, _ := m.GetVlanVID()
+			match.DLVlan = ofp10.OfpVlanNone
+			if vid&ofpgeneral.OfpVIDPresent != 0 {
+				match.DLVlan = vid &^ ofpgeneral.OfpVIDPresent
+			}
+			match.Wildcards &^= ofp10.OfpFlowWildCardsDLVlan
+		case ofpgeneral.OxmFieldVlanPCP:
+			match.DLVlanPCP, _ = m.GetVlanPCP()
+			match.Wildcards &^= ofp10.OfpFlowWildCardsDLVlanPCP
+		case ofpgeneral.OxmFieldIPDSCP:
+			dscp, _ := m.GetIPDSCP()
+			match.NWToS = dscp << 2
+			match.Wildcards &^= ofp10.OfpFlowWildCardsNWToS
+		case ofpgeneral.OxmFieldIPProto:
+			match.NWProto, _ = m.GetIPProto()
+			match.Wildcards &^= ofp10.OfpFlowWildCardsNWProto
+		case ofpgeneral.OxmFieldARPOp:
+			op, _ := m.GetARPOp()
+			match.NWProto = uint8(op)
+			match.Wildcards &^= ofp10.OfpFlowWildCardsNWProto
+		case ofpgeneral.OxmFieldIPv4Src, ofpgeneral.OxmFieldARPSpa:
+			addr, mask, _ := m.getIPv4(field.Field)
+			bits, err := wildcardBitsV10(field, mask)
+			if err != nil {
+				return nil, err
+			}
+			match.NWSrc = addr
+			match.Wildcards = match.Wildcards&^ofp10.OfpFlowWildCardsNWSrcMask | bits<<ofp10.OfpFlowWildCardsNWSrcShift
+		case ofpgeneral.OxmFieldIPv4Dst, ofpgeneral.OxmFieldARPTpa:
+			addr, mask, _ := m.getIPv4(field.Field)
+			bits, err := wildcardBitsV10(field, mask)
+			if err != nil {
+				return nil, err
+			}
+			match.NWDst = addr
+			match.Wildcards = match.Wildcards&^ofp10.OfpFlowWildCardsNWDstMask | bits<<ofp10.OfpFlowWildCardsNWDstShift
+		case ofpgeneral.OxmFieldTCPSrc, ofpgeneral.OxmFieldUDPSrc, ofpgeneral.OxmFieldICMPv4Type:
+			match.TPSrc = m.getTPValue(field.Field)
+			match.Wildcards &^= ofp10.OfpFlowWildCardsTPSrc
+		case ofpgeneral.OxmFieldTCPDst, ofpgeneral.OxmFieldUDPDst, ofpgeneral.OxmFieldICMPv4Code:
+			match.TPDst = m.getTPValue(field.Field)
+			match.Wildcards &^= ofp10.OfpFlowWildCardsTPDst
+		default:
+			return nil, notExpressible(ofp10.Version, "match on %s", field.Name())
+		}
+	}
+	return match, nil
+}
+
+// encodeV11 translates the match to the openflow 1.1 standard match, whose
+// masks are the inverse of the OXM ones
+func (m *Match) encodeV11() (*ofp11.OfpMatch, error) {
+	match := ofp11.NewOfpMatch()
+	for idx := range m.OxmFields {
+		field := &m.OxmFields[idx]
+		if field.Class != ofpgeneral.OxmClassOpenflowBasic {
+			return nil, notExpressible(ofp11.Version, "match on %s", field.Name())
+		}
+		switch field.Field {
+		case ofpgeneral.OxmFieldMetadata, ofpgeneral.OxmFieldEthSrc, ofpgeneral.OxmFieldEthDst,
+			ofpgeneral.OxmFieldVlanVID, ofpgeneral.OxmFieldIPv4Src, ofpgeneral.OxmFieldIPv4Dst,
+			ofpgeneral.OxmFieldARPSpa, ofpgeneral.OxmFieldARPTpa:
+		default:
+			if field.HasMask {
+				return nil, notExpressible(ofp11.Version, "masked match on %s", field.Name())
+			}
+		}
+		switch field.Field {
+		case ofpgeneral.OxmFieldInPort:
+			match.InPort, _ = m.GetInPort()
+			match.Wildcards &^= ofp11.OfpFlowWildCardInPort
+		case ofpgeneral.OxmFieldMetadata:
+			metadata, mask, _ := m.GetMetadata()
+			match.Metadata = metadata
+			match.MetadataMask = 0
+			if field.HasMask {
+				match.MetadataMask = ^mask
+			}
+		case ofpgeneral.OxmFieldEthSrc:
+			addr, mask, _ := m.GetEthSrc()
+			match.DLSrc, match.DLSrcMask = addr, invertHwAddrMask(mask)
+		case ofpgeneral.OxmFieldEthDst:
+			addr, mask, _ := m.GetEthDst()
+			match.DLDst, match.DLDstMask = addr, invertHwAddrMask(mask)
+		case ofpgeneral.OxmFieldEthType:
+			match.DLType, _ = m.GetEthType()
+			match.Wildcards &^= ofp11.OfpFlowWildCardDLType
+		case ofpgeneral.OxmFieldVlanVID:
+			vid, mask, _ := m.GetVlanVID()
+			switch {
+			case !field.HasMask && vid&ofpgeneral.OfpVIDPresent != 0:
+				match.DLVlan = vid &^ ofpgeneral.OfpVIDPresent
+			case !field.HasMask && vid == ofpgeneral.OfpVIDNone:
+				match.DLVlan = ofp11.OfpVlanNone
+			case vid == ofpgeneral.OfpVIDPresent && mask == ofpgeneral.OfpVIDPresent:
+				match.DLVlan = ofp11.OfpVlanAny
+			default:
+				return nil, notExpressible(ofp11.Version, "masked match on %s", field.Name())
+			}
+			match.Wildcards &^= ofp11.OfpFlowWildCardDLVlan
+		case ofpgeneral.OxmFieldVlanPCP:
+			match.DLVlanPCP, _ = m.GetVlanPCP()
+			match.Wildcards &^= ofp11.OfpFlowWildCardDLVlanPCP
+		case ofpgeneral.OxmFieldIPDSCP:
+			dscp, _ := m.GetIPDSCP()
+			match.NWToS = dscp << 2
+			match.Wildcards &^= ofp11.OfpFlowWildCardNWToS
+		case ofpgeneral.OxmFieldIPProto:
+			match.NWProto, _ = m.GetIPProto()
+			match.Wildcards &^= ofp11.OfpFlowWildCardNWProto
+		case ofpgeneral.OxmFieldARPOp:
+			op, _ := m.GetARPOp()
+			match.NWProto = uint8(op)
+			match.Wildcards &^= ofp11.OfpFlowWildCardNWProto
+		case ofpgeneral.OxmFieldIPv4Src, ofpgeneral.OxmFieldARPSpa:
+			addr, mask, _ := m.getIPv4(field.Field)
+			match.NWSrc, match.NWSrcMask = addr, invertIPv4Mask(mask)
+		case ofpgeneral.OxmFieldIPv4Dst, ofpgeneral.OxmFieldARPTpa:
+			addr, mask, _ := m.getIPv4(field.Field)
+			match.NWDst, match.NWDstMask = addr, invertIPv4Mask(mask)
+		case ofpgeneral.OxmFieldTCPSrc, ofpgeneral.OxmFieldUDPSrc, ofpgeneral.OxmFieldSCTPSrc,
+			ofpgeneral.OxmFieldICMPv4Type:
+			match.TPSrc = m.getTPValue(field.Field)
+			match.Wildcards &^= ofp11.OfpFlowWildCardTPSrc
+		case ofpgeneral.OxmFieldTCPDst, ofpgeneral.OxmFieldUDPDst, ofpgeneral.OxmFieldSCTPDst,
+			ofpgeneral.OxmFieldICMPv4Code:
+			match.TPDst = m.getTPValue(field.Field)
+			match.Wildcards &^= ofp11.OfpFlowWildCardTPDst
+		case ofpgeneral.OxmFieldMplsLabel:
+			match.MplsLabel, _ = m.GetMplsLabel()
+			match.Wildcards &^= ofp11.OfpFlowWildCardMplsLabel
+		case ofpgeneral.OxmFieldMplsTC:
+			match.MplsTC, _ = m.GetMplsTC()
+			match.Wildcards &^= ofp11.OfpFlowWildCardMplsTC
+		default:
+			return nil, notExpressible(ofp11.Version, "match on %s", field.Name())
+		}
+	}
+	return match, nil
+}
+
+// getIPv4 returns the ipv4 or arp protocol address of the field
+func (m *Match) getIPv4(field uint8) (net.IP, net.IPMask, bool) {
+	switch field {
+	case ofpgeneral.OxmFieldIPv4Src:
+		return m.GetIPv4Src()
+	case ofpgeneral.OxmFieldIPv4Dst:
+		return m.GetIPv4Dst()
+	case ofpgeneral.OxmFieldARPSpa:
+		return m.GetARPSpa()
+	}
+	return m.GetARPTpa()
+}
+
+// getTPValue returns the transport port, or the icmp type or code, carried
+// by the tp_src and tp_dst fields before openflow 1.2
+func (m *Match) getTPValue(field uint8) uint16 {
+	var value uint16
+	switch field {
+	case ofpgeneral.OxmFieldTCPSrc:
+		value, _ = m.GetTCPSrc()
+	case ofpgeneral.OxmFieldTCPDst:
+		value, _ = m.GetTCPDst()
+	case ofpgeneral.OxmFieldUDPSrc:
+		value, _ = m.GetUDPSrc()
+	case ofpgeneral.OxmFieldUDPDst:
+		value, _ = m.GetUDPDst()
+	case ofpgeneral.OxmFieldSCTPSrc:
+		value, _ = m.GetSCTPSrc()
+	case ofpgeneral.OxmFieldSCTPDst:
+		value, _ = m.GetSCTPDst()
+	case ofpgeneral.OxmFieldICMPv4Type:
+		icmpType, _ := m.GetICMPv4Type()
+		value = uint16(icmpType)
+	case ofpgeneral.OxmFieldICMPv4Code:
+		icmpCode, _ := m.GetICMPv4Code()
+		value = uint16(icmpCode)
+	}
+	return value
+}
+
+// wildcardBitsV10 returns the number of wildcarded low bits of the ip
+// address, openflow 1.0 can only wildcard the suffix of the address
+func wildcardBitsV10(field *ofpgeneral.OxmField, mask net.IPMask) (uint32, error) {
+	if mask == nil {
+		return 0, nil
+	}
+	ones, bits := mask.Size()
+	if bits == 0 {
+		return 0, notExpressible(ofp10.Version, "non prefix mask of %s", field.Name())
+	}
+	return uint32(bits - ones), nil
+}
+
+// invertHwAddrMask converts the OXM mask to the openflow 1.1 one, whose
+// set bits are wildcarded
+func invertHwAddrMask(mask net.HardwareAddr) net.HardwareAddr {
+	inverted := make(net.HardwareAddr, 6)
+	for idx := range inverted {
+		if idx < len(mask) {
+			inverted[idx] = ^mask[idx]
+		}
+	}
+	return inverted
+}
+
+// invertIPv4Mask converts the OXM mask to the openflow 1.1 one, whose
+// set bits are wildcarded
+func invertIPv4Mask(mask net.IPMask) uint32 {
+	if len(mask) != net.IPv4len {
+		return 0
+	}
+	return ^(uint32(mask[0])<<24 | uint32(mask[1])<<16 | uint32(mask[2])<<8 | uint32(mask[3]))
+}
+
+// reservedPortsV10 maps the reserved ports to the ones of openflow 1.0,
+// OFPP_ANY becomes OFPP_NONE
+var reservedPortsV10 = map[uint32]uint16{
+	ofp13.OfpPortInPort:     ofp10.OfpPortInPort,
+	ofp13.OfpPortTable:      ofp10.OfpPortTable,
+	ofp13.OfpPortNormal:     ofp10.OfpPortNormal,
+	ofp13.OfpPortFlood:      ofp10.OfpPortFlood,
+	ofp13.OfpPortAll:        ofp10.OfpPortAll,
+	ofp13.OfpPortController: ofp10.OfpPortController,
+	ofp13.OfpPortLocal:      ofp10.OfpPortLocal,
+	ofp13.OfpPortAny:        ofp10.OfpPortNone,
+}
+
+// portV10 converts the port number to the one of openflow 1.0, the
+// reserved ports keep their meaning
+func portV10(port uint32) (uint16, error) {
+	if port < ofp10.OfpPortMax {
+		return uint16(port), nil
+	}
+	if reserved, ok := reservedPortsV10[port]; ok {
+		return reserved, nil
+	}
+	return 0, notExpressible(ofp10.Version, "port number %d", port)
+}
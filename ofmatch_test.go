@@ -0,0 +1,91 @@
+package goof
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// TestOxmFieldCutoffs checks the last openflow basic field known by each
+// version is matched and set, and the next one is rejected
+func TestOxmFieldCutoffs(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint8
+		set     func(fields *ofpgeneral.OxmFields)
+		known   bool
+	}{
+		{"mpls tc on openflow 1.2", ofp12.Version, func(fields *ofpgeneral.OxmFields) { fields.SetMplsTC(1) }, true},
+		{"mpls bos on openflow 1.2", ofp12.Version, func(fields *ofpgeneral.OxmFields) { fields.SetMplsBOS(1) }, false},
+		{"ipv6 exthdr on openflow 1.3", ofp13.Version, func(fields *ofpgeneral.OxmFields) { fields.SetIPv6ExtHdr(1) }, true},
+		{"pbb uca on openflow 1.3", ofp13.Version, func(fields *ofpgeneral.OxmFields) { fields.SetPbbUCA(1) }, false},
+		{"pbb uca on openflow 1.4", ofp14.Version, func(fields *ofpgeneral.OxmFields) { fields.SetPbbUCA(1) }, true},
+		{"tcp flags on openflow 1.4", ofp14.Version, func(fields *ofpgeneral.OxmFields) { fields.SetTCPFlags(1) }, false},
+		{"tcp flags on openflow 1.5", ofp15.Version, func(fields *ofpgeneral.OxmFields) { fields.SetTCPFlags(1) }, true},
+		{"packet register on openflow 1.4", ofp14.Version, func(fields *ofpgeneral.OxmFields) { fields.SetPacketReg(0, 1) }, false},
+		{"packet register on openflow 1.5", ofp15.Version, func(fields *ofpgeneral.OxmFields) { fields.SetPacketReg(0, 1) }, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fm := NewFlowMod(ofp13.OfpFlowModCmdAdd, 0)
+			tc.set(&fm.Match.OxmFields)
+			action := NewSetFieldAction()
+			tc.set(&action.OxmFields)
+			fm.Actions = []Action{action}
+			msg, err := fm.Encode(tc.version)
+			if !tc.known {
+				checkNotExpressible(t, err, tc.version)
+				// The field is rejected by the set field action too
+				_, err = action.encode(tc.version)
+				checkNotExpressible(t, err, tc.version)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := msg.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded := &ofp13.OfpFlowModMsg{}
+			checkRoundTrip(t, data, decoded)
+			if fields := decoded.Match.OXMFields; len(fields) != 1 || fields[0].Name() != fm.Match.OxmFields[0].Name() {
+				t.Errorf("Unexpected match %+v", decoded.Match)
+			}
+		})
+	}
+}
+
+func TestPortV10(t *testing.T) {
+	tests := []struct {
+		port     uint32
+		expected uint16
+	}{
+		{1, 1},
+		{ofp10.OfpPortMax - 1, ofp10.OfpPortMax - 1},
+		{ofp13.OfpPortInPort, ofp10.OfpPortInPort},
+		{ofp13.OfpPortTable, ofp10.OfpPortTable},
+		{ofp13.OfpPortNormal, ofp10.OfpPortNormal},
+		{ofp13.OfpPortFlood, ofp10.OfpPortFlood},
+		{ofp13.OfpPortAll, ofp10.OfpPortAll},
+		{ofp13.OfpPortController, ofp10.OfpPortController},
+		{ofp13.OfpPortLocal, ofp10.OfpPortLocal},
+		{ofp13.OfpPortAny, ofp10.OfpPortNone},
+	}
+	for _, tc := range tests {
+		if port, err := portV10(tc.port); err != nil || port != tc.expected {
+			t.Errorf("The port %#x is converted to %#x, %v, expected %#x", tc.port, port, err, tc.expected)
+		}
+	}
+	// The physical ports beyond the openflow 1.0 ones and the undefined
+	// reserved ports are rejected
+	for _, port := range []uint32{ofp10.OfpPortMax, ofp13.OfpPortMax, 0xfffffff7} {
+		_, err := portV10(port)
+		checkNotExpressible(t, err, ofp10.Version)
+	}
+}
@@ -0,0 +1,33 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// AddMeter installs the meter with the flags and the bands
+func (sw *openflowSwitchImpl) AddMeter(ctx context.Context, flags uint16, meterID uint32, bands []ofp13.OfpMeterBand) error {
+	return sw.modifyMeter(ctx, ofp13.OfpMeterModCmdAdd, flags, meterID, bands)
+}
+
+// ModifyMeter replaces the flags and the bands of the meter
+func (sw *openflowSwitchImpl) ModifyMeter(ctx context.Context, flags uint16, meterID uint32, bands []ofp13.OfpMeterBand) error {
+	return sw.modifyMeter(ctx, ofp13.OfpMeterModCmdModify, flags, meterID, bands)
+}
+
+// DeleteMeter removes the meter, ofp13.OfpMeterAll removes all the meters
+func (sw *openflowSwitchImpl) DeleteMeter(ctx context.Context, meterID uint32) error {
+	return sw.modifyMeter(ctx, ofp13.OfpMeterModCmdDelete, 0, meterID, nil)
+}
+
+func (sw *openflowSwitchImpl) modifyMeter(ctx context.Context, command uint16, flags uint16, meterID uint32, bands []ofp13.OfpMeterBand) error {
+	// The meters are introduced by openflow 1.3
+	if sw.version < ofp13.Version {
+		return fmt.Errorf("The meters aren't supported by openflow version %d", sw.version)
+	}
+	msg := ofp13.NewMeterModMsg(command, flags, meterID, bands)
+	msg.Header.Version = sw.version
+	return sw.modify(ctx, msg)
+}
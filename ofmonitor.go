@@ -0,0 +1,184 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// FlowMonitorHandler is invoked with each flow update of the monitor, the
+// update is one of *ofp14.OfpFlowUpdateFull, *ofp14.OfpFlowUpdateAbbrev and
+// *ofp14.OfpFlowUpdatePaused. The handler is invoked by the dispatch loop of
+// the switch, it may wait for the replies of the switch, but the later
+// messages of the switch are delivered once it returns.
+type FlowMonitorHandler func(sw OpenflowSwitch, update ofpgeneral.OfpMessage)
+
+// FlowMonitorFilter selects the flows and the changes reported by the flow
+// monitor
+type FlowMonitorFilter struct {
+	TableID  uint8           /* One table's ID or ofp13.OfpTableAll. */
+	OutPort  uint32          /* Required output port, if not ofp13.OfpPortAny. */
+	OutGroup uint32          /* Required group, if not ofp13.OfpGroupAny. */
+	Flags    uint16          /* Bitmap of ofp14.OfpFlowMonitorFlag* flags. */
+	Match    *ofp13.OfpMatch /* Fields to match, nil matches all flows. */
+}
+
+// NewFlowMonitorFilter creates the filter which reports the existing flows
+// and all the later changes of the flows in all the tables
+func NewFlowMonitorFilter() *FlowMonitorFilter {
+	return &FlowMonitorFilter{
+		TableID:  ofp13.OfpTableAll,
+		OutPort:  ofp13.OfpPortAny,
+		OutGroup: ofp13.OfpGroupAny,
+		Flags: ofp14.OfpFlowMonitorFlagInitial | ofp14.OfpFlowMonitorFlagAdd |
+			ofp14.OfpFlowMonitorFlagRemoved | ofp14.OfpFlowMonitorFlagModify |
+			ofp14.OfpFlowMonitorFlagInstructions,
+	}
+}
+
+type flowMonitor struct {
+	tableID uint8
+	handler FlowMonitorHandler
+	// requestXid is the xid of the request which created the monitor,
+	// the initial flows replied to it are only given to this monitor
+	requestXid   uint32
+	replyPending bool
+}
+
+// MonitorFlows subscribes to the changes of the flows selected by the
+// filter. The handler first receives the flows present when the monitor is
+// created if ofp14.OfpFlowMonitorFlagInitial is set, then the updates as the
+// flows are changed by any controller. The monitor lasts until it is
+// cancelled or the switch disconnects. The flow monitors are introduced by
+// openflow 1.4.
+func (sw *openflowSwitchImpl) MonitorFlows(ctx context.Context, filter *FlowMonitorFilter, handler FlowMonitorHandler) (uint32, error) {
+	if sw.version < ofp14.Version {
+		return 0, fmt.Errorf("The flow monitors aren't supported by openflow version %d", sw.version)
+	}
+	match := filter.Match
+	if match == nil {
+		match = ofp13.NewOfpMatch()
+	}
+	monitorID := sw.nextMonitorID()
+	msg, err := ofp14.NewFlowMonitorRequestMsg(&ofp14.OfpFlowMonitorRequest{
+		MonitorID: monitorID,
+		OutPort:   filter.OutPort,
+		OutGroup:  filter.OutGroup,
+		Flags:     filter.Flags,
+		TableID:   filter.TableID,
+		Command:   ofp14.OfpFlowMonitorCmdAdd,
+		Match:     *match,
+	})
+	if err != nil {
+		return 0, err
+	}
+	msg.Header.Version = sw.version
+	sw.addMonitor(monitorID, &flowMonitor{tableID: filter.TableID, handler: handler, requestXid: msg.Header.Xid, replyPending: true})
+	// The initial flows are handed to the dispatch loop, so that they are
+	// given to the handler before the updates received afterwards
+	errChan := make(chan error, 1)
+	err = sw.RequestMultipartAsync(msg, defaultRequestTimeout, func(reply ofpgeneral.OfpMessage, err error) {
+		if err == nil {
+			sw.tunnel.deliver(reply)
+		}
+		errChan <- err
+	})
+	if err == nil {
+		select {
+		case err = <-errChan:
+		case <-ctx.Done():
+			sw.tunnel.transactions.remove(msg.Header.Xid)
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		sw.removeMonitor(monitorID)
+		return 0, err
+	}
+	return monitorID, nil
+}
+
+// CancelFlowMonitor deletes the flow monitor, the handler of the monitor
+// isn't invoked any more
+func (sw *openflowSwitchImpl) CancelFlowMonitor(ctx context.Context, monitorID uint32) error {
+	if sw.removeMonitor(monitorID) == nil {
+		return fmt.Errorf("Unknown flow monitor %d", monitorID)
+	}
+	msg, err := ofp14.NewFlowMonitorRequestMsg(&ofp14.OfpFlowMonitorRequest{
+		MonitorID: monitorID,
+		OutPort:   ofp13.OfpPortAny,
+		OutGroup:  ofp13.OfpGroupAny,
+		TableID:   ofp13.OfpTableAll,
+		Command:   ofp14.OfpFlowMonitorCmdDelete,
+		Match:     *ofp13.NewOfpMatch(),
+	})
+	if err != nil {
+		return err
+	}
+	msg.Header.Version = sw.version
+	return sw.modify(ctx, msg)
+}
+
+// handleFlowUpdates gives the flow updates to the handlers of the monitors.
+// The switch doesn't tell which monitors an update belongs to, so the
+// updates are given to all the monitors of the flow table.
+func (sw *openflowSwitchImpl) handleFlowUpdates(reply *ofp13.OfpMultipartReplyMsg) {
+	updates, err := ofp14.ParseFlowUpdates(reply.Body)
+	if err != nil {
+		log.Warnf("Failed to decode the flow updates of switch %s: %v", sw.GetDatapathID().GetHwAddr(), err)
+		return
+	}
+	monitors := sw.getMonitors(reply.Header.Xid)
+	for _, update := range updates {
+		for _, monitor := range monitors {
+			if full, ok := update.(*ofp14.OfpFlowUpdateFull); ok &&
+				monitor.tableID != ofp13.OfpTableAll && monitor.tableID != full.TableID {
+				continue
+			}
+			monitor.handler(sw, update)
+		}
+	}
+}
+
+// getMonitors returns the monitor created by the request of the xid, or all
+// the monitors if the xid isn't the one of a monitor request
+func (sw *openflowSwitchImpl) getMonitors(xid uint32) []*flowMonitor {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	monitors := make([]*flowMonitor, 0, len(sw.monitors))
+	for _, monitor := range sw.monitors {
+		if monitor.replyPending && monitor.requestXid == xid {
+			// The request is answered once
+			monitor.replyPending = false
+			return []*flowMonitor{monitor}
+		}
+		monitors = append(monitors, monitor)
+	}
+	return monitors
+}
+
+func (sw *openflowSwitchImpl) nextMonitorID() uint32 {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.lastMonitorID++
+	return sw.lastMonitorID
+}
+
+func (sw *openflowSwitchImpl) addMonitor(monitorID uint32, monitor *flowMonitor) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.monitors[monitorID] = monitor
+}
+
+func (sw *openflowSwitchImpl) removeMonitor(monitorID uint32) *flowMonitor {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	monitor := sw.monitors[monitorID]
+	delete(sw.monitors, monitorID)
+	return monitor
+}
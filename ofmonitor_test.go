@@ -0,0 +1,151 @@
+package goof
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// newTestFlowUpdatesReply creates the flow monitor reply of the xid carrying
+// the updates
+func newTestFlowUpdatesReply(t *testing.T, xid uint32, updates ...ofpgeneral.OfpMessage) *ofp13.OfpMultipartReplyMsg {
+	reply := &ofp13.OfpMultipartReplyMsg{Header: *ofpgeneral.NewOfpHeader(ofp14.Version), Type: ofp14.OfpMultipartTypeFlowMonitor}
+	reply.Header.Type = ofp13.OfpTypeMultiPartReply
+	reply.Header.Xid = xid
+	for _, update := range updates {
+		data, err := update.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply.Body = append(reply.Body, data...)
+	}
+	return reply
+}
+
+// newTestFlowUpdate creates the full flow update of the event in the table
+func newTestFlowUpdate(event uint16, tableID uint8) *ofp14.OfpFlowUpdateFull {
+	return &ofp14.OfpFlowUpdateFull{Event: event, TableID: tableID, Match: *ofp13.NewOfpMatch()}
+}
+
+// checkFlowMonitorRequest checks the message is the flow monitor request of
+// the command
+func checkFlowMonitorRequest(t *testing.T, msg []byte, command uint8) *ofp14.OfpFlowMonitorRequest {
+	request := &ofp13.OfpMultipartRequestMsg{}
+	if err := request.UnmarshalBinary(msg); err != nil {
+		t.Fatal(err)
+	}
+	body := &ofp14.OfpFlowMonitorRequest{}
+	if err := body.UnmarshalBinary(request.Body); err != nil {
+		t.Fatal(err)
+	}
+	if request.Header.Version != ofp14.Version || request.Type != ofp14.OfpMultipartTypeFlowMonitor || body.Command != command {
+		t.Fatalf("Expected the flow monitor command %d, got %+v %+v", command, request, body)
+	}
+	return body
+}
+
+func TestMonitorFlows(t *testing.T) {
+	sw, received := newTestSwitch(t, ofp14.Version, func(msg []byte) []ofpgeneral.OfpMessage {
+		if msg[1] != ofp13.OfpTypeMultiPartRequest {
+			return nil
+		}
+		// The initial flows are replied to the monitor request
+		xid, _ := ofpgeneral.GetMessageXid(msg)
+		return []ofpgeneral.OfpMessage{newTestFlowUpdatesReply(t, xid,
+			newTestFlowUpdate(ofp14.OfpFlowUpdateEventInitial, 1))}
+	})
+	sw.monitors = make(map[uint32]*flowMonitor)
+	defer sw.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var updates []ofpgeneral.OfpMessage
+	filter := NewFlowMonitorFilter()
+	filter.TableID = 1
+	monitorID, err := sw.MonitorFlows(ctx, filter, func(sw OpenflowSwitch, update ofpgeneral.OfpMessage) {
+		updates = append(updates, update)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := checkFlowMonitorRequest(t, <-received, ofp14.OfpFlowMonitorCmdAdd)
+	if request.MonitorID != monitorID || request.TableID != 1 || request.Flags != filter.Flags {
+		t.Errorf("Unexpected flow monitor request %+v", request)
+	}
+
+	// The dispatch loop is played by the test
+	select {
+	case msg := <-sw.tunnel.Incomming:
+		sw.handleFlowUpdates(msg.(*ofp13.OfpMultipartReplyMsg))
+	case <-ctx.Done():
+		t.Fatal("The initial flows aren't delivered")
+	}
+	sw.handleFlowUpdates(newTestFlowUpdatesReply(t, 0,
+		newTestFlowUpdate(ofp14.OfpFlowUpdateEventAdded, 2),
+		newTestFlowUpdate(ofp14.OfpFlowUpdateEventRemoved, 1),
+		&ofp14.OfpFlowUpdateAbbrev{Xid: 7}))
+	// The update of the table 2 isn't given to the monitor of the table 1
+	events := []uint16{ofp14.OfpFlowUpdateEventInitial, ofp14.OfpFlowUpdateEventRemoved, ofp14.OfpFlowUpdateEventAbbrev}
+	if len(updates) != len(events) {
+		t.Fatalf("Received %d flow updates, expected %d: %+v", len(updates), len(events), updates)
+	}
+	for i, update := range updates {
+		data, err := update.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := &ofp14.OfpFlowUpdateHeader{}
+		if err := header.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if header.Event != events[i] {
+			t.Errorf("Expected the flow update event %d, got %+v", events[i], update)
+		}
+	}
+
+	if err := sw.CancelFlowMonitor(ctx, monitorID); err != nil {
+		t.Fatal(err)
+	}
+	if request := checkFlowMonitorRequest(t, <-received, ofp14.OfpFlowMonitorCmdDelete); request.MonitorID != monitorID {
+		t.Errorf("Unexpected flow monitor request %+v", request)
+	}
+	sw.handleFlowUpdates(newTestFlowUpdatesReply(t, 0, newTestFlowUpdate(ofp14.OfpFlowUpdateEventAdded, 1)))
+	if len(updates) != len(events) {
+		t.Errorf("The flow update is given to the cancelled monitor")
+	}
+	if err := sw.CancelFlowMonitor(ctx, monitorID); err == nil {
+		t.Error("The cancelled flow monitor is cancelled again")
+	}
+}
+
+// TestFlowMonitorHandlerRequest checks the handler receives the replies of
+// the requests it sends while it blocks the dispatch loop
+func TestFlowMonitorHandlerRequest(t *testing.T) {
+	sw, _ := newTestSwitch(t, ofp14.Version, nil)
+	defer sw.Close()
+	sw.monitors = make(map[uint32]*flowMonitor)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var errs []error
+	sw.addMonitor(1, &flowMonitor{tableID: ofp13.OfpTableAll, handler: func(sw OpenflowSwitch, update ofpgeneral.OfpMessage) {
+		errs = append(errs, sw.Barrier(ctx))
+	}})
+
+	// The dispatch loop is played by the test
+	sw.handleFlowUpdates(newTestFlowUpdatesReply(t, 0, newTestFlowUpdate(ofp14.OfpFlowUpdateEventAdded, 1)))
+	if len(errs) != 1 || errs[0] != nil {
+		t.Fatalf("The barrier of the handler failed: %v", errs)
+	}
+}
+
+func TestMonitorFlowsUnsupported(t *testing.T) {
+	sw := &openflowSwitchImpl{version: ofp13.Version}
+	_, err := sw.MonitorFlows(context.Background(), NewFlowMonitorFilter(), func(sw OpenflowSwitch, update ofpgeneral.OfpMessage) {})
+	if err == nil {
+		t.Fatal("The flow monitor is accepted by openflow 1.3")
+	}
+}
@@ -1,11 +1,16 @@
 package goof
 
 import (
-	"bytes"
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"net"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
@@ -20,153 +25,456 @@ import (
 
 const (
 	defaultBufferSize = 50
+	// defaultMsgBufferSize is the initial capacity of a message buffer,
+	// the buffer grows when a bigger message is received
+	defaultMsgBufferSize = 2048
+	// defaultReadBufferSize is the size of the buffered reader of the
+	// connection, it is able to hold the largest openflow message
+	defaultReadBufferSize = 65536
+	ofpHeaderLen          = 8
+	// defaultFlushTimeout is the time given to the sender to write
+	// the pending message before the connection is closed
+	defaultFlushTimeout = time.Second
+	// defaultEventQueueSize is the number of the messages waiting to be
+	// delivered to the incoming channel
+	defaultEventQueueSize = 1024
 )
 
 // ofpBufferPool is the message buffer pool
 type ofpBufferPool struct {
-	empty chan *bytes.Buffer
-	full  chan *bytes.Buffer
+	empty chan []byte
+	full  chan []byte
 }
 
 // newBufferPool creates the message buffer pool
 func newBufferPool(size int) *ofpBufferPool {
 	m := &ofpBufferPool{}
-	m.empty = make(chan *bytes.Buffer, size)
-	m.full = make(chan *bytes.Buffer, size)
+	m.empty = make(chan []byte, size)
+	m.full = make(chan []byte, size)
 	for i := 0; i < size; i++ {
-		m.empty <- bytes.NewBuffer(make([]byte, 0, 2048))
+		m.empty <- make([]byte, 0, defaultMsgBufferSize)
 	}
 	return m
 }
 
-// MessageParser is the interface for message parser
+// ofpMsgQueue is the bounded queue of the messages waiting to be
+// delivered to the incoming channel. The replies are given to the
+// pending requests before they reach the queue, so they aren't delayed
+// by the applications unless the queue is full. Once the queue is full
+// the packet ins are dropped and the other messages wait for room, which
+// stops reading the connection until the dispatch loop catches up.
+type ofpMsgQueue struct {
+	lock    sync.Mutex
+	msgs    []ofpgeneral.OfpMessage
+	size    int
+	dropped uint64
+	// ready is signalled once messages are pushed and room once they
+	// are popped
+	ready chan struct{}
+	room  chan struct{}
+}
+
+func newMsgQueue(size int) *ofpMsgQueue {
+	return &ofpMsgQueue{size: size, ready: make(chan struct{}, 1), room: make(chan struct{}, 1)}
+}
+
+// push appends the message to the queue and wakes up the consumer. The
+// packet in is dropped if the queue is full, the other messages wait for
+// room until the done channel is closed. It returns false if the message
+// isn't queued.
+func (q *ofpMsgQueue) push(msg ofpgeneral.OfpMessage, isPacketIn bool, done <-chan struct{}) bool {
+	for {
+		q.lock.Lock()
+		if len(q.msgs) < q.size {
+			q.msgs = append(q.msgs, msg)
+			q.lock.Unlock()
+			signal(q.ready)
+			return true
+		}
+		if isPacketIn {
+			q.dropped++
+			q.lock.Unlock()
+			return false
+		}
+		q.lock.Unlock()
+		select {
+		case <-q.room:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// popAll takes all the queued messages in the order they are pushed
+func (q *ofpMsgQueue) popAll() []ofpgeneral.OfpMessage {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	msgs := q.msgs
+	q.msgs = nil
+	signal(q.room)
+	return msgs
+}
+
+// droppedCount returns the number of the packet ins dropped because the
+// queue was full
+func (q *ofpMsgQueue) droppedCount() uint64 {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.dropped
+}
+
+// signal wakes up the waiter of the channel, if any, without blocking
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// ofpFrameReader splits the byte stream of the connection into
+// openflow messages
+type ofpFrameReader struct {
+	reader *bufio.Reader
+}
+
+func newFrameReader(conn io.Reader) *ofpFrameReader {
+	return newFrameReaderSize(conn, defaultReadBufferSize)
+}
+
+// newFrameReaderSize creates the frame reader whose buffered reader has
+// the size, the messages may be bigger than the buffer
+func newFrameReaderSize(conn io.Reader, size int) *ofpFrameReader {
+	return &ofpFrameReader{reader: bufio.NewReaderSize(conn, size)}
+}
+
+// readFrame reads the next message into the buffer and returns the slice
+// holding the whole message. The buffer is reallocated if it is too
+// small for the message. A message cut short by the end of the stream
+// returns io.ErrUnexpectedEOF.
+func (fr *ofpFrameReader) readFrame(buf []byte) ([]byte, error) {
+	if cap(buf) < ofpHeaderLen {
+		buf = make([]byte, 0, defaultMsgBufferSize)
+	}
+	buf = buf[:ofpHeaderLen]
+	if _, err := io.ReadFull(fr.reader, buf); err != nil {
+		return buf[:0], err
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:4]))
+	if length < ofpHeaderLen {
+		return buf[:0], fmt.Errorf("Invalid message length %d in header %x", length, buf)
+	}
+	if cap(buf) < length {
+		newBuf := make([]byte, length)
+		copy(newBuf, buf)
+		buf = newBuf
+	}
+	buf = buf[:length]
+	if _, err := io.ReadFull(fr.reader, buf[ofpHeaderLen:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf[:0], err
+	}
+	return buf, nil
+}
+
+// MessageParser is the interface for message parser. The byte slice
+// is reused once ParseMsg returns, so the parsed message must not
+// refer to it.
 type MessageParser interface {
 	ParseMsg(b []byte) (ofpgeneral.OfpMessage, error)
 }
 
-// OfpMessageTunnel is the tunnel of messages in one tcp connection
+// helloMsgParser parses the messages received before the version
+// is negotiated, these messages have the same layout in all versions
+type helloMsgParser struct {
+}
+
+// ParseMsg is used to convert bytes into ofp message
+func (p *helloMsgParser) ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
+	var message ofpgeneral.OfpMessage
+	switch b[1] {
+	case ofpgeneral.OfpTypeHello:
+		message = &ofpgeneral.OfpHelloMsg{}
+	case ofpgeneral.OfpTypeError:
+		message = &ofpgeneral.OfpErrMsg{}
+	case ofpgeneral.OfpTypeEchoRequest, ofpgeneral.OfpTypeEchoReply:
+		message = &ofpgeneral.OfpEchoMsg{}
+	default:
+		return nil, fmt.Errorf("Message type %d is received before version negotiation", b[1])
+	}
+	err := message.UnmarshalBinary(b)
+	return message, err
+}
+
+// OfpMessageTunnel is the tunnel of messages in one connection
 // between the openflow controller and datapath
 type OfpMessageTunnel struct {
-	conn *net.TCPConn
+	conn net.Conn
 	pool *ofpBufferPool
 	// Openflow Version
 	Version   uint8
 	Incomming chan ofpgeneral.OfpMessage
 	Outgoing  chan ofpgeneral.OfpMessage
 	MsgParser MessageParser
+	// The versions advertised by the peer during the negotiation
+	peerVersions []uint8
+	parserLock   sync.RWMutex
 	// Channel on which to receive a shutdown command
 	Shutdown chan bool
 	// Channel on which to publish connection errors
 	Error chan error
+	// The requests waiting for replies
+	transactions *ofpTransactionTable
+	// The messages waiting to be delivered to the incoming channel
+	events *ofpMsgQueue
+	// Closed once the connection is closed
+	done      chan struct{}
+	closeOnce sync.Once
+	// Waits for the goroutines of the tunnel
+	wg sync.WaitGroup
 }
 
 // NewOfpMsgTunnel return the message stream
-func NewOfpMsgTunnel(con *net.TCPConn) *OfpMessageTunnel {
+func NewOfpMsgTunnel(con net.Conn) *OfpMessageTunnel {
 
 	msgTunnel := &OfpMessageTunnel{conn: con}
 	msgTunnel.Incomming = make(chan ofpgeneral.OfpMessage)
 	msgTunnel.Outgoing = make(chan ofpgeneral.OfpMessage)
+	msgTunnel.Shutdown = make(chan bool, 1)
+	msgTunnel.Error = make(chan error, 1)
+	msgTunnel.transactions = newTransactionTable()
+	msgTunnel.done = make(chan struct{})
+	msgTunnel.events = newMsgQueue(defaultEventQueueSize)
 	msgTunnel.pool = newBufferPool(defaultBufferSize)
-	msgTunnel.MsgParser = nil
+	msgTunnel.MsgParser = &helloMsgParser{}
+	msgTunnel.wg.Add(4)
 	go msgTunnel.sendMessage()
 	go msgTunnel.receiveMessage()
-	for i := 0; i < defaultBufferSize/2; i++ {
-		go msgTunnel.parseWorker()
-	}
+	go msgTunnel.deliverWorker()
+	// A single parse worker per connection keeps the messages in the
+	// order they are sent by the switch, the connections are decoded
+	// in parallel with each other
+	go msgTunnel.parseWorker()
 	return msgTunnel
 }
 
+// Send queues the message to be sent to the datapath
+func (mt *OfpMessageTunnel) Send(msg ofpgeneral.OfpMessage) error {
+	select {
+	case mt.Outgoing <- msg:
+		return nil
+	case <-mt.done:
+		return ErrSwitchClosed
+	}
+}
+
+// Close closes the connection and waits until the goroutines of the
+// tunnel exit. It must not be called from a ReplyCallback.
+func (mt *OfpMessageTunnel) Close() error {
+	// The sender closes the connection once the message being sent
+	// is written, unless it is stuck in writing to the connection
+	select {
+	case mt.Shutdown <- true:
+	case <-mt.done:
+	case <-time.After(defaultFlushTimeout):
+	}
+	select {
+	case <-mt.done:
+	case <-time.After(defaultFlushTimeout):
+		mt.closeConn()
+	}
+	mt.wg.Wait()
+	return nil
+}
+
+// closeConn closes the connection only once, receiveMessage then
+// publishes the disconnection on the error channel
+func (mt *OfpMessageTunnel) closeConn() error {
+	var err error
+	mt.closeOnce.Do(func() {
+		err = mt.conn.Close()
+		close(mt.done)
+	})
+	return err
+}
+
+// SetVersion sets the negotiated openflow version of the tunnel and
+// switches to the message parser of that version
+func (mt *OfpMessageTunnel) SetVersion(version uint8) error {
+	parser, err := genMsgParser(version)
+	if err != nil {
+		return err
+	}
+	mt.parserLock.Lock()
+	defer mt.parserLock.Unlock()
+	mt.Version = version
+	mt.MsgParser = parser
+	return nil
+}
+
+// PeerCertificate returns the certificate presented by the datapath,
+// nil is returned if the connection isn't secured by tls or the
+// datapath didn't send any certificate
+func (mt *OfpMessageTunnel) PeerCertificate() *x509.Certificate {
+	tlsConn, ok := mt.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return certs[0]
+}
+
+func (mt *OfpMessageTunnel) getMsgParser() MessageParser {
+	mt.parserLock.RLock()
+	defer mt.parserLock.RUnlock()
+	return mt.MsgParser
+}
+
+// DroppedPacketIns returns the number of the packet ins dropped because
+// the incoming channel wasn't read fast enough
+func (mt *OfpMessageTunnel) DroppedPacketIns() uint64 {
+	return mt.events.droppedCount()
+}
+
 // SendFeatureRequest is used to send the feature request message to datapath
 func (mt *OfpMessageTunnel) SendFeatureRequest() {
 	header := ofpgeneral.NewOfpHeader(mt.Version)
 	switch mt.Version {
 	case ofp10.Version:
 		header.Type = ofp10.OfpTypeFeaturesRequest
-	case ofp13.Version:
+	default:
 		header.Type = ofp13.OfpTypeFeaturesRequest
 	}
-	mt.Outgoing <- header
+	mt.Send(header)
 }
 
 func (mt *OfpMessageTunnel) sendMessage() {
+	defer mt.wg.Done()
 	for {
-		msg := <-mt.Outgoing
-		data, _ := msg.MarshalBinary()
-		if _, err := mt.conn.Write(data); err != nil {
-			log.Printf("Error in sending messages %s", err.Error())
+		select {
+		case msg := <-mt.Outgoing:
+			data, _ := msg.MarshalBinary()
+			if _, err := mt.conn.Write(data); err != nil {
+				log.Printf("Error in sending messages %s", err.Error())
+			}
+		case <-mt.Shutdown:
+			mt.closeConn()
+		case <-mt.done:
+			return
 		}
 	}
 }
 
 func (mt *OfpMessageTunnel) receiveMessage() {
-	msg := 0
-	hdr := 0
-	hdrBuf := make([]byte, 4)
-
-	tmp := make([]byte, 2048)
-	buf := <-mt.pool.empty
+	defer mt.wg.Done()
+	frameReader := newFrameReader(mt.conn)
 	for {
-		n, err := mt.conn.Read(tmp)
+		var buf []byte
+		select {
+		case buf = <-mt.pool.empty:
+		case <-mt.done:
+			mt.disconnected(ErrSwitchClosed)
+			return
+		}
+		buf, err := frameReader.readFrame(buf)
 		if err != nil {
+			mt.pool.empty <- buf
 			// Handle explicitly disconnecting by closing connection
-			if strings.Contains(err.Error(), "use of closed network connection") {
-				return
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Warnln("InboundError", err)
 			}
-			log.Warnln("InboundError", err)
-			mt.Error <- err
-			mt.Shutdown <- true
+			mt.closeConn()
+			mt.disconnected(err)
 			return
 		}
-		for i := 0; i < n; i++ {
-			if hdr < 4 {
-				hdrBuf[hdr] = tmp[i]
-				buf.WriteByte(tmp[i])
-				hdr++
-				if hdr >= 4 {
-					msg = int(binary.BigEndian.Uint16(hdrBuf[2:])) - 4
-				}
-				continue
-			}
-			if msg > 0 {
-				buf.WriteByte(tmp[i])
-				msg = msg - 1
-				if msg == 0 {
-					hdr = 0
-					mt.pool.full <- buf
-					buf = <-mt.pool.empty
-				}
-				continue
-			}
+		select {
+		case mt.pool.full <- buf:
+		case <-mt.done:
+			mt.disconnected(ErrSwitchClosed)
+			return
 		}
 	}
 }
 
+// disconnected aborts the pending requests and publishes the reason
+// of the disconnection on the error channel
+func (mt *OfpMessageTunnel) disconnected(err error) {
+	mt.transactions.failAll(ErrSwitchClosed)
+	mt.Error <- err
+}
+
+// parseWorker decodes the received messages in order and delivers them
+// to the pending requests or the incoming channel
 func (mt *OfpMessageTunnel) parseWorker() {
-	var err error
+	defer mt.wg.Done()
 	for {
-		msgBufBytes := <-mt.pool.full
-		if mt.MsgParser == nil {
-			mt.MsgParser, err = genMsgParser(msgBufBytes.Bytes())
-			if err != nil {
-				log.Printf("Message parser generation error %s", err.Error())
-			}
+		var msgBytes []byte
+		select {
+		case msgBytes = <-mt.pool.full:
+		case <-mt.done:
+			return
 		}
-		msg, err := mt.MsgParser.ParseMsg(msgBufBytes.Bytes())
+		msg, err := mt.getMsgParser().ParseMsg(msgBytes)
 		if err != nil {
 			log.Printf("Message parsing error %s", err.Error())
+		} else if msg != nil {
+			// The replies of pending requests are delivered to
+			// the requesters instead of the incoming channel
+			xid, _ := ofpgeneral.GetMessageXid(msgBytes)
+			if isAsyncMsg(msgBytes) || !mt.transactions.complete(xid, msg) {
+				if msgBytes[1] == ofp10.OfpTypePacketIn {
+					mt.deliverPacketIn(msg)
+				} else {
+					mt.deliver(msg)
+				}
+			}
 		}
-		mt.Incomming <- msg
-		msgBufBytes.Reset()
-		mt.pool.empty <- msgBufBytes
+		mt.pool.empty <- msgBytes[:0]
 	}
 }
 
-func genMsgParser(msg []byte) (MessageParser, error) {
-	version, err := ofpgeneral.GetMessageVersion(msg)
-	if err != nil {
-		return nil, err
+// deliver queues the message for the incoming channel, it waits for room
+// while the queue is full. The messages are delivered in the order they
+// are queued.
+func (mt *OfpMessageTunnel) deliver(msg ofpgeneral.OfpMessage) {
+	mt.events.push(msg, false, mt.done)
+}
+
+// deliverPacketIn queues the packet in for the incoming channel, it is
+// dropped if the queue is full
+func (mt *OfpMessageTunnel) deliverPacketIn(msg ofpgeneral.OfpMessage) {
+	if !mt.events.push(msg, true, mt.done) {
+		log.Debugf("The packet in is dropped, %d packet ins are dropped so far", mt.events.droppedCount())
 	}
+}
+
+// deliverWorker hands the queued messages to the incoming channel until
+// the tunnel is closed
+func (mt *OfpMessageTunnel) deliverWorker() {
+	defer mt.wg.Done()
+	for {
+		select {
+		case <-mt.events.ready:
+		case <-mt.done:
+			return
+		}
+		for _, msg := range mt.events.popAll() {
+			select {
+			case mt.Incomming <- msg:
+			case <-mt.done:
+				return
+			}
+		}
+	}
+}
+
+func genMsgParser(version uint8) (MessageParser, error) {
 	var parser MessageParser
+	var err error
 	switch version {
 	case ofp10.Version:
 		parser = &ofp10.OfpMessageParser{}
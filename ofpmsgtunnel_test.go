@@ -0,0 +1,276 @@
+package goof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// TestParseWorkerOrdering interleaves thousands of flow mod errors and
+// barrier replies and checks the transaction callbacks and the incoming
+// channel see them in the order they are sent by the switch
+func TestParseWorkerOrdering(t *testing.T) {
+	const count = 5000
+	const (
+		flowModXid     = 1000000
+		barrierXid     = 2000000
+		unsolicitedXid = 3000000
+	)
+	tunnel, switchConn := newTestTunnel(t, ofp13.Version)
+	defer tunnel.Close()
+	go io.Copy(io.Discard, switchConn)
+
+	var lock sync.Mutex
+	completed := make([]uint32, 0, 2*count)
+	allCompleted := make(chan struct{})
+	register := func(msg ofpgeneral.OfpMessage, xid uint32, expectErr bool) {
+		err := tunnel.RequestAsync(msg, 0, func(reply ofpgeneral.OfpMessage, err error) {
+			if (err != nil) != expectErr {
+				t.Errorf("Unexpected result %+v %v of request %d", reply, err, xid)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			completed = append(completed, xid)
+			if len(completed) == 2*count {
+				close(allCompleted)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wire bytes.Buffer
+	for i := uint32(0); i < count; i++ {
+		flowMod := ofp13.NewFlowModMsg(ofp13.OfpFlowModCmdAdd, 0)
+		flowMod.Header.Xid = flowModXid + i
+		register(flowMod, flowMod.Header.Xid, true)
+		barrier := ofpgeneral.NewOfpHeader(ofp13.Version)
+		barrier.Type = ofp13.OfpTypeBarrierRequest
+		barrier.Xid = barrierXid + i
+		register(barrier, barrier.Xid, false)
+
+		// The flow mod is rejected, then an error nobody waits for is
+		// received before the barrier reply
+		errMsg := ofpgeneral.NewErrMsg(ofp13.Version, ofp13.OfpErrTypeFlowModFailed, 0, nil)
+		errMsg.Header.Xid = flowModXid + i
+		data, _ := errMsg.MarshalBinary()
+		wire.Write(data)
+		errMsg.Header.Xid = unsolicitedXid + i
+		data, _ = errMsg.MarshalBinary()
+		wire.Write(data)
+		data, _ = newTestBarrierReply(ofp13.Version, barrierXid+i).MarshalBinary()
+		wire.Write(data)
+	}
+	go switchConn.Write(wire.Bytes())
+
+	for i := uint32(0); i < count; i++ {
+		select {
+		case msg := <-tunnel.Incomming:
+			errMsg, ok := msg.(*ofpgeneral.OfpErrMsg)
+			if !ok || errMsg.Header.Xid != unsolicitedXid+i {
+				t.Fatalf("Expected the error %d on the incoming channel, got %+v", unsolicitedXid+i, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("The error %d isn't delivered", unsolicitedXid+i)
+		}
+	}
+	select {
+	case <-allCompleted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The requests aren't completed")
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	for i := uint32(0); i < count; i++ {
+		if completed[2*i] != flowModXid+i || completed[2*i+1] != barrierXid+i {
+			t.Fatalf("The requests are completed out of order at %d: %v", i, completed[2*i:2*i+2])
+		}
+	}
+}
+
+// TestParseOfp11And12 checks the messages of openflow 1.1 and 1.2 are
+// decoded by their parsers and the unknown messages aren't delivered
+func TestParseOfp11And12(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint8
+		unknown string
+		wire    string
+		check   func(msg ofpgeneral.OfpMessage) bool
+	}{
+		{"1.1", ofp11.Version, "0218000800000001", "020a001e00000002 ffffffff 00000003 00000003 0006 00 00 aabbccddeeff",
+			func(msg ofpgeneral.OfpMessage) bool {
+				in, ok := msg.(*ofp11.OfpPacketInMsg)
+				return ok && in.InPort == 3
+			}},
+		{"1.2", ofp12.Version, "031b000800000001", "030a002800000002 ffffffff 0006 00 00 0001000c 80000004 00000003 00000000" +
+			"0000 aabbccddeeff",
+			func(msg ofpgeneral.OfpMessage) bool {
+				in, ok := msg.(*ofp12.OfpPacketInMsg)
+				return ok && len(in.Match.OXMFields) == 1
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tunnel, switchConn := newTestTunnel(t, tc.version)
+			defer tunnel.Close()
+			for _, wire := range []string{tc.unknown, tc.wire} {
+				data, err := hex.DecodeString(strings.Replace(wire, " ", "", -1))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := switchConn.Write(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			select {
+			case msg := <-tunnel.Incomming:
+				if !tc.check(msg) {
+					t.Errorf("Unexpected message %+v", msg)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("The packet in isn't delivered")
+			}
+		})
+	}
+}
+
+// newTestFrame creates the multipart reply of the length with the body
+// filled with the byte
+func newTestFrame(length int, fill byte) []byte {
+	frame := bytes.Repeat([]byte{fill}, length)
+	frame[0], frame[1] = ofp13.Version, ofp13.OfpTypeMultiPartReply
+	binary.BigEndian.PutUint16(frame[2:4], uint16(length))
+	binary.BigEndian.PutUint32(frame[4:8], uint32(fill))
+	return frame
+}
+
+func TestReadFrame(t *testing.T) {
+	joinFrames := func(frames ...[]byte) []byte {
+		return bytes.Join(frames, nil)
+	}
+	large := newTestFrame(100, 1)
+	maxFrame := newTestFrame(65535, 2)
+	small := [][]byte{newTestFrame(8, 3), newTestFrame(40, 4), newTestFrame(24, 5)}
+	tests := []struct {
+		name   string
+		size   int
+		stream []byte
+		frames [][]byte
+		err    error
+	}{
+		{"empty stream", defaultReadBufferSize, nil, nil, io.EOF},
+		{"single frame", defaultReadBufferSize, large, [][]byte{large}, io.EOF},
+		{"largest frame", defaultReadBufferSize, maxFrame, [][]byte{maxFrame}, io.EOF},
+		{"frame longer than the buffer", 16, large, [][]byte{large}, io.EOF},
+		{"frames across the buffer", 16, joinFrames(small...), small, io.EOF},
+		{"truncated header", defaultReadBufferSize, large[:4], nil, io.ErrUnexpectedEOF},
+		{"missing body", defaultReadBufferSize, large[:8], nil, io.ErrUnexpectedEOF},
+		{"truncated body", defaultReadBufferSize, large[:60], nil, io.ErrUnexpectedEOF},
+		{"truncated body after frame", 16, joinFrames(small[0], large[:60]), small[:1], io.ErrUnexpectedEOF},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fr := newFrameReaderSize(bytes.NewReader(tc.stream), tc.size)
+			buf := make([]byte, 0, 32)
+			for i, want := range tc.frames {
+				frame, err := fr.readFrame(buf)
+				if err != nil {
+					t.Fatalf("Frame %d: %v", i, err)
+				}
+				if !bytes.Equal(frame, want) {
+					t.Fatalf("Frame %d is %x, expected %x", i, frame, want)
+				}
+				buf = frame[:0]
+			}
+			if _, err := fr.readFrame(buf); err != tc.err {
+				t.Fatalf("Expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+// TestReadFrameInvalidLength checks the header lengths smaller than the
+// header are rejected without waiting for more bytes
+func TestReadFrameInvalidLength(t *testing.T) {
+	for length := 0; length < ofpHeaderLen; length++ {
+		t.Run(fmt.Sprintf("length %d", length), func(t *testing.T) {
+			r, w := io.Pipe()
+			defer w.Close()
+			header := newTestFrame(ofpHeaderLen, 0)
+			binary.BigEndian.PutUint16(header[2:4], uint16(length))
+			// The stream stays open after the header, reading beyond
+			// it would block forever
+			go w.Write(header)
+			errChan := make(chan error, 1)
+			go func() {
+				_, err := newFrameReader(r).readFrame(nil)
+				errChan <- err
+			}()
+			select {
+			case err := <-errChan:
+				if err == nil {
+					t.Fatal("The invalid length is accepted")
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("The reader is blocked by the invalid length")
+			}
+		})
+	}
+}
+
+// repeatReader returns the data over and over
+type repeatReader struct {
+	data []byte
+	pos  int
+}
+
+func (rr *repeatReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], rr.data[rr.pos:])
+		n += copied
+		rr.pos = (rr.pos + copied) % len(rr.data)
+	}
+	return n, nil
+}
+
+func benchmarkReadFrame(b *testing.B, frame []byte) {
+	fr := newFrameReader(&repeatReader{data: frame})
+	buf := make([]byte, 0, defaultMsgBufferSize)
+	b.SetBytes(int64(len(frame)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		frame, err := fr.readFrame(buf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf = frame[:0]
+	}
+}
+
+// BenchmarkReadFrameMultipart reads the largest multipart reply fragments,
+// as sent for the stats of big flow tables
+func BenchmarkReadFrameMultipart(b *testing.B) {
+	benchmarkReadFrame(b, newTestFrame(65535, 1))
+}
+
+// BenchmarkReadFramePacketInStorm reads small packet-in messages carrying
+// the first 128 bytes of the packets
+func BenchmarkReadFramePacketInStorm(b *testing.B) {
+	frame := newTestFrame(ofpHeaderLen+24+128, 1)
+	frame[1] = ofp13.OfpTypePacketIn
+	benchmarkReadFrame(b, frame)
+}
@@ -0,0 +1,105 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// collectMultipart returns the collector which reassembles the reply split
+// into several fragments. The fragments flagged with more are held back and
+// their bodies are joined in front of the body of the last fragment, which
+// completes the transaction.
+func collectMultipart() func(msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, bool) {
+	var body []byte
+	return func(msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, bool) {
+		switch reply := msg.(type) {
+		case *ofp10.OfpStatsReplyMsg:
+			body = append(body, reply.Body...)
+			if reply.IsMore() {
+				return nil, false
+			}
+			reply.Body = body
+			return reply, true
+		case *ofp13.OfpMultipartReplyMsg:
+			body = append(body, reply.Body...)
+			if reply.IsMore() {
+				return nil, false
+			}
+			reply.Body = body
+			return reply, true
+		}
+		return msg, true
+	}
+}
+
+// RequestMultipartAsync sends the stats or multipart request and invokes the
+// callback once all the reply fragments with the same xid are received. The
+// callback is given a single reply carrying the joined bodies.
+func (mt *OfpMessageTunnel) RequestMultipartAsync(msg ofpgeneral.OfpMessage, timeout time.Duration, callback ReplyCallback) error {
+	xid, err := ofpgeneral.GetOfpMsgXid(msg)
+	if err != nil {
+		return err
+	}
+	return mt.request(&ofpTransaction{xid: xid, callback: callback, collect: collectMultipart()}, msg, timeout)
+}
+
+// RequestMultipart sends the stats or multipart request and waits until all
+// the reply fragments with the same xid are received or the context is done
+func (mt *OfpMessageTunnel) RequestMultipart(ctx context.Context, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error) {
+	xid, err := ofpgeneral.GetOfpMsgXid(msg)
+	if err != nil {
+		return nil, err
+	}
+	return mt.wait(ctx, &ofpTransaction{xid: xid, collect: collectMultipart()}, msg)
+}
+
+// RequestMultipart sends the stats or multipart request and waits for the
+// reassembled reply
+func (sw *openflowSwitchImpl) RequestMultipart(ctx context.Context, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error) {
+	return sw.tunnel.RequestMultipart(ctx, msg)
+}
+
+// RequestMultipartAsync sends the stats or multipart request and invokes the
+// callback with the reassembled reply
+func (sw *openflowSwitchImpl) RequestMultipartAsync(msg ofpgeneral.OfpMessage, timeout time.Duration, callback ReplyCallback) error {
+	return sw.tunnel.RequestMultipartAsync(msg, timeout, callback)
+}
+
+// RequestStats sends the stats or multipart request and decodes the body of
+// the reassembled reply, see the DecodeBody of ofp10.OfpStatsReplyMsg and
+// ofp13.OfpMultipartReplyMsg, ofp11.DecodeStatsBody, ofp12.DecodeStatsBody,
+// ofp14.DecodeMultipartBody and ofp15.DecodeMultipartBody for the type of
+// the result
+func (sw *openflowSwitchImpl) RequestStats(ctx context.Context, msg ofpgeneral.OfpMessage) (interface{}, error) {
+	reply, err := sw.RequestMultipart(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	switch r := reply.(type) {
+	case *ofp10.OfpStatsReplyMsg:
+		return r.DecodeBody()
+	case *ofp13.OfpMultipartReplyMsg:
+		if r.Header.Version >= ofp15.Version {
+			return ofp15.DecodeMultipartBody(r)
+		}
+		switch r.Header.Version {
+		case ofp11.Version:
+			return ofp11.DecodeStatsBody(r)
+		case ofp12.Version:
+			return ofp12.DecodeStatsBody(r)
+		case ofp14.Version:
+			return ofp14.DecodeMultipartBody(r)
+		}
+		return r.DecodeBody()
+	}
+	return nil, fmt.Errorf("Unexpected reply %T to the stats request", reply)
+}
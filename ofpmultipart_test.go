@@ -0,0 +1,104 @@
+package goof
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// newTestStatsReply creates the port stats reply fragment of the version
+// carrying the stats of the port
+func newTestStatsReply(version uint8, xid uint32, portNo uint32, more bool) ofpgeneral.OfpMessage {
+	if version == ofp10.Version {
+		body := make([]byte, 104)
+		binary.BigEndian.PutUint16(body, uint16(portNo))
+		header := ofpgeneral.NewOfpHeader(version)
+		header.Type = ofp10.OfpTypeStatsReply
+		header.Xid = xid
+		reply := &ofp10.OfpStatsReplyMsg{Header: *header, Type: ofp10.OfpStatsTypePort, Body: body}
+		if more {
+			reply.Flags = ofp10.OfpStatsReplyMore
+		}
+		return reply
+	}
+	body := make([]byte, 112)
+	binary.BigEndian.PutUint32(body, portNo)
+	header := ofpgeneral.NewOfpHeader(version)
+	header.Type = ofp13.OfpTypeMultiPartReply
+	header.Xid = xid
+	reply := &ofp13.OfpMultipartReplyMsg{Header: *header, Type: ofp13.OfpMultipartTypePortStats, Body: body}
+	if more {
+		reply.Flags = ofp13.OfpMultipartReplyMore
+	}
+	return reply
+}
+
+// TestRequestStatsReassembly checks the reply fragments, interleaved with
+// other messages, are joined into the decoded stats of all the ports
+func TestRequestStatsReassembly(t *testing.T) {
+	const fragments = 3
+	tests := []struct {
+		name    string
+		version uint8
+		request ofpgeneral.OfpMessage
+		ports   func(body interface{}) []uint32
+	}{
+		{"openflow 1.0", ofp10.Version, ofp10.NewPortStatsRequestMsg(ofp10.OfpPortNone),
+			func(body interface{}) []uint32 {
+				ports := make([]uint32, 0)
+				for _, stats := range body.([]ofp10.OfpPortStats) {
+					ports = append(ports, uint32(stats.PortNo))
+				}
+				return ports
+			}},
+		{"openflow 1.3", ofp13.Version, ofp13.NewPortStatsRequestMsg(ofp13.OfpPortAny),
+			func(body interface{}) []uint32 {
+				ports := make([]uint32, 0)
+				for _, stats := range body.([]ofp13.OfpPortStats) {
+					ports = append(ports, stats.PortNo)
+				}
+				return ports
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tunnel, switchConn := newTestTunnel(t, tc.version)
+			defer tunnel.Close()
+			go func() {
+				msg, err := readTestMsg(switchConn)
+				if err != nil {
+					return
+				}
+				xid, _ := ofpgeneral.GetMessageXid(msg)
+				for port := uint32(1); port <= fragments; port++ {
+					// The asynchronous messages don't interrupt the
+					// reassembly
+					writeTestMsg(t, switchConn, ofpgeneral.NewEchoRequestMsg(tc.version, nil))
+					writeTestMsg(t, switchConn, newTestStatsReply(tc.version, xid, port, port < fragments))
+				}
+			}()
+
+			sw := &openflowSwitchImpl{tunnel: tunnel, version: tc.version}
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			body, err := sw.RequestStats(ctx, tc.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ports := tc.ports(body)
+			if len(ports) != fragments {
+				t.Fatalf("Expected the stats of %d ports, got %v", fragments, ports)
+			}
+			for i, port := range ports {
+				if port != uint32(i+1) {
+					t.Fatalf("The stats are joined out of order: %v", ports)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package goof
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// defaultRequestTimeout is the time given to the switch to answer a
+// request whose context has no deadline
+const defaultRequestTimeout = 10 * time.Second
+
+// ErrRequestTimeout is returned when the reply of a request is not
+// received before the timeout
+var ErrRequestTimeout = errors.New("The request timed out")
+
+// OfpRequestError is returned when the switch answers a request
+// with an error message
+type OfpRequestError struct {
+	Msg *ofpgeneral.OfpErrMsg
+}
+
+// Error returns the description of the error
+func (re *OfpRequestError) Error() string {
+	return fmt.Sprintf("The request %d is rejected with error type %d code %d",
+		re.Msg.Header.Xid, re.Msg.Type, re.Msg.Code)
+}
+
+// ReplyCallback is invoked with the reply of a request or the error
+// if the request failed. It is called from the receiving goroutine of
+// the tunnel and should not block.
+type ReplyCallback func(reply ofpgeneral.OfpMessage, err error)
+
+// ofpTransaction is a request waiting for the reply with the same xid
+type ofpTransaction struct {
+	xid      uint32
+	callback ReplyCallback
+	timer    *time.Timer
+	// collect is given each reply of the request when it isn't nil, the
+	// transaction is completed with the returned message once it reports
+	// the reply is the last one
+	collect func(msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, bool)
+}
+
+// ofpTransactionTable keeps the pending transactions of a tunnel
+type ofpTransactionTable struct {
+	lock    sync.Mutex
+	pending map[uint32]*ofpTransaction
+}
+
+func newTransactionTable() *ofpTransactionTable {
+	return &ofpTransactionTable{pending: make(map[uint32]*ofpTransaction)}
+}
+
+// add registers the transaction, it is aborted with ErrRequestTimeout
+// if it isn't completed within the timeout
+func (tt *ofpTransactionTable) add(trans *ofpTransaction, timeout time.Duration) {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	tt.pending[trans.xid] = trans
+	if timeout > 0 {
+		trans.timer = time.AfterFunc(timeout, func() {
+			if tt.remove(trans.xid) != nil {
+				trans.callback(nil, ErrRequestTimeout)
+			}
+		})
+	}
+}
+
+// remove takes the transaction out of the table, nil is returned if the
+// transaction is already completed
+func (tt *ofpTransactionTable) remove(xid uint32) *ofpTransaction {
+	tt.lock.Lock()
+	defer tt.lock.Unlock()
+	trans, ok := tt.pending[xid]
+	if !ok {
+		return nil
+	}
+	delete(tt.pending, xid)
+	if trans.timer != nil {
+		trans.timer.Stop()
+	}
+	return trans
+}
+
+// complete delivers the message to the transaction with the same xid.
+// It returns false if no transaction is waiting for the message.
+func (tt *ofpTransactionTable) complete(xid uint32, msg ofpgeneral.OfpMessage) bool {
+	tt.lock.Lock()
+	trans, ok := tt.pending[xid]
+	tt.lock.Unlock()
+	if !ok {
+		return false
+	}
+	errMsg, isErr := msg.(*ofpgeneral.OfpErrMsg)
+	if !isErr && trans.collect != nil {
+		reply, last := trans.collect(msg)
+		if !last {
+			return true
+		}
+		msg = reply
+	}
+	// The transaction may have timed out in the meantime
+	if tt.remove(xid) == nil {
+		return true
+	}
+	if isErr {
+		trans.callback(nil, &OfpRequestError{Msg: errMsg})
+	} else {
+		trans.callback(msg, nil)
+	}
+	return true
+}
+
+// failAll aborts all the pending transactions with the error
+func (tt *ofpTransactionTable) failAll(err error) {
+	tt.lock.Lock()
+	pending := tt.pending
+	tt.pending = make(map[uint32]*ofpTransaction)
+	tt.lock.Unlock()
+	for _, trans := range pending {
+		if trans.timer != nil {
+			trans.timer.Stop()
+		}
+		trans.callback(nil, err)
+	}
+}
+
+// RequestAsync sends the message and invokes the callback once the reply
+// or the error with the same xid is received. The callback receives
+// ErrRequestTimeout if nothing is received within the timeout, zero
+// timeout waits until the connection is closed.
+func (mt *OfpMessageTunnel) RequestAsync(msg ofpgeneral.OfpMessage, timeout time.Duration, callback ReplyCallback) error {
+	xid, err := ofpgeneral.GetOfpMsgXid(msg)
+	if err != nil {
+		return err
+	}
+	return mt.request(&ofpTransaction{xid: xid, callback: callback}, msg, timeout)
+}
+
+// Request sends the message and waits for the reply or the error with
+// the same xid until the context is done. ErrRequestTimeout is returned
+// if the context has no deadline and the reply isn't received within
+// the default request timeout.
+func (mt *OfpMessageTunnel) Request(ctx context.Context, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error) {
+	xid, err := ofpgeneral.GetOfpMsgXid(msg)
+	if err != nil {
+		return nil, err
+	}
+	return mt.wait(ctx, &ofpTransaction{xid: xid}, msg)
+}
+
+// wait sends the message and blocks until the transaction is completed
+// or the context is done
+func (mt *OfpMessageTunnel) wait(ctx context.Context, trans *ofpTransaction, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error) {
+	type result struct {
+		reply ofpgeneral.OfpMessage
+		err   error
+	}
+	resultChan := make(chan result, 1)
+	trans.callback = func(reply ofpgeneral.OfpMessage, err error) {
+		resultChan <- result{reply: reply, err: err}
+	}
+	var timeout time.Duration
+	if _, ok := ctx.Deadline(); !ok {
+		timeout = defaultRequestTimeout
+	}
+	if err := mt.request(trans, msg, timeout); err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-resultChan:
+		return res.reply, res.err
+	case <-ctx.Done():
+		// Abandon the transaction, a late reply is delivered as
+		// an ordinary message
+		mt.transactions.remove(trans.xid)
+		return nil, ctx.Err()
+	}
+}
+
+func (mt *OfpMessageTunnel) request(trans *ofpTransaction, msg ofpgeneral.OfpMessage, timeout time.Duration) error {
+	mt.transactions.add(trans, timeout)
+	select {
+	case <-mt.done:
+		mt.transactions.remove(trans.xid)
+		return ErrSwitchClosed
+	default:
+	}
+	select {
+	case mt.Outgoing <- msg:
+		return nil
+	case <-mt.done:
+		mt.transactions.remove(trans.xid)
+		return ErrSwitchClosed
+	}
+}
+
+// isAsyncMsg returns true for the messages initiated by the switch, they
+// never answer a request even if they carry a matching xid
+func isAsyncMsg(msg []byte) bool {
+	switch msg[1] {
+	case ofpgeneral.OfpTypeHello, ofpgeneral.OfpTypeEchoRequest,
+		ofp10.OfpTypePacketIn, ofp10.OfpTypeFlowRemoved, ofp10.OfpTypePortStatus:
+		return true
+	}
+	if msg[0] >= ofp14.Version {
+		switch msg[1] {
+		case ofp14.OfpTypeRoleStatus, ofp14.OfpTypeTableStatus, ofp14.OfpTypeRequestForward:
+			return true
+		}
+	}
+	return msg[0] >= ofp15.Version && msg[1] == ofp15.OfpTypeControllerStatus
+}
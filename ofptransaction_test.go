@@ -0,0 +1,247 @@
+package goof
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// newTestTunnel creates the tunnel of the version over a pipe, the other
+// end of the pipe plays the switch
+func newTestTunnel(t testing.TB, version uint8) (*OfpMessageTunnel, net.Conn) {
+	controllerConn, switchConn := net.Pipe()
+	tunnel := NewOfpMsgTunnel(controllerConn)
+	if err := tunnel.SetVersion(version); err != nil {
+		t.Fatal(err)
+	}
+	return tunnel, switchConn
+}
+
+func newTestBarrierReply(version uint8, xid uint32) *ofpgeneral.OfpHeader {
+	header := ofpgeneral.NewOfpHeader(version)
+	header.Type = ofp13.OfpTypeBarrierReply
+	if version == ofp10.Version {
+		header.Type = ofp10.OfpTypeBarrierReply
+	}
+	header.Xid = xid
+	return header
+}
+
+// newTestSwitch creates the switch of the version whose connection is
+// played by runTestSwitch
+func newTestSwitch(t testing.TB, version uint8, handle func(msg []byte) []ofpgeneral.OfpMessage) (*openflowSwitchImpl, <-chan []byte) {
+	tunnel, switchConn := newTestTunnel(t, version)
+	sw := &openflowSwitchImpl{tunnel: tunnel, version: version, datapathID: &DatapathID{rawValue: 1},
+		done: make(chan struct{})}
+	return sw, runTestSwitch(t, switchConn, version, handle)
+}
+
+// runTestSwitch plays the switch on the connection until it is closed. The
+// barrier requests are answered, the other messages are sent to the
+// returned channel and answered with the replies returned by the handler,
+// if any.
+func runTestSwitch(t testing.TB, conn net.Conn, version uint8, handle func(msg []byte) []ofpgeneral.OfpMessage) <-chan []byte {
+	barrierType := uint8(ofp13.OfpTypeBarrierRequest)
+	if version == ofp10.Version {
+		barrierType = ofp10.OfpTypeBarrierRequest
+	}
+	received := make(chan []byte, 16)
+	go func() {
+		defer close(received)
+		for {
+			msg, err := readTestMsg(conn)
+			if err != nil {
+				return
+			}
+			if msg[1] == barrierType {
+				xid, _ := ofpgeneral.GetMessageXid(msg)
+				writeTestMsg(t, conn, newTestBarrierReply(version, xid))
+				continue
+			}
+			received <- msg
+			if handle != nil {
+				for _, reply := range handle(msg) {
+					writeTestMsg(t, conn, reply)
+				}
+			}
+		}
+	}()
+	return received
+}
+
+// TestRequestWithUndrainedIncomming checks the reply of a request is
+// delivered while the incoming channel isn't read, as it happens when an
+// application sends a request from a callback of the dispatch loop
+func TestRequestWithUndrainedIncomming(t *testing.T) {
+	tunnel, switchConn := newTestTunnel(t, ofp13.Version)
+	defer tunnel.Close()
+	go func() {
+		for {
+			msg, err := readTestMsg(switchConn)
+			if err != nil {
+				return
+			}
+			xid := binary.BigEndian.Uint32(msg[4:])
+			// The asynchronous messages received before the reply
+			// wait in the queue of the incoming channel
+			for i := 0; i < 3; i++ {
+				writeTestMsg(t, switchConn, ofpgeneral.NewEchoRequestMsg(ofp13.Version, nil))
+			}
+			writeTestMsg(t, switchConn, newTestBarrierReply(ofp13.Version, xid))
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		request := ofpgeneral.NewOfpHeader(ofp13.Version)
+		request.Type = ofp13.OfpTypeBarrierRequest
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		reply, err := tunnel.Request(ctx, request)
+		cancel()
+		if err != nil {
+			t.Fatalf("Request %d failed: %v", i, err)
+		}
+		if header, ok := reply.(*ofpgeneral.OfpHeader); !ok || header.Xid != request.Xid {
+			t.Fatalf("Unexpected reply %+v to request %d", reply, request.Xid)
+		}
+	}
+	// The queued messages are still delivered afterwards
+	for i := 0; i < 6; i++ {
+		select {
+		case msg := <-tunnel.Incomming:
+			if _, ok := msg.(*ofpgeneral.OfpEchoMsg); !ok {
+				t.Fatalf("Unexpected message %T", msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("The message %d isn't delivered", i)
+		}
+	}
+}
+
+func TestMsgQueueBounded(t *testing.T) {
+	queue := newMsgQueue(2)
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		if !queue.push(ofpgeneral.NewEchoRequestMsg(ofp13.Version, nil), false, done) {
+			t.Fatalf("The message %d isn't queued", i)
+		}
+	}
+	// The packet in is dropped once the queue is full
+	if queue.push(ofpgeneral.NewEchoRequestMsg(ofp13.Version, nil), true, done) || queue.droppedCount() != 1 {
+		t.Fatalf("The packet in isn't dropped, %d messages are dropped", queue.droppedCount())
+	}
+
+	// The other messages wait for room
+	pushed := make(chan bool, 1)
+	go func() {
+		pushed <- queue.push(ofpgeneral.NewEchoReplyMsg(ofpgeneral.NewEchoRequestMsg(ofp13.Version, nil)), false, done)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("The message is queued while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if msgs := queue.popAll(); len(msgs) != 2 {
+		t.Fatalf("Popped %d messages, expected 2", len(msgs))
+	}
+	select {
+	case ok := <-pushed:
+		if !ok {
+			t.Fatal("The message isn't queued")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The message doesn't get room")
+	}
+	if msgs := queue.popAll(); len(msgs) != 1 {
+		t.Fatalf("Popped %d messages, expected 1", len(msgs))
+	}
+
+	// The waiting message is abandoned once the tunnel is closed
+	queue = newMsgQueue(0)
+	close(done)
+	if queue.push(ofpgeneral.NewEchoRequestMsg(ofp13.Version, nil), false, done) {
+		t.Error("The message is queued in the full queue")
+	}
+}
+
+// TestRequestWithPacketInStorm checks the reply of a request is delivered
+// while the packet ins received before it overflow the undrained incoming
+// channel
+func TestRequestWithPacketInStorm(t *testing.T) {
+	tunnel, switchConn := newTestTunnel(t, ofp10.Version)
+	defer tunnel.Close()
+	// The packet in of the buffer none received on the port 1
+	packetIn, _ := hex.DecodeString("010a001200000000ffffffff000000010000")
+	count := 2*defaultEventQueueSize + 10
+	go func() {
+		msg, err := readTestMsg(switchConn)
+		if err != nil {
+			return
+		}
+		for i := 0; i < count; i++ {
+			if _, err := switchConn.Write(packetIn); err != nil {
+				return
+			}
+		}
+		reply := ofpgeneral.NewOfpHeader(ofp10.Version)
+		reply.Type = ofp10.OfpTypeBarrierReply
+		reply.Xid = binary.BigEndian.Uint32(msg[4:])
+		writeTestMsg(t, switchConn, reply)
+	}()
+
+	request := ofpgeneral.NewOfpHeader(ofp10.Version)
+	request.Type = ofp10.OfpTypeBarrierRequest
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := tunnel.Request(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if dropped := tunnel.DroppedPacketIns(); dropped == 0 || dropped > uint64(count) {
+		t.Fatalf("%d packet ins are dropped", dropped)
+	}
+	// The packet ins which aren't dropped are delivered
+	deadline := time.After(2 * time.Second)
+	for delivered := uint64(0); delivered+tunnel.DroppedPacketIns() < uint64(count); {
+		select {
+		case msg := <-tunnel.Incomming:
+			if _, ok := msg.(*ofp10.OfpPacketInMsg); !ok {
+				t.Fatalf("Unexpected message %T", msg)
+			}
+			delivered++
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("%d packet ins are delivered and %d dropped, expected %d", delivered, tunnel.DroppedPacketIns(), count)
+		}
+	}
+}
+
+func TestRequestAsyncTimeout(t *testing.T) {
+	tunnel, switchConn := newTestTunnel(t, ofp13.Version)
+	defer tunnel.Close()
+	go io.Copy(io.Discard, switchConn)
+
+	request := ofpgeneral.NewOfpHeader(ofp13.Version)
+	request.Type = ofp13.OfpTypeBarrierRequest
+	errChan := make(chan error, 1)
+	err := tunnel.RequestAsync(request, 50*time.Millisecond, func(reply ofpgeneral.OfpMessage, err error) {
+		errChan <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errChan:
+		if err != ErrRequestTimeout {
+			t.Fatalf("Expected ErrRequestTimeout, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The request didn't time out")
+	}
+}
@@ -0,0 +1,231 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp12"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp15"
+)
+
+// RoleStatusHandler is implemented by the applications which want to be
+// notified when the switch reports that the role of the controller has
+// been changed, e.g. another controller became the master. The role is
+// one of the ofp13.OfpControllerRole* constants.
+type RoleStatusHandler interface {
+	RoleStatusRcvd(sw OpenflowSwitch, role uint32, generationID uint64)
+}
+
+// ControllerStatusHandler is implemented by the applications which want to
+// be notified when an openflow 1.5 switch reports that the status of one of
+// its controllers has changed, e.g. a controller has been added or its
+// channel went down.
+type ControllerStatusHandler interface {
+	ControllerStatusRcvd(sw OpenflowSwitch, status *ofp15.OfpControllerStatus)
+}
+
+// GetRole returns the role of the controller on the switch, which is one
+// of the ofp13.OfpControllerRole* constants
+func (sw *openflowSwitchImpl) GetRole() uint32 {
+	sw.lock.RLock()
+	defer sw.lock.RUnlock()
+	return sw.role
+}
+
+// SetRole requests the role of the controller on the switch. The master
+// and slave requests carry the generation id following the last one known
+// for the switch, and are retried once with a fresh generation id if the
+// switch rejects the request as stale. The openflow 1.0 switches are asked
+// by the Nicira role extension, which has no generation id.
+func (sw *openflowSwitchImpl) SetRole(ctx context.Context, role uint32) error {
+	switch role {
+	case ofp13.OfpControllerRoleEqual, ofp13.OfpControllerRoleMaster, ofp13.OfpControllerRoleSlave:
+	default:
+		return fmt.Errorf("Invalid controller role %d", role)
+	}
+	if sw.version == ofp10.Version {
+		return sw.requestNxRole(ctx, role)
+	}
+	if sw.version < ofp12.Version {
+		return fmt.Errorf("The controller roles aren't supported by openflow version %d", sw.version)
+	}
+	if role == ofp13.OfpControllerRoleEqual {
+		return sw.requestRole(ctx, role, 0)
+	}
+	generationID, known := sw.getGenerationID()
+	if !known {
+		if err := sw.requestRole(ctx, ofp13.OfpControllerRoleNoChange, 0); err != nil {
+			return err
+		}
+		generationID, _ = sw.getGenerationID()
+	}
+	err := sw.requestRole(ctx, role, generationID+1)
+	if !isStaleRoleError(err) {
+		return err
+	}
+	// Another controller has claimed a newer generation, catch up with
+	// the generation id known by the switch
+	if err := sw.requestRole(ctx, ofp13.OfpControllerRoleNoChange, 0); err != nil {
+		return err
+	}
+	generationID, _ = sw.getGenerationID()
+	return sw.requestRole(ctx, role, generationID+1)
+}
+
+func (sw *openflowSwitchImpl) requestRole(ctx context.Context, role uint32, generationID uint64) error {
+	msg := ofp13.NewRoleRequestMsg(role, generationID)
+	msg.Header.Version = sw.version
+	reply, err := sw.Request(ctx, msg)
+	if err != nil {
+		return err
+	}
+	roleReply, ok := reply.(*ofp13.OfpRoleRequestMsg)
+	if !ok {
+		return fmt.Errorf("Unexpected reply %T to the role request", reply)
+	}
+	sw.updateRole(roleReply.Role, roleReply.GenerationID)
+	return nil
+}
+
+func (sw *openflowSwitchImpl) requestNxRole(ctx context.Context, role uint32) error {
+	nxRole := uint32(ofp10.NxRoleOther)
+	switch role {
+	case ofp13.OfpControllerRoleMaster:
+		nxRole = ofp10.NxRoleMaster
+	case ofp13.OfpControllerRoleSlave:
+		nxRole = ofp10.NxRoleSlave
+	}
+	reply, err := sw.Request(ctx, ofp10.NewNxRoleRequestMsg(nxRole))
+	if err != nil {
+		return err
+	}
+	roleReply, ok := reply.(*ofp10.OfpNxRoleMsg)
+	if !ok {
+		return fmt.Errorf("Unexpected reply %T to the role request", reply)
+	}
+	switch roleReply.Role {
+	case ofp10.NxRoleMaster:
+		role = ofp13.OfpControllerRoleMaster
+	case ofp10.NxRoleSlave:
+		role = ofp13.OfpControllerRoleSlave
+	default:
+		role = ofp13.OfpControllerRoleEqual
+	}
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.role = role
+	return nil
+}
+
+// updateRole records the role and the generation id reported by the switch
+func (sw *openflowSwitchImpl) updateRole(role uint32, generationID uint64) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.role = role
+	sw.generationID = generationID
+	sw.generationKnown = true
+}
+
+func (sw *openflowSwitchImpl) getGenerationID() (uint64, bool) {
+	sw.lock.RLock()
+	defer sw.lock.RUnlock()
+	return sw.generationID, sw.generationKnown
+}
+
+// isStaleRoleError tells whether the role request is rejected because of
+// an old generation id
+func isStaleRoleError(err error) bool {
+	reqErr, ok := err.(*OfpRequestError)
+	return ok && reqErr.Msg.Type == ofp13.OfpErrTypeRoleRequestFailed &&
+		reqErr.Msg.Code == ofp13.OfpRoleRequestFailedStale
+}
+
+// SetRole declares the role requested on every switch once it connects,
+// ofp13.OfpControllerRoleNoChange leaves the switches in the role they
+// assign. The role is requested on the connected switches right away.
+func (oc *ofpControllerImpl) SetRole(role uint32) {
+	oc.lock.Lock()
+	oc.defaultRole = role
+	oc.lock.Unlock()
+	for _, sw := range oc.getSwitches() {
+		oc.applyRole(sw)
+	}
+}
+
+// SetSwitchRole declares the role requested on the switch of the datapath
+// id, which overrides the role declared by SetRole
+func (oc *ofpControllerImpl) SetSwitchRole(dpid uint64, role uint32) {
+	oc.lock.Lock()
+	oc.switchRoles[dpid] = role
+	sw, ok := oc.switches[dpid]
+	oc.lock.Unlock()
+	if ok {
+		oc.applyRole(sw.(*openflowSwitchImpl))
+	}
+}
+
+func (oc *ofpControllerImpl) getRole(dpid uint64) uint32 {
+	oc.lock.RLock()
+	defer oc.lock.RUnlock()
+	if role, ok := oc.switchRoles[dpid]; ok {
+		return role
+	}
+	return oc.defaultRole
+}
+
+func (oc *ofpControllerImpl) getSwitches() []*openflowSwitchImpl {
+	oc.lock.RLock()
+	defer oc.lock.RUnlock()
+	switches := make([]*openflowSwitchImpl, 0, len(oc.switches))
+	for _, sw := range oc.switches {
+		switches = append(switches, sw.(*openflowSwitchImpl))
+	}
+	return switches
+}
+
+// applyRole requests the declared role on the switch in the background,
+// the dispatch loop of the switch must not wait for the reply. The request
+// is abandoned when the controller is stopped, which waits for it.
+func (oc *ofpControllerImpl) applyRole(sw *openflowSwitchImpl) {
+	role := oc.getRole(sw.GetDatapathID().GetRawValue())
+	if role == ofp13.OfpControllerRoleNoChange {
+		return
+	}
+	oc.lock.Lock()
+	select {
+	case <-oc.stopChan:
+		oc.lock.Unlock()
+		return
+	default:
+	}
+	oc.wg.Add(1)
+	oc.lock.Unlock()
+	go func() {
+		defer oc.wg.Done()
+		ctx, cancel := context.WithTimeout(oc.ctx, defaultHandshakeTimeout)
+		defer cancel()
+		if err := sw.SetRole(ctx, role); err != nil {
+			log.Warnf("Failed to request the role %d on switch %s: %v", role, sw.GetDatapathID().GetHwAddr(), err)
+		}
+	}()
+}
+
+func (oc *ofpControllerImpl) notifyRoleStatus(sw OpenflowSwitch, role uint32, generationID uint64) {
+	for _, app := range oc.getApps() {
+		if handler, ok := app.(RoleStatusHandler); ok {
+			handler.RoleStatusRcvd(sw, role, generationID)
+		}
+	}
+}
+
+func (oc *ofpControllerImpl) notifyControllerStatus(sw OpenflowSwitch, status *ofp15.OfpControllerStatus) {
+	for _, app := range oc.getApps() {
+		if handler, ok := app.(ControllerStatusHandler); ok {
+			handler.ControllerStatusRcvd(sw, status)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package goof
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// TestApplyRoleStop checks Stop abandons the pending role request and
+// waits for it, and no role is requested once the controller is stopped
+func TestApplyRoleStop(t *testing.T) {
+	ctrler, _ := NewOfpController()
+	oc := ctrler.(*ofpControllerImpl)
+	oc.SetRole(ofp13.OfpControllerRoleEqual)
+
+	// The switch reads the role request and never replies
+	tunnel, switchConn := newTestTunnel(t, ofp13.Version)
+	defer tunnel.Close()
+	requested := make(chan struct{}, 2)
+	go func() {
+		for {
+			if _, err := readTestMsg(switchConn); err != nil {
+				return
+			}
+			requested <- struct{}{}
+		}
+	}()
+	sw := &openflowSwitchImpl{tunnel: tunnel, version: ofp13.Version, datapathID: &DatapathID{rawValue: 1}}
+	oc.applyRole(sw)
+	select {
+	case <-requested:
+	case <-time.After(2 * time.Second):
+		t.Fatal("The role isn't requested")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		oc.Stop()
+		close(stopped)
+	}()
+	// The stop doesn't wait for the handshake timeout of the request
+	select {
+	case <-stopped:
+	case <-time.After(defaultHandshakeTimeout / 2):
+		t.Fatal("The controller isn't stopped")
+	}
+
+	oc.applyRole(sw)
+	select {
+	case <-requested:
+		t.Fatal("The role is requested after the controller is stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -1,12 +1,30 @@
 package goof
 
 import (
+	"context"
+	"crypto/x509"
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
 
 	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofp15"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
+// ErrSwitchClosed is returned when a message is sent to a switch whose
+// connection has already been closed
+var ErrSwitchClosed = errors.New("The switch connection is closed")
+
 // DatapathID represents the datapath object
 type DatapathID struct {
 	rawValue uint64
@@ -24,14 +42,498 @@ func (dpid *DatapathID) GetHwAddr() net.HardwareAddr {
 	return hardwareAddr
 }
 
+// SwitchPort describes a port of the switch regardless of the
+// openflow version used to report it
+type SwitchPort struct {
+	PortNo uint32
+	HwAddr net.HardwareAddr
+	Name   string
+	Config uint32 /* Bitmap of OFPPC_* flags. */
+	State  uint32 /* Bitmap of OFPPS_* flags. */
+
+	/* Bitmaps of OFPPF_* that describe features.  All bits zeroed if
+	 * unsupported or unavailable. */
+	Curr       uint32 /* Current features. */
+	Advertised uint32 /* Features being advertised by the port. */
+	Supported  uint32 /* Features supported by the port. */
+	Peer       uint32 /* Features advertised by peer. */
+}
+
 // OpenflowSwitch descibes the switch supports openflow
 type OpenflowSwitch interface {
 	GetDatapathID() *DatapathID
 	DoesSupportOFVer(ofpversion uint8) bool
+	// GetVersion returns the openflow version negotiated with the switch
+	GetVersion() uint8
+	// GetNoOfBuffers returns the max packets buffered at once
+	GetNoOfBuffers() uint32
+	// GetNoOfTables returns the number of tables supported by datapath
+	GetNoOfTables() uint8
+	// GetCapabilities returns the bitmap of the supported capabilities
+	GetCapabilities() uint32
+	// GetPorts returns the ports known by the switch
+	GetPorts() []SwitchPort
+	// Send sends the message to the switch
+	Send(msg ofpgeneral.OfpMessage) error
+	// Close disconnects the switch from the controller
+	Close() error
+	// GetRTT returns the round trip time measured by the last echo exchange
+	GetRTT() time.Duration
+	// Request sends the message and waits for the reply with the same xid
+	Request(ctx context.Context, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error)
+	// RequestAsync sends the message and invokes the callback with the reply
+	RequestAsync(msg ofpgeneral.OfpMessage, timeout time.Duration, callback ReplyCallback) error
+	// RequestMultipart sends the stats or multipart request and waits for
+	// all the reply fragments, which are joined into a single reply
+	RequestMultipart(ctx context.Context, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error)
+	// RequestMultipartAsync sends the stats or multipart request and invokes
+	// the callback with the joined reply
+	RequestMultipartAsync(msg ofpgeneral.OfpMessage, timeout time.Duration, callback ReplyCallback) error
+	// RequestStats sends the stats or multipart request and returns the
+	// decoded body of the joined reply
+	RequestStats(ctx context.Context, msg ofpgeneral.OfpMessage) (interface{}, error)
+	// Barrier waits until the switch has processed the preceding messages
+	Barrier(ctx context.Context) error
+	// GetPeerCertificate returns the verified certificate of the switch,
+	// nil is returned if the connection isn't secured by tls
+	GetPeerCertificate() *x509.Certificate
+	// AddGroup installs the group of the type with the buckets
+	AddGroup(ctx context.Context, groupType uint8, groupID uint32, buckets []ofp13.OfpBucket) error
+	// ModifyGroup replaces the type and the buckets of the group
+	ModifyGroup(ctx context.Context, groupType uint8, groupID uint32, buckets []ofp13.OfpBucket) error
+	// DeleteGroup removes the group, ofp13.OfpGroupAll removes all the groups
+	DeleteGroup(ctx context.Context, groupID uint32) error
+	// AddMeter installs the meter with the flags and the bands
+	AddMeter(ctx context.Context, flags uint16, meterID uint32, bands []ofp13.OfpMeterBand) error
+	// ModifyMeter replaces the flags and the bands of the meter
+	ModifyMeter(ctx context.Context, flags uint16, meterID uint32, bands []ofp13.OfpMeterBand) error
+	// DeleteMeter removes the meter, ofp13.OfpMeterAll removes all the meters
+	DeleteMeter(ctx context.Context, meterID uint32) error
+	// GetRole returns the role of the controller on the switch, which is
+	// one of the ofp13.OfpControllerRole* constants
+	GetRole() uint32
+	// SetRole requests the role of the controller on the switch
+	SetRole(ctx context.Context, role uint32) error
+	// SetAsyncConfig replaces the asynchronous message configuration
+	SetAsyncConfig(ctx context.Context, config *AsyncConfig) error
+	// GetAsyncConfig queries the asynchronous message configuration
+	GetAsyncConfig(ctx context.Context) (*AsyncConfig, error)
+	// Bundle applies the messages added by fill as a single operation
+	Bundle(ctx context.Context, flags uint16, fill func(b *Bundle)) error
+	// MonitorFlows subscribes to the changes of the flows selected by
+	// the filter, the id of the monitor is returned
+	MonitorFlows(ctx context.Context, filter *FlowMonitorFilter, handler FlowMonitorHandler) (uint32, error)
+	// CancelFlowMonitor deletes the flow monitor
+	CancelFlowMonitor(ctx context.Context, monitorID uint32) error
+	// GetTableFeatures queries the features of the flow tables
+	GetTableFeatures(ctx context.Context) ([]ofp15.OfpTableFeatures, error)
+	// SetFirstEgressTable configures the first egress table of the pipeline
+	SetFirstEgressTable(ctx context.Context, tableID uint8) error
+	// ModifyFlows sends the version-neutral flow mod translated to the
+	// negotiated version
+	ModifyFlows(ctx context.Context, fm *FlowMod) error
+	// SendPacketOut sends the version-neutral packet out translated to the
+	// negotiated version
+	SendPacketOut(po *PacketOut) error
+}
+
+type openflowSwitchImpl struct {
+	tunnel       *OfpMessageTunnel
+	datapathID   *DatapathID
+	version      uint8
+	versions     []uint8
+	noOfBuffers  uint32
+	noOfTables   uint8
+	capabilities uint32
+
+	lock  sync.RWMutex
+	ports []SwitchPort
+
+	// The controller role and the last master election generation id
+	// reported by the switch
+	role            uint32
+	generationID    uint64
+	generationKnown bool
+
+	// The id of the last bundle opened on the switch
+	lastBundleID uint32
+
+	// The flow monitors by monitor id
+	monitors      map[uint32]*flowMonitor
+	lastMonitorID uint32
+
+	// Liveness detection states
+	lastRcvd   time.Time
+	echoMisses int
+	echoXid    uint32
+	echoSent   time.Time
+	rtt        time.Duration
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewSwitch generates a new switch object from the features reply
+// received on the message tunnel
+func NewSwitch(tunnel *OfpMessageTunnel, msg ofpgeneral.OfpMessage) (OpenflowSwitch, error) {
+	sw := &openflowSwitchImpl{tunnel: tunnel, version: tunnel.Version}
+	sw.role = ofp13.OfpControllerRoleEqual
+	sw.monitors = make(map[uint32]*flowMonitor)
+	sw.versions = tunnel.peerVersions
+	sw.done = make(chan struct{})
+	sw.lastRcvd = time.Now()
+	switch m := msg.(type) {
+	case *ofp10.OfpSwitchFeatureMsg:
+		sw.datapathID = &DatapathID{rawValue: m.DatapathID}
+		sw.noOfBuffers = m.NoOfBuffers
+		sw.noOfTables = m.NoOfTables
+		sw.capabilities = m.Capabilities
+		sw.ports = make([]SwitchPort, 0, len(m.Ports))
+		for _, port := range m.Ports {
+			sw.ports = append(sw.ports, newSwitchPortV10(&port))
+		}
+	case *ofp11.OfpSwitchFeatureMsg:
+		sw.datapathID = &DatapathID{rawValue: m.DatapathID}
+		sw.noOfBuffers = m.NoOfBuffers
+		sw.noOfTables = m.NoOfTables
+		sw.capabilities = m.Capabilities
+		// The ports of openflow 1.1 and 1.2 have the layout of
+		// openflow 1.3
+		sw.ports = make([]SwitchPort, 0, len(m.Ports))
+		for idx := range m.Ports {
+			sw.ports = append(sw.ports, newSwitchPortV13(&m.Ports[idx]))
+		}
+	case *ofp13.OfpSwitchFeatureMsg:
+		sw.datapathID = &DatapathID{rawValue: m.DatapathID}
+		sw.noOfBuffers = m.NoOfBuffers
+		sw.noOfTables = m.NoOfTables
+		sw.capabilities = m.Capabilities
+		// The ports are no longer part of the features reply since
+		// openflow 1.3, they are retrieved by port description request
+		sw.ports = make([]SwitchPort, 0)
+	default:
+		return nil, fmt.Errorf("Unsupported features reply %T", msg)
+	}
+	return sw, nil
+}
+
+func newSwitchPortV10(port *ofp10.OfpPhysPort) SwitchPort {
+	return SwitchPort{
+		PortNo:     uint32(port.PortNo),
+		HwAddr:     port.HwAddr,
+		Name:       strings.TrimRight(string(port.Name), "\x00"),
+		Config:     port.Config,
+		State:      port.State,
+		Curr:       port.Curr,
+		Advertised: port.Advertised,
+		Supported:  port.Supported,
+		Peer:       port.Peer,
+	}
+}
+
+func newSwitchPortV13(port *ofp13.OfpPhysPort) SwitchPort {
+	return SwitchPort{
+		PortNo:     port.PortNo,
+		HwAddr:     port.HwAddr,
+		Name:       strings.TrimRight(string(port.Name), "\x00"),
+		Config:     port.Config,
+		State:      port.State,
+		Curr:       port.Curr,
+		Advertised: port.Advertised,
+		Supported:  port.Supported,
+		Peer:       port.Peer,
+	}
+}
+
+func newSwitchPortV14(port *ofp14.OfpPort) SwitchPort {
+	switchPort := SwitchPort{
+		PortNo: port.PortNo,
+		HwAddr: port.HwAddr,
+		Name:   strings.TrimRight(string(port.Name), "\x00"),
+		Config: port.Config,
+		State:  port.State,
+	}
+	// The features are only known for the ethernet ports
+	if ethernet := port.GetEthernetProp(); ethernet != nil {
+		switchPort.Curr = ethernet.Curr
+		switchPort.Advertised = ethernet.Advertised
+		switchPort.Supported = ethernet.Supported
+		switchPort.Peer = ethernet.Peer
+	}
+	return switchPort
+}
+
+func newSwitchPortV15(port *ofp15.OfpPort) SwitchPort {
+	switchPort := SwitchPort{
+		PortNo: port.PortNo,
+		HwAddr: port.HwAddr,
+		Name:   strings.TrimRight(string(port.Name), "\x00"),
+		Config: port.Config,
+		State:  port.State,
+	}
+	// The features are only known for the ethernet ports
+	if ethernet := port.GetEthernetProp(); ethernet != nil {
+		switchPort.Curr = ethernet.Curr
+		switchPort.Advertised = ethernet.Advertised
+		switchPort.Supported = ethernet.Supported
+		switchPort.Peer = ethernet.Peer
+	}
+	return switchPort
+}
+
+// requestPortDesc retrieves the ports of an openflow 1.3 or later switch by
+// the port description multipart request, the known ports are replaced
+// once the reply is received
+func (sw *openflowSwitchImpl) requestPortDesc() error {
+	msg := ofp13.NewPortDescRequestMsg()
+	msg.Header.Version = sw.version
+	return sw.RequestMultipartAsync(msg, defaultHandshakeTimeout, func(reply ofpgeneral.OfpMessage, err error) {
+		if err != nil {
+			log.Warnf("Failed to retrieve the ports of switch %s: %v", sw.GetDatapathID().GetHwAddr(), err)
+			return
+		}
+		mp, ok := reply.(*ofp13.OfpMultipartReplyMsg)
+		if !ok {
+			log.Warnf("Unexpected reply %T to the port description request", reply)
+			return
+		}
+		var switchPorts []SwitchPort
+		switch sw.version {
+		case ofp13.Version:
+			ports, err := ofp13.ParsePortDescBody(mp.Body)
+			if err != nil {
+				log.Warnf("Failed to decode the port description: %v", err)
+				return
+			}
+			for idx := range ports {
+				switchPorts = append(switchPorts, newSwitchPortV13(&ports[idx]))
+			}
+		case ofp14.Version:
+			ports, err := ofp14.ParsePortDescBody(mp.Body)
+			if err != nil {
+				log.Warnf("Failed to decode the port description: %v", err)
+				return
+			}
+			for idx := range ports {
+				switchPorts = append(switchPorts, newSwitchPortV14(&ports[idx]))
+			}
+		default:
+			ports, err := ofp15.ParsePortDescBody(mp.Body)
+			if err != nil {
+				log.Warnf("Failed to decode the port description: %v", err)
+				return
+			}
+			for idx := range ports {
+				switchPorts = append(switchPorts, newSwitchPortV15(&ports[idx]))
+			}
+		}
+		sw.lock.Lock()
+		defer sw.lock.Unlock()
+		sw.ports = make([]SwitchPort, 0, len(switchPorts))
+		sw.ports = append(sw.ports, switchPorts...)
+	})
+}
+
+// updatePort applies the port change reported by the port status
+// message to the known ports of the switch
+func (sw *openflowSwitchImpl) updatePort(reason uint8, port SwitchPort) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	for idx := range sw.ports {
+		if sw.ports[idx].PortNo != port.PortNo {
+			continue
+		}
+		if reason == ofp10.OfpPortReasonDelete {
+			sw.ports = append(sw.ports[:idx], sw.ports[idx+1:]...)
+		} else {
+			sw.ports[idx] = port
+		}
+		return
+	}
+	if reason != ofp10.OfpPortReasonDelete {
+		sw.ports = append(sw.ports, port)
+	}
+}
+
+// GetDatapathID returns the datapath id of the switch
+func (sw *openflowSwitchImpl) GetDatapathID() *DatapathID {
+	return sw.datapathID
+}
+
+// DoesSupportOFVer returns whether the switch talks the openflow version or not
+func (sw *openflowSwitchImpl) DoesSupportOFVer(ofpversion uint8) bool {
+	if ofpversion == sw.version {
+		return true
+	}
+	return containsVersion(sw.versions, ofpversion)
+}
+
+// GetVersion returns the openflow version negotiated with the switch
+func (sw *openflowSwitchImpl) GetVersion() uint8 {
+	return sw.version
+}
+
+// GetNoOfBuffers returns the max packets buffered at once
+func (sw *openflowSwitchImpl) GetNoOfBuffers() uint32 {
+	return sw.noOfBuffers
+}
+
+// GetNoOfTables returns the number of tables supported by datapath
+func (sw *openflowSwitchImpl) GetNoOfTables() uint8 {
+	return sw.noOfTables
+}
+
+// GetCapabilities returns the bitmap of the supported capabilities
+func (sw *openflowSwitchImpl) GetCapabilities() uint32 {
+	return sw.capabilities
+}
+
+// GetPorts returns the ports known by the switch
+func (sw *openflowSwitchImpl) GetPorts() []SwitchPort {
+	sw.lock.RLock()
+	defer sw.lock.RUnlock()
+	ports := make([]SwitchPort, len(sw.ports))
+	copy(ports, sw.ports)
+	return ports
+}
+
+// Send sends the message to the switch
+func (sw *openflowSwitchImpl) Send(msg ofpgeneral.OfpMessage) error {
+	select {
+	case <-sw.done:
+		return ErrSwitchClosed
+	default:
+	}
+	return sw.tunnel.Send(msg)
+}
+
+// Request sends the message and waits for the reply with the same xid
+func (sw *openflowSwitchImpl) Request(ctx context.Context, msg ofpgeneral.OfpMessage) (ofpgeneral.OfpMessage, error) {
+	return sw.tunnel.Request(ctx, msg)
+}
+
+// RequestAsync sends the message and invokes the callback with the reply
+// with the same xid, or ErrRequestTimeout if the reply isn't received
+// within the timeout
+func (sw *openflowSwitchImpl) RequestAsync(msg ofpgeneral.OfpMessage, timeout time.Duration, callback ReplyCallback) error {
+	return sw.tunnel.RequestAsync(msg, timeout, callback)
+}
+
+// Barrier waits until the switch has processed the preceding messages
+func (sw *openflowSwitchImpl) Barrier(ctx context.Context) error {
+	header := ofpgeneral.NewOfpHeader(sw.version)
+	switch sw.version {
+	case ofp10.Version:
+		header.Type = ofp10.OfpTypeBarrierRequest
+	default:
+		header.Type = ofp13.OfpTypeBarrierRequest
+	}
+	_, err := sw.Request(ctx, header)
+	return err
+}
+
+// modify sends the state modification message followed by a barrier, as
+// the switch answers a modification only if it fails. The error sent by
+// the switch to reject the modification is returned.
+func (sw *openflowSwitchImpl) modify(ctx context.Context, msg ofpgeneral.OfpMessage) error {
+	xid, err := ofpgeneral.GetOfpMsgXid(msg)
+	if err != nil {
+		return err
+	}
+	errChan := make(chan error, 1)
+	err = sw.RequestAsync(msg, 0, func(reply ofpgeneral.OfpMessage, err error) {
+		errChan <- err
+	})
+	if err != nil {
+		return err
+	}
+	// The error of the modification is always received before the
+	// barrier reply, the transaction is dropped once the barrier is
+	// answered since no reply is expected on success
+	barrierErr := sw.Barrier(ctx)
+	sw.tunnel.transactions.remove(xid)
+	select {
+	case err := <-errChan:
+		return err
+	default:
+	}
+	return barrierErr
+}
+
+// Close disconnects the switch from the controller
+func (sw *openflowSwitchImpl) Close() error {
+	sw.closeOnce.Do(func() {
+		close(sw.done)
+	})
+	return sw.tunnel.Close()
+}
+
+// disconnected marks the switch as closed once the connection is lost
+func (sw *openflowSwitchImpl) disconnected() {
+	sw.closeOnce.Do(func() {
+		close(sw.done)
+	})
+}
+
+// GetPeerCertificate returns the verified certificate of the switch,
+// nil is returned if the connection isn't secured by tls
+func (sw *openflowSwitchImpl) GetPeerCertificate() *x509.Certificate {
+	return sw.tunnel.PeerCertificate()
+}
+
+// GetRTT returns the round trip time measured by the last echo exchange
+func (sw *openflowSwitchImpl) GetRTT() time.Duration {
+	sw.lock.RLock()
+	defer sw.lock.RUnlock()
+	return sw.rtt
+}
+
+// msgReceived records that the switch is alive since a message is
+// received from it
+func (sw *openflowSwitchImpl) msgReceived() {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.lastRcvd = time.Now()
+	sw.echoMisses = 0
+}
+
+// handleEcho answers the echo requests and measures the round
+// trip time with the echo replies
+func (sw *openflowSwitchImpl) handleEcho(msg *ofpgeneral.OfpEchoMsg) {
+	if msg.Header.Type == ofpgeneral.OfpTypeEchoRequest {
+		if err := sw.Send(ofpgeneral.NewEchoReplyMsg(msg)); err != nil {
+			log.Warnf("Failed to reply echo request of switch %s: %v", sw.datapathID.GetHwAddr(), err)
+		}
+		return
+	}
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	if msg.Header.Xid == sw.echoXid && !sw.echoSent.IsZero() {
+		sw.rtt = time.Since(sw.echoSent)
+		sw.echoSent = time.Time{}
+	}
 }
 
-// NewSwitch generates a new switch object
-func NewSwitch(tunnel *OfpMessageTunnel, msg *ofp10.OfpSwitchFeatureMsg) OpenflowSwitch {
-	// TODO
-	return nil
+// probe sends an echo request if the connection has been idle for the
+// interval. It returns false when the switch missed too many echo
+// requests and is considered dead.
+func (sw *openflowSwitchImpl) probe(interval time.Duration, maxMisses int) bool {
+	sw.lock.Lock()
+	if time.Since(sw.lastRcvd) < interval {
+		sw.lock.Unlock()
+		return true
+	}
+	if sw.echoMisses >= maxMisses {
+		sw.lock.Unlock()
+		return false
+	}
+	echo := ofpgeneral.NewEchoRequestMsg(sw.version, nil)
+	sw.echoMisses++
+	sw.echoXid = echo.Header.Xid
+	sw.echoSent = time.Now()
+	sw.lock.Unlock()
+	if err := sw.Send(echo); err != nil {
+		log.Warnf("Failed to send echo request to switch %s: %v", sw.datapathID.GetHwAddr(), err)
+	}
+	return true
 }
@@ -0,0 +1,106 @@
+package goof
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kopwei/goof/protocols/ofp10"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// connectTestSwitch serves the openflow 1.0 switch played by the returned
+// end of a pipe with the echo interval, the switch is connected once it
+// returns
+func connectTestSwitch(t *testing.T, interval time.Duration, maxMisses int) (net.Conn, OpenflowSwitch, *testApp, <-chan bool) {
+	oc := newTestController(t)
+	oc.SetEchoInterval(interval, maxMisses)
+	app := newTestApp()
+	oc.RegisterApp(app)
+	conn, result := runTestConnection(t, oc)
+	handshakeTestSwitch(t, conn)
+	sw := waitTestEvent(t, app.connected, "connected")
+	return conn, sw, app, result
+}
+
+// readTestEchoRequest waits for the next echo request sent to the switch
+func readTestEchoRequest(t *testing.T, conn net.Conn) *ofpgeneral.OfpEchoMsg {
+	for {
+		msg, err := readTestMsg(conn)
+		if err != nil {
+			t.Fatalf("Expected the echo request, got %v", err)
+		}
+		if msg[1] != ofpgeneral.OfpTypeEchoRequest {
+			continue
+		}
+		echo := &ofpgeneral.OfpEchoMsg{}
+		if err := echo.UnmarshalBinary(msg); err != nil {
+			t.Fatal(err)
+		}
+		return echo
+	}
+}
+
+func TestEchoRequestAnswered(t *testing.T) {
+	conn, _, _, result := connectTestSwitch(t, 0, 0)
+	request := ofpgeneral.NewEchoRequestMsg(ofp10.Version, []byte{0xaa, 0xbb})
+	writeTestMsg(t, conn, request)
+
+	msg, err := readTestMsg(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := &ofpgeneral.OfpEchoMsg{}
+	if err := reply.UnmarshalBinary(msg); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Header.Type != ofpgeneral.OfpTypeEchoReply || reply.Header.Xid != request.Header.Xid ||
+		!bytes.Equal(reply.Data, request.Data) {
+		t.Fatalf("Unexpected echo reply %+v", reply)
+	}
+	conn.Close()
+	checkConnectionResult(t, result, true)
+}
+
+func TestEchoProbe(t *testing.T) {
+	interval := 50 * time.Millisecond
+	conn, sw, app, result := connectTestSwitch(t, interval, 2)
+
+	// The answered echo requests keep the idle switch connected, they are
+	// sent once the switch has been idle for the interval
+	last := time.Now()
+	for i := 0; i < 4; i++ {
+		request := readTestEchoRequest(t, conn)
+		if elapsed := time.Since(last); elapsed < interval/2 {
+			t.Fatalf("The echo request %d is sent %v after the previous one", i, elapsed)
+		}
+		last = time.Now()
+		writeTestMsg(t, conn, ofpgeneral.NewEchoReplyMsg(request))
+	}
+	select {
+	case <-app.disconnected:
+		t.Fatal("The answering switch is disconnected")
+	default:
+	}
+	if sw.GetRTT() <= 0 {
+		t.Error("The round trip time isn't measured")
+	}
+	conn.Close()
+	checkConnectionResult(t, result, true)
+}
+
+func TestEchoMaxMisses(t *testing.T) {
+	conn, _, app, result := connectTestSwitch(t, 20*time.Millisecond, 2)
+
+	// The switch is disconnected once it missed the replies of the two
+	// echo requests
+	for i := 0; i < 2; i++ {
+		readTestEchoRequest(t, conn)
+	}
+	waitTestEvent(t, app.disconnected, "disconnected")
+	checkConnectionResult(t, result, true)
+	if msg, err := readTestMsg(conn); err == nil {
+		t.Errorf("Received %x after the missed echo requests", msg)
+	}
+}
@@ -0,0 +1,68 @@
+package goof
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp15"
+)
+
+// GetTableFeatures queries the features of the flow tables of the switch,
+// only the openflow 1.5 layout is supported
+func (sw *openflowSwitchImpl) GetTableFeatures(ctx context.Context) ([]ofp15.OfpTableFeatures, error) {
+	if sw.version < ofp15.Version {
+		return nil, fmt.Errorf("The table features aren't supported by openflow version %d", sw.version)
+	}
+	msg, err := ofp15.NewTableFeaturesRequestMsg(nil)
+	if err != nil {
+		return nil, err
+	}
+	msg.Header.Version = sw.version
+	body, err := sw.RequestStats(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	features, ok := body.([]ofp15.OfpTableFeatures)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected body %T of the table features reply", body)
+	}
+	return features, nil
+}
+
+// SetFirstEgressTable configures the table as the first egress table of the
+// pipeline, the packets output to a port are then processed by the egress
+// tables from this one. The table must be able to be an egress table.
+func (sw *openflowSwitchImpl) SetFirstEgressTable(ctx context.Context, tableID uint8) error {
+	features, err := sw.GetTableFeatures(ctx)
+	if err != nil {
+		return err
+	}
+	for idx := range features {
+		table := &features[idx]
+		if table.TableID != tableID {
+			continue
+		}
+		if table.Features&ofp15.OfpTableFeatureFlagEgressTable == 0 {
+			return fmt.Errorf("The table %d can't be configured as egress table", tableID)
+		}
+		// The modify command without property only changes the flags
+		update := ofp15.OfpTableFeatures{
+			TableID:       tableID,
+			Command:       ofp15.OfpTableFeaturesCmdModify,
+			Features:      table.Features | ofp15.OfpTableFeatureFlagFirstEgress,
+			Name:          table.Name,
+			MetadataMatch: table.MetadataMatch,
+			MetadataWrite: table.MetadataWrite,
+			Capabilities:  table.Capabilities,
+			MaxEntries:    table.MaxEntries,
+		}
+		msg, err := ofp15.NewTableFeaturesRequestMsg([]ofp15.OfpTableFeatures{update})
+		if err != nil {
+			return err
+		}
+		msg.Header.Version = sw.version
+		_, err = sw.RequestMultipart(ctx, msg)
+		return err
+	}
+	return fmt.Errorf("The table %d doesn't exist in switch %s", tableID, sw.GetDatapathID().GetHwAddr())
+}
@@ -0,0 +1,38 @@
+package goof
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// NewTLSConfig creates the tls configuration of the controller from the
+// certificate and private key of the controller and the CA certificate
+// used to verify the datapaths. The datapaths have to present a
+// certificate signed by the CA, the same way as the ssl controller
+// connection of OVS, both when they connect to the controller and when
+// the controller connects to them. The host name of the switch connected
+// to isn't verified unless the ServerName of the configuration is set.
+func NewTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("No valid certificate is found in %s", caFile)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    caPool,
+		RootCAs:      caPool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return config, nil
+}
@@ -0,0 +1,205 @@
+package goof
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues the certificates of the tls tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA creates the self signed CA of the name
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue creates the certificate of the name signed by the CA, like the
+// certificates of OVS it carries no address. The pem encoded certificate
+// and key are returned too.
+func (ca *testCA) issue(t *testing.T, name string) (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, certPEM, keyPEM
+}
+
+// newTestTLSConfig creates the configuration of the controller with
+// NewTLSConfig from the certificate of the name signed by the CA
+func newTestTLSConfig(t *testing.T, ca *testCA, name string) *tls.Config {
+	_, certPEM, keyPEM := ca.issue(t, name)
+	dir := t.TempDir()
+	files := map[string][]byte{"cert.pem": certPEM, "key.pem": keyPEM, "ca.pem": ca.pem}
+	for file, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := NewTLSConfig(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// runTestTLSListener accepts the tls connections with the configuration
+// in the background, the result of the handshake of each connection is
+// sent to the returned channel
+func runTestTLSListener(t *testing.T, config *tls.Config) (string, <-chan error) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	handshakes := make(chan error, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+			handshakes <- conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), handshakes
+}
+
+func TestDialTargetVerifiesSwitchChain(t *testing.T) {
+	ca := newTestCA(t, "switch ca")
+	config := newTestTLSConfig(t, ca, "controller")
+	switchCert, _, _ := ca.issue(t, "br0")
+	otherCert, _, _ := newTestCA(t, "other ca").issue(t, "br0")
+
+	tests := []struct {
+		name       string
+		cert       tls.Certificate
+		serverName string
+		accepted   bool
+	}{
+		{"certificate signed by the CA", switchCert, "", true},
+		{"certificate signed by another CA", otherCert, "", false},
+		{"certificate without the server name", switchCert, "br1", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			address, _ := runTestTLSListener(t, &tls.Config{Certificates: []tls.Certificate{tc.cert}})
+			dialConfig := config.Clone()
+			dialConfig.ServerName = tc.serverName
+			conn, err := dialTarget(context.Background(), schemeSSL, address, dialConfig)
+			if err == nil {
+				conn.Close()
+			}
+			if accepted := err == nil; accepted != tc.accepted {
+				t.Fatalf("The switch certificate is accepted: %t, expected %t, %v", accepted, tc.accepted, err)
+			}
+		})
+	}
+}
+
+func TestTLSConfigVerifiesSwitchCertificate(t *testing.T) {
+	ca := newTestCA(t, "switch ca")
+	address, handshakes := runTestTLSListener(t, newTestTLSConfig(t, ca, "controller"))
+	switchCert, _, _ := ca.issue(t, "br0")
+	otherCert, _, _ := newTestCA(t, "other ca").issue(t, "br0")
+
+	tests := []struct {
+		name     string
+		certs    []tls.Certificate
+		accepted bool
+	}{
+		{"certificate signed by the CA", []tls.Certificate{switchCert}, true},
+		{"certificate signed by another CA", []tls.Certificate{otherCert}, false},
+		{"no certificate", nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// The switch doesn't verify the controller here, only the
+			// verification of the switch by the controller is tested
+			conn, err := tls.Dial("tcp", address, &tls.Config{Certificates: tc.certs, InsecureSkipVerify: true})
+			if err == nil {
+				defer conn.Close()
+			}
+			select {
+			case err := <-handshakes:
+				if accepted := err == nil; accepted != tc.accepted {
+					t.Fatalf("The switch certificate is accepted: %t, expected %t, %v", accepted, tc.accepted, err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("The handshake isn't completed")
+			}
+		})
+	}
+}
+
+// TestDialTargetNoCertificate checks the switch presenting no certificate
+// is rejected by the chain verification
+func TestDialTargetNoCertificate(t *testing.T) {
+	config := switchTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()})
+	if err := config.VerifyPeerCertificate(nil, nil); err == nil {
+		t.Error("The switch without certificate is accepted")
+	}
+	// The host name is verified once the server name is configured
+	if config := switchTLSConfig(&tls.Config{ServerName: "br0"}); config.InsecureSkipVerify {
+		t.Error("The host name verification is skipped")
+	}
+}
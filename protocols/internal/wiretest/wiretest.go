@@ -0,0 +1,48 @@
+// Package wiretest checks the openflow codecs against the wire bytes, it
+// is shared by the tests of the protocol packages
+package wiretest
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Parser decodes the messages of an openflow version
+type Parser interface {
+	ParseMsg(data []byte) (ofpgeneral.OfpMessage, error)
+}
+
+// Decode converts the hex string, which may contain spaces, to bytes
+func Decode(t *testing.T, wire string) []byte {
+	data, err := hex.DecodeString(strings.Replace(wire, " ", "", -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Parse decodes the message with the parser and checks it is encoded
+// back to the same bytes
+func Parse(t *testing.T, parser Parser, wire string) ofpgeneral.OfpMessage {
+	data := Decode(t, wire)
+	msg, err := parser.ParseMsg(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	CheckEncoding(t, msg, data)
+	return msg
+}
+
+// CheckEncoding checks the message is encoded to the data
+func CheckEncoding(t *testing.T, msg ofpgeneral.OfpMessage, data []byte) {
+	encoded, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(encoded) != hex.EncodeToString(data) {
+		t.Fatalf("%T is encoded as %x, expected %x", msg, encoded, data)
+	}
+}
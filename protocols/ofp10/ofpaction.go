@@ -2,7 +2,6 @@ package ofp10
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"net"
 
@@ -324,9 +323,10 @@ func (oam *OfpActionMsg) UnmarshalBinary(data []byte) error {
 	if err := (&oam.Header).UnmarshalBinary(data); err != nil {
 		return err
 	}
-	dataStartIdx := 4
-	actionType := binary.BigEndian.Uint16(data[dataStartIdx : dataStartIdx+2])
-	switch actionType {
+	if oam.Header.Len < 8 || int(oam.Header.Len) > len(data) {
+		return fmt.Errorf("Invalid action length %d", oam.Header.Len)
+	}
+	switch oam.Header.Type {
 	case OfpActionOutputToPort:
 		oam.Body = &OfpActionOutput{}
 	case OfpActionSetVlanVID:
@@ -349,9 +349,12 @@ func (oam *OfpActionMsg) UnmarshalBinary(data []byte) error {
 		oam.Body = &OfpActionTPPort{}
 	case OfpActionEnqueue:
 		oam.Body = &OfpActionEnqueueInfo{}
+	default:
+		// Strip vlan carries no argument and the vendor actions are
+		// opaque, so only the header is kept
+		oam.Body = &OfpActionHeader{}
 	}
-	oam.Body.UnmarshalBinary(data[dataStartIdx:])
-	return nil
+	return oam.Body.UnmarshalBinary(data[:oam.Header.Len])
 }
 
 // MarshalBinary transforms the msg data into byte array
@@ -366,6 +369,92 @@ func (oam *OfpActionMsg) MarshalBinary() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	copy(data[4:], bodyData)
+	// The body starts with its own type and length
+	copy(data, bodyData)
 	return data, nil
 }
+
+// newActionMsg wraps the action body of the type and the length
+func newActionMsg(actionType, length uint16, body ofpgeneral.OfpMessage) *OfpActionMsg {
+	return &OfpActionMsg{Header: OfpActionHeader{Type: actionType, Len: length}, Body: body}
+}
+
+// NewActionOutput creates the output action to the port, maxLen is the
+// number of bytes sent when the port is OFPP_CONTROLLER
+func NewActionOutput(port, maxLen uint16) *OfpActionMsg {
+	return newActionMsg(OfpActionOutputToPort, 8,
+		&OfpActionOutput{Type: OfpActionOutputToPort, Len: 8, Port: port, MaxLen: maxLen})
+}
+
+// NewActionSetVlanVID creates the action setting the vlan id
+func NewActionSetVlanVID(vid uint16) *OfpActionMsg {
+	return newActionMsg(OfpActionSetVlanVID, 8, &OfpActionVlanVID{Type: OfpActionSetVlanVID, Len: 8, VlanVID: vid})
+}
+
+// NewActionSetVlanPCP creates the action setting the vlan priority
+func NewActionSetVlanPCP(pcp uint8) *OfpActionMsg {
+	return newActionMsg(OfpActionSetVlanPCP, 8, &OfpActionVlanPCP{Type: OfpActionSetVlanPCP, Len: 8, VlanPCP: pcp})
+}
+
+// NewActionStripVlan creates the action stripping the vlan header
+func NewActionStripVlan() *OfpActionMsg {
+	return newActionMsg(OfpActionStripVlan, 8, &OfpActionHeader{Type: OfpActionStripVlan, Len: 8})
+}
+
+// NewActionSetDLAddr creates the action setting the ethernet source or
+// destination address, the type is OFPAT_SET_DL_SRC or OFPAT_SET_DL_DST
+func NewActionSetDLAddr(actionType uint16, addr net.HardwareAddr) *OfpActionMsg {
+	return newActionMsg(actionType, 16, &OfpActionDLAddt{Type: actionType, Len: 16, DLAddr: addr})
+}
+
+// NewActionSetNWAddr creates the action setting the ip source or
+// destination address, the type is OFPAT_SET_NW_SRC or OFPAT_SET_NW_DST
+func NewActionSetNWAddr(actionType uint16, addr net.IP) *OfpActionMsg {
+	return newActionMsg(actionType, 8, &OfpActionNWAddt{Type: actionType, Len: 8, NWAddr: addr.To4()})
+}
+
+// NewActionSetNWToS creates the action setting the ip ToS, the DSCP is
+// carried by the 6 upper bits
+func NewActionSetNWToS(tos uint8) *OfpActionMsg {
+	return newActionMsg(OfpActionSetNWToS, 8, &OfpActionNWToS{Type: OfpActionSetNWToS, Len: 8, NWTos: tos})
+}
+
+// NewActionSetTPPort creates the action setting the tcp or udp source or
+// destination port, the type is OFPAT_SET_TP_SRC or OFPAT_SET_TP_DST
+func NewActionSetTPPort(actionType uint16, port uint16) *OfpActionMsg {
+	return newActionMsg(actionType, 8, &OfpActionTPPort{Type: actionType, Len: 8, TPPort: port})
+}
+
+// NewActionEnqueue creates the action sending the packets to the queue of
+// the port
+func NewActionEnqueue(port uint16, queueID uint32) *OfpActionMsg {
+	return newActionMsg(OfpActionEnqueue, 16,
+		&OfpActionEnqueueInfo{Type: OfpActionEnqueue, Len: 16, Port: port, QueueID: queueID})
+}
+
+// marshalActionMsgs encodes the array of actions
+func marshalActionMsgs(actions []OfpActionMsg) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for idx := range actions {
+		actionData, err := (&actions[idx]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(actionData)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseActionMsgs decodes the array of actions
+func parseActionMsgs(data []byte) ([]OfpActionMsg, error) {
+	actions := make([]OfpActionMsg, 0)
+	for idx := 0; idx < len(data); {
+		action := OfpActionMsg{}
+		if err := action.UnmarshalBinary(data[idx:]); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+		idx += int(action.Header.Len)
+	}
+	return actions, nil
+}
@@ -51,6 +51,9 @@ const (
 	OfpFlowWildCardsALL = ((1 << 22) - 1)
 )
 
+// OfpVlanNone is the vlan id matching the packets without vlan tag
+const OfpVlanNone = 0xffff
+
 // ofp_flow_mod_flags {
 const (
 	OfpFlowFlagSendFlowRemove = 1 << iota /* Send flow removed message when flow
@@ -98,6 +101,17 @@ type OfpMatch struct {
 	TPDst    uint16  /* TCP/UDP destination port. */
 }
 
+// NewOfpMatch creates the match which wildcards all the fields
+func NewOfpMatch() *OfpMatch {
+	return &OfpMatch{
+		Wildcards: OfpFlowWildCardsALL,
+		DLSrc:     make([]byte, 6),
+		DLDst:     make([]byte, 6),
+		NWSrc:     make([]byte, 4),
+		NWDst:     make([]byte, 4),
+	}
+}
+
 // Len returns the length of the struct message
 func (om *OfpMatch) Len() uint16 {
 	return 40
@@ -118,17 +132,34 @@ func (om *OfpMatch) UnmarshalBinary(data []byte) error {
 		&om.Padding2, &om.NWSrc, &om.NWDst, &om.TPSrc, &om.TPDst)
 }
 
-// MarshalBinary converts the header fields into byte array
+// MarshalBinary converts the header fields into byte array, the nil
+// addresses, which are usually wildcarded, are encoded as zero
 func (om *OfpMatch) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, om.Wildcards, om.InPort, om.DLSrc, om.DLDst,
-		om.DLVlan, om.DLVlanPCP, om.Padding1, om.DLType, om.NWToS, om.NWProto, om.Padding2,
-		om.NWSrc, om.NWDst, om.TPSrc, om.TPDst); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, om.Wildcards, om.InPort, hwAddrBytes(om.DLSrc),
+		hwAddrBytes(om.DLDst), om.DLVlan, om.DLVlanPCP, om.Padding1, om.DLType, om.NWToS, om.NWProto,
+		om.Padding2, ipv4Bytes(om.NWSrc), ipv4Bytes(om.NWDst), om.TPSrc, om.TPDst); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
+// hwAddrBytes returns the 6 bytes of the ethernet address
+func hwAddrBytes(addr net.HardwareAddr) []byte {
+	data := make([]byte, 6)
+	copy(data, addr)
+	return data
+}
+
+// ipv4Bytes returns the 4 bytes of the ip address
+func ipv4Bytes(addr net.IP) []byte {
+	data := make([]byte, 4)
+	if ip4 := addr.To4(); ip4 != nil {
+		copy(data, ip4)
+	}
+	return data
+}
+
 // OfpModFlowMsg represents the structure of flow setup and teardown (controller -> datapath).
 type OfpModFlowMsg struct {
 	Header ofpgeneral.OfpHeader
@@ -152,36 +183,43 @@ type OfpModFlowMsg struct {
 	   header. */
 }
 
-// MarshalBinary converts the packet out msg fields into byte array
+// NewFlowModMsg creates the flow mod of the command, it matches all the
+// packets and applies no buffered packet and out port restriction
+func NewFlowModMsg(command uint16) *OfpModFlowMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeFlowMod
+	return &OfpModFlowMsg{
+		Header:   *header,
+		Match:    *NewOfpMatch(),
+		Command:  command,
+		BufferID: OfpNoBuffer,
+		OutPort:  OfpPortNone,
+	}
+}
+
+// MarshalBinary converts the flow mod msg fields into byte array, the
+// length in the header is set according to the actions
 func (mfm *OfpModFlowMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, mfm.Header.Length)
-	headerData, err := (&mfm.Header).MarshalBinary()
+	matchData, err := (&mfm.Match).MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
-	copy(data, headerData)
-	matchData, err := (&mfm.Match).MarshalBinary()
+	actionsData, err := marshalActionMsgs(mfm.Actions)
 	if err != nil {
 		return nil, err
 	}
-	copy(data[8:], matchData)
+	mfm.Header.Length = uint16(8 + len(matchData) + 24 + len(actionsData))
 	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, mfm.Command, mfm.IdleTimeout,
-		mfm.HardTimeout, mfm.Priority, mfm.BufferID, mfm.OutPort, mfm.Flags)
-	if err != nil {
+	if err := ofpgeneral.MarshalFields(buf, mfm.Header); err != nil {
 		return nil, err
 	}
-	copy(data[48:], buf.Bytes())
-	actionByteIdx := uint16(72)
-	for _, action := range mfm.Actions {
-		actionData, err := action.MarshalBinary()
-		if err != nil {
-			return nil, err
-		}
-		copy(data[actionByteIdx:actionByteIdx+action.Header.Len], actionData)
-		actionByteIdx = actionByteIdx + action.Header.Len
+	buf.Write(matchData)
+	if err := ofpgeneral.MarshalFields(buf, mfm.Cookie, mfm.Command, mfm.IdleTimeout,
+		mfm.HardTimeout, mfm.Priority, mfm.BufferID, mfm.OutPort, mfm.Flags); err != nil {
+		return nil, err
 	}
-	return data, err
+	buf.Write(actionsData)
+	return buf.Bytes(), nil
 }
 
 // UnmarshalBinary transforms the byte array into packet out message data
@@ -239,21 +277,31 @@ func (frm *OfpFlowRemovedMsg) UnmarshalBinary(data []byte) error {
 	if len(data) < 88 {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-
-	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &frm.Header, &frm.Match, &frm.Cookie, &frm.Priority,
-		&frm.Reason, &frm.DurationSec, &frm.DurationNanoSec, &frm.IdleTimeout, &frm.PacketCount,
-		&frm.ByteCount); err != nil {
+	if err := (&frm.Header).UnmarshalBinary(data); err != nil {
 		return err
 	}
-	return nil
+	if err := (&frm.Match).UnmarshalBinary(data[8:48]); err != nil {
+		return err
+	}
+	buf := bytes.NewReader(data[48:88])
+	return ofpgeneral.UnMarshalFields(buf, &frm.Cookie, &frm.Priority, &frm.Reason, &frm.Padding1,
+		&frm.DurationSec, &frm.DurationNanoSec, &frm.IdleTimeout, &frm.Padding2, &frm.PacketCount,
+		&frm.ByteCount)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (frm *OfpFlowRemovedMsg) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, frm.Header, frm.Match, frm.Cookie, frm.Priority,
-		frm.Reason, frm.DurationSec, frm.DurationNanoSec, frm.IdleTimeout, frm.PacketCount,
+	if err := ofpgeneral.MarshalFields(buf, frm.Header); err != nil {
+		return nil, err
+	}
+	matchData, err := (&frm.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	if err := ofpgeneral.MarshalFields(buf, frm.Cookie, frm.Priority, frm.Reason, frm.Padding1,
+		frm.DurationSec, frm.DurationNanoSec, frm.IdleTimeout, frm.Padding2, frm.PacketCount,
 		frm.ByteCount); err != nil {
 		return nil, err
 	}
@@ -0,0 +1,71 @@
+package ofp10
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// NiciraVendorID is the vendor id of the Nicira extensions
+const NiciraVendorID = 0x00002320
+
+// Nicira vendor message subtypes
+const (
+	NxtRoleRequest = 10 /* struct nx_role_request */
+	NxtRoleReply   = 11 /* struct nx_role_request */
+)
+
+// Controller roles of the Nicira role extension
+const (
+	NxRoleOther  = iota /* Default role, full access. */
+	NxRoleMaster        /* Full access, at most one. */
+	NxRoleSlave         /* Read-only access. */
+)
+
+const (
+	nxRoleRequestLen = 20
+)
+
+// OfpNxRoleMsg represents the Nicira role request and reply, which brings
+// the controller roles to openflow 1.0
+type OfpNxRoleMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Vendor  uint32 /* NX_VENDOR_ID. */
+	Subtype uint32 /* NXT_ROLE_REQUEST or NXT_ROLE_REPLY. */
+	Role    uint32 /* One of NX_ROLE_*. */
+}
+
+// NewNxRoleRequestMsg creates the Nicira role request of the role
+func NewNxRoleRequestMsg(role uint32) *OfpNxRoleMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeVendor
+	return &OfpNxRoleMsg{Header: *header, Vendor: NiciraVendorID, Subtype: NxtRoleRequest, Role: role}
+}
+
+// UnmarshalBinary transforms the byte array into role message data
+func (nr *OfpNxRoleMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < nxRoleRequestLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, nr)
+}
+
+// MarshalBinary converts the role message fields into byte array
+func (nr *OfpNxRoleMsg) MarshalBinary() ([]byte, error) {
+	nr.Header.Length = nxRoleRequestLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, nr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isNxRoleReply tells whether the vendor message is the Nicira role reply
+func isNxRoleReply(data []byte) bool {
+	return len(data) >= nxRoleRequestLen &&
+		binary.BigEndian.Uint32(data[8:]) == NiciraVendorID &&
+		binary.BigEndian.Uint32(data[12:]) == NxtRoleReply
+}
@@ -1,7 +1,7 @@
 package ofp10
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
@@ -13,22 +13,40 @@ type OfpMessageParser struct {
 // ParseMsg is used to convert bytes into ofp message
 func (p *OfpMessageParser) ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
 	var message ofpgeneral.OfpMessage
-	var err error
 	switch b[1] {
 	case OfpTypeHello:
 		message = &ofpgeneral.OfpHelloMsg{}
-		message.UnmarshalBinary(b)
 	case OfpTypeError:
 		message = &ofpgeneral.OfpErrMsg{}
-		message.UnmarshalBinary(b)
 	case OfpTypeEchoRequest:
-		message = &ofpgeneral.OfpHeader{}
-		message.UnmarshalBinary(b)
+		message = &ofpgeneral.OfpEchoMsg{}
 	case OfpTypeEchoReply:
+		message = &ofpgeneral.OfpEchoMsg{}
+	case OfpTypeVendor:
+		if isNxRoleReply(b) {
+			message = &OfpNxRoleMsg{}
+		} else {
+			message = &OfpVendorHeader{}
+		}
+	case OfpTypeFeaturesReply:
+		message = &OfpSwitchFeatureMsg{}
+	case OfpTypeGetConfigReply:
+		message = &OfpSwitchConfigMsg{}
+	case OfpTypePacketIn:
+		message = &OfpPacketInMsg{}
+	case OfpTypeFlowRemoved:
+		message = &OfpFlowRemovedMsg{}
+	case OfpTypePortStatus:
+		message = &OfpPortStatusMsg{}
+	case OfpTypeStatsReply:
+		message = &OfpStatsReplyMsg{}
+	case OfpTypeBarrierReply:
 		message = &ofpgeneral.OfpHeader{}
-		message.UnmarshalBinary(b)
+	case OfpTypeQueueGetConfigReply:
+		message = &OfpQueueGetConfReplyMsg{}
 	default:
-		err = errors.New("An unknown v1.0 packet type was received. Parse function will discard data.")
+		return nil, fmt.Errorf("An unknown v1.0 packet type %d was received. Parse function will discard data.", b[1])
 	}
+	err := message.UnmarshalBinary(b)
 	return message, err
 }
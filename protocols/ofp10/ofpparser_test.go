@@ -0,0 +1,198 @@
+package ofp10
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// matchWire is the match of the port 1 with the other fields wildcarded
+const matchWire = "003ffffe" + "0001" + "000000000000" + "000000000000" + "0000" + "00" + "00" +
+	"0000" + "00" + "00" + "0000" + "00000000" + "00000000" + "0000" + "0000"
+
+func TestParseSwitchMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, msg ofpgeneral.OfpMessage)
+	}{
+		{"packet in", "010a001800000001 00000100 0006 0003 01 00 aabbccddeeff",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPacketInMsg)
+				if m.BufferID != 0x100 || m.TotalLen != 6 || m.InPort != 3 || m.Reason != 1 ||
+					hex.EncodeToString(m.Data) != "aabbccddeeff" {
+					t.Errorf("Unexpected packet in %+v", m)
+				}
+			}},
+		{"flow removed", "010b005800000002" + matchWire + "0000000000000007 8000 00 00 0000000a 00000014" +
+			"003c 0000 0000000000000005 00000000000001f4",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpFlowRemovedMsg)
+				if m.Match.InPort != 1 || m.Cookie != 7 || m.Priority != 0x8000 || m.DurationSec != 10 ||
+					m.IdleTimeout != 60 || m.PacketCount != 5 || m.ByteCount != 500 {
+					t.Errorf("Unexpected flow removed %+v", m)
+				}
+			}},
+		{"port status", "010c004000000003 02 00000000000000 0001 020304050607" +
+			"6574683000000000 0000000000000000 00000001 00000000 00000080 00000000 00000000 00000000",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPortStatusMsg)
+				if m.Reason != OfpPortReasonModify || m.Desc.PortNo != 1 || m.Desc.HwAddr.String() != "02:03:04:05:06:07" ||
+					m.Desc.Config != OfpPortConfPortDown || m.Desc.Curr != 0x80 {
+					t.Errorf("Unexpected port status %+v", m)
+				}
+			}},
+		{"get config reply", "0108000c00000004 0000 0080",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpSwitchConfigMsg)
+				if m.Flags != OfpConfFragNormal || m.MissSendLen != 128 {
+					t.Errorf("Unexpected switch config %+v", m)
+				}
+			}},
+		{"queue get config reply", "0115002800000005 0001 000000000000 00000001 0018 0000" +
+			"0001 0010 00000000 01f4 000000000000",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpQueueGetConfReplyMsg)
+				if m.Port != 1 || len(m.Queues) != 1 || m.Queues[0].QueueID != 1 || len(m.Queues[0].Properties) != 1 {
+					t.Fatalf("Unexpected queue config %+v", m)
+				}
+				if rate, ok := m.Queues[0].Properties[0].(*OfpQueuePropMinRate); !ok || rate.Rate != 500 {
+					t.Errorf("Unexpected queue property %+v", m.Queues[0].Properties[0])
+				}
+			}},
+		{"barrier reply", "0113000800000006",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m := msg.(*ofpgeneral.OfpHeader); m.Xid != 6 {
+					t.Errorf("Unexpected barrier reply %+v", m)
+				}
+			}},
+		{"error", "0101001400000007 0003 0000 0102030405060708",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*ofpgeneral.OfpErrMsg)
+				if m.Type != OfpErrTypeFlowModFailed || m.Code != 0 || len(m.Data) != 8 {
+					t.Errorf("Unexpected error %+v", m)
+				}
+			}},
+		{"nicira role reply", "0104001400000008 00002320 0000000b 00000001",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpNxRoleMsg)
+				if m.Subtype != NxtRoleReply || m.Role != NxRoleMaster {
+					t.Errorf("Unexpected role reply %+v", m)
+				}
+			}},
+		{"vendor", "0104001000000009 00001234 deadbeef",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m := msg.(*OfpVendorHeader); m.Vendor != 0x1234 {
+					t.Errorf("Unexpected vendor message %+v", m)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, wiretest.Parse(t, &OfpMessageParser{}, tc.wire))
+		})
+	}
+}
+
+func TestDecodeStatsReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, body interface{})
+	}{
+		{"flow stats", "0111006c0000000a 0001 0000" + "0060 00 00" + matchWire +
+			"0000000a 00000014 8000 003c 0000 000000000000 0000000000000007 0000000000000005 00000000000001f4" +
+			"0000 0008 0002 0000",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpFlowStats)
+				if len(stats) != 1 || stats[0].Length != 96 || stats[0].Match.InPort != 1 || stats[0].Cookie != 7 ||
+					stats[0].PacketCount != 5 || len(stats[0].Actions) != 1 {
+					t.Errorf("Unexpected flow stats %+v", stats)
+				}
+			}},
+		{"aggregate stats", "011100240000000b 0002 0000 000000000000000a 00000000000003e8 00000002 00000000",
+			func(t *testing.T, body interface{}) {
+				agg := body.(*OfpAggStatsReply)
+				if agg.PacketCount != 10 || agg.ByteCount != 1000 || agg.FlowCount != 2 {
+					t.Errorf("Unexpected aggregate stats %+v", agg)
+				}
+			}},
+		{"port stats", "011100740000000c 0004 0000 0001 000000000000" +
+			"0000000000000001 0000000000000002 0000000000000003 0000000000000004" +
+			"0000000000000005 0000000000000006 0000000000000007 0000000000000008" +
+			"0000000000000009 000000000000000a 000000000000000b 000000000000000c",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpPortStats)
+				if len(stats) != 1 || stats[0].PortNo != 1 || stats[0].RxPackets != 1 || stats[0].Collisions != 12 {
+					t.Errorf("Unexpected port stats %+v", stats)
+				}
+			}},
+		{"queue stats", "0111002c0000000d 0005 0000 0001 0000 00000002" +
+			"0000000000000064 000000000000000a 0000000000000001",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpQueueStatsInfo)
+				if len(stats) != 1 || stats[0].PortNo != 1 || stats[0].QueueID != 2 || stats[0].TxBytes != 100 ||
+					stats[0].TxPackets != 10 || stats[0].TxErrors != 1 {
+					t.Errorf("Unexpected queue stats %+v", stats)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := wiretest.Parse(t, &OfpMessageParser{}, tc.wire).(*OfpStatsReplyMsg)
+			body, err := reply.DecodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, body)
+		})
+	}
+}
+
+func TestStatsRequestsWireFormat(t *testing.T) {
+	flowStats, err := NewFlowStatsRequestMsg(nil, 0xff, OfpPortNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allMatchWire := "003fffff" + "0000" + "000000000000" + "000000000000" + "0000" + "00" + "00" +
+		"0000" + "00" + "00" + "0000" + "00000000" + "00000000" + "0000" + "0000"
+	tests := []struct {
+		name string
+		msg  *OfpStatsReqMsg
+		wire string
+	}{
+		{"desc", NewDescStatsRequestMsg(), "0110000c00000001 0000 0000"},
+		{"flow stats", flowStats, "0110003800000001 0001 0000" + allMatchWire + "ff 00 ffff"},
+		{"table stats", NewTableStatsRequestMsg(), "0110000c00000001 0003 0000"},
+		{"port stats", NewPortStatsRequestMsg(OfpPortNone), "0110001400000001 0004 0000 ffff 000000000000"},
+		{"queue stats", NewQueueStatsRequestMsg(OfpPortAll, OfpQueueAll),
+			"0110001400000001 0005 0000 fffc 0000 ffffffff"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.msg.Header.Xid = 1
+			encoded, err := tc.msg.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wire := strings.Replace(tc.wire, " ", "", -1); hex.EncodeToString(encoded) != wire {
+				t.Fatalf("Encoded %x, expected %s", encoded, wire)
+			}
+		})
+	}
+}
+
+func TestNxRoleRequestWireFormat(t *testing.T) {
+	msg := NewNxRoleRequestMsg(NxRoleSlave)
+	msg.Header.Xid = 1
+	encoded, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wire := "010400140000000100002320" + "0000000a00000002"; hex.EncodeToString(encoded) != wire {
+		t.Fatalf("Encoded %x, expected %s", encoded, wire)
+	}
+}
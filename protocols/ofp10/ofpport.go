@@ -8,6 +8,28 @@ import (
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
+// Port numbering. Physical ports are numbered starting from 1.
+// enum ofp_port {
+const (
+	/* Maximum number of physical switch ports. */
+	OfpPortMax = 0xff00
+
+	/* Fake output "ports". */
+	OfpPortInPort = 0xfff8 /* Send the packet out the input port.  This
+	   virtual port must be explicitly used
+	   in order to send back out of the input
+	   port. */
+	OfpPortTable = 0xfff9 /* Perform actions in flow table.
+	   NB: This can only be the destination
+	   port for packet-out messages. */
+	OfpPortNormal     = 0xfffa /* Process with normal L2/L3 switching. */
+	OfpPortFlood      = 0xfffb /* All physical ports except input port and those disabled by STP. */
+	OfpPortAll        = 0xfffc /* All physical ports except input port. */
+	OfpPortController = 0xfffd /* Send to controller. */
+	OfpPortLocal      = 0xfffe /* Local openflow "port". */
+	OfpPortNone       = 0xffff /* Not associated with a physical port. */
+)
+
 // OFP Port Config
 // Flags to indicate behavior of the physical port.  These flags are
 // used in ofp_phy_port to describe the current configuration.  They are
@@ -73,13 +95,17 @@ const (
 	OfpPortModFailedCodeBadHwAddr        /* Specified hardware address is wrong. */
 )
 
+const (
+	physPortLen = 48
+)
+
 // OfpPhysPort represents the physical port structure
 type OfpPhysPort struct {
 	PortNo uint16
 	HwAddr net.HardwareAddr
 	Name   []byte
-	config uint32 /* Bitmap of OFPPC_* flags. */
-	state  uint32 /* Bitmap of OFPPS_* flags. *
+	Config uint32 /* Bitmap of OFPPC_* flags. */
+	State  uint32 /* Bitmap of OFPPS_* flags. *
 
 	/* Bitmaps of OpfPortFeature* that describe features.  All bits zeroed if
 	 * unsupported or unavailable. */
@@ -91,21 +117,21 @@ type OfpPhysPort struct {
 
 // UnmarshalBinary transforms the byte array into body data
 func (pp *OfpPhysPort) UnmarshalBinary(data []byte) error {
-	if len(data) < 64 {
+	if len(data) < physPortLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
 	pp.HwAddr = make([]byte, 6)
 	pp.Name = make([]byte, 16)
-	return ofpgeneral.UnMarshalFields(buf, &pp.PortNo, &pp.HwAddr, &pp.Name, &pp.config,
-		&pp.state, &pp.Curr, &pp.Advertised, &pp.Supported, &pp.Peer)
+	return ofpgeneral.UnMarshalFields(buf, &pp.PortNo, &pp.HwAddr, &pp.Name, &pp.Config,
+		&pp.State, &pp.Curr, &pp.Advertised, &pp.Supported, &pp.Peer)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (pp *OfpPhysPort) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, pp.PortNo, pp.HwAddr, pp.Name, pp.config,
-		pp.state, pp.Curr, pp.Advertised, pp.Supported, pp.Peer); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, pp.PortNo, pp.HwAddr, pp.Name, pp.Config,
+		pp.State, pp.Curr, pp.Advertised, pp.Supported, pp.Peer); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -160,19 +186,26 @@ type OfpPortStatusMsg struct {
 
 // UnmarshalBinary transforms the byte array into header data
 func (psm *OfpPortStatusMsg) UnmarshalBinary(data []byte) error {
-	if len(data) < 48 {
+	if len(data) < 16+physPortLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &psm.Header, &psm.Reason, &psm.Padding,
-		&psm.Desc)
+	if err := ofpgeneral.UnMarshalFields(buf, &psm.Header, &psm.Reason, &psm.Padding); err != nil {
+		return err
+	}
+	return (&psm.Desc).UnmarshalBinary(data[16:])
 }
 
 // MarshalBinary converts the header fields into byte array
 func (psm *OfpPortStatusMsg) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, psm.Header, psm.Reason, psm.Padding, psm.Desc); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, psm.Header, psm.Reason, psm.Padding); err != nil {
+		return nil, err
+	}
+	descData, err := (&psm.Desc).MarshalBinary()
+	if err != nil {
 		return nil, err
 	}
+	buf.Write(descData)
 	return buf.Bytes(), nil
 }
@@ -1,6 +1,14 @@
 package ofp10
 
-import "github.com/kopwei/goof/protocols/ofpgeneral"
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// OfpQueueAll is the queue id which selects all the queues of a port
+const OfpQueueAll = 0xffffffff
 
 // enum ofp_queue_properties {
 const (
@@ -17,6 +25,24 @@ type OfpQueuePropHeader struct {
 	Paddint  [4]byte /* 64-bit alignemnt. */
 }
 
+// UnmarshalBinary transforms the byte array into property header data
+func (qph *OfpQueuePropHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qph)
+}
+
+// MarshalBinary converts the property header fields into byte array
+func (qph *OfpQueuePropHeader) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qph); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpQueuePropMinRate represents the min-Rate queue property description.
 type OfpQueuePropMinRate struct {
 	PropHeader OfpQueuePropHeader /* prop: OFPQT_MIN, len: 16. */
@@ -24,12 +50,89 @@ type OfpQueuePropMinRate struct {
 	Padding    [6]byte            /* 64-bit alignment */
 }
 
+// UnmarshalBinary transforms the byte array into min rate property data
+func (qpm *OfpQueuePropMinRate) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qpm)
+}
+
+// MarshalBinary converts the min rate property fields into byte array
+func (qpm *OfpQueuePropMinRate) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qpm); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpPacketQueue represents the full description for a queue.
 type OfpPacketQueue struct {
-	QueueID    uint32               /* id for the specific queue. */
-	Len        uint16               /* Length in bytes of this queue desc. */
-	Padding    [2]byte              /* 64-bit alignment. */
-	Properties []OfpQueuePropHeader /* List of properties. */
+	QueueID uint32  /* id for the specific queue. */
+	Len     uint16  /* Length in bytes of this queue desc. */
+	Padding [2]byte /* 64-bit alignment. */
+	// Properties holds *OfpQueuePropMinRate for the min rate property,
+	// and *OfpQueuePropHeader for the properties which are unknown
+	Properties []ofpgeneral.OfpMessage /* List of properties. */
+}
+
+// UnmarshalBinary transforms the byte array into queue description data
+func (pq *OfpPacketQueue) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &pq.QueueID, &pq.Len, &pq.Padding); err != nil {
+		return err
+	}
+	if int(pq.Len) < 8 || int(pq.Len) > len(data) {
+		return fmt.Errorf("Invalid queue description length %d", pq.Len)
+	}
+	pq.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := 8; idx+8 <= int(pq.Len); {
+		propHeader := OfpQueuePropHeader{}
+		if err := propHeader.UnmarshalBinary(data[idx:pq.Len]); err != nil {
+			return err
+		}
+		if propHeader.Len < 8 || idx+int(propHeader.Len) > int(pq.Len) {
+			return fmt.Errorf("Invalid queue property length %d", propHeader.Len)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch propHeader.Property {
+		case OfpQueMinRate:
+			prop = &OfpQueuePropMinRate{}
+		default:
+			prop = &OfpQueuePropHeader{}
+		}
+		if err := prop.UnmarshalBinary(data[idx : idx+int(propHeader.Len)]); err != nil {
+			return err
+		}
+		pq.Properties = append(pq.Properties, prop)
+		idx += int(propHeader.Len)
+	}
+	return nil
+}
+
+// MarshalBinary converts the queue description fields into byte array,
+// the length is set according to the properties
+func (pq *OfpPacketQueue) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range pq.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	pq.Len = uint16(8 + propBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, pq.QueueID, pq.Len, pq.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
 }
 
 // OfpQueueGetConfReqMsg represents the query msg for port queue configuration.
@@ -39,6 +142,25 @@ type OfpQueueGetConfReqMsg struct {
 	Padding [2]byte /* 32-bit alignment. */
 }
 
+// UnmarshalBinary transforms the byte array into queue config request data
+func (qcr *OfpQueueGetConfReqMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qcr)
+}
+
+// MarshalBinary converts the queue config request fields into byte array
+func (qcr *OfpQueueGetConfReqMsg) MarshalBinary() ([]byte, error) {
+	qcr.Header.Length = 12
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qcr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpQueueGetConfReplyMsg represents queue configuration for a given port.
 type OfpQueueGetConfReplyMsg struct {
 	Header   ofpgeneral.OfpHeader
@@ -46,3 +168,44 @@ type OfpQueueGetConfReplyMsg struct {
 	Paddingt [6]byte
 	Queues   []OfpPacketQueue /* List of configured queues. */
 }
+
+// UnmarshalBinary transforms the byte array into queue config reply data
+func (qcr *OfpQueueGetConfReplyMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &qcr.Header, &qcr.Port, &qcr.Paddingt); err != nil {
+		return err
+	}
+	qcr.Queues = make([]OfpPacketQueue, 0)
+	for idx := 16; idx+8 <= len(data); {
+		queue := OfpPacketQueue{}
+		if err := queue.UnmarshalBinary(data[idx:]); err != nil {
+			return err
+		}
+		qcr.Queues = append(qcr.Queues, queue)
+		idx += int(queue.Len)
+	}
+	return nil
+}
+
+// MarshalBinary converts the queue config reply fields into byte array,
+// the length in the header is set according to the queues
+func (qcr *OfpQueueGetConfReplyMsg) MarshalBinary() ([]byte, error) {
+	queueBuf := new(bytes.Buffer)
+	for i := range qcr.Queues {
+		queueData, err := (&qcr.Queues[i]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		queueBuf.Write(queueData)
+	}
+	qcr.Header.Length = uint16(16 + queueBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qcr.Header, qcr.Port, qcr.Paddingt); err != nil {
+		return nil, err
+	}
+	buf.Write(queueBuf.Bytes())
+	return buf.Bytes(), nil
+}
@@ -1,6 +1,12 @@
 package ofp10
 
-import "github.com/kopwei/goof/protocols/ofpgeneral"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
 
 //enum ofp_stats_types {
 const (
@@ -43,12 +49,29 @@ const (
 
 // enum ofp_stats_reply_flags {
 const (
-	OfpStatsReplyMore = iota /* More replies to follow. */
+	OfpStatsReplyMore = 1 << 0 /* More replies to follow. */
+)
+
+// Table numbering used by the flow and aggregate stats requests
+const (
+	OfpTableEmergency = 0xfe /* Emergency flow table. */
+	OfpTableAll       = 0xff /* All the flow tables. */
 )
 
 const (
 	descStrLen   = 256
 	serialNumLen = 32
+
+	statsHeaderLen      = 12
+	descStatsLen        = 4*descStrLen + serialNumLen
+	flowStatsReqLen     = 44
+	flowStatsHeaderLen  = 88
+	aggStatsReplyLen    = 24
+	tableStatsLen       = 64
+	portStatsRequestLen = 8
+	portStatsLen        = 104
+	queueStatsReqLen    = 8
+	queueStatsInfoLen   = 32
 )
 
 // OfpStatsReqMsg represents the structure of Stats request msg
@@ -59,6 +82,96 @@ type OfpStatsReqMsg struct {
 	Body   []byte /* Body of the request. */
 }
 
+// NewStatsRequestMsg creates the stats request of the type
+func NewStatsRequestMsg(statsType uint16, body []byte) *OfpStatsReqMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeStatsRequest
+	return &OfpStatsReqMsg{Header: *header, Type: statsType, Body: body}
+}
+
+// NewDescStatsRequestMsg creates the request of the switch description
+func NewDescStatsRequestMsg() *OfpStatsReqMsg {
+	return NewStatsRequestMsg(OfpStatsTypeDesc, nil)
+}
+
+// NewFlowStatsRequestMsg creates the request of the individual flow stats,
+// a nil match selects all the flows
+func NewFlowStatsRequestMsg(match *OfpMatch, tableID uint8, outPort uint16) (*OfpStatsReqMsg, error) {
+	req := &OfpFlowStatsReq{Match: matchOrAll(match), TableID: tableID, OutPort: outPort}
+	body, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return NewStatsRequestMsg(OfpStatsTypeFlow, body), nil
+}
+
+// NewAggregateStatsRequestMsg creates the request of the aggregate flow stats,
+// a nil match selects all the flows
+func NewAggregateStatsRequestMsg(match *OfpMatch, tableID uint8, outPort uint16) (*OfpStatsReqMsg, error) {
+	req := &OfpAggStatsRequest{Match: matchOrAll(match), TableID: tableID, OutPort: outPort}
+	body, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return NewStatsRequestMsg(OfpStatsTypeAggregate, body), nil
+}
+
+// NewTableStatsRequestMsg creates the request of the flow table stats
+func NewTableStatsRequestMsg() *OfpStatsReqMsg {
+	return NewStatsRequestMsg(OfpStatsTypeTable, nil)
+}
+
+// NewPortStatsRequestMsg creates the request of the port stats, OFPP_NONE
+// selects all the ports
+func NewPortStatsRequestMsg(portNo uint16) *OfpStatsReqMsg {
+	body := make([]byte, portStatsRequestLen)
+	binary.BigEndian.PutUint16(body, portNo)
+	return NewStatsRequestMsg(OfpStatsTypePort, body)
+}
+
+// NewQueueStatsRequestMsg creates the request of the queue stats, OFPP_ALL
+// and OFPQ_ALL select all the ports and queues
+func NewQueueStatsRequestMsg(portNo uint16, queueID uint32) *OfpStatsReqMsg {
+	body := make([]byte, queueStatsReqLen)
+	binary.BigEndian.PutUint16(body, portNo)
+	binary.BigEndian.PutUint32(body[4:], queueID)
+	return NewStatsRequestMsg(OfpStatsTypeQueue, body)
+}
+
+// matchOrAll returns the match or the one wildcarding all the fields
+func matchOrAll(match *OfpMatch) OfpMatch {
+	if match != nil {
+		return *match
+	}
+	return *NewOfpMatch()
+}
+
+// UnmarshalBinary transforms the byte array into stats request data
+func (sr *OfpStatsReqMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < statsHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &sr.Header, &sr.Type, &sr.Flags); err != nil {
+		return err
+	}
+	sr.Body = make([]byte, len(data)-statsHeaderLen)
+	copy(sr.Body, data[statsHeaderLen:])
+	return nil
+}
+
+// MarshalBinary converts the stats request fields into byte array,
+// the length in the header is set according to the body
+func (sr *OfpStatsReqMsg) MarshalBinary() ([]byte, error) {
+	sr.Header.Length = uint16(statsHeaderLen + len(sr.Body))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, sr.Header, sr.Type, sr.Flags); err != nil {
+		return nil, err
+	}
+	buf.Write(sr.Body)
+	return buf.Bytes(), nil
+}
+
 // OfpStatsReplyMsg represents the structure of stats reply msg
 type OfpStatsReplyMsg struct {
 	Header ofpgeneral.OfpHeader
@@ -67,6 +180,69 @@ type OfpStatsReplyMsg struct {
 	Body   []byte /* Body of the reply. */
 }
 
+// IsMore tells whether more replies of the same request follow
+func (sr *OfpStatsReplyMsg) IsMore() bool {
+	return sr.Flags&OfpStatsReplyMore != 0
+}
+
+// DecodeBody decodes the body of the reply according to its type. The
+// result is the *OfpDescStats, []OfpFlowStats, *OfpAggStatsReply,
+// []OfpTableStats, []OfpPortStats or []OfpQueueStatsInfo, the vendor
+// body is returned as it is
+func (sr *OfpStatsReplyMsg) DecodeBody() (interface{}, error) {
+	switch sr.Type {
+	case OfpStatsTypeDesc:
+		desc := &OfpDescStats{}
+		if err := desc.UnmarshalBinary(sr.Body); err != nil {
+			return nil, err
+		}
+		return desc, nil
+	case OfpStatsTypeFlow:
+		return ParseFlowStatsBody(sr.Body)
+	case OfpStatsTypeAggregate:
+		agg := &OfpAggStatsReply{}
+		if err := agg.UnmarshalBinary(sr.Body); err != nil {
+			return nil, err
+		}
+		return agg, nil
+	case OfpStatsTypeTable:
+		return ParseTableStatsBody(sr.Body)
+	case OfpStatsTypePort:
+		return ParsePortStatsBody(sr.Body)
+	case OfpStatsTypeQueue:
+		return ParseQueueStatsBody(sr.Body)
+	case OfpStatsTypeVendor:
+		return sr.Body, nil
+	}
+	return nil, fmt.Errorf("Unknown stats type %d", sr.Type)
+}
+
+// UnmarshalBinary transforms the byte array into stats reply data
+func (sr *OfpStatsReplyMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < statsHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &sr.Header, &sr.Type, &sr.Flags); err != nil {
+		return err
+	}
+	sr.Body = make([]byte, len(data)-statsHeaderLen)
+	copy(sr.Body, data[statsHeaderLen:])
+	return nil
+}
+
+// MarshalBinary converts the stats reply fields into byte array,
+// the length in the header is set according to the body
+func (sr *OfpStatsReplyMsg) MarshalBinary() ([]byte, error) {
+	sr.Header.Length = uint16(statsHeaderLen + len(sr.Body))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, sr.Header, sr.Type, sr.Flags); err != nil {
+		return nil, err
+	}
+	buf.Write(sr.Body)
+	return buf.Bytes(), nil
+}
+
 // OfpDescStats represents the structure of descriptive stats
 type OfpDescStats struct {
 	ManufacurerDesc [descStrLen]byte   /* Manufacturer description. */
@@ -76,6 +252,26 @@ type OfpDescStats struct {
 	DatapathDesc    [descStrLen]byte   /* Human readable description of datapath. */
 }
 
+// UnmarshalBinary transforms the byte array into desc stats data
+func (ds *OfpDescStats) UnmarshalBinary(data []byte) error {
+	if len(data) < descStatsLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, &ds.ManufacurerDesc, &ds.HwDesc, &ds.SwDesc,
+		&ds.SerialNum, &ds.DatapathDesc)
+}
+
+// MarshalBinary converts the desc stats fields into byte array
+func (ds *OfpDescStats) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ds.ManufacurerDesc, ds.HwDesc, ds.SwDesc,
+		ds.SerialNum, ds.DatapathDesc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpFlowStatsReq represents the structure body for ofp_stats_request of type OFPST_FLOW.
 type OfpFlowStatsReq struct {
 	Match   OfpMatch // Fields to match.
@@ -84,6 +280,31 @@ type OfpFlowStatsReq struct {
 	OutPort uint16   // Require matching entries to include this as an output port.  A value of OFPP_NONE indicates no restriction.
 }
 
+// UnmarshalBinary transforms the byte array into flow stats request data
+func (fsr *OfpFlowStatsReq) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsReqLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	if err := (&fsr.Match).UnmarshalBinary(data); err != nil {
+		return err
+	}
+	buf := bytes.NewReader(data[fsr.Match.Len():])
+	return ofpgeneral.UnMarshalFields(buf, &fsr.TableID, &fsr.Padding, &fsr.OutPort)
+}
+
+// MarshalBinary converts the flow stats request fields into byte array
+func (fsr *OfpFlowStatsReq) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fsr.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(matchData)
+	if err := ofpgeneral.MarshalFields(buf, fsr.TableID, fsr.Padding, fsr.OutPort); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpFlowStats represents the structure body of reply to OFPST_FLOW request.
 type OfpFlowStats struct {
 	Length          uint16 /* Length of this entry. */
@@ -95,13 +316,86 @@ type OfpFlowStats struct {
 	   duration_sec. */
 	Priority uint16 /* Priority of the entry. Only meaningful
 	   when this is not an exact-match entry. */
-	IdleTimeout uint16            /* Number of seconds idle before expiration. */
-	HardTimeout uint16            /* Number of seconds before expiration. */
-	Padding2    [6]byte           /* Align to 64-bits. */
-	Cookie      uint64            /* Opaque controller-issued identifier. */
-	PacketCount uint64            /* Number of packets in flow. */
-	ByteCount   uint64            /* Number of bytes in flow. */
-	Actions     []OfpActionHeader /* Actions. */
+	IdleTimeout uint16         /* Number of seconds idle before expiration. */
+	HardTimeout uint16         /* Number of seconds before expiration. */
+	Padding2    [6]byte        /* Align to 64-bits. */
+	Cookie      uint64         /* Opaque controller-issued identifier. */
+	PacketCount uint64         /* Number of packets in flow. */
+	ByteCount   uint64         /* Number of bytes in flow. */
+	Actions     []OfpActionMsg /* Actions. */
+}
+
+// UnmarshalBinary transforms the byte array into flow stats data
+func (fs *OfpFlowStats) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fs.Length, &fs.TableID, &fs.Padding1); err != nil {
+		return err
+	}
+	if fs.Length < flowStatsHeaderLen || int(fs.Length) > len(data) {
+		return fmt.Errorf("Invalid flow stats length %d", fs.Length)
+	}
+	if err := (&fs.Match).UnmarshalBinary(data[4:]); err != nil {
+		return err
+	}
+	buf = bytes.NewReader(data[4+fs.Match.Len():])
+	if err := ofpgeneral.UnMarshalFields(buf, &fs.DurationSec, &fs.DurationNanoSec, &fs.Priority,
+		&fs.IdleTimeout, &fs.HardTimeout, &fs.Padding2, &fs.Cookie, &fs.PacketCount,
+		&fs.ByteCount); err != nil {
+		return err
+	}
+	actions, err := parseActionMsgs(data[flowStatsHeaderLen:fs.Length])
+	if err != nil {
+		return err
+	}
+	fs.Actions = actions
+	return nil
+}
+
+// MarshalBinary converts the flow stats fields into byte array, the
+// length is set according to the actions
+func (fs *OfpFlowStats) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fs.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	actionBuf := new(bytes.Buffer)
+	for _, action := range fs.Actions {
+		actionData, err := action.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		actionBuf.Write(actionData)
+	}
+	fs.Length = uint16(flowStatsHeaderLen + actionBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fs.Length, fs.TableID, fs.Padding1); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	if err := ofpgeneral.MarshalFields(buf, fs.DurationSec, fs.DurationNanoSec, fs.Priority,
+		fs.IdleTimeout, fs.HardTimeout, fs.Padding2, fs.Cookie, fs.PacketCount,
+		fs.ByteCount); err != nil {
+		return nil, err
+	}
+	buf.Write(actionBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// ParseFlowStatsBody decodes the body of the flow stats reply
+func ParseFlowStatsBody(body []byte) ([]OfpFlowStats, error) {
+	stats := make([]OfpFlowStats, 0)
+	for idx := 0; idx < len(body); {
+		flowStats := OfpFlowStats{}
+		if err := flowStats.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		stats = append(stats, flowStats)
+		idx += int(flowStats.Length)
+	}
+	return stats, nil
 }
 
 // OfpAggStatsRequest represents the structure body for ofp_stats_request of type OFPST_AGGREGATE.
@@ -113,6 +407,31 @@ type OfpAggStatsRequest struct {
 	   indicates no restriction. */
 }
 
+// UnmarshalBinary transforms the byte array into aggregate stats request data
+func (asr *OfpAggStatsRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsReqLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	if err := (&asr.Match).UnmarshalBinary(data); err != nil {
+		return err
+	}
+	buf := bytes.NewReader(data[asr.Match.Len():])
+	return ofpgeneral.UnMarshalFields(buf, &asr.TableID, &asr.Padding, &asr.OutPort)
+}
+
+// MarshalBinary converts the aggregate stats request fields into byte array
+func (asr *OfpAggStatsRequest) MarshalBinary() ([]byte, error) {
+	matchData, err := (&asr.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(matchData)
+	if err := ofpgeneral.MarshalFields(buf, asr.TableID, asr.Padding, asr.OutPort); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpAggStatsReply represents the structure body of reply to OFPST_AGGREGATE request. */
 type OfpAggStatsReply struct {
 	PacketCount uint64  /* Number of packets in flows. */
@@ -121,6 +440,24 @@ type OfpAggStatsReply struct {
 	Padding     [4]byte /* Align to 64 bits. */
 }
 
+// UnmarshalBinary transforms the byte array into aggregate stats reply data
+func (asr *OfpAggStatsReply) UnmarshalBinary(data []byte) error {
+	if len(data) < aggStatsReplyLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, asr)
+}
+
+// MarshalBinary converts the aggregate stats reply fields into byte array
+func (asr *OfpAggStatsReply) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, asr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // OfpTableStats represents the structure body of reply to OFPST_TABLE request.
 type OfpTableStats struct {
 	TableID      uint8   // Identifier of table.  Lower numbered tables are consulted first.
@@ -133,6 +470,36 @@ type OfpTableStats struct {
 	MatchedCount uint64 /* Number of packets that hit table. */
 }
 
+// UnmarshalBinary transforms the byte array into table stats data
+func (ts *OfpTableStats) UnmarshalBinary(data []byte) error {
+	if len(data) < tableStatsLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, ts)
+}
+
+// MarshalBinary converts the table stats fields into byte array
+func (ts *OfpTableStats) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseTableStatsBody decodes the body of the table stats reply
+func ParseTableStatsBody(body []byte) ([]OfpTableStats, error) {
+	if len(body)%tableStatsLen != 0 {
+		return nil, fmt.Errorf("The table stats size %d is not a multiple of %d", len(body), tableStatsLen)
+	}
+	stats := make([]OfpTableStats, len(body)/tableStatsLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
 // OfpPortStatsRequest represents structure body for ofp_stats_request of type OFPST_PORT.
 type OfpPortStatsRequest struct {
 	// PortNo is the OFPST_PORT message must request statistics
@@ -168,6 +535,36 @@ type OfpPortStats struct {
 	Collisions uint64 /* Number of collisions. */
 }
 
+// UnmarshalBinary transforms the byte array into port stats data
+func (ps *OfpPortStats) UnmarshalBinary(data []byte) error {
+	if len(data) < portStatsLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, ps)
+}
+
+// MarshalBinary converts the port stats fields into byte array
+func (ps *OfpPortStats) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ps); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParsePortStatsBody decodes the body of the port stats reply
+func ParsePortStatsBody(body []byte) ([]OfpPortStats, error) {
+	if len(body)%portStatsLen != 0 {
+		return nil, fmt.Errorf("The port stats size %d is not a multiple of %d", len(body), portStatsLen)
+	}
+	stats := make([]OfpPortStats, len(body)/portStatsLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
 // OfpVendorHeader represents the header structure of Vendor extension.
 type OfpVendorHeader struct {
 	Header ofpgeneral.OfpHeader /* Type OFPT_VENDOR. */
@@ -175,7 +572,33 @@ type OfpVendorHeader struct {
 	 * - MSB 0: low-order bytes are IEEE OUI.
 	 * - MSB != 0: defined by OpenFlow
 	 *   consortium. */
-	/* Vendor-defined arbitrary additional data. */
+	Data []byte /* Vendor-defined arbitrary additional data. */
+}
+
+// UnmarshalBinary transforms the byte array into vendor message data
+func (vh *OfpVendorHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &vh.Header, &vh.Vendor); err != nil {
+		return err
+	}
+	vh.Data = make([]byte, len(data)-12)
+	copy(vh.Data, data[12:])
+	return nil
+}
+
+// MarshalBinary converts the vendor message fields into byte array,
+// the length in the header is set according to the data
+func (vh *OfpVendorHeader) MarshalBinary() ([]byte, error) {
+	vh.Header.Length = uint16(12 + len(vh.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, vh.Header, vh.Vendor); err != nil {
+		return nil, err
+	}
+	buf.Write(vh.Data)
+	return buf.Bytes(), nil
 }
 
 // OfpQueueStatsReq represents the ofp queue stats query structure
@@ -194,3 +617,33 @@ type OfpQueueStatsInfo struct {
 	TxPackets uint64  /* Number of transmitted packets. */
 	TxErrors  uint64  /* Number of packets dropped due to overrun. */
 }
+
+// UnmarshalBinary transforms the byte array into queue stats data
+func (qs *OfpQueueStatsInfo) UnmarshalBinary(data []byte) error {
+	if len(data) < queueStatsInfoLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qs)
+}
+
+// MarshalBinary converts the queue stats fields into byte array
+func (qs *OfpQueueStatsInfo) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseQueueStatsBody decodes the body of the queue stats reply
+func ParseQueueStatsBody(body []byte) ([]OfpQueueStatsInfo, error) {
+	if len(body)%queueStatsInfoLen != 0 {
+		return nil, fmt.Errorf("The queue stats size %d is not a multiple of %d", len(body), queueStatsInfoLen)
+	}
+	stats := make([]OfpQueueStatsInfo, len(body)/queueStatsInfoLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -36,17 +36,35 @@ func (sf *OfpSwitchFeatureMsg) UnmarshalBinary(data []byte) error {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &sf.Header, &sf.DatapathID, &sf.NoOfBuffers,
-		&sf.NoOfTables, &sf.Padding, &sf.Capabilities, &sf.Actions, &sf.Ports)
+	if err := ofpgeneral.UnMarshalFields(buf, &sf.Header, &sf.DatapathID, &sf.NoOfBuffers,
+		&sf.NoOfTables, &sf.Padding, &sf.Capabilities, &sf.Actions); err != nil {
+		return err
+	}
+	sf.Ports = make([]OfpPhysPort, 0, (len(data)-32)/physPortLen)
+	for idx := 32; idx+physPortLen <= len(data); idx += physPortLen {
+		port := OfpPhysPort{}
+		if err := port.UnmarshalBinary(data[idx:]); err != nil {
+			return err
+		}
+		sf.Ports = append(sf.Ports, port)
+	}
+	return nil
 }
 
 // MarshalBinary converts the header fields into byte array
 func (sf *OfpSwitchFeatureMsg) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
 	if err := ofpgeneral.MarshalFields(buf, sf.Header, sf.DatapathID, sf.NoOfBuffers,
-		sf.NoOfTables, sf.Padding, sf.Capabilities, sf.Actions, sf.Ports); err != nil {
+		sf.NoOfTables, sf.Padding, sf.Capabilities, sf.Actions); err != nil {
 		return nil, err
 	}
+	for _, port := range sf.Ports {
+		portData, err := port.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(portData)
+	}
 	return buf.Bytes(), nil
 }
 
@@ -2,6 +2,7 @@ package ofp10
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
@@ -40,7 +41,7 @@ const (
 	OfpTypeError        /* Symmetric message */
 	OfpTypeEchoRequest
 	OfpTypeEchoReply
-	OfpTypeExperimenter
+	OfpTypeVendor
 
 	/* Swiitch_configuration messages */
 	OfpTypeFeaturesRequest
@@ -57,13 +58,11 @@ const (
 	/* Controller command messages */
 	OfpTypePacketOut
 	OfpTypeFlowMod
-	OfpTypeGroupMod
 	OfpTypePortMod
-	OfpTypeTableMod
 
-	/* Multipart messages */
-	OfpTypeMultiPartRequest
-	OfpTypeMultiPartReply
+	/* Statistics messages */
+	OfpTypeStatsRequest
+	OfpTypeStatsReply
 
 	/* Barrier messages */
 	OfpTypeBarrierRequest
@@ -119,6 +118,9 @@ const (
 	OfpQueFailedCodeErrPerm        /* Permissions error. */
 )
 
+// OfpNoBuffer is the buffer id which indicates the packet isn't buffered
+const OfpNoBuffer = 0xffffffff
+
 // OfpPacketInMsg reprensents the packet_in message received by controller
 /* Packet received on port (datapath -> controller). */
 type OfpPacketInMsg struct {
@@ -153,6 +155,9 @@ func (in *OfpPacketInMsg) MarshalBinary() ([]byte, error) {
 
 // UnmarshalBinary transforms the byte array into packet in message data
 func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 18 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
 	if err := (&in.Header).UnmarshalBinary(data); err != nil {
 		return err
 	}
@@ -160,6 +165,7 @@ func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
 	if err := ofpgeneral.UnMarshalFields(buf, &in.BufferID, &in.TotalLen, &in.InPort, &in.Reason, &in.Padding); err != nil {
 		return err
 	}
+	in.Data = make([]byte, len(data)-18)
 	copy(in.Data, data[18:])
 	return nil
 }
@@ -172,66 +178,67 @@ type OfpPacketOutMsg struct {
 	InPort     uint16         /* Packet's input port (OFPP_NONE if none). */
 	ActionsLen uint16         /* Size of action array in bytes. */
 	Actions    []OfpActionMsg /* Actions. */
-	/* uint8_t data[0]; */ /* Packet data.  The length is inferred
+	Data       []byte         /* Packet data.  The length is inferred
 	   from the length field in the header.
 	   (Only meaningful if buffer_id == -1.) */
 }
 
-// MarshalBinary converts the packet out msg fields into byte array
+// NewPacketOutMsg creates the packet out of the unbuffered packet data
+// received on the port
+func NewPacketOutMsg(inPort uint16, actions []OfpActionMsg, data []byte) *OfpPacketOutMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypePacketOut
+	return &OfpPacketOutMsg{
+		Header:   *header,
+		BufferID: OfpNoBuffer,
+		InPort:   inPort,
+		Actions:  actions,
+		Data:     data,
+	}
+}
+
+// MarshalBinary converts the packet out msg fields into byte array, the
+// lengths are set according to the actions and the data
 func (out *OfpPacketOutMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, out.Header.Length)
-	headerData, err := (&out.Header).MarshalBinary()
+	actionsData, err := marshalActionMsgs(out.Actions)
 	if err != nil {
 		return nil, err
 	}
-	copy(data, headerData)
+	out.ActionsLen = uint16(len(actionsData))
+	out.Header.Length = uint16(16 + len(actionsData) + len(out.Data))
 	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, out.BufferID, out.InPort, out.ActionsLen)
-	if err != nil {
+	if err := ofpgeneral.MarshalFields(buf, out.Header, out.BufferID, out.InPort, out.ActionsLen); err != nil {
 		return nil, err
 	}
-	copy(data[8:16], buf.Bytes())
-	actionByteIdx := uint16(16)
-	for _, action := range out.Actions {
-		actionData, err := action.MarshalBinary()
-		if err != nil {
-			return nil, err
-		}
-		copy(data[actionByteIdx:actionByteIdx+action.Header.Len], actionData)
-		actionByteIdx = actionByteIdx + action.Header.Len
-	}
-	return data, err
+	buf.Write(actionsData)
+	buf.Write(out.Data)
+	return buf.Bytes(), nil
 }
 
 // UnmarshalBinary transforms the byte array into packet out message data
 func (out *OfpPacketOutMsg) UnmarshalBinary(data []byte) error {
-	if err := (&out.Header).UnmarshalBinary(data); err != nil {
-		return err
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	buf := bytes.NewReader(data[8:16])
-	if err := ofpgeneral.UnMarshalFields(buf, &out.BufferID, &out.InPort, &out.ActionsLen); err != nil {
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &out.Header, &out.BufferID, &out.InPort, &out.ActionsLen); err != nil {
 		return err
 	}
-	actionByteIdx := uint16(16)
-	for i := uint16(0); i < out.ActionsLen; i++ {
-		if err := out.Actions[i].UnmarshalBinary(data[actionByteIdx:]); err != nil {
-			return err
-		}
-		actionByteIdx += out.Actions[i].Header.Len
+	actionsEnd := 16 + int(out.ActionsLen)
+	if actionsEnd > len(data) {
+		return fmt.Errorf("Invalid actions length %d", out.ActionsLen)
+	}
+	actions, err := parseActionMsgs(data[16:actionsEnd])
+	if err != nil {
+		return err
 	}
+	out.Actions = actions
+	out.Data = make([]byte, len(data)-actionsEnd)
+	copy(out.Data, data[actionsEnd:])
 	return nil
 }
 
 // ParseMsg is the function which parses the message
 func ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	var msg ofpgeneral.OfpMessage
-	var err error
-	switch b[1] {
-	case OfpTypeHello:
-		msg = &ofpgeneral.OfpHelloMsg{}
-		err = msg.UnmarshalBinary(b)
-
-	}
-
-	return msg, err
+	return (&OfpMessageParser{}).ParseMsg(b)
 }
@@ -6,366 +6,435 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/kopwei/goof/protocols/ofp13"
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
-// OFP Action Type
+// OFP Action Type, the types from the copy ttl out are kept by the later
+// versions, whose ofp13.OfpAction* constants and structures are used
+// enum ofp_action_type {
 const (
-	OfpActionOutputToPort = iota /* Output to switch port. */
-	OfpActionSetVlanVID          /* Set the 802.1q VLAN id. */
-	OfpActionSetVlanPCP          /* Set the 802.1q priority. */
-	OfpActionStripVlan           /* Strip the 802.1q header. */
-	OfpActionSetDLSrc            /* Ethernet source address. */
-	OfpActionSetDLDst            /* Ethernet destination address. */
-	OfpActionSetNWSrc            /* IP source address. */
-	OfpActionSetNWDst            /* IP destination address. */
-	OfpActionSetNWToS            /* IP ToS (DSCP field, 6 bits). */
-	OfpActionSetTPSrc            /* TCP/UDP source port. */
-	OfpActionSetTPDst            /* TCP/UDP destination port. */
-	OfpActionEnqueue             /* Output to queue.  */
-	OfpActionVendor       = 0xffff
+	OfpActionOutputToPort = 0  /* Output to switch port. */
+	OfpActionSetVlanVID   = 1  /* Set the 802.1q VLAN id. */
+	OfpActionSetVlanPCP   = 2  /* Set the 802.1q priority. */
+	OfpActionSetDLSrc     = 3  /* Ethernet source address. */
+	OfpActionSetDLDst     = 4  /* Ethernet destination address. */
+	OfpActionSetNWSrc     = 5  /* IP source address. */
+	OfpActionSetNWDst     = 6  /* IP destination address. */
+	OfpActionSetNWToS     = 7  /* IP ToS (DSCP field, 6 bits). */
+	OfpActionSetNWEcn     = 8  /* IP ECN (2 bits). */
+	OfpActionSetTPSrc     = 9  /* TCP/UDP/SCTP source port. */
+	OfpActionSetTPDst     = 10 /* TCP/UDP/SCTP destination port. */
+	OfpActionSetMplsLabel = 13 /* MPLS label */
+	OfpActionSetMplsTC    = 14 /* MPLS TC */
 )
 
 // ofp_error_msg 'code' values for OFPET_BAD_ACTION.  'data' contains at least
 // the first 64 bytes of the failed request. */
-//enum ofp_bad_action_code {
+// enum ofp_bad_action_code {
 const (
-	OfpBadActionCodeBadType       = iota /* Unknown action type. */
-	OfpBadActionCodeBadLen               /* Length problem in actions. */
-	OfpBadActionCodeBadVendor            /* Unknown vendor id specified. */
-	OfpBadActionCodeBadVendorType        /* Unknown action type for vendor id. */
-	OfpBadActionCodeBadOutPort           /* Problem validating output action. */
-	OfpBadActionCodeBadArgument          /* Bad action argument. */
-	OfpBadActionCodeErrPerm              /* Permissions error. */
-	OfpBadActionCodeTooMany              /* Can't handle this many actions. */
-	OfpBadActionCodeBadQueue             /* Problem validating output queue. */
+	OfpBadActionCodeBadType           = iota /* Unknown action type. */
+	OfpBadActionCodeBadLen                   /* Length problem in actions. */
+	OfpBadActionCodeBadExperimenter          /* Unknown experimenter id specified. */
+	OfpBadActionCodeBadExpType               /* Unknown action type for experimenter id. */
+	OfpBadActionCodeBadOutPort               /* Problem validating output port. */
+	OfpBadActionCodeBadArgument              /* Bad action argument. */
+	OfpBadActionCodeErrPerm                  /* Permissions error. */
+	OfpBadActionCodeTooMany                  /* Can't handle this many actions. */
+	OfpBadActionCodeBadQueue                 /* Problem validating output queue. */
+	OfpBadActionCodeBadOutGroup              /* Invalid group id in forward action. */
+	OfpBadActionCodeMatchInconsistent        /* Action can't apply for this match. */
+	OfpBadActionCodeUnsupportedOrder         /* Action order is unsupported for the action list in an Apply-Actions instruction */
+	OfpBadActionCodeBadTag                   /* Actions uses an unsupported tag/encap. */
 )
 
-// OfpActionOutput represents the ofp action output
-// Action structure for OFPAT_OUTPUT, which sends packets out 'port'.
-// When the 'port' is the OFPP_CONTROLLER, 'max_len' indicates the max
-// number of bytes to send.  A 'max_len' of zero means no bytes of the
-// packet should be sent
-type OfpActionOutput struct {
-	Type   uint16 /* OFPAT_OUTPUT. */
-	Len    uint16 /* Length is 8. */
-	Port   uint16 /* Output port. */
-	MaxLen uint16 /* Max length to send to controller. */
-}
-
-// UnmarshalBinary transforms the byte array into body data
-func (ao *OfpActionOutput) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ao.Type, &ao.Len, &ao.Port, &ao.MaxLen)
-}
-
-// MarshalBinary converts the header fields into byte array
-func (ao *OfpActionOutput) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ao.Type, ao.Len, ao.Port, ao.MaxLen); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
+const (
+	ofpActionHeaderLen = 8
+	actionDLAddrLen    = 16
+)
 
-// OfpActionVlanVID represents action structure for OFPAT_SET_VLAN_VID.
-// The VLAN id is 12 bits, so we can use the entire 16 bits to indicate
-// special conditions.  All ones is used to match that no VLAN id was
-// set.
+// OfpActionVlanVID represents the action structure for OFPAT_SET_VLAN_VID
 type OfpActionVlanVID struct {
 	Type    uint16 /* OFPAT_SET_VLAN_VID. */
-	Len     uint16 /* Length is 8. */
+	Length  uint16 /* Length is 8. */
 	VlanVID uint16 /* VLAN id. */
 	Padding [2]byte
 }
 
+// NewActionSetVlanVID creates the action setting the vlan id
+func NewActionSetVlanVID(vlanVID uint16) *OfpActionVlanVID {
+	return &OfpActionVlanVID{Type: OfpActionSetVlanVID, Length: ofpActionHeaderLen, VlanVID: vlanVID}
+}
+
+// Len returns the length of the action
+func (avv *OfpActionVlanVID) Len() uint16 {
+	return ofpActionHeaderLen
+}
+
 // UnmarshalBinary transforms the byte array into body data
 func (avv *OfpActionVlanVID) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &avv.Type, &avv.Len, &avv.VlanVID)
+	return ofpgeneral.UnMarshalFields(buf, avv)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (avv *OfpActionVlanVID) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, avv.Type, avv.Len, avv.VlanVID, avv.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, avv); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionVlanPCP represents action structure for OFPAT_SET_VLAN_PCP.
+// OfpActionVlanPCP represents the action structure for OFPAT_SET_VLAN_PCP
 type OfpActionVlanPCP struct {
 	Type    uint16 /* OFPAT_SET_VLAN_PCP. */
-	Len     uint16 /* Length is 8. */
+	Length  uint16 /* Length is 8. */
 	VlanPCP uint8  /* VLAN priority. */
 	Padding [3]byte
 }
 
+// NewActionSetVlanPCP creates the action setting the vlan priority
+func NewActionSetVlanPCP(vlanPCP uint8) *OfpActionVlanPCP {
+	return &OfpActionVlanPCP{Type: OfpActionSetVlanPCP, Length: ofpActionHeaderLen, VlanPCP: vlanPCP}
+}
+
+// Len returns the length of the action
+func (avp *OfpActionVlanPCP) Len() uint16 {
+	return ofpActionHeaderLen
+}
+
 // UnmarshalBinary transforms the byte array into body data
 func (avp *OfpActionVlanPCP) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &avp.Type, &avp.Len, &avp.VlanPCP)
+	return ofpgeneral.UnMarshalFields(buf, avp)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (avp *OfpActionVlanPCP) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, avp.Type, avp.Len, avp.VlanPCP, avp.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, avp); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionDLAddt represents action structure for OFPAT_SET_DL_SRC/DST.
+// OfpActionDLAddt represents the action structure for OFPAT_SET_DL_SRC and
+// OFPAT_SET_DL_DST
 type OfpActionDLAddt struct {
-	Type    uint16 /* OFPAT_SET_DL_SRC/DST. */
-	Len     uint16 /* Length is 16. */
-	DLAddr  net.HardwareAddr
+	Type    uint16           /* OFPAT_SET_DL_SRC/DST. */
+	Length  uint16           /* Length is 16. */
+	DLAddr  net.HardwareAddr /* Ethernet address. */
 	Padding [6]byte
 }
 
+// NewActionSetDLSrc creates the action setting the ethernet source address
+func NewActionSetDLSrc(addr net.HardwareAddr) *OfpActionDLAddt {
+	return &OfpActionDLAddt{Type: OfpActionSetDLSrc, Length: actionDLAddrLen, DLAddr: addr}
+}
+
+// NewActionSetDLDst creates the action setting the ethernet destination
+// address
+func NewActionSetDLDst(addr net.HardwareAddr) *OfpActionDLAddt {
+	return &OfpActionDLAddt{Type: OfpActionSetDLDst, Length: actionDLAddrLen, DLAddr: addr}
+}
+
+// Len returns the length of the action
+func (ada *OfpActionDLAddt) Len() uint16 {
+	return actionDLAddrLen
+}
+
 // UnmarshalBinary transforms the byte array into body data
 func (ada *OfpActionDLAddt) UnmarshalBinary(data []byte) error {
-	if len(data) < 16 {
+	if len(data) < actionDLAddrLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &ada.Type, &ada.Len); err != nil {
-		return err
-	}
 	ada.DLAddr = make([]byte, 6)
-	copy(ada.DLAddr, data[4:])
-	return nil
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, &ada.Type, &ada.Length, &ada.DLAddr, &ada.Padding)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (ada *OfpActionDLAddt) MarshalBinary() ([]byte, error) {
-	data := make([]byte, 16)
+	if len(ada.DLAddr) != 6 {
+		return nil, fmt.Errorf("Invalid ethernet address %v", ada.DLAddr)
+	}
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ada.Type, ada.Len); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, ada.Type, ada.Length, ada.DLAddr, ada.Padding); err != nil {
 		return nil, err
 	}
-	copy(data, buf.Bytes())
-	copy(data[4:], ada.DLAddr)
-	return data, nil
+	return buf.Bytes(), nil
 }
 
-// OfpActionNWAddt represents Action structure for OFPAT_SET_NW_SRC/DST.
+// OfpActionNWAddt represents the action structure for OFPAT_SET_NW_SRC and
+// OFPAT_SET_NW_DST
 type OfpActionNWAddt struct {
-	Type   uint16 /* OFPAT_SET_TW_SRC/DST. */
-	Len    uint16 /* Length is 8. */
-	NWAddr net.IP /* IP address. */
+	Type   uint16 /* OFPAT_SET_NW_SRC/DST. */
+	Length uint16 /* Length is 8. */
+	NWAddr net.IP /* IPv4 address. */
+}
+
+// NewActionSetNWSrc creates the action setting the ipv4 source address
+func NewActionSetNWSrc(addr net.IP) *OfpActionNWAddt {
+	return &OfpActionNWAddt{Type: OfpActionSetNWSrc, Length: ofpActionHeaderLen, NWAddr: addr}
+}
+
+// NewActionSetNWDst creates the action setting the ipv4 destination address
+func NewActionSetNWDst(addr net.IP) *OfpActionNWAddt {
+	return &OfpActionNWAddt{Type: OfpActionSetNWDst, Length: ofpActionHeaderLen, NWAddr: addr}
+}
+
+// Len returns the length of the action
+func (ana *OfpActionNWAddt) Len() uint16 {
+	return ofpActionHeaderLen
 }
 
 // UnmarshalBinary transforms the byte array into body data
 func (ana *OfpActionNWAddt) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &ana.Type, &ana.Len); err != nil {
-		return err
-	}
 	ana.NWAddr = make([]byte, 4)
-	copy(ana.NWAddr, data[4:])
-	return nil
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, &ana.Type, &ana.Length, &ana.NWAddr)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (ana *OfpActionNWAddt) MarshalBinary() ([]byte, error) {
-	data := make([]byte, 8)
+	addr := ana.NWAddr.To4()
+	if addr == nil {
+		return nil, fmt.Errorf("Invalid ipv4 address %v", ana.NWAddr)
+	}
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ana.Type, ana.Len); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, ana.Type, ana.Length, addr); err != nil {
 		return nil, err
 	}
-	copy(data, buf.Bytes())
-	copy(data[4:], ana.NWAddr)
-	return data, nil
+	return buf.Bytes(), nil
 }
 
-// OfpActionTPPort represents action structure for OFPAT_SET_TP_SRC/DST.
-type OfpActionTPPort struct {
-	Type    uint16 /* OFPAT_SET_TP_SRC/DST. */
-	Len     uint16 /* Length is 8. */
-	TPPort  uint16 /* TCP/UDP port. */
-	Padding [2]byte
+// OfpActionNWToS represents the action structure for OFPAT_SET_NW_TOS and
+// OFPAT_SET_NW_ECN, which carry a single byte
+type OfpActionNWToS struct {
+	Type    uint16 /* OFPAT_SET_NW_TOS/ECN. */
+	Length  uint16 /* Length is 8. */
+	NWToS   uint8  /* IP ToS (DSCP field, 6 bits) or IP ECN (2 bits). */
+	Padding [3]byte
+}
+
+// NewActionSetNWToS creates the action setting the ip dscp
+func NewActionSetNWToS(tos uint8) *OfpActionNWToS {
+	return &OfpActionNWToS{Type: OfpActionSetNWToS, Length: ofpActionHeaderLen, NWToS: tos}
+}
+
+// NewActionSetNWEcn creates the action setting the ip ecn
+func NewActionSetNWEcn(ecn uint8) *OfpActionNWToS {
+	return &OfpActionNWToS{Type: OfpActionSetNWEcn, Length: ofpActionHeaderLen, NWToS: ecn}
+}
+
+// Len returns the length of the action
+func (ant *OfpActionNWToS) Len() uint16 {
+	return ofpActionHeaderLen
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (atp *OfpActionTPPort) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+func (ant *OfpActionNWToS) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &atp.Type, &atp.Len, &atp.TPPort)
+	return ofpgeneral.UnMarshalFields(buf, ant)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (atp *OfpActionTPPort) MarshalBinary() ([]byte, error) {
+func (ant *OfpActionNWToS) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, atp.Type, atp.Len, atp.TPPort, atp.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, ant); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionNWToS represents action structure for OFPAT_SET_NW_TOS.
-type OfpActionNWToS struct {
-	Type    uint16 /* OFPAT_SET_TW_SRC/DST. */
-	Len     uint16 /* Length is 8. */
-	NWTos   uint8  /* IP ToS (DSCP field, 6 bits). */
-	Padding [3]byte
+// OfpActionTPPort represents the action structure for OFPAT_SET_TP_SRC and
+// OFPAT_SET_TP_DST
+type OfpActionTPPort struct {
+	Type    uint16 /* OFPAT_SET_TP_SRC/DST. */
+	Length  uint16 /* Length is 8. */
+	TPPort  uint16 /* TCP/UDP/SCTP port. */
+	Padding [2]byte
+}
+
+// NewActionSetTPSrc creates the action setting the transport source port
+func NewActionSetTPSrc(port uint16) *OfpActionTPPort {
+	return &OfpActionTPPort{Type: OfpActionSetTPSrc, Length: ofpActionHeaderLen, TPPort: port}
+}
+
+// NewActionSetTPDst creates the action setting the transport destination
+// port
+func NewActionSetTPDst(port uint16) *OfpActionTPPort {
+	return &OfpActionTPPort{Type: OfpActionSetTPDst, Length: ofpActionHeaderLen, TPPort: port}
+}
+
+// Len returns the length of the action
+func (atp *OfpActionTPPort) Len() uint16 {
+	return ofpActionHeaderLen
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (ant *OfpActionNWToS) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+func (atp *OfpActionTPPort) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ant.Type, &ant.Len, &ant.NWTos)
+	return ofpgeneral.UnMarshalFields(buf, atp)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ant *OfpActionNWToS) MarshalBinary() ([]byte, error) {
+func (atp *OfpActionTPPort) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ant.Type, ant.Len, ant.NWTos, ant.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, atp); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionVendorHeader represents action header for OFPAT_VENDOR.
-// The rest of the body is vendor-defined.
-type OfpActionVendorHeader struct {
-	Type   uint16 /* OFPAT_VENDOR. */
-	Len    uint16 /* Length is a multiple of 8. */
-	Vendor uint32 /* Vendor ID, which takes the same form
-	   as in "struct ofp_vendor_header". */
+// OfpActionMplsLabel represents the action structure for
+// OFPAT_SET_MPLS_LABEL
+type OfpActionMplsLabel struct {
+	Type      uint16 /* OFPAT_SET_MPLS_LABEL. */
+	Length    uint16 /* Length is 8. */
+	MplsLabel uint32 /* MPLS label */
+}
+
+// NewActionSetMplsLabel creates the action setting the mpls label
+func NewActionSetMplsLabel(label uint32) *OfpActionMplsLabel {
+	return &OfpActionMplsLabel{Type: OfpActionSetMplsLabel, Length: ofpActionHeaderLen, MplsLabel: label}
 }
 
-// OfpActionHeader represents the header structure that is common to all actions.
-// The length includes the header and any padding used to make the action 64-bit aligned.
-// NB: The length of an action *must* always be a multiple of eight.
-type OfpActionHeader struct {
-	Type uint16 /* One of OFPAT_*. */
-	Len  uint16 /* Length of action, including this
-	   header.  This is the length of action,
-	   including any padding to make it
-	   64-bit aligned. */
-	Padding [4]byte
+// Len returns the length of the action
+func (aml *OfpActionMplsLabel) Len() uint16 {
+	return ofpActionHeaderLen
 }
 
-// UnmarshalBinary transforms the byte array into header data
-func (ah *OfpActionHeader) UnmarshalBinary(data []byte) error {
-	if len(data) < 4 {
+// UnmarshalBinary transforms the byte array into body data
+func (aml *OfpActionMplsLabel) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ah.Type, &ah.Len)
+	return ofpgeneral.UnMarshalFields(buf, aml)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ah *OfpActionHeader) MarshalBinary() ([]byte, error) {
+func (aml *OfpActionMplsLabel) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ah.Type, ah.Len, ah.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, aml); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionEnqueueInfo represents the OFPAT_ENQUEUE action struct: send packets to given queue on port.
-type OfpActionEnqueueInfo struct {
-	Type uint16 /* OFPAT_ENQUEUE. */
-	Len  uint16 /* Len is 16. */
-	Port uint16 /* Port that queue belongs. Should
-	   refer to a valid physical port
-	   (i.e. < OFPP_MAX) or OFPP_IN_PORT. */
-	Padding [6]byte /* Pad for 64-bit alignment. */
-	QueueID uint32  /* Where to enqueue the packets. */
+// OfpActionMplsTC represents the action structure for OFPAT_SET_MPLS_TC
+type OfpActionMplsTC struct {
+	Type    uint16 /* OFPAT_SET_MPLS_TC. */
+	Length  uint16 /* Length is 8. */
+	MplsTC  uint8  /* MPLS TC */
+	Padding [3]byte
+}
+
+// NewActionSetMplsTC creates the action setting the mpls traffic class
+func NewActionSetMplsTC(tc uint8) *OfpActionMplsTC {
+	return &OfpActionMplsTC{Type: OfpActionSetMplsTC, Length: ofpActionHeaderLen, MplsTC: tc}
+}
+
+// Len returns the length of the action
+func (amt *OfpActionMplsTC) Len() uint16 {
+	return ofpActionHeaderLen
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (aei *OfpActionEnqueueInfo) UnmarshalBinary(data []byte) error {
-	if len(data) < 16 {
+func (amt *OfpActionMplsTC) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &aei.Type, &aei.Len, &aei.Port, &aei.Padding, &aei.QueueID)
+	return ofpgeneral.UnMarshalFields(buf, amt)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (aei *OfpActionEnqueueInfo) MarshalBinary() ([]byte, error) {
+func (amt *OfpActionMplsTC) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, aei.Type, aei.Len, aei.Port, aei.Padding, aei.QueueID); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, amt); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionMsg represents the body structure of the action msg sent to datapath
-type OfpActionMsg struct {
-	Header OfpActionHeader
-	Body   ofpgeneral.OfpMessage
-}
-
-// UnmarshalBinary transforms the byte array into msg data
-func (oam *OfpActionMsg) UnmarshalBinary(data []byte) error {
-	if err := (&oam.Header).UnmarshalBinary(data); err != nil {
-		return err
-	}
-	dataStartIdx := 4
-	actionType := binary.BigEndian.Uint16(data[dataStartIdx : dataStartIdx+2])
-	switch actionType {
-	case OfpActionOutputToPort:
-		oam.Body = &OfpActionOutput{}
-	case OfpActionSetVlanVID:
-		oam.Body = &OfpActionVlanVID{}
-	case OfpActionSetVlanPCP:
-		oam.Body = &OfpActionVlanPCP{}
-	case OfpActionSetDLSrc:
-		oam.Body = &OfpActionDLAddt{}
-	case OfpActionSetDLDst:
-		oam.Body = &OfpActionDLAddt{}
-	case OfpActionSetNWSrc:
-		oam.Body = &OfpActionNWAddt{}
-	case OfpActionSetNWDst:
-		oam.Body = &OfpActionNWAddt{}
-	case OfpActionSetNWToS:
-		oam.Body = &OfpActionNWToS{}
-	case OfpActionSetTPSrc:
-		oam.Body = &OfpActionTPPort{}
-	case OfpActionSetTPDst:
-		oam.Body = &OfpActionTPPort{}
-	case OfpActionEnqueue:
-		oam.Body = &OfpActionEnqueueInfo{}
+// ParseActions decodes the list of openflow 1.1 actions, the actions kept
+// by the later versions are decoded into the ofp13 structures
+func ParseActions(data []byte) ([]ofp13.OfpAction, error) {
+	actions := make([]ofp13.OfpAction, 0)
+	for idx := 0; idx < len(data); {
+		if len(data)-idx < ofpActionHeaderLen {
+			return nil, fmt.Errorf("The data size %d is not big enough to be decoded", len(data)-idx)
+		}
+		actionType := binary.BigEndian.Uint16(data[idx : idx+2])
+		actionLen := int(binary.BigEndian.Uint16(data[idx+2 : idx+4]))
+		if actionLen < ofpActionHeaderLen || idx+actionLen > len(data) {
+			return nil, fmt.Errorf("Invalid length %d of action type %d", actionLen, actionType)
+		}
+		var action ofp13.OfpAction
+		switch actionType {
+		case OfpActionSetVlanVID:
+			action = &OfpActionVlanVID{}
+		case OfpActionSetVlanPCP:
+			action = &OfpActionVlanPCP{}
+		case OfpActionSetDLSrc, OfpActionSetDLDst:
+			action = &OfpActionDLAddt{}
+		case OfpActionSetNWSrc, OfpActionSetNWDst:
+			action = &OfpActionNWAddt{}
+		case OfpActionSetNWToS, OfpActionSetNWEcn:
+			action = &OfpActionNWToS{}
+		case OfpActionSetTPSrc, OfpActionSetTPDst:
+			action = &OfpActionTPPort{}
+		case OfpActionSetMplsLabel:
+			action = &OfpActionMplsLabel{}
+		case OfpActionSetMplsTC:
+			action = &OfpActionMplsTC{}
+		case ofp13.OfpActionSetField, ofp13.OfpActionPushPBB, ofp13.OfpActionPopPBB:
+			return nil, fmt.Errorf("Unknown action type %d", actionType)
+		default:
+			parsed, err := ofp13.ParseActions(data[idx : idx+actionLen])
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, parsed...)
+			idx += actionLen
+			continue
+		}
+		if err := action.UnmarshalBinary(data[idx : idx+actionLen]); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+		idx += actionLen
 	}
-	oam.Body.UnmarshalBinary(data[dataStartIdx:])
-	return nil
+	return actions, nil
 }
 
-// MarshalBinary transforms the msg data into byte array
-func (oam *OfpActionMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, oam.Header.Len)
-	headerData, err := oam.Header.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	copy(data, headerData)
-	bodyData, err := oam.Body.MarshalBinary()
-	if err != nil {
-		return nil, err
+// marshalActions encodes the list of actions
+func marshalActions(actions []ofp13.OfpAction) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, action := range actions {
+		actionData, err := action.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(actionData)
 	}
-	copy(data[4:], bodyData)
-	return data, nil
+	return buf.Bytes(), nil
 }
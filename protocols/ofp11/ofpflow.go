@@ -0,0 +1,204 @@
+package ofp11
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	flowModFixedLen     = 48
+	flowRemovedFixedLen = 48
+	ofpInstructionLen   = 8
+)
+
+// OfpFlowModMsg represents the structure of flow setup and teardown
+// (controller -> datapath). The layout is the one of openflow 1.3 with the
+// standard match, the commands and flags are the ofp13.OfpFlowModCmd* and
+// ofp13.OfpFlowFlag* constants.
+type OfpFlowModMsg struct {
+	Header     ofpgeneral.OfpHeader
+	Cookie     uint64 /* Opaque controller-issued identifier. */
+	CookieMask uint64 /* Mask used to restrict the cookie bits
+	   that must match when the command is
+	   OFPFC_MODIFY* or OFPFC_DELETE*. A value
+	   of 0 indicates no restriction. */
+	TableID     uint8  /* ID of the table to put the flow in */
+	Command     uint8  /* One of OFPFC_*. */
+	IdleTimeout uint16 /* Idle time before discarding (seconds). */
+	HardTimeout uint16 /* Max time before discarding (seconds). */
+	Priority    uint16 /* Priority level of flow entry. */
+	BufferID    uint32 /* Buffered packet to apply to, or
+	   OFP_NO_BUFFER.
+	   Not meaningful for OFPFC_DELETE*. */
+	OutPort      uint32 /* For OFPFC_DELETE* commands, require matching entries to include this as an output port. */
+	OutGroup     uint32 /* For OFPFC_DELETE* commands, require matching entries to include this as an output group. */
+	Flags        uint16 /* One of OFPFF_*. */
+	Padding      [2]byte
+	Match        OfpMatch               /* Fields to match */
+	Instructions []ofp13.OfpInstruction /* Instruction set */
+}
+
+// NewFlowModMsg creates the flow mod of the command in the table, it
+// matches all the packets and applies no buffered packet, out port
+// and out group restriction
+func NewFlowModMsg(command uint8, tableID uint8) *OfpFlowModMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeFlowMod
+	return &OfpFlowModMsg{
+		Header:   *header,
+		TableID:  tableID,
+		Command:  command,
+		BufferID: ofp13.OfpNoBuffer,
+		OutPort:  ofp13.OfpPortAny,
+		OutGroup: ofp13.OfpGroupAny,
+		Match:    *NewOfpMatch(),
+	}
+}
+
+// UnmarshalBinary transforms the byte array into flow mod data
+func (fm *OfpFlowModMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < flowModFixedLen+ofpMatchStandardLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fm.Header, &fm.Cookie, &fm.CookieMask, &fm.TableID,
+		&fm.Command, &fm.IdleTimeout, &fm.HardTimeout, &fm.Priority, &fm.BufferID, &fm.OutPort,
+		&fm.OutGroup, &fm.Flags, &fm.Padding); err != nil {
+		return err
+	}
+	if err := (&fm.Match).UnmarshalBinary(data[flowModFixedLen:]); err != nil {
+		return err
+	}
+	instructions, err := ParseInstructions(data[flowModFixedLen+ofpMatchStandardLen:])
+	if err != nil {
+		return err
+	}
+	fm.Instructions = instructions
+	return nil
+}
+
+// MarshalBinary converts the flow mod fields into byte array, the length
+// in the header is set according to the instructions
+func (fm *OfpFlowModMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fm.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	instructionsData, err := marshalInstructions(fm.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	fm.Header.Length = uint16(flowModFixedLen + len(matchData) + len(instructionsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fm.Header, fm.Cookie, fm.CookieMask, fm.TableID,
+		fm.Command, fm.IdleTimeout, fm.HardTimeout, fm.Priority, fm.BufferID, fm.OutPort,
+		fm.OutGroup, fm.Flags, fm.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(instructionsData)
+	return buf.Bytes(), nil
+}
+
+// OfpFlowRemovedMsg represents the msg structure of flow removed (datapath -> controller).
+type OfpFlowRemovedMsg struct {
+	Header ofpgeneral.OfpHeader
+	Cookie uint64 /* Opaque controller-issued identifier. */
+
+	Priority uint16 /* Priority level of flow entry. */
+	Reason   uint8  /* One of OFPRR_*. */
+	TableID  uint8  /* ID of the table */
+
+	DurationSec     uint32 /* Time flow was alive in seconds. */
+	DurationNanoSec uint32 /* Time flow was alive in nanoseconds beyond
+	   duration_sec. */
+	IdleTimeout uint16 /* Idle timeout from original flow mod. */
+	Padding     [2]byte
+	PacketCount uint64
+	ByteCount   uint64
+	Match       OfpMatch /* Description of fields. */
+}
+
+// UnmarshalBinary transforms the byte array into header data
+func (frm *OfpFlowRemovedMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < flowRemovedFixedLen+ofpMatchStandardLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &frm.Header, &frm.Cookie, &frm.Priority, &frm.Reason,
+		&frm.TableID, &frm.DurationSec, &frm.DurationNanoSec, &frm.IdleTimeout, &frm.Padding,
+		&frm.PacketCount, &frm.ByteCount); err != nil {
+		return err
+	}
+	return (&frm.Match).UnmarshalBinary(data[flowRemovedFixedLen:])
+}
+
+// MarshalBinary converts the header fields into byte array
+func (frm *OfpFlowRemovedMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&frm.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	frm.Header.Length = uint16(flowRemovedFixedLen + len(matchData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, frm.Header, frm.Cookie, frm.Priority, frm.Reason,
+		frm.TableID, frm.DurationSec, frm.DurationNanoSec, frm.IdleTimeout, frm.Padding,
+		frm.PacketCount, frm.ByteCount); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	return buf.Bytes(), nil
+}
+
+// ParseInstructions decodes the list of openflow 1.1 instructions, their
+// layouts are the ones of openflow 1.3 but the actions are decoded by
+// ParseActions
+func ParseInstructions(data []byte) ([]ofp13.OfpInstruction, error) {
+	instructions := make([]ofp13.OfpInstruction, 0)
+	for idx := 0; idx < len(data); {
+		if len(data)-idx < ofpInstructionLen {
+			return nil, fmt.Errorf("The data size %d is not big enough to be decoded", len(data)-idx)
+		}
+		instructionType := binary.BigEndian.Uint16(data[idx : idx+2])
+		instructionLen := int(binary.BigEndian.Uint16(data[idx+2 : idx+4]))
+		if instructionLen < ofpInstructionLen || idx+instructionLen > len(data) {
+			return nil, fmt.Errorf("Invalid length %d of instruction type %d", instructionLen, instructionType)
+		}
+		switch instructionType {
+		case ofp13.OfpInstructionTypeWriteActions, ofp13.OfpInstructionTypeApplyActions,
+			ofp13.OfpInstructionTypeClearActions:
+			actions, err := ParseActions(data[idx+ofpInstructionLen : idx+instructionLen])
+			if err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, ofp13.NewInstructionActions(instructionType, actions...))
+		case ofp13.OfpInstructionTypeMeter:
+			return nil, fmt.Errorf("Unknown instruction type %d", instructionType)
+		default:
+			parsed, err := ofp13.ParseInstructions(data[idx : idx+instructionLen])
+			if err != nil {
+				return nil, err
+			}
+			instructions = append(instructions, parsed...)
+		}
+		idx += instructionLen
+	}
+	return instructions, nil
+}
+
+// marshalInstructions encodes the list of instructions
+func marshalInstructions(instructions []ofp13.OfpInstruction) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, instruction := range instructions {
+		instructionData, err := instruction.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(instructionData)
+	}
+	return buf.Bytes(), nil
+}
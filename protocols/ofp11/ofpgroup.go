@@ -0,0 +1,23 @@
+package ofp11
+
+import (
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// The group mod and the buckets of openflow 1.1 share the layout of
+// openflow 1.3 and are handled by ofp13.OfpGroupModMsg and ofp13.OfpBucket,
+// only the actions differ
+
+// CheckBuckets checks the actions of the buckets are openflow 1.1 actions
+func CheckBuckets(buckets []ofp13.OfpBucket) error {
+	for _, bucket := range buckets {
+		data, err := marshalActions(bucket.Actions)
+		if err != nil {
+			return err
+		}
+		if _, err := ParseActions(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
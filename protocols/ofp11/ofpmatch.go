@@ -0,0 +1,181 @@
+package ofp11
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// The match type
+// enum ofp_match_type {
+const (
+	OfpMatchTypeStandard = 0 /* The match fields defined in the ofp_match structure apply */
+)
+
+// Flow wildcards.
+// enum ofp_flow_wildcards {
+const (
+	OfpFlowWildCardInPort    = 1 << 0 /* Switch input port. */
+	OfpFlowWildCardDLVlan    = 1 << 1 /* VLAN id. */
+	OfpFlowWildCardDLVlanPCP = 1 << 2 /* VLAN priority. */
+	OfpFlowWildCardDLType    = 1 << 3 /* Ethernet frame type. */
+	OfpFlowWildCardNWToS     = 1 << 4 /* IP ToS (DSCP field, 6 bits). */
+	OfpFlowWildCardNWProto   = 1 << 5 /* IP protocol. */
+	OfpFlowWildCardTPSrc     = 1 << 6 /* TCP/UDP/SCTP source port. */
+	OfpFlowWildCardTPDst     = 1 << 7 /* TCP/UDP/SCTP destination port. */
+	OfpFlowWildCardMplsLabel = 1 << 8 /* MPLS label. */
+	OfpFlowWildCardMplsTC    = 1 << 9 /* MPLS TC. */
+	OfpFlowWildCardAll       = (1 << 10) - 1
+)
+
+// The VLAN id is 12-bits, so we can use the entire 16 bits to indicate
+// special conditions.
+// enum ofp_vlan_id {
+const (
+	OfpVlanAny  = 0xfffe /* Indicate that a VLAN id is set but don't care about it's value. */
+	OfpVlanNone = 0xffff /* No VLAN id was set. */
+)
+
+const (
+	// ofpMatchStandardLen is the length of the standard match
+	ofpMatchStandardLen = 88
+)
+
+// OfpMatch represents the standard match of openflow 1.1. The bits set in
+// the masks are wildcarded, the nil addresses are encoded as zero and the
+// nil masks as fully wildcarded.
+type OfpMatch struct {
+	Type         uint16           /* One of OFPMT_* */
+	Length       uint16           /* Length of ofp_match */
+	InPort       uint32           /* Input switch port. */
+	Wildcards    uint32           /* Wildcard fields. */
+	DLSrc        net.HardwareAddr /* Ethernet source address. */
+	DLSrcMask    net.HardwareAddr /* Ethernet source address mask. */
+	DLDst        net.HardwareAddr /* Ethernet destination address. */
+	DLDstMask    net.HardwareAddr /* Ethernet destination address mask. */
+	DLVlan       uint16           /* Input VLAN id. */
+	DLVlanPCP    uint8            /* Input VLAN priority. */
+	Padding1     uint8            /* Align to 32-bits */
+	DLType       uint16           /* Ethernet frame type. */
+	NWToS        uint8            /* IP ToS (actually DSCP field, 6 bits). */
+	NWProto      uint8            /* IP protocol or lower 8 bits of ARP opcode. */
+	NWSrc        net.IP           /* IP source address. */
+	NWSrcMask    uint32           /* IP source address mask. */
+	NWDst        net.IP           /* IP destination address. */
+	NWDstMask    uint32           /* IP destination address mask. */
+	TPSrc        uint16           /* TCP/UDP/SCTP source port. */
+	TPDst        uint16           /* TCP/UDP/SCTP destination port. */
+	MplsLabel    uint32           /* MPLS label. */
+	MplsTC       uint8            /* MPLS TC. */
+	Padding2     [3]byte          /* Align to 64-bits */
+	Metadata     uint64           /* Metadata passed between tables. */
+	MetadataMask uint64           /* Mask for metadata. */
+}
+
+// NewOfpMatch creates the match which matches all the packets
+func NewOfpMatch() *OfpMatch {
+	return &OfpMatch{
+		Type:         OfpMatchTypeStandard,
+		Length:       ofpMatchStandardLen,
+		Wildcards:    OfpFlowWildCardAll,
+		NWSrcMask:    0xffffffff,
+		NWDstMask:    0xffffffff,
+		MetadataMask: 0xffffffffffffffff,
+	}
+}
+
+// Len returns the length of the match
+func (om *OfpMatch) Len() uint16 {
+	return ofpMatchStandardLen
+}
+
+// UnmarshalBinary transforms the byte array into match data
+func (om *OfpMatch) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpMatchStandardLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	om.DLSrc = make([]byte, 6)
+	om.DLSrcMask = make([]byte, 6)
+	om.DLDst = make([]byte, 6)
+	om.DLDstMask = make([]byte, 6)
+	om.NWSrc = make([]byte, 4)
+	om.NWDst = make([]byte, 4)
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &om.Type, &om.Length, &om.InPort, &om.Wildcards,
+		&om.DLSrc, &om.DLSrcMask, &om.DLDst, &om.DLDstMask, &om.DLVlan, &om.DLVlanPCP, &om.Padding1,
+		&om.DLType, &om.NWToS, &om.NWProto, &om.NWSrc, &om.NWSrcMask, &om.NWDst, &om.NWDstMask,
+		&om.TPSrc, &om.TPDst, &om.MplsLabel, &om.MplsTC, &om.Padding2, &om.Metadata,
+		&om.MetadataMask); err != nil {
+		return err
+	}
+	if om.Type != OfpMatchTypeStandard || om.Length != ofpMatchStandardLen {
+		return fmt.Errorf("Unsupported match type %d of length %d", om.Type, om.Length)
+	}
+	return nil
+}
+
+// MarshalBinary converts the match fields into byte array
+func (om *OfpMatch) MarshalBinary() ([]byte, error) {
+	om.Type = OfpMatchTypeStandard
+	om.Length = ofpMatchStandardLen
+	dlSrc, err := hwAddrBytes(om.DLSrc, 0)
+	if err != nil {
+		return nil, err
+	}
+	dlSrcMask, err := hwAddrBytes(om.DLSrcMask, 0xff)
+	if err != nil {
+		return nil, err
+	}
+	dlDst, err := hwAddrBytes(om.DLDst, 0)
+	if err != nil {
+		return nil, err
+	}
+	dlDstMask, err := hwAddrBytes(om.DLDstMask, 0xff)
+	if err != nil {
+		return nil, err
+	}
+	nwSrc, err := ipv4Bytes(om.NWSrc)
+	if err != nil {
+		return nil, err
+	}
+	nwDst, err := ipv4Bytes(om.NWDst)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, om.Type, om.Length, om.InPort, om.Wildcards,
+		dlSrc, dlSrcMask, dlDst, dlDstMask, om.DLVlan, om.DLVlanPCP, om.Padding1,
+		om.DLType, om.NWToS, om.NWProto, nwSrc, om.NWSrcMask, nwDst, om.NWDstMask,
+		om.TPSrc, om.TPDst, om.MplsLabel, om.MplsTC, om.Padding2, om.Metadata,
+		om.MetadataMask); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hwAddrBytes returns the 6 bytes of the ethernet address, the nil
+// address is filled with the byte
+func hwAddrBytes(addr net.HardwareAddr, fill byte) ([]byte, error) {
+	if addr == nil {
+		return bytes.Repeat([]byte{fill}, 6), nil
+	}
+	if len(addr) != 6 {
+		return nil, fmt.Errorf("Invalid ethernet address %v", addr)
+	}
+	return addr, nil
+}
+
+// ipv4Bytes returns the 4 bytes of the ipv4 address, the nil address is
+// encoded as zero
+func ipv4Bytes(addr net.IP) ([]byte, error) {
+	if addr == nil {
+		return make([]byte, 4), nil
+	}
+	ip := addr.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("Invalid ipv4 address %v", addr)
+	}
+	return ip, nil
+}
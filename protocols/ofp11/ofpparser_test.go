@@ -0,0 +1,250 @@
+package ofp11
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	// matchWire is the standard match of the ipv4 packets received on the
+	// port 3
+	matchWire = "0000 0058 00000003 000003f6 000000000000 ffffffffffff 000000000000 ffffffffffff" +
+		"0000 00 00 0800 00 00 00000000 ffffffff 00000000 ffffffff 0000 0000 00000000 00 000000" +
+		"0000000000000000 ffffffffffffffff"
+	// portWire is the description of the port 1 named eth0, the ports of
+	// openflow 1.1 have the layout of openflow 1.3
+	portWire = "00000001 00000000 020304050607 0000 6574683000000000 0000000000000000" +
+		"00000001 00000004 00002000 00000000 00000000 00000000 00989680 00989680"
+)
+
+// newTestMatch creates the match of matchWire
+func newTestMatch() *OfpMatch {
+	match := NewOfpMatch()
+	match.InPort = 3
+	match.DLType = 0x0800
+	match.Wildcards &^= OfpFlowWildCardInPort | OfpFlowWildCardDLType
+	return match
+}
+
+// checkMatch checks the match is the one of matchWire
+func checkMatch(t *testing.T, match *OfpMatch) {
+	if match.InPort != 3 || match.DLType != 0x0800 || match.Wildcards != 0x3f6 ||
+		match.DLSrcMask.String() != "ff:ff:ff:ff:ff:ff" || match.NWSrcMask != 0xffffffff ||
+		match.MetadataMask != 0xffffffffffffffff {
+		t.Errorf("Unexpected match %+v", match)
+	}
+}
+
+func TestMatchWireFormat(t *testing.T) {
+	data := wiretest.Decode(t, matchWire)
+	wiretest.CheckEncoding(t, newTestMatch(), data)
+	match := &OfpMatch{}
+	if err := match.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	checkMatch(t, match)
+	wiretest.CheckEncoding(t, match, data)
+
+	// The oxm match of the later versions isn't decoded
+	if err := match.UnmarshalBinary(wiretest.Decode(t, "0001 0058"+matchWire[9:])); err == nil {
+		t.Error("The oxm match is decoded as a standard match")
+	}
+	if err := match.UnmarshalBinary(data[:80]); err == nil {
+		t.Error("The truncated match is decoded")
+	}
+}
+
+func TestParseSwitchMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, msg ofpgeneral.OfpMessage)
+	}{
+		{"features reply", "0206006000000001 0000000000000001 00000100 fe 000000 00000007 00000000" + portWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpSwitchFeatureMsg)
+				if m.DatapathID != 1 || m.NoOfBuffers != 256 || m.NoOfTables != 0xfe || m.Capabilities != 7 ||
+					len(m.Ports) != 1 || m.Ports[0].PortNo != 1 {
+					t.Errorf("Unexpected features reply %+v", m)
+				}
+			}},
+		{"packet in", "020a001e00000000 ffffffff 00000003 00000004 0006 01 02 aabbccddeeff",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPacketInMsg)
+				if m.BufferID != ofp13.OfpNoBuffer || m.InPort != 3 || m.InPhyPort != 4 || m.TotalLen != 6 ||
+					m.Reason != OfpPacketInReasonAction || m.TableID != 2 || hex.EncodeToString(m.Data) != "aabbccddeeff" {
+					t.Errorf("Unexpected packet in %+v", m)
+				}
+			}},
+		{"flow removed", "020b008800000000 0000000000000007 8000 00 01 0000000a 00000000 000a 0000" +
+			"0000000000000005 00000000000001f4" + matchWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpFlowRemovedMsg)
+				if m.Cookie != 7 || m.Priority != 0x8000 || m.TableID != 1 || m.DurationSec != 10 ||
+					m.IdleTimeout != 10 || m.PacketCount != 5 || m.ByteCount != 500 {
+					t.Errorf("Unexpected flow removed %+v", m)
+				}
+				checkMatch(t, &m.Match)
+			}},
+		// The messages unchanged in openflow 1.3 are decoded into the
+		// ofp13 structures
+		{"port status", "020c005000000000 02 00000000000000" + portWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m, ok := msg.(*ofp13.OfpPortStatusMsg); !ok || m.Reason != ofp13.OfpPortReasonModify || m.Desc.PortNo != 1 {
+					t.Errorf("Unexpected port status %+v", msg)
+				}
+			}},
+		{"barrier reply", "0215000800000009", func(t *testing.T, msg ofpgeneral.OfpMessage) {
+			if m, ok := msg.(*ofpgeneral.OfpHeader); !ok || m.Xid != 9 {
+				t.Errorf("Unexpected barrier reply %+v", msg)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, wiretest.Parse(t, &OfpMessageParser{}, tc.wire))
+		})
+	}
+}
+
+func TestParseMsgInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0218000800000000",                   // Role request is introduced by openflow 1.2
+		"0206001000000001 0000000000000001",  // Truncated features reply
+		"020a001000000000 ffffffff 00000003", // Truncated packet in
+		"020b003000000000 0000000000000007 8000 00 01 0000000a 00000000 000a 0000" +
+			"0000000000000005 00000000000001f4", // Flow removed without match
+	} {
+		if msg, err := (&OfpMessageParser{}).ParseMsg(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The message %s is decoded as %+v", wire, msg)
+		}
+	}
+}
+
+func TestFlowModWireFormat(t *testing.T) {
+	msg := NewFlowModMsg(ofp13.OfpFlowModCmdAdd, 1)
+	msg.Header.Xid = 1
+	msg.Priority = 0x8000
+	msg.Match = *newTestMatch()
+	msg.Instructions = []ofp13.OfpInstruction{
+		ofp13.NewInstructionActions(ofp13.OfpInstructionTypeApplyActions, NewActionSetVlanVID(10), ofp13.NewActionOutput(2)),
+		ofp13.NewInstructionGotoTable(2),
+	}
+	data := wiretest.Decode(t, "020e00b000000001 0000000000000000 0000000000000000 01 00 0000 0000 8000"+
+		"ffffffff ffffffff ffffffff 0000 0000"+matchWire+
+		"0004 0020 00000000 0001 0008 000a 0000 0000 0010 00000002 ffff 000000000000"+
+		"0001 0008 02 000000")
+	wiretest.CheckEncoding(t, msg, data)
+
+	decoded := &OfpFlowModMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, data)
+	checkMatch(t, &decoded.Match)
+	if decoded.TableID != 1 || decoded.Command != ofp13.OfpFlowModCmdAdd || len(decoded.Instructions) != 2 {
+		t.Fatalf("Unexpected flow mod %+v", decoded)
+	}
+	apply, ok := decoded.Instructions[0].(*ofp13.OfpInstructionActions)
+	if !ok || len(apply.Actions) != 2 {
+		t.Fatalf("Unexpected instruction %+v", decoded.Instructions[0])
+	}
+	if vlan, ok := apply.Actions[0].(*OfpActionVlanVID); !ok || vlan.VlanVID != 10 {
+		t.Errorf("Unexpected action %+v", apply.Actions[0])
+	}
+}
+
+func TestFlowModInvalid(t *testing.T) {
+	header := "020e00b000000001 0000000000000000 0000000000000000 01 00 0000 0000 8000" +
+		"ffffffff ffffffff ffffffff 0000 0000"
+	for _, wire := range []string{
+		header, // No match
+		header + matchWire + "0006 0008 00000001",                                      // Meter instruction
+		header + matchWire + "0004 0018 00000000 0019 0010 80000c02 1005 000000000000", // Set field action
+		header + matchWire + "0004 0010 00000000 001a 0008 88e7 0000",                  // PBB action
+		header + matchWire + "0004 0010 00000000",                                      // Instruction beyond the message
+	} {
+		msg := &OfpFlowModMsg{}
+		if err := msg.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The flow mod %s is decoded as %+v", wire, msg)
+		}
+	}
+}
+
+func TestPacketOutWireFormat(t *testing.T) {
+	actions := []ofp13.OfpAction{NewActionSetDLDst([]byte{2, 3, 4, 5, 6, 7}), ofp13.NewActionOutput(1)}
+	msg := NewPacketOutMsg(ofp13.OfpPortController, actions, []byte{0xaa, 0xbb})
+	msg.Header.Xid = 1
+	data := wiretest.Decode(t, "020d003a00000001 ffffffff fffffffd 0020 000000000000"+
+		"0004 0010 020304050607 000000000000 0000 0010 00000001 ffff 000000000000 aabb")
+	wiretest.CheckEncoding(t, msg, data)
+
+	decoded := &OfpPacketOutMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, data)
+	if decoded.InPort != ofp13.OfpPortController || len(decoded.Actions) != 2 || hex.EncodeToString(decoded.Data) != "aabb" {
+		t.Errorf("Unexpected packet out %+v", decoded)
+	}
+	if dlDst, ok := decoded.Actions[0].(*OfpActionDLAddt); !ok || dlDst.DLAddr.String() != "02:03:04:05:06:07" {
+		t.Errorf("Unexpected action %+v", decoded.Actions[0])
+	}
+
+	if err := decoded.UnmarshalBinary(wiretest.Decode(t, "020d002000000001 ffffffff fffffffd 0010 000000000000 00000000")); err == nil {
+		t.Error("The packet out whose actions are beyond the message is decoded")
+	}
+}
+
+func TestActionsWireFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		action ofp13.OfpAction
+		wire   string
+	}{
+		{"set vlan vid", NewActionSetVlanVID(10), "0001 0008 000a 0000"},
+		{"set vlan pcp", NewActionSetVlanPCP(3), "0002 0008 03 000000"},
+		{"set dl src", NewActionSetDLSrc([]byte{2, 3, 4, 5, 6, 7}), "0003 0010 020304050607 000000000000"},
+		{"set nw src", NewActionSetNWSrc([]byte{10, 0, 0, 1}), "0005 0008 0a000001"},
+		{"set nw dst", NewActionSetNWDst([]byte{10, 0, 0, 2}), "0006 0008 0a000002"},
+		{"set nw tos", NewActionSetNWToS(0x10), "0007 0008 10 000000"},
+		{"set nw ecn", NewActionSetNWEcn(1), "0008 0008 01 000000"},
+		{"set tp src", NewActionSetTPSrc(80), "0009 0008 0050 0000"},
+		{"set mpls label", NewActionSetMplsLabel(100), "000d 0008 00000064"},
+		{"set mpls tc", NewActionSetMplsTC(5), "000e 0008 05 000000"},
+		// The actions kept by the later versions are the ofp13 ones
+		{"group", ofp13.NewActionGroup(1), "0016 0008 00000001"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := wiretest.Decode(t, tc.wire)
+			wiretest.CheckEncoding(t, tc.action, data)
+			actions, err := ParseActions(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(actions) != 1 {
+				t.Fatalf("Decoded %d actions, expected 1", len(actions))
+			}
+			wiretest.CheckEncoding(t, actions[0], data)
+		})
+	}
+}
+
+func TestParseActionsInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0001 0008 000a",                       // Truncated action
+		"0003 0008 020304050607",               // Ethernet address beyond the action
+		"0001 0010 000a 0000",                  // Length beyond the data
+		"0019 0010 80000c02 1005 000000000000", // Set field is introduced by openflow 1.2
+		"001b 0008 00000000",                   // Pop PBB is introduced by openflow 1.3
+	} {
+		if actions, err := ParseActions(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The actions %s are decoded as %+v", wire, actions)
+		}
+	}
+}
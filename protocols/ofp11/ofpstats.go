@@ -0,0 +1,226 @@
+package ofp11
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// The stats types of openflow 1.1, the request and the reply share the
+// layout of the openflow 1.3 multipart messages and are handled by
+// ofp13.OfpMultipartRequestMsg and ofp13.OfpMultipartReplyMsg
+// enum ofp_stats_types {
+const (
+	OfpStatsTypeDesc         = 0      /* Description of this OpenFlow switch. */
+	OfpStatsTypeFlow         = 1      /* Individual flow statistics. */
+	OfpStatsTypeAggregate    = 2      /* Aggregate flow statistics. */
+	OfpStatsTypeTable        = 3      /* Flow table statistics. */
+	OfpStatsTypePort         = 4      /* Port statistics. */
+	OfpStatsTypeQueue        = 5      /* Queue statistics for a port. */
+	OfpStatsTypeGroup        = 6      /* Group counter statistics. */
+	OfpStatsTypeGroupDesc    = 7      /* Group description statistics. */
+	OfpStatsTypeExperimenter = 0xffff /* Experimenter extension. */
+)
+
+const (
+	flowStatsRequestLen = 32
+	flowStatsHeaderLen  = 48
+	portStatsLen        = 104
+)
+
+// OfpFlowStatsRequest represents the body of the flow and the aggregate
+// stats requests
+type OfpFlowStatsRequest struct {
+	TableID    uint8 /* ID of table to read (from ofp_table_stats), OFPTT_ALL for all tables. */
+	Padding1   [3]byte
+	OutPort    uint32 /* Require matching entries to include this as an output port. A value of OFPP_ANY indicates no restriction. */
+	OutGroup   uint32 /* Require matching entries to include this as an output group. A value of OFPG_ANY indicates no restriction. */
+	Padding2   [4]byte
+	Cookie     uint64   /* Require matching entries to contain this cookie value */
+	CookieMask uint64   /* Mask used to restrict the cookie bits that must match. A value of 0 indicates no restriction. */
+	Match      OfpMatch /* Fields to match. */
+}
+
+// NewFlowStatsRequestMsg creates the request of the stats of the flows in
+// the table, a nil match selects all the flows
+func NewFlowStatsRequestMsg(tableID uint8, match *OfpMatch) (*ofp13.OfpMultipartRequestMsg, error) {
+	return newFlowStatsRequestMsg(OfpStatsTypeFlow, tableID, match)
+}
+
+// NewAggregateStatsRequestMsg creates the request of the aggregated stats
+// of the flows in the table, a nil match selects all the flows
+func NewAggregateStatsRequestMsg(tableID uint8, match *OfpMatch) (*ofp13.OfpMultipartRequestMsg, error) {
+	return newFlowStatsRequestMsg(OfpStatsTypeAggregate, tableID, match)
+}
+
+func newFlowStatsRequestMsg(statsType uint16, tableID uint8, match *OfpMatch) (*ofp13.OfpMultipartRequestMsg, error) {
+	if match == nil {
+		match = NewOfpMatch()
+	}
+	req := &OfpFlowStatsRequest{TableID: tableID, OutPort: ofp13.OfpPortAny, OutGroup: ofp13.OfpGroupAny, Match: *match}
+	body, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	msg := ofp13.NewMultipartRequestMsg(statsType, body)
+	msg.Header.Version = Version
+	return msg, nil
+}
+
+// UnmarshalBinary transforms the byte array into flow stats request data
+func (fsr *OfpFlowStatsRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsRequestLen+ofpMatchStandardLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fsr.TableID, &fsr.Padding1, &fsr.OutPort, &fsr.OutGroup,
+		&fsr.Padding2, &fsr.Cookie, &fsr.CookieMask); err != nil {
+		return err
+	}
+	return (&fsr.Match).UnmarshalBinary(data[flowStatsRequestLen:])
+}
+
+// MarshalBinary converts the flow stats request fields into byte array
+func (fsr *OfpFlowStatsRequest) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fsr.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fsr.TableID, fsr.Padding1, fsr.OutPort, fsr.OutGroup,
+		fsr.Padding2, fsr.Cookie, fsr.CookieMask); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	return buf.Bytes(), nil
+}
+
+// OfpFlowStats represents the body of reply to OFPST_FLOW request.
+type OfpFlowStats struct {
+	Length          uint16 /* Length of this entry. */
+	TableID         uint8  /* ID of table flow came from. */
+	Padding1        byte
+	DurationSec     uint32 /* Time flow has been alive in seconds. */
+	DurationNanoSec uint32 /* Time flow has been alive in nanoseconds beyond
+	   duration_sec. */
+	Priority     uint16 /* Priority of the entry. */
+	IdleTimeout  uint16 /* Number of seconds idle before expiration. */
+	HardTimeout  uint16 /* Number of seconds before expiration. */
+	Padding2     [6]byte
+	Cookie       uint64                 /* Opaque controller-issued identifier. */
+	PacketCount  uint64                 /* Number of packets in flow. */
+	ByteCount    uint64                 /* Number of bytes in flow. */
+	Match        OfpMatch               /* Description of fields. */
+	Instructions []ofp13.OfpInstruction /* Instruction set. */
+}
+
+// UnmarshalBinary transforms the byte array into flow stats data
+func (fs *OfpFlowStats) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsHeaderLen+ofpMatchStandardLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fs.Length, &fs.TableID, &fs.Padding1, &fs.DurationSec,
+		&fs.DurationNanoSec, &fs.Priority, &fs.IdleTimeout, &fs.HardTimeout, &fs.Padding2,
+		&fs.Cookie, &fs.PacketCount, &fs.ByteCount); err != nil {
+		return err
+	}
+	if fs.Length < flowStatsHeaderLen+ofpMatchStandardLen || int(fs.Length) > len(data) {
+		return fmt.Errorf("Invalid flow stats length %d", fs.Length)
+	}
+	if err := (&fs.Match).UnmarshalBinary(data[flowStatsHeaderLen:fs.Length]); err != nil {
+		return err
+	}
+	instructions, err := ParseInstructions(data[flowStatsHeaderLen+ofpMatchStandardLen : fs.Length])
+	if err != nil {
+		return err
+	}
+	fs.Instructions = instructions
+	return nil
+}
+
+// MarshalBinary converts the flow stats fields into byte array, the length
+// is set according to the instructions
+func (fs *OfpFlowStats) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fs.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	instructionsData, err := marshalInstructions(fs.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	fs.Length = uint16(flowStatsHeaderLen + len(matchData) + len(instructionsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fs.Length, fs.TableID, fs.Padding1, fs.DurationSec,
+		fs.DurationNanoSec, fs.Priority, fs.IdleTimeout, fs.HardTimeout, fs.Padding2,
+		fs.Cookie, fs.PacketCount, fs.ByteCount); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(instructionsData)
+	return buf.Bytes(), nil
+}
+
+// ParseFlowStatsBody decodes the body of the flow stats reply
+func ParseFlowStatsBody(body []byte) ([]OfpFlowStats, error) {
+	stats := make([]OfpFlowStats, 0)
+	for idx := 0; idx < len(body); {
+		flowStats := OfpFlowStats{}
+		if err := flowStats.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		stats = append(stats, flowStats)
+		idx += int(flowStats.Length)
+	}
+	return stats, nil
+}
+
+// OfpPortStats represents the body of reply to OFPST_PORT request of
+// openflow 1.1 and 1.2. If a counter is unsupported, set the field to all
+// ones.
+type OfpPortStats struct {
+	PortNo     uint32
+	Padding    [4]byte
+	RxPackets  uint64 /* Number of received packets. */
+	TxPackets  uint64 /* Number of transmitted packets. */
+	RxBytes    uint64 /* Number of received bytes. */
+	TxBytes    uint64 /* Number of transmitted bytes. */
+	RxDropped  uint64 /* Number of packets dropped by RX. */
+	TxDropped  uint64 /* Number of packets dropped by TX. */
+	RxErrors   uint64 /* Number of receive errors. */
+	TxErrors   uint64 /* Number of transmit errors. */
+	RxFrameErr uint64 /* Number of frame alignment errors. */
+	RxOverErr  uint64 /* Number of packets with RX overrun. */
+	RxCrcErr   uint64 /* Number of CRC errors. */
+	Collisions uint64 /* Number of collisions. */
+}
+
+// ParsePortStatsBody decodes the body of the port stats reply
+func ParsePortStatsBody(body []byte) ([]OfpPortStats, error) {
+	if len(body)%portStatsLen != 0 {
+		return nil, fmt.Errorf("The port stats size %d is not a multiple of %d", len(body), portStatsLen)
+	}
+	stats := make([]OfpPortStats, len(body)/portStatsLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// DecodeStatsBody decodes the body of the openflow 1.1 stats reply, the
+// bodies whose layout is unchanged in openflow 1.3 are decoded by
+// ofp13.OfpMultipartReplyMsg.DecodeBody
+func DecodeStatsBody(mr *ofp13.OfpMultipartReplyMsg) (interface{}, error) {
+	switch mr.Type {
+	case OfpStatsTypeDesc, OfpStatsTypeAggregate, OfpStatsTypeExperimenter:
+		return mr.DecodeBody()
+	case OfpStatsTypeFlow:
+		return ParseFlowStatsBody(mr.Body)
+	case OfpStatsTypePort:
+		return ParsePortStatsBody(mr.Body)
+	}
+	return nil, fmt.Errorf("Decoding of the v1.1 stats type %d is not supported", mr.Type)
+}
@@ -0,0 +1,89 @@
+package ofp11
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+func TestFlowStatsRequestWireFormat(t *testing.T) {
+	allWire := "0000 0058 00000000 000003ff 000000000000 ffffffffffff 000000000000 ffffffffffff" +
+		"0000 00 00 0000 00 00 00000000 ffffffff 00000000 ffffffff 0000 0000 00000000 00 000000" +
+		"0000000000000000 ffffffffffffffff"
+	tests := []struct {
+		name  string
+		build func() (*ofp13.OfpMultipartRequestMsg, error)
+		wire  string
+	}{
+		{"flow", func() (*ofp13.OfpMultipartRequestMsg, error) { return NewFlowStatsRequestMsg(1, newTestMatch()) },
+			"0212008800000001 0001 0000 00000000 01 000000 ffffffff ffffffff 00000000" +
+				"0000000000000000 0000000000000000" + matchWire},
+		// A nil match selects all the flows
+		{"aggregate", func() (*ofp13.OfpMultipartRequestMsg, error) {
+			return NewAggregateStatsRequestMsg(ofp13.OfpTableAll, nil)
+		}, "0212008800000001 0002 0000 00000000 ff 000000 ffffffff ffffffff 00000000" +
+			"0000000000000000 0000000000000000" + allWire},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := tc.build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg.Header.Xid = 1
+			data := wiretest.Decode(t, tc.wire)
+			wiretest.CheckEncoding(t, msg, data)
+
+			request := &OfpFlowStatsRequest{}
+			if err := request.UnmarshalBinary(msg.Body); err != nil {
+				t.Fatal(err)
+			}
+			wiretest.CheckEncoding(t, request, data[16:])
+		})
+	}
+}
+
+func TestDecodeStatsBody(t *testing.T) {
+	flowStatsWire := "0090 01 00 0000000a 00000000 8000 000a 0000 000000000000" +
+		"0000000000000007 0000000000000005 00000000000001f4" + matchWire + "0001 0008 02 000000"
+	reply := &ofp13.OfpMultipartReplyMsg{Type: OfpStatsTypeFlow, Body: wiretest.Decode(t, flowStatsWire)}
+	body, err := DecodeStatsBody(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flowStats := body.([]OfpFlowStats)
+	if len(flowStats) != 1 {
+		t.Fatalf("Decoded %d flow stats, expected 1", len(flowStats))
+	}
+	if fs := &flowStats[0]; fs.Length != 144 || fs.TableID != 1 || fs.DurationSec != 10 || fs.Priority != 0x8000 ||
+		fs.Cookie != 7 || fs.PacketCount != 5 || fs.ByteCount != 500 || len(fs.Instructions) != 1 {
+		t.Errorf("Unexpected flow stats %+v", fs)
+	}
+	checkMatch(t, &flowStats[0].Match)
+	wiretest.CheckEncoding(t, &flowStats[0], wiretest.Decode(t, flowStatsWire))
+
+	reply = &ofp13.OfpMultipartReplyMsg{Type: OfpStatsTypePort, Body: wiretest.Decode(t, "00000001 00000000"+
+		"000000000000000a 0000000000000014 00000000000003e8 00000000000007d0 0000000000000000 0000000000000000"+
+		"0000000000000000 0000000000000000 0000000000000000 0000000000000000 0000000000000000 ffffffffffffffff")}
+	if body, err = DecodeStatsBody(reply); err != nil {
+		t.Fatal(err)
+	}
+	if portStats := body.([]OfpPortStats); len(portStats) != 1 || portStats[0].PortNo != 1 ||
+		portStats[0].RxPackets != 10 || portStats[0].TxBytes != 2000 || portStats[0].Collisions != 0xffffffffffffffff {
+		t.Errorf("Unexpected port stats %+v", portStats)
+	}
+}
+
+func TestDecodeStatsBodyInvalid(t *testing.T) {
+	for _, reply := range []*ofp13.OfpMultipartReplyMsg{
+		{Type: OfpStatsTypeFlow, Body: wiretest.Decode(t, "0090 01 00 0000000a")},                         // Truncated flow stats
+		{Type: OfpStatsTypeFlow, Body: wiretest.Decode(t, "0010 01 00 0000000a 00000000 8000 000a 0000")}, // Flow stats without match
+		{Type: OfpStatsTypePort, Body: wiretest.Decode(t, "00000001 00000000 000000000000000a")},          // Truncated port stats
+		{Type: OfpStatsTypeTable, Body: nil},                                                              // Table stats of openflow 1.1 aren't decoded
+	} {
+		if body, err := DecodeStatsBody(reply); err == nil {
+			t.Errorf("The stats %+v are decoded as %+v", reply, body)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package ofp11
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	switchFeaturesLen = 32
+)
+
+// OfpSwitchFeatureMsg represents the switch feature message structure of
+// openflow 1.1 and 1.2, the ports are still part of the reply and their
+// layout is the one of openflow 1.3
+type OfpSwitchFeatureMsg struct {
+	Header     ofpgeneral.OfpHeader
+	DatapathID uint64 /* Datapath unique ID.  The lower 48-bits are for
+	   a MAC address, while the upper 16-bits are
+	   implementer-defined. */
+
+	NoOfBuffers uint32 /* Max packets buffered at once. */
+
+	NoOfTables uint8   /* Number of tables supported by datapath. */
+	Padding    [3]byte /* Align to 64-bits. */
+
+	/* Features. */
+	Capabilities uint32 /* Bitmap of support "ofp_capabilities". */
+	Reserved     uint32
+
+	/* Port info.*/
+	Ports []ofp13.OfpPhysPort /* Port definitions.  The number of ports
+	   is inferred from the length field in
+	   the header. */
+}
+
+// UnmarshalBinary transforms the byte array into header data
+func (sf *OfpSwitchFeatureMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < switchFeaturesLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &sf.Header, &sf.DatapathID, &sf.NoOfBuffers,
+		&sf.NoOfTables, &sf.Padding, &sf.Capabilities, &sf.Reserved); err != nil {
+		return err
+	}
+	ports, err := ofp13.ParsePortDescBody(data[switchFeaturesLen:])
+	if err != nil {
+		return err
+	}
+	sf.Ports = ports
+	return nil
+}
+
+// MarshalBinary converts the header fields into byte array, the length in
+// the header is set according to the ports
+func (sf *OfpSwitchFeatureMsg) MarshalBinary() ([]byte, error) {
+	portBuf := new(bytes.Buffer)
+	for idx := range sf.Ports {
+		portData, err := (&sf.Ports[idx]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		portBuf.Write(portData)
+	}
+	sf.Header.Length = uint16(switchFeaturesLen + portBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, sf.Header, sf.DatapathID, sf.NoOfBuffers,
+		sf.NoOfTables, sf.Padding, sf.Capabilities, sf.Reserved); err != nil {
+		return nil, err
+	}
+	buf.Write(portBuf.Bytes())
+	return buf.Bytes(), nil
+}
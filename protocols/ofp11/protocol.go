@@ -2,7 +2,9 @@ package ofp11
 
 import (
 	"bytes"
+	"fmt"
 
+	"github.com/kopwei/goof/protocols/ofp13"
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
@@ -22,15 +24,15 @@ const (
 
 // Ofp Capability flags
 // Capabilities supported by the datapath.
+// enum ofp_capabilities {
 const (
-	OfpCapFlowStats  = 1 << iota /* Flow statistics. */
-	OfpCapTableStats             /* Table statistics. */
-	OfpCapPortStats              /* Port statistics. */
-	OfpCapSTP                    /* 802.1d spanning tree. */
-	OfpCapReserved               /* Reserved, must be zero. */
-	OfpCapIPReAsm                /* Can reassemble IP fragments. */
-	OfpQueueStats                /* Queue statistics. */
-	OfpArpMatchIP                /* Match IP addresses in ARP pkts. */
+	OfpCapFlowStats  = 1 << 0 /* Flow statistics. */
+	OfpCapTableStats = 1 << 1 /* Table statistics. */
+	OfpCapPortStats  = 1 << 2 /* Port statistics. */
+	OfpCapGroupStats = 1 << 3 /* Group statistics. */
+	OfpCapIPReAsm    = 1 << 5 /* Can reassemble IP fragments. */
+	OfpQueueStats    = 1 << 6 /* Queue statistics. */
+	OfpArpMatchIP    = 1 << 7 /* Match IP addresses in ARP pkts. */
 )
 
 // The OFP Type constants
@@ -77,19 +79,24 @@ const (
 // Values for 'type' in ofp_error_message.  These values are immutable: they
 // will not change in future versions of the protocol (although new values may
 // be added).
-//enum ofp_error_type {
+// enum ofp_error_type {
 const (
-	OfpErrTypeHelloFailed   = iota /* Hello protocol failed. */
-	OfpErrTypeBadRequest           /* Request was not understood. */
-	OfpErrTypeBadAction            /* Error in action description. */
-	OfpErrTypeFlowModFailed        /* Problem modifying flow entry. */
-	OfpErrTypePortModFailed        /* Port mod request failed. */
-	OfpErrTypeQueueOpFailed        /* Queue operation failed. */
+	OfpErrTypeHelloFailed        = iota /* Hello protocol failed. */
+	OfpErrTypeBadRequest                /* Request was not understood. */
+	OfpErrTypeBadAction                 /* Error in action description. */
+	OfpErrTypeBadInstruction            /* Error in instruction list. */
+	OfpErrTypeBadMatch                  /* Error in match. */
+	OfpErrTypeFlowModFailed             /* Problem modifying flow entry. */
+	OfpErrTypeGroupModFailed            /* Problem modifying group entry. */
+	OfpErrTypePortModFailed             /* Port mod request failed. */
+	OfpErrTypeTableModFailed            /* Table mod request failed. */
+	OfpErrTypeQueueOpFailed             /* Queue operation failed. */
+	OfpErrTypeSwitchConfigFailed        /* Switch config request failed. */
 )
 
 // ofp_error_msg 'code' values for OFPET_HELLO_FAILED.  'data' contains an
 // ASCII text string that may give failure details. */
-//enum ofp_hello_failed_code {
+// enum ofp_hello_failed_code {
 const (
 	OfpHelloFaildCodeIncompatioble = iota /* No compatible version. */
 	OfpHelloFaildCodeErrPerm              /* Permissions error. */
@@ -97,7 +104,7 @@ const (
 
 // ofp_error_msg 'code' values for OFPET_BAD_REQUEST.  'data' contains at least
 // the first 64 bytes of the failed request.
-//enum ofp_bad_request_code {
+// enum ofp_bad_request_code {
 const (
 	OfpBadReqCodeBadVersion    = iota /* ofp_header.version not supported. */
 	OfpBadReqCodeBadType              /* ofp_header.type not supported. */
@@ -119,48 +126,58 @@ const (
 	OfpQueFailedCodeErrPerm        /* Permissions error. */
 )
 
+// enum ofp_packet_in_reason {
+const (
+	OfpPacketInReasonNoMatch = iota /* No matching flow. */
+	OfpPacketInReasonAction         /* Action explicitly output to controller. */
+)
+
+const (
+	packetInHeaderLen  = 24
+	packetOutHeaderLen = 24
+)
+
 // OfpPacketInMsg reprensents the packet_in message received by controller
 /* Packet received on port (datapath -> controller). */
 type OfpPacketInMsg struct {
-	Header   ofpgeneral.OfpHeader
-	BufferID uint32 /* ID assigned by datapath. */
-	TotalLen uint16 /* Full length of frame. */
-	InPort   uint16 /* Port on which frame was received. */
-	Reason   uint8  /* Reason packet is being sent (one of OFPR_*) */
-	Padding  uint8
-	Data     []byte /* Ethernet frame, halfway through 32-bit word,
+	Header    ofpgeneral.OfpHeader
+	BufferID  uint32 /* ID assigned by datapath. */
+	InPort    uint32 /* Port on which frame was received. */
+	InPhyPort uint32 /* Physical Port on which frame was received. */
+	TotalLen  uint16 /* Full length of frame. */
+	Reason    uint8  /* Reason packet is being sent (one of OFPR_*) */
+	TableID   uint8  /* ID of the table that was looked up */
+	Data      []byte /* Ethernet frame, halfway through 32-bit word,
 	   so the IP header is 32-bit aligned.  The
 	   amount of data is inferred from the length
-	   field in the header.  Because of padding,
-	   offsetof(struct ofp_packet_in, data) ==
-	   sizeof(struct ofp_packet_in) - 2. */
+	   field in the header. */
 }
 
-// MarshalBinary converts the packet in msg fields into byte array
+// MarshalBinary converts the packet in msg fields into byte array, the
+// length in the header is set according to the data
 func (in *OfpPacketInMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, in.Header.Length)
-	headerData, err := (&in.Header).MarshalBinary()
-	copy(data, headerData)
+	in.Header.Length = uint16(packetInHeaderLen + len(in.Data))
 	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, in.BufferID, in.TotalLen, in.InPort, in.Reason, in.Padding)
-	if err != nil {
+	if err := ofpgeneral.MarshalFields(buf, in.Header, in.BufferID, in.InPort, in.InPhyPort,
+		in.TotalLen, in.Reason, in.TableID); err != nil {
 		return nil, err
 	}
-	copy(data[8:18], buf.Bytes())
-	copy(data[18:], in.Data)
-	return data, err
+	buf.Write(in.Data)
+	return buf.Bytes(), nil
 }
 
 // UnmarshalBinary transforms the byte array into packet in message data
 func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
-	if err := (&in.Header).UnmarshalBinary(data); err != nil {
-		return err
+	if len(data) < packetInHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	buf := bytes.NewReader(data[8:18])
-	if err := ofpgeneral.UnMarshalFields(buf, &in.BufferID, &in.TotalLen, &in.InPort, &in.Reason, &in.Padding); err != nil {
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &in.Header, &in.BufferID, &in.InPort, &in.InPhyPort,
+		&in.TotalLen, &in.Reason, &in.TableID); err != nil {
 		return err
 	}
-	copy(in.Data, data[18:])
+	in.Data = make([]byte, len(data)-packetInHeaderLen)
+	copy(in.Data, data[packetInHeaderLen:])
 	return nil
 }
 
@@ -168,70 +185,74 @@ func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
 /* Send packet (controller -> datapath). */
 type OfpPacketOutMsg struct {
 	Header     ofpgeneral.OfpHeader
-	BufferID   uint32         /* ID assigned by datapath (-1 if none). */
-	InPort     uint16         /* Packet's input port (OFPP_NONE if none). */
-	ActionsLen uint16         /* Size of action array in bytes. */
-	Actions    []OfpActionMsg /* Actions. */
-	/* uint8_t data[0]; */ /* Packet data.  The length is inferred
+	BufferID   uint32            /* ID assigned by datapath (OFP_NO_BUFFER if none). */
+	InPort     uint32            /* Packet's input port or OFPP_CONTROLLER. */
+	ActionsLen uint16            /* Size of action array in bytes. */
+	Padding    [6]byte           /* Align to 64 bits. */
+	Actions    []ofp13.OfpAction /* Action list. */
+	Data       []byte            /* Packet data. The length is inferred
 	   from the length field in the header.
 	   (Only meaningful if buffer_id == -1.) */
 }
 
-// MarshalBinary converts the packet out msg fields into byte array
+// NewPacketOutMsg creates a packet out message which sends the unbuffered
+// data as if it was received on the in port
+func NewPacketOutMsg(inPort uint32, actions []ofp13.OfpAction, data []byte) *OfpPacketOutMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypePacketOut
+	return &OfpPacketOutMsg{
+		Header:   *header,
+		BufferID: ofp13.OfpNoBuffer,
+		InPort:   inPort,
+		Actions:  actions,
+		Data:     data,
+	}
+}
+
+// MarshalBinary converts the packet out msg fields into byte array,
+// the lengths are set according to the actions and the data
 func (out *OfpPacketOutMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, out.Header.Length)
-	headerData, err := (&out.Header).MarshalBinary()
+	actionData, err := marshalActions(out.Actions)
 	if err != nil {
 		return nil, err
 	}
-	copy(data, headerData)
+	out.ActionsLen = uint16(len(actionData))
+	out.Header.Length = uint16(packetOutHeaderLen + len(actionData) + len(out.Data))
 	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, out.BufferID, out.InPort, out.ActionsLen)
-	if err != nil {
+	if err := ofpgeneral.MarshalFields(buf, out.Header, out.BufferID, out.InPort,
+		out.ActionsLen, out.Padding); err != nil {
 		return nil, err
 	}
-	copy(data[8:16], buf.Bytes())
-	actionByteIdx := uint16(16)
-	for _, action := range out.Actions {
-		actionData, err := action.MarshalBinary()
-		if err != nil {
-			return nil, err
-		}
-		copy(data[actionByteIdx:actionByteIdx+action.Header.Len], actionData)
-		actionByteIdx = actionByteIdx + action.Header.Len
-	}
-	return data, err
+	buf.Write(actionData)
+	buf.Write(out.Data)
+	return buf.Bytes(), nil
 }
 
 // UnmarshalBinary transforms the byte array into packet out message data
 func (out *OfpPacketOutMsg) UnmarshalBinary(data []byte) error {
-	if err := (&out.Header).UnmarshalBinary(data); err != nil {
-		return err
+	if len(data) < packetOutHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	buf := bytes.NewReader(data[8:16])
-	if err := ofpgeneral.UnMarshalFields(buf, &out.BufferID, &out.InPort, &out.ActionsLen); err != nil {
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &out.Header, &out.BufferID, &out.InPort,
+		&out.ActionsLen, &out.Padding); err != nil {
 		return err
 	}
-	actionByteIdx := uint16(16)
-	for i := uint16(0); i < out.ActionsLen; i++ {
-		if err := out.Actions[i].UnmarshalBinary(data[actionByteIdx:]); err != nil {
-			return err
-		}
-		actionByteIdx += out.Actions[i].Header.Len
+	actionEnd := packetOutHeaderLen + int(out.ActionsLen)
+	if len(data) < actionEnd {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	actions, err := ParseActions(data[packetOutHeaderLen:actionEnd])
+	if err != nil {
+		return err
 	}
+	out.Actions = actions
+	out.Data = make([]byte, len(data)-actionEnd)
+	copy(out.Data, data[actionEnd:])
 	return nil
 }
 
 // ParseMsg is the function which parses the message
 func ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	var msg ofpgeneral.OfpMessage
-	var err error
-	switch b[1] {
-	case OfpTypeHello:
-		msg = &ofpgeneral.OfpHelloMsg{}
-		err = msg.UnmarshalBinary(b)
-
-	}
-
-	return msg, err
+	return (&OfpMessageParser{}).ParseMsg(b)
 }
@@ -0,0 +1,44 @@
+package ofp12
+
+import (
+	"bytes"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// The group mod, the buckets and the group descriptions of openflow 1.2
+// share the layout of openflow 1.3 and are handled by ofp13.OfpGroupModMsg,
+// ofp13.OfpBucket and ofp13.OfpGroupDesc, only the actions differ
+
+// CheckBuckets checks the actions of the buckets are openflow 1.2 actions
+func CheckBuckets(buckets []ofp13.OfpBucket) error {
+	for _, bucket := range buckets {
+		buf := new(bytes.Buffer)
+		for _, action := range bucket.Actions {
+			actionData, err := action.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			buf.Write(actionData)
+		}
+		if _, err := ParseActions(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseGroupDescBody decodes the body of the openflow 1.2 group description
+// reply
+func ParseGroupDescBody(body []byte) ([]ofp13.OfpGroupDesc, error) {
+	descs, err := ofp13.ParseGroupDescBody(body)
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range descs {
+		if err := CheckBuckets(desc.Buckets); err != nil {
+			return nil, err
+		}
+	}
+	return descs, nil
+}
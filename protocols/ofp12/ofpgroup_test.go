@@ -0,0 +1,43 @@
+package ofp12
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseGroupDescBody(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		valid bool
+	}{
+		{"output", "0028 01 00 00000005 0020 0002 ffffffff ffffffff 00000000 0000 0010 00000002 ffff 000000000000",
+			true},
+		{"set field", "0028 01 00 00000005 0020 0002 ffffffff ffffffff 00000000 0019 0010 80000c02 1005 000000000000",
+			true},
+		{"push pbb", "0020 01 00 00000005 0018 0002 ffffffff ffffffff 00000000 001a 0008 88e7 0000", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := hex.DecodeString(strings.Replace(tc.wire, " ", "", -1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			descs, err := ParseGroupDescBody(data)
+			if !tc.valid {
+				if err == nil {
+					t.Fatalf("The group descriptions are decoded as %+v", descs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(descs) != 1 || descs[0].GroupID != 5 || len(descs[0].Buckets) != 1 ||
+				len(descs[0].Buckets[0].Actions) != 1 {
+				t.Errorf("Unexpected group descriptions %+v", descs)
+			}
+		})
+	}
+}
@@ -1,12 +1,35 @@
 package ofp12
 
-import "github.com/kopwei/goof/protocols/ofpgeneral"
+import (
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
 
 // OfpMessageParser is the message parser implementation
 type OfpMessageParser struct {
+	// The messages whose layout is unchanged in openflow 1.3 are
+	// decoded into the ofp13 structures
+	ofp13Parser ofp13.OfpMessageParser
 }
 
 // ParseMsg is used to convert bytes into ofp message
 func (p *OfpMessageParser) ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	return nil, nil
+	var message ofpgeneral.OfpMessage
+	switch b[1] {
+	case OfpTypeFeaturesReply:
+		message = &ofp11.OfpSwitchFeatureMsg{}
+	case OfpTypePacketIn:
+		message = &OfpPacketInMsg{}
+	case OfpTypeHello, OfpTypeError, OfpTypeEchoRequest, OfpTypeEchoReply, OfpTypeExperimenter,
+		OfpTypeGetConfigReply, OfpTypeFlowRemoved, OfpTypePortStatus, OfpTypeStatsReply,
+		OfpTypeBarrierReply, OfpTypeRoleReply:
+		return p.ofp13Parser.ParseMsg(b)
+	default:
+		return nil, fmt.Errorf("An unknown v1.2 packet type %d was received. Parse function will discard data.", b[1])
+	}
+	err := message.UnmarshalBinary(b)
+	return message, err
 }
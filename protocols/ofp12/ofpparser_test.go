@@ -0,0 +1,119 @@
+package ofp12
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	// inPortMatchWire is the oxm match of the packets received on the port 3
+	inPortMatchWire = "0001000c 80000004 00000003 00000000"
+	// portWire is the description of the port 1 named eth0
+	portWire = "00000001 00000000 020304050607 0000 6574683000000000 0000000000000000" +
+		"00000001 00000004 00002000 00000000 00000000 00000000 00989680 00989680"
+)
+
+func TestParseSwitchMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, msg ofpgeneral.OfpMessage)
+	}{
+		// The features reply of openflow 1.2 still carries the ports
+		{"features reply", "0306006000000001 0000000000000001 00000100 fe 000000 00000007 00000000" + portWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*ofp11.OfpSwitchFeatureMsg)
+				if m.Header.Version != Version || m.DatapathID != 1 || m.NoOfTables != 0xfe ||
+					len(m.Ports) != 1 || m.Ports[0].PortNo != 1 {
+					t.Errorf("Unexpected features reply %+v", m)
+				}
+			}},
+		{"packet in", "030a002800000000 ffffffff 0006 01 02" + inPortMatchWire + "0000 aabbccddeeff",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPacketInMsg)
+				inPort, ok := m.Match.OXMFields.GetInPort()
+				if m.BufferID != ofp13.OfpNoBuffer || m.TotalLen != 6 || m.Reason != 1 || m.TableID != 2 ||
+					!ok || inPort != 3 || hex.EncodeToString(m.Data) != "aabbccddeeff" {
+					t.Errorf("Unexpected packet in %+v", m)
+				}
+			}},
+		// The messages unchanged in openflow 1.3 are decoded into the
+		// ofp13 structures
+		{"flow removed", "030b004000000000 0000000000000007 8000 00 01 0000000a 00000000 000a 0000" +
+			"0000000000000005 00000000000001f4" + inPortMatchWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m, ok := msg.(*ofp13.OfpFlowRemovedMsg); !ok || m.Cookie != 7 || m.PacketCount != 5 {
+					t.Errorf("Unexpected flow removed %+v", msg)
+				}
+			}},
+		{"port status", "030c005000000000 02 00000000000000" + portWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m, ok := msg.(*ofp13.OfpPortStatusMsg); !ok || m.Desc.PortNo != 1 {
+					t.Errorf("Unexpected port status %+v", msg)
+				}
+			}},
+		{"role reply", "0319001800000000 00000002 00000000 0000000000000005",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m, ok := msg.(*ofp13.OfpRoleRequestMsg); !ok || m.Role != ofp13.OfpControllerRoleMaster ||
+					m.GenerationID != 5 {
+					t.Errorf("Unexpected role reply %+v", msg)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, wiretest.Parse(t, &OfpMessageParser{}, tc.wire))
+		})
+	}
+}
+
+func TestParseMsgInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"031b000800000000",                                       // Get async reply is introduced by openflow 1.3
+		"030a001400000000 ffffffff 0006 01 02 0001",              // Truncated packet in
+		"030a002000000000 ffffffff 0006 01 02" + inPortMatchWire, // Packet in without padding
+	} {
+		if msg, err := (&OfpMessageParser{}).ParseMsg(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The message %s is decoded as %+v", wire, msg)
+		}
+	}
+}
+
+func TestParseActions(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		valid bool
+	}{
+		{"output", "0000 0010 00000002 ffff 000000000000", true},
+		{"set field", "0019 0010 80000c02 1005 000000000000", true},
+		{"push pbb", "001a 0008 88e7 0000", false},
+		{"pop pbb", "001b 0008 00000000", false},
+		{"zero length", "0000 0000 00000002", false},
+		{"truncated", "0000 0010 00000002", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := wiretest.Decode(t, tc.wire)
+			actions, err := ParseActions(data)
+			if !tc.valid {
+				if err == nil {
+					t.Fatalf("The actions are decoded as %+v", actions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(actions) != 1 {
+				t.Fatalf("Decoded %d actions, expected 1", len(actions))
+			}
+			wiretest.CheckEncoding(t, actions[0], data)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package ofp12
+
+import (
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// The stats types of openflow 1.2, the request and the reply share the
+// layout of the openflow 1.3 multipart messages
+// enum ofp_stats_types {
+const (
+	OfpStatsTypeDesc          = 0      /* Description of this OpenFlow switch. */
+	OfpStatsTypeFlow          = 1      /* Individual flow statistics. */
+	OfpStatsTypeAggregate     = 2      /* Aggregate flow statistics. */
+	OfpStatsTypeTable         = 3      /* Flow table statistics. */
+	OfpStatsTypePort          = 4      /* Port statistics. */
+	OfpStatsTypeQueue         = 5      /* Queue statistics for a port. */
+	OfpStatsTypeGroup         = 6      /* Group counter statistics. */
+	OfpStatsTypeGroupDesc     = 7      /* Group description statistics. */
+	OfpStatsTypeGroupFeatures = 8      /* Group features. */
+	OfpStatsTypeExperimenter  = 0xffff /* Experimenter extension. */
+)
+
+// DecodeStatsBody decodes the body of the openflow 1.2 stats reply, the
+// bodies whose layout is unchanged in openflow 1.3 are decoded by
+// ofp13.OfpMultipartReplyMsg.DecodeBody
+func DecodeStatsBody(mr *ofp13.OfpMultipartReplyMsg) (interface{}, error) {
+	switch mr.Type {
+	case OfpStatsTypeDesc, OfpStatsTypeFlow, OfpStatsTypeAggregate, OfpStatsTypeGroupFeatures,
+		OfpStatsTypeExperimenter:
+		return mr.DecodeBody()
+	case OfpStatsTypeGroupDesc:
+		return ParseGroupDescBody(mr.Body)
+	case OfpStatsTypePort:
+		return ofp11.ParsePortStatsBody(mr.Body)
+	}
+	return nil, fmt.Errorf("Decoding of the v1.2 stats type %d is not supported", mr.Type)
+}
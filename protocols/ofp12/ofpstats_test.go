@@ -0,0 +1,42 @@
+package ofp12
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp11"
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+func TestDecodeStatsBody(t *testing.T) {
+	// The flow stats of openflow 1.2 have the layout of openflow 1.3
+	reply := &ofp13.OfpMultipartReplyMsg{Type: OfpStatsTypeFlow, Body: wiretest.Decode(t,
+		"0048 01 00 0000000a 00000000 8000 000a 0000 000000000000"+
+			"0000000000000007 0000000000000005 00000000000001f4"+inPortMatchWire+"0001 0008 02 000000")}
+	body, err := DecodeStatsBody(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flowStats := body.([]ofp13.OfpFlowStats); len(flowStats) != 1 || flowStats[0].Cookie != 7 ||
+		len(flowStats[0].Instructions) != 1 {
+		t.Errorf("Unexpected flow stats %+v", flowStats)
+	}
+
+	// The port stats of openflow 1.2 have the layout of openflow 1.1
+	reply = &ofp13.OfpMultipartReplyMsg{Type: OfpStatsTypePort, Body: wiretest.Decode(t, "00000001 00000000"+
+		"000000000000000a 0000000000000014 00000000000003e8 00000000000007d0 0000000000000000 0000000000000000"+
+		"0000000000000000 0000000000000000 0000000000000000 0000000000000000 0000000000000000 0000000000000000")}
+	if body, err = DecodeStatsBody(reply); err != nil {
+		t.Fatal(err)
+	}
+	if portStats := body.([]ofp11.OfpPortStats); len(portStats) != 1 || portStats[0].PortNo != 1 ||
+		portStats[0].TxPackets != 20 {
+		t.Errorf("Unexpected port stats %+v", portStats)
+	}
+
+	// The table stats of openflow 1.2 aren't decoded
+	reply = &ofp13.OfpMultipartReplyMsg{Type: OfpStatsTypeTable}
+	if body, err := DecodeStatsBody(reply); err == nil {
+		t.Errorf("The table stats are decoded as %+v", body)
+	}
+}
@@ -1,6 +1,118 @@
 package ofp12
 
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
 const (
 	// Version is the value of version byte in ofp header
 	Version = uint8(0x03)
 )
+
+// OfpType is the type of the ofp messages
+// enum ofp_type {
+const (
+	/* Immutable messages. */
+	OfpTypeHello = iota /* Symmetric message */
+	OfpTypeError        /* Symmetric message */
+	OfpTypeEchoRequest
+	OfpTypeEchoReply
+	OfpTypeExperimenter
+
+	/* Switch configuration messages. */
+	OfpTypeFeaturesRequest
+	OfpTypeFeaturesReply
+	OfpTypeGetConfigRequest
+	OfpTypeGetConfigReply
+	OfpTypeSetConfig
+
+	/* Asynchronous messages. */
+	OfpTypePacketIn
+	OfpTypeFlowRemoved
+	OfpTypePortStatus
+
+	/* Controller command messages. */
+	OfpTypePacketOut
+	OfpTypeFlowMod
+	OfpTypeGroupMod
+	OfpTypePortMod
+	OfpTypeTableMod
+
+	/* Statistics messages. */
+	OfpTypeStatsRequest
+	OfpTypeStatsReply
+
+	/* Barrier messages. */
+	OfpTypeBarrierRequest
+	OfpTypeBarrierReply
+
+	/* Queue Configuration messages. */
+	OfpTypeQueueGetConfigRequest /* Controller/switch message */
+	OfpTypeQueueGetConfigReply   /* Controller/switch message */
+
+	/* Controller role change request messages. */
+	OfpTypeRoleRequest /* Controller/switch message */
+	OfpTypeRoleReply   /* Controller/switch message */
+)
+
+const (
+	packetInHeaderLen = 16
+)
+
+// OfpPacketInMsg represents the packet in message of openflow 1.2, it
+// differs from the one of openflow 1.3 by the lack of the cookie
+/* Packet received on port (datapath -> controller). */
+type OfpPacketInMsg struct {
+	Header   ofpgeneral.OfpHeader
+	BufferID uint32         /* ID assigned by datapath. */
+	TotalLen uint16         /* Full length of frame. */
+	Reason   uint8          /* Reason packet is being sent (one of OFPR_*) */
+	TableID  uint8          /* ID of the table that was looked up */
+	Match    ofp13.OfpMatch /* Packet metadata. Variable size. */
+	Padding  [2]byte        /* Align to 64 bit + 16 bit */
+	Data     []byte         /* Ethernet frame */
+}
+
+// MarshalBinary converts the packet in msg fields into byte array
+func (in *OfpPacketInMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&in.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	in.Header.Length = uint16(packetInHeaderLen + len(matchData) + 2 + len(in.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, in.Header, in.BufferID, in.TotalLen, in.Reason,
+		in.TableID); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(in.Padding[:])
+	buf.Write(in.Data)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary transforms the byte array into packet in message data
+func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < packetInHeaderLen+4 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &in.Header, &in.BufferID, &in.TotalLen, &in.Reason,
+		&in.TableID); err != nil {
+		return err
+	}
+	if err := (&in.Match).UnmarshalBinary(data[packetInHeaderLen:]); err != nil {
+		return err
+	}
+	dataIdx := packetInHeaderLen + int(in.Match.Len()) + 2
+	if len(data) < dataIdx {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	in.Data = make([]byte, len(data)-dataIdx)
+	copy(in.Data, data[dataIdx:])
+	return nil
+}
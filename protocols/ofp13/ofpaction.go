@@ -4,368 +4,494 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"net"
 
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
 // OFP Action Type
+// enum ofp_action_type {
 const (
-	OfpActionOutputToPort = iota /* Output to switch port. */
-	OfpActionSetVlanVID          /* Set the 802.1q VLAN id. */
-	OfpActionSetVlanPCP          /* Set the 802.1q priority. */
-	OfpActionStripVlan           /* Strip the 802.1q header. */
-	OfpActionSetDLSrc            /* Ethernet source address. */
-	OfpActionSetDLDst            /* Ethernet destination address. */
-	OfpActionSetNWSrc            /* IP source address. */
-	OfpActionSetNWDst            /* IP destination address. */
-	OfpActionSetNWToS            /* IP ToS (DSCP field, 6 bits). */
-	OfpActionSetTPSrc            /* TCP/UDP source port. */
-	OfpActionSetTPDst            /* TCP/UDP destination port. */
-	OfpActionEnqueue             /* Output to queue.  */
-	OfpActionVendor       = 0xffff
+	OfpActionOutputToPort = 0      /* Output to switch port. */
+	OfpActionCopyTTLOut   = 11     /* Copy TTL "outwards" -- from next-to-outermost to outermost */
+	OfpActionCopyTTLIn    = 12     /* Copy TTL "inwards" -- from outermost to next-to-outermost */
+	OfpActionSetMplsTTL   = 15     /* MPLS TTL */
+	OfpActionDecMplsTTL   = 16     /* Decrement MPLS TTL */
+	OfpActionPushVlan     = 17     /* Push a new VLAN tag */
+	OfpActionPopVlan      = 18     /* Pop the outer VLAN tag */
+	OfpActionPushMpls     = 19     /* Push a new MPLS tag */
+	OfpActionPopMpls      = 20     /* Pop the outer MPLS tag */
+	OfpActionSetQueue     = 21     /* Set queue id when outputting to a port */
+	OfpActionGroup        = 22     /* Apply group. */
+	OfpActionSetNWTTL     = 23     /* IP TTL. */
+	OfpActionDecNWTTL     = 24     /* Decrement IP TTL. */
+	OfpActionSetField     = 25     /* Set a header field using OXM TLV format. */
+	OfpActionPushPBB      = 26     /* Push a new PBB service tag (I-TAG) */
+	OfpActionPopPBB       = 27     /* Pop the outer PBB service tag (I-TAG) */
+	OfpActionExperimenter = 0xffff /* Experimenter action. */
 )
 
 // ofp_error_msg 'code' values for OFPET_BAD_ACTION.  'data' contains at least
 // the first 64 bytes of the failed request. */
-//enum ofp_bad_action_code {
+// enum ofp_bad_action_code {
 const (
-	OfpBadActionCodeBadType       = iota /* Unknown action type. */
-	OfpBadActionCodeBadLen               /* Length problem in actions. */
-	OfpBadActionCodeBadVendor            /* Unknown vendor id specified. */
-	OfpBadActionCodeBadVendorType        /* Unknown action type for vendor id. */
-	OfpBadActionCodeBadOutPort           /* Problem validating output action. */
-	OfpBadActionCodeBadArgument          /* Bad action argument. */
-	OfpBadActionCodeErrPerm              /* Permissions error. */
-	OfpBadActionCodeTooMany              /* Can't handle this many actions. */
-	OfpBadActionCodeBadQueue             /* Problem validating output queue. */
+	OfpBadActionCodeBadType           = iota /* Unknown action type. */
+	OfpBadActionCodeBadLen                   /* Length problem in actions. */
+	OfpBadActionCodeBadExperimenter          /* Unknown experimenter id specified. */
+	OfpBadActionCodeBadExpType               /* Unknown action for experimenter id. */
+	OfpBadActionCodeBadOutPort               /* Problem validating output port. */
+	OfpBadActionCodeBadArgument              /* Bad action argument. */
+	OfpBadActionCodeErrPerm                  /* Permissions error. */
+	OfpBadActionCodeTooMany                  /* Can't handle this many actions. */
+	OfpBadActionCodeBadQueue                 /* Problem validating output queue. */
+	OfpBadActionCodeBadOutGroup              /* Invalid group id in forward action. */
+	OfpBadActionCodeMatchInconsistent        /* Action can't apply for this match, or Set-Field missing prerequisite. */
+	OfpBadActionCodeUnsupportedOrder         /* Action order is unsupported for the action list in an Apply-Actions instruction */
+	OfpBadActionCodeBadTag                   /* Actions uses an unsupported tag/encap. */
+	OfpBadActionCodeBadSetType               /* Unsupported type in SET_FIELD action. */
+	OfpBadActionCodeBadSetLen                /* Length problem in SET_FIELD action. */
+	OfpBadActionCodeBadSetArgument           /* Bad argument in SET_FIELD action. */
 )
 
+// enum ofp_controller_max_len {
+const (
+	OfpControllerMaxLen      = 0xffe5 /* maximum max_len value which can be used to request a specific byte length. */
+	OfpControllerMaxLenNoBuf = 0xffff /* indicates that no buffering should be applied and the whole packet is to be sent to the controller. */
+)
+
+const (
+	ofpActionHeaderLen = 8
+)
+
+// OfpAction is implemented by all the action structures
+type OfpAction interface {
+	ofpgeneral.OfpMessage
+	// Len returns the length of the action including the padding
+	Len() uint16
+}
+
+// OfpActionHeader represents the header structure that is common to all
+// actions. It is also the whole action of the actions without argument,
+// which are copy ttl out/in, dec mpls ttl, pop vlan, dec nw ttl and pop pbb.
+// NB: The length of an action *must* always be a multiple of eight.
+type OfpActionHeader struct {
+	Type   uint16 /* One of OFPAT_*. */
+	Length uint16 /* Length of action, including this
+	   header.  This is the length of action,
+	   including any padding to make it
+	   64-bit aligned. */
+	Padding [4]byte
+}
+
+// NewActionHeader creates the action without argument of the type
+func NewActionHeader(actionType uint16) *OfpActionHeader {
+	return &OfpActionHeader{Type: actionType, Length: ofpActionHeaderLen}
+}
+
+// Len returns the length of the action
+func (ah *OfpActionHeader) Len() uint16 {
+	return ofpActionHeaderLen
+}
+
+// UnmarshalBinary transforms the byte array into header data
+func (ah *OfpActionHeader) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, ah.Len(), ah)
+}
+
+// MarshalBinary converts the header fields into byte array
+func (ah *OfpActionHeader) MarshalBinary() ([]byte, error) {
+	ah.Length = ofpActionHeaderLen
+	return marshalFixedAction(ah)
+}
+
 // OfpActionOutput represents the ofp action output
 // Action structure for OFPAT_OUTPUT, which sends packets out 'port'.
 // When the 'port' is the OFPP_CONTROLLER, 'max_len' indicates the max
 // number of bytes to send.  A 'max_len' of zero means no bytes of the
-// packet should be sent
+// packet should be sent. A 'max_len' of OFPCML_NO_BUFFER means that
+// the packet is not buffered and the complete packet is to be sent to
+// the controller.
 type OfpActionOutput struct {
-	Type   uint16 /* OFPAT_OUTPUT. */
-	Len    uint16 /* Length is 8. */
-	Port   uint16 /* Output port. */
-	MaxLen uint16 /* Max length to send to controller. */
+	Type    uint16  /* OFPAT_OUTPUT. */
+	Length  uint16  /* Length is 16. */
+	Port    uint32  /* Output port. */
+	MaxLen  uint16  /* Max length to send to controller. */
+	Padding [6]byte /* Pad to 64 bits. */
+}
+
+// NewActionOutput creates the output action to the port
+func NewActionOutput(port uint32) *OfpActionOutput {
+	return &OfpActionOutput{Type: OfpActionOutputToPort, Length: 16, Port: port,
+		MaxLen: OfpControllerMaxLenNoBuf}
+}
+
+// Len returns the length of the action
+func (ao *OfpActionOutput) Len() uint16 {
+	return 16
 }
 
 // UnmarshalBinary transforms the byte array into body data
 func (ao *OfpActionOutput) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ao.Type, &ao.Len, &ao.Port, &ao.MaxLen)
+	return unmarshalFixedAction(data, ao.Len(), ao)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (ao *OfpActionOutput) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ao.Type, ao.Len, ao.Port, ao.MaxLen); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+	ao.Length = 16
+	return marshalFixedAction(ao)
+}
+
+// OfpActionMplsTTL represents action structure for OFPAT_SET_MPLS_TTL
+type OfpActionMplsTTL struct {
+	Type    uint16 /* OFPAT_SET_MPLS_TTL. */
+	Length  uint16 /* Length is 8. */
+	MplsTTL uint8  /* MPLS TTL */
+	Padding [3]byte
+}
+
+// NewActionMplsTTL creates the action setting the mpls ttl
+func NewActionMplsTTL(ttl uint8) *OfpActionMplsTTL {
+	return &OfpActionMplsTTL{Type: OfpActionSetMplsTTL, Length: 8, MplsTTL: ttl}
 }
 
-// OfpActionVlanVID represents action structure for OFPAT_SET_VLAN_VID.
-// The VLAN id is 12 bits, so we can use the entire 16 bits to indicate
-// special conditions.  All ones is used to match that no VLAN id was
-// set.
-type OfpActionVlanVID struct {
-	Type    uint16 /* OFPAT_SET_VLAN_VID. */
-	Len     uint16 /* Length is 8. */
-	VlanVID uint16 /* VLAN id. */
-	Padding [2]byte
+// Len returns the length of the action
+func (amt *OfpActionMplsTTL) Len() uint16 {
+	return 8
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (avv *OfpActionVlanVID) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &avv.Type, &avv.Len, &avv.VlanVID)
+func (amt *OfpActionMplsTTL) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, amt.Len(), amt)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (avv *OfpActionVlanVID) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, avv.Type, avv.Len, avv.VlanVID, avv.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+func (amt *OfpActionMplsTTL) MarshalBinary() ([]byte, error) {
+	amt.Length = 8
+	return marshalFixedAction(amt)
 }
 
-// OfpActionVlanPCP represents action structure for OFPAT_SET_VLAN_PCP.
-type OfpActionVlanPCP struct {
-	Type    uint16 /* OFPAT_SET_VLAN_PCP. */
-	Len     uint16 /* Length is 8. */
-	VlanPCP uint8  /* VLAN priority. */
-	Padding [3]byte
+// OfpActionPush represents action structure for OFPAT_PUSH_VLAN/MPLS/PBB
+type OfpActionPush struct {
+	Type      uint16 /* OFPAT_PUSH_VLAN/MPLS/PBB. */
+	Length    uint16 /* Length is 8. */
+	EtherType uint16 /* Ethertype */
+	Padding   [2]byte
+}
+
+// NewActionPush creates the push action of the type with the ethertype
+func NewActionPush(actionType uint16, etherType uint16) *OfpActionPush {
+	return &OfpActionPush{Type: actionType, Length: 8, EtherType: etherType}
+}
+
+// Len returns the length of the action
+func (ap *OfpActionPush) Len() uint16 {
+	return 8
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (avp *OfpActionVlanPCP) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &avp.Type, &avp.Len, &avp.VlanPCP)
+func (ap *OfpActionPush) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, ap.Len(), ap)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (avp *OfpActionVlanPCP) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, avp.Type, avp.Len, avp.VlanPCP, avp.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+func (ap *OfpActionPush) MarshalBinary() ([]byte, error) {
+	ap.Length = 8
+	return marshalFixedAction(ap)
+}
+
+// OfpActionPopMplsInfo represents action structure for OFPAT_POP_MPLS
+type OfpActionPopMplsInfo struct {
+	Type      uint16 /* OFPAT_POP_MPLS. */
+	Length    uint16 /* Length is 8. */
+	EtherType uint16 /* Ethertype */
+	Padding   [2]byte
+}
+
+// NewActionPopMpls creates the action popping the mpls tag
+func NewActionPopMpls(etherType uint16) *OfpActionPopMplsInfo {
+	return &OfpActionPopMplsInfo{Type: OfpActionPopMpls, Length: 8, EtherType: etherType}
 }
 
-// OfpActionDLAddt represents action structure for OFPAT_SET_DL_SRC/DST.
-type OfpActionDLAddt struct {
-	Type    uint16 /* OFPAT_SET_DL_SRC/DST. */
-	Len     uint16 /* Length is 16. */
-	DLAddr  net.HardwareAddr
-	Padding [6]byte
+// Len returns the length of the action
+func (apm *OfpActionPopMplsInfo) Len() uint16 {
+	return 8
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (ada *OfpActionDLAddt) UnmarshalBinary(data []byte) error {
-	if len(data) < 16 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &ada.Type, &ada.Len); err != nil {
-		return err
-	}
-	ada.DLAddr = make([]byte, 6)
-	copy(ada.DLAddr, data[4:])
-	return nil
+func (apm *OfpActionPopMplsInfo) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, apm.Len(), apm)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ada *OfpActionDLAddt) MarshalBinary() ([]byte, error) {
-	data := make([]byte, 16)
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ada.Type, ada.Len); err != nil {
-		return nil, err
-	}
-	copy(data, buf.Bytes())
-	copy(data[4:], ada.DLAddr)
-	return data, nil
+func (apm *OfpActionPopMplsInfo) MarshalBinary() ([]byte, error) {
+	apm.Length = 8
+	return marshalFixedAction(apm)
 }
 
-// OfpActionNWAddt represents Action structure for OFPAT_SET_NW_SRC/DST.
-type OfpActionNWAddt struct {
-	Type   uint16 /* OFPAT_SET_TW_SRC/DST. */
-	Len    uint16 /* Length is 8. */
-	NWAddr net.IP /* IP address. */
+// OfpActionSetQueueInfo represents the OFPAT_SET_QUEUE action struct: send
+// packets to given queue on port.
+type OfpActionSetQueueInfo struct {
+	Type    uint16 /* OFPAT_SET_QUEUE. */
+	Length  uint16 /* Len is 8. */
+	QueueID uint32 /* Queue id for the packets. */
+}
+
+// NewActionSetQueue creates the action setting the queue
+func NewActionSetQueue(queueID uint32) *OfpActionSetQueueInfo {
+	return &OfpActionSetQueueInfo{Type: OfpActionSetQueue, Length: 8, QueueID: queueID}
+}
+
+// Len returns the length of the action
+func (asq *OfpActionSetQueueInfo) Len() uint16 {
+	return 8
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (ana *OfpActionNWAddt) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &ana.Type, &ana.Len); err != nil {
-		return err
-	}
-	ana.NWAddr = make([]byte, 4)
-	copy(ana.NWAddr, data[4:])
-	return nil
+func (asq *OfpActionSetQueueInfo) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, asq.Len(), asq)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ana *OfpActionNWAddt) MarshalBinary() ([]byte, error) {
-	data := make([]byte, 8)
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ana.Type, ana.Len); err != nil {
-		return nil, err
-	}
-	copy(data, buf.Bytes())
-	copy(data[4:], ana.NWAddr)
-	return data, nil
+func (asq *OfpActionSetQueueInfo) MarshalBinary() ([]byte, error) {
+	asq.Length = 8
+	return marshalFixedAction(asq)
+}
+
+// OfpActionGroupInfo represents action structure for OFPAT_GROUP
+type OfpActionGroupInfo struct {
+	Type    uint16 /* OFPAT_GROUP. */
+	Length  uint16 /* Length is 8. */
+	GroupID uint32 /* Group identifier. */
 }
 
-// OfpActionTPPort represents action structure for OFPAT_SET_TP_SRC/DST.
-type OfpActionTPPort struct {
-	Type    uint16 /* OFPAT_SET_TP_SRC/DST. */
-	Len     uint16 /* Length is 8. */
-	TPPort  uint16 /* TCP/UDP port. */
-	Padding [2]byte
+// NewActionGroup creates the action applying the group
+func NewActionGroup(groupID uint32) *OfpActionGroupInfo {
+	return &OfpActionGroupInfo{Type: OfpActionGroup, Length: 8, GroupID: groupID}
+}
+
+// Len returns the length of the action
+func (ag *OfpActionGroupInfo) Len() uint16 {
+	return 8
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (atp *OfpActionTPPort) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &atp.Type, &atp.Len, &atp.TPPort)
+func (ag *OfpActionGroupInfo) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, ag.Len(), ag)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (atp *OfpActionTPPort) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, atp.Type, atp.Len, atp.TPPort, atp.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+func (ag *OfpActionGroupInfo) MarshalBinary() ([]byte, error) {
+	ag.Length = 8
+	return marshalFixedAction(ag)
 }
 
-// OfpActionNWToS represents action structure for OFPAT_SET_NW_TOS.
-type OfpActionNWToS struct {
-	Type    uint16 /* OFPAT_SET_TW_SRC/DST. */
-	Len     uint16 /* Length is 8. */
-	NWTos   uint8  /* IP ToS (DSCP field, 6 bits). */
+// OfpActionNWTTL represents action structure for OFPAT_SET_NW_TTL
+type OfpActionNWTTL struct {
+	Type    uint16 /* OFPAT_SET_NW_TTL. */
+	Length  uint16 /* Length is 8. */
+	NWTTL   uint8  /* IP TTL */
 	Padding [3]byte
 }
 
+// NewActionNWTTL creates the action setting the ip ttl
+func NewActionNWTTL(ttl uint8) *OfpActionNWTTL {
+	return &OfpActionNWTTL{Type: OfpActionSetNWTTL, Length: 8, NWTTL: ttl}
+}
+
+// Len returns the length of the action
+func (ant *OfpActionNWTTL) Len() uint16 {
+	return 8
+}
+
 // UnmarshalBinary transforms the byte array into body data
-func (ant *OfpActionNWToS) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ant.Type, &ant.Len, &ant.NWTos)
+func (ant *OfpActionNWTTL) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, ant.Len(), ant)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ant *OfpActionNWToS) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ant.Type, ant.Len, ant.NWTos, ant.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+func (ant *OfpActionNWTTL) MarshalBinary() ([]byte, error) {
+	ant.Length = 8
+	return marshalFixedAction(ant)
 }
 
-// OfpActionVendorHeader represents action header for OFPAT_VENDOR.
-// The rest of the body is vendor-defined.
-type OfpActionVendorHeader struct {
-	Type   uint16 /* OFPAT_VENDOR. */
-	Len    uint16 /* Length is a multiple of 8. */
-	Vendor uint32 /* Vendor ID, which takes the same form
-	   as in "struct ofp_vendor_header". */
+// OfpActionSetFieldInfo represents action structure for OFPAT_SET_FIELD
+type OfpActionSetFieldInfo struct {
+	Type   uint16 /* OFPAT_SET_FIELD. */
+	Length uint16 /* Length is padded to 64 bits. */
+	/* Followed by:
+	 * - Exactly oxm_len bytes containing a single OXM TLV, then
+	 * - Exactly ((oxm_len + 4) + 7)/8*8 - (oxm_len + 4) (between 0 and 7)
+	 * bytes of all-zero bytes
+	 */
+	Field ofpgeneral.OxmField /* OXM TLV */
 }
 
-// OfpActionHeader represents the header structure that is common to all actions.
-// The length includes the header and any padding used to make the action 64-bit aligned.
-// NB: The length of an action *must* always be a multiple of eight.
-type OfpActionHeader struct {
-	Type uint16 /* One of OFPAT_*. */
-	Len  uint16 /* Length of action, including this
-	   header.  This is the length of action,
-	   including any padding to make it
-	   64-bit aligned. */
-	Padding [4]byte
+// NewActionSetField creates the action setting the header field
+func NewActionSetField(field ofpgeneral.OxmField) *OfpActionSetFieldInfo {
+	action := &OfpActionSetFieldInfo{Type: OfpActionSetField, Field: field}
+	action.Length = action.Len()
+	return action
 }
 
-// UnmarshalBinary transforms the byte array into header data
-func (ah *OfpActionHeader) UnmarshalBinary(data []byte) error {
-	if len(data) < 4 {
+// Len returns the length of the action including the padding
+func (asf *OfpActionSetFieldInfo) Len() uint16 {
+	return (4 + asf.Field.Len() + 7) / 8 * 8
+}
+
+// UnmarshalBinary transforms the byte array into body data
+func (asf *OfpActionSetFieldInfo) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ah.Type, &ah.Len)
+	if err := ofpgeneral.UnMarshalFields(buf, &asf.Type, &asf.Length); err != nil {
+		return err
+	}
+	if int(asf.Length) > len(data) || asf.Length < ofpActionHeaderLen {
+		return fmt.Errorf("Invalid set field action length %d", asf.Length)
+	}
+	if err := (&asf.Field).UnmarshalBinary(data[4:asf.Length]); err != nil {
+		return err
+	}
+	if 4+asf.Field.Len() > asf.Length {
+		return fmt.Errorf("Invalid set field action length %d", asf.Length)
+	}
+	return nil
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ah *OfpActionHeader) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ah.Type, ah.Len, ah.Padding); err != nil {
+func (asf *OfpActionSetFieldInfo) MarshalBinary() ([]byte, error) {
+	fieldData, err := (&asf.Field).MarshalBinary()
+	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	asf.Type = OfpActionSetField
+	asf.Length = asf.Len()
+	data := make([]byte, asf.Length)
+	binary.BigEndian.PutUint16(data[0:2], asf.Type)
+	binary.BigEndian.PutUint16(data[2:4], asf.Length)
+	copy(data[4:], fieldData)
+	return data, nil
+}
+
+// OfpActionExperimenterHeader represents action header for OFPAT_EXPERIMENTER.
+// The rest of the body is experimenter-defined.
+type OfpActionExperimenterHeader struct {
+	Type         uint16 /* OFPAT_EXPERIMENTER. */
+	Length       uint16 /* Length is a multiple of 8. */
+	Experimenter uint32 /* Experimenter ID which takes the same
+	   form as in struct
+	   ofp_experimenter_header. */
+	Data []byte /* Experimenter-defined data, including the padding */
 }
 
-// OfpActionEnqueueInfo represents the OFPAT_ENQUEUE action struct: send packets to given queue on port.
-type OfpActionEnqueueInfo struct {
-	Type uint16 /* OFPAT_ENQUEUE. */
-	Len  uint16 /* Len is 16. */
-	Port uint16 /* Port that queue belongs. Should
-	   refer to a valid physical port
-	   (i.e. < OFPP_MAX) or OFPP_IN_PORT. */
-	Padding [6]byte /* Pad for 64-bit alignment. */
-	QueueID uint32  /* Where to enqueue the packets. */
+// Len returns the length of the action including the padding
+func (aeh *OfpActionExperimenterHeader) Len() uint16 {
+	return uint16((ofpActionHeaderLen + len(aeh.Data) + 7) / 8 * 8)
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (aei *OfpActionEnqueueInfo) UnmarshalBinary(data []byte) error {
-	if len(data) < 16 {
+func (aeh *OfpActionExperimenterHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpActionHeaderLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &aei.Type, &aei.Len, &aei.Port, &aei.Padding, &aei.QueueID)
+	if err := ofpgeneral.UnMarshalFields(buf, &aeh.Type, &aeh.Length, &aeh.Experimenter); err != nil {
+		return err
+	}
+	if aeh.Length < ofpActionHeaderLen || int(aeh.Length) > len(data) {
+		return fmt.Errorf("Invalid experimenter action length %d", aeh.Length)
+	}
+	aeh.Data = make([]byte, aeh.Length-ofpActionHeaderLen)
+	copy(aeh.Data, data[ofpActionHeaderLen:aeh.Length])
+	return nil
 }
 
 // MarshalBinary converts the header fields into byte array
-func (aei *OfpActionEnqueueInfo) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, aei.Type, aei.Len, aei.Port, aei.Padding, aei.QueueID); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+func (aeh *OfpActionExperimenterHeader) MarshalBinary() ([]byte, error) {
+	aeh.Type = OfpActionExperimenter
+	aeh.Length = aeh.Len()
+	data := make([]byte, aeh.Length)
+	binary.BigEndian.PutUint16(data[0:2], aeh.Type)
+	binary.BigEndian.PutUint16(data[2:4], aeh.Length)
+	binary.BigEndian.PutUint32(data[4:8], aeh.Experimenter)
+	copy(data[ofpActionHeaderLen:], aeh.Data)
+	return data, nil
 }
 
-// OfpActionMsg represents the body structure of the action msg sent to datapath
-type OfpActionMsg struct {
-	Header OfpActionHeader
-	Body   ofpgeneral.OfpMessage
+// ParseActions decodes the list of actions
+func ParseActions(data []byte) ([]OfpAction, error) {
+	actions := make([]OfpAction, 0)
+	for idx := 0; idx < len(data); {
+		if len(data)-idx < ofpActionHeaderLen {
+			return nil, fmt.Errorf("The data size %d is not big enough to be decoded", len(data)-idx)
+		}
+		actionType := binary.BigEndian.Uint16(data[idx : idx+2])
+		actionLen := int(binary.BigEndian.Uint16(data[idx+2 : idx+4]))
+		if actionLen < ofpActionHeaderLen || idx+actionLen > len(data) {
+			return nil, fmt.Errorf("Invalid length %d of action type %d", actionLen, actionType)
+		}
+		var action OfpAction
+		switch actionType {
+		case OfpActionOutputToPort:
+			action = &OfpActionOutput{}
+		case OfpActionCopyTTLOut, OfpActionCopyTTLIn, OfpActionDecMplsTTL,
+			OfpActionPopVlan, OfpActionDecNWTTL, OfpActionPopPBB:
+			action = &OfpActionHeader{}
+		case OfpActionSetMplsTTL:
+			action = &OfpActionMplsTTL{}
+		case OfpActionPushVlan, OfpActionPushMpls, OfpActionPushPBB:
+			action = &OfpActionPush{}
+		case OfpActionPopMpls:
+			action = &OfpActionPopMplsInfo{}
+		case OfpActionSetQueue:
+			action = &OfpActionSetQueueInfo{}
+		case OfpActionGroup:
+			action = &OfpActionGroupInfo{}
+		case OfpActionSetNWTTL:
+			action = &OfpActionNWTTL{}
+		case OfpActionSetField:
+			action = &OfpActionSetFieldInfo{}
+		case OfpActionExperimenter:
+			action = &OfpActionExperimenterHeader{}
+		default:
+			return nil, fmt.Errorf("Unknown action type %d", actionType)
+		}
+		if err := action.UnmarshalBinary(data[idx : idx+actionLen]); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+		idx += actionLen
+	}
+	return actions, nil
 }
 
-// UnmarshalBinary transforms the byte array into msg data
-func (oam *OfpActionMsg) UnmarshalBinary(data []byte) error {
-	if err := (&oam.Header).UnmarshalBinary(data); err != nil {
-		return err
-	}
-	dataStartIdx := 4
-	actionType := binary.BigEndian.Uint16(data[dataStartIdx : dataStartIdx+2])
-	switch actionType {
-	case OfpActionOutputToPort:
-		oam.Body = &OfpActionOutput{}
-	case OfpActionSetVlanVID:
-		oam.Body = &OfpActionVlanVID{}
-	case OfpActionSetVlanPCP:
-		oam.Body = &OfpActionVlanPCP{}
-	case OfpActionSetDLSrc:
-		oam.Body = &OfpActionDLAddt{}
-	case OfpActionSetDLDst:
-		oam.Body = &OfpActionDLAddt{}
-	case OfpActionSetNWSrc:
-		oam.Body = &OfpActionNWAddt{}
-	case OfpActionSetNWDst:
-		oam.Body = &OfpActionNWAddt{}
-	case OfpActionSetNWToS:
-		oam.Body = &OfpActionNWToS{}
-	case OfpActionSetTPSrc:
-		oam.Body = &OfpActionTPPort{}
-	case OfpActionSetTPDst:
-		oam.Body = &OfpActionTPPort{}
-	case OfpActionEnqueue:
-		oam.Body = &OfpActionEnqueueInfo{}
+// marshalActions encodes the list of actions
+func marshalActions(actions []OfpAction) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, action := range actions {
+		actionData, err := action.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(actionData)
 	}
-	oam.Body.UnmarshalBinary(data[dataStartIdx:])
-	return nil
+	return buf.Bytes(), nil
 }
 
-// MarshalBinary transforms the msg data into byte array
-func (oam *OfpActionMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, oam.Header.Len)
-	headerData, err := oam.Header.MarshalBinary()
-	if err != nil {
-		return nil, err
+// unmarshalFixedAction decodes the action whose fields are all fixed size
+func unmarshalFixedAction(data []byte, length uint16, action interface{}) error {
+	if len(data) < int(length) {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	copy(data, headerData)
-	bodyData, err := oam.Body.MarshalBinary()
-	if err != nil {
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, action)
+}
+
+// marshalFixedAction encodes the action whose fields are all fixed size
+func marshalFixedAction(action interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, action); err != nil {
 		return nil, err
 	}
-	copy(data[4:], bodyData)
-	return data, nil
+	return buf.Bytes(), nil
 }
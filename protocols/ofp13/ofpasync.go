@@ -0,0 +1,57 @@
+package ofp13
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	asyncConfigLen = 32
+)
+
+// OfpAsyncConfigMsg represents the asynchronous message configuration used
+// by the set async message and the get async reply. The element 0 of the
+// masks applies to the master or equal role and the element 1 to the slave
+// role, the bit (1 << reason) enables the messages of the reason.
+type OfpAsyncConfigMsg struct {
+	Header          ofpgeneral.OfpHeader
+	PacketInMask    [2]uint32 /* Bitmasks of OFPR_* values. */
+	PortStatusMask  [2]uint32 /* Bitmasks of OFPPR_* values. */
+	FlowRemovedMask [2]uint32 /* Bitmasks of OFPRR_* values. */
+}
+
+// NewSetAsyncMsg creates the set async message with all the messages disabled
+func NewSetAsyncMsg() *OfpAsyncConfigMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeSetAsync
+	return &OfpAsyncConfigMsg{Header: *header}
+}
+
+// NewGetAsyncRequestMsg creates the request of the asynchronous message
+// configuration, the request has no body
+func NewGetAsyncRequestMsg() *ofpgeneral.OfpHeader {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeGetAsyncRequest
+	return header
+}
+
+// UnmarshalBinary transforms the byte array into async config data
+func (ac *OfpAsyncConfigMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < asyncConfigLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, ac)
+}
+
+// MarshalBinary converts the async config fields into byte array
+func (ac *OfpAsyncConfigMsg) MarshalBinary() ([]byte, error) {
+	ac.Header.Length = asyncConfigLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ac); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
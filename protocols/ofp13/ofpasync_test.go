@@ -0,0 +1,25 @@
+package ofp13
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+func TestAsyncConfigWireFormat(t *testing.T) {
+	msg := NewSetAsyncMsg()
+	msg.Header.Xid = 1
+	msg.PacketInMask = [2]uint32{7, 0}
+	msg.PortStatusMask = [2]uint32{7, 7}
+	msg.FlowRemovedMask = [2]uint32{0xf, 0}
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "041c002000000001 00000007 00000000 00000007 00000007 0000000f 00000000"))
+
+	reply := wiretest.Parse(t, &OfpMessageParser{}, "041b002000000002 00000007 00000000 00000007 00000007 0000000f 00000000").(*OfpAsyncConfigMsg)
+	if reply.PacketInMask != msg.PacketInMask || reply.PortStatusMask != msg.PortStatusMask ||
+		reply.FlowRemovedMask != msg.FlowRemovedMask {
+		t.Errorf("Unexpected get async reply %+v", reply)
+	}
+	request := NewGetAsyncRequestMsg()
+	request.Xid = 3
+	wiretest.CheckEncoding(t, request, wiretest.Decode(t, "041a000800000003"))
+}
@@ -0,0 +1,211 @@
+package ofp13
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// enum ofp_flow_mod_command {
+const (
+	OfpFlowModCmdAdd          = iota /* New flow. */
+	OfpFlowModCmdModify              /* Modify all matching flows. */
+	OfpFlowModCmdModifyStrict        /* Modify entry strictly matching wildcards and priority. */
+	OfpFlowModCmdDelete              /* Delete all matching flows. */
+	OfpFlowModCmdDeleteStrict        /* Delete entry strictly matching wildcards and priority. */
+)
+
+// enum ofp_flow_mod_flags {
+const (
+	OfpFlowFlagSendFlowRemove = 1 << iota /* Send flow removed message when flow
+	 * expires or is deleted. */
+	OfpFlowFlagCheckOverlap /* Check for overlapping entries first. */
+	OfpFlowFlagResetCounts  /* Reset flow packet and byte counts. */
+	OfpFlowFlagNoPktCounts  /* Don't keep track of packet count. */
+	OfpFlowFlagNoBytCounts  /* Don't keep track of byte count. */
+)
+
+// Group numbering. Groups can use any number up to OFPG_MAX.
+// enum ofp_group {
+const (
+	OfpGroupMax = 0xffffff00 /* Last usable group number. */
+	OfpGroupAll = 0xfffffffc /* Represents all groups for group delete commands. */
+	OfpGroupAny = 0xffffffff /* Wildcard group used only for flow stats requests. Selects all flows regardless of group (including flows with no group). */
+)
+
+// ofp_error_msg 'code' values for OFPET_FLOW_MOD_FAILED.  'data' contains
+// at least the first 64 bytes of the failed request. */
+// enum ofp_flow_mod_failed_code {
+const (
+	OfpFlowModFailedUnknown    = iota /* Unspecified error. */
+	OfpFlowModFailedTableFull         /* Flow not added because table was full. */
+	OfpFlowModFailedBadTableID        /* Table does not exist */
+	OfpFlowModFailedOverlap           /* Attempted to add overlapping flow with CHECK_OVERLAP flag set. */
+	OfpFlowModFailedErrPerm           /* Permissions error. */
+	OfpFlowModFailedBadTimeout        /* Flow not added because of unsupported idle/hard timeout. */
+	OfpFlowModFailedBadCommand        /* Unsupported or unknown command. */
+	OfpFlowModFailedBadFlags          /* Unsupported or unknown flags. */
+)
+
+const (
+	flowModFixedLen = 48
+)
+
+// OfpFlowModMsg represents the structure of flow setup and teardown (controller -> datapath).
+type OfpFlowModMsg struct {
+	Header     ofpgeneral.OfpHeader
+	Cookie     uint64 /* Opaque controller-issued identifier. */
+	CookieMask uint64 /* Mask used to restrict the cookie bits
+	   that must match when the command is
+	   OFPFC_MODIFY* or OFPFC_DELETE*. A value
+	   of 0 indicates no restriction. */
+
+	TableID uint8 /* ID of the table to put the flow in.
+	   For OFPFC_DELETE_* commands, OFPTT_ALL
+	   can also be used to delete matching
+	   flows from all tables. */
+	Command     uint8  /* One of OFPFC_*. */
+	IdleTimeout uint16 /* Idle time before discarding (seconds). */
+	HardTimeout uint16 /* Max time before discarding (seconds). */
+	Priority    uint16 /* Priority level of flow entry. */
+	BufferID    uint32 /* Buffered packet to apply to, or
+	   OFP_NO_BUFFER.
+	   Not meaningful for OFPFC_DELETE*. */
+	OutPort uint32 /* For OFPFC_DELETE* commands, require
+	   matching entries to include this as an
+	   output port.  A value of OFPP_ANY
+	   indicates no restriction. */
+	OutGroup uint32 /* For OFPFC_DELETE* commands, require
+	   matching entries to include this as an
+	   output group.  A value of OFPG_ANY
+	   indicates no restriction. */
+	Flags        uint16 /* Bitmap of OFPFF_* flags. */
+	Padding      [2]byte
+	Match        OfpMatch         /* Fields to match. Variable size. */
+	Instructions []OfpInstruction /* Instruction set - 0 or more.
+	   The length of the instruction set is
+	   inferred from the length field in the
+	   header. */
+}
+
+// NewFlowModMsg creates the flow mod of the command in the table, it
+// matches all the packets and applies no buffered packet, out port
+// and out group restriction
+func NewFlowModMsg(command uint8, tableID uint8) *OfpFlowModMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeFlowMod
+	return &OfpFlowModMsg{
+		Header:   *header,
+		TableID:  tableID,
+		Command:  command,
+		BufferID: OfpNoBuffer,
+		OutPort:  OfpPortAny,
+		OutGroup: OfpGroupAny,
+		Match:    *NewOfpMatch(),
+	}
+}
+
+// UnmarshalBinary transforms the byte array into flow mod data
+func (fm *OfpFlowModMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < flowModFixedLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fm.Header, &fm.Cookie, &fm.CookieMask, &fm.TableID,
+		&fm.Command, &fm.IdleTimeout, &fm.HardTimeout, &fm.Priority, &fm.BufferID, &fm.OutPort,
+		&fm.OutGroup, &fm.Flags, &fm.Padding); err != nil {
+		return err
+	}
+	if err := (&fm.Match).UnmarshalBinary(data[flowModFixedLen:]); err != nil {
+		return err
+	}
+	instructionIdx := flowModFixedLen + int(fm.Match.Len())
+	instructions, err := ParseInstructions(data[instructionIdx:])
+	if err != nil {
+		return err
+	}
+	fm.Instructions = instructions
+	return nil
+}
+
+// MarshalBinary converts the flow mod fields into byte array, the length
+// in the header is set according to the match and the instructions
+func (fm *OfpFlowModMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fm.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	instructionsData, err := marshalInstructions(fm.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	fm.Header.Length = uint16(flowModFixedLen + len(matchData) + len(instructionsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fm.Header, fm.Cookie, fm.CookieMask, fm.TableID,
+		fm.Command, fm.IdleTimeout, fm.HardTimeout, fm.Priority, fm.BufferID, fm.OutPort,
+		fm.OutGroup, fm.Flags, fm.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(instructionsData)
+	return buf.Bytes(), nil
+}
+
+// ofp_flow_removed_reason
+const (
+	OfpFlowRemoveReasonIdleTimeout = iota /* Flow idle time exceeded idle_timeout. */
+	OfpFlowRemoveReasonHardTimeout        /* Time exceeded hard_timeout. */
+	OfpFlowRemoveReasonDelete             /* Evicted by a DELETE flow mod. */
+	OfpFlowRemoveReasonGroupDelete        /* Group was removed. */
+)
+
+// OfpFlowRemovedMsg represents the msg structure of flow removed (datapath -> controller).
+type OfpFlowRemovedMsg struct {
+	Header ofpgeneral.OfpHeader
+	Cookie uint64 /* Opaque controller-issued identifier. */
+
+	Priority uint16 /* Priority level of flow entry. */
+	Reason   uint8  /* One of OFPRR_*. */
+	TableID  uint8  /* ID of the table */
+
+	DurationSec     uint32 /* Time flow was alive in seconds. */
+	DurationNanoSec uint32 /* Time flow was alive in nanoseconds beyond
+	   duration_sec. */
+	IdleTimeout uint16 /* Idle timeout from original flow mod. */
+	HardTimeout uint16 /* Hard timeout from original flow mod. */
+	PacketCount uint64
+	ByteCount   uint64
+	Match       OfpMatch /* Description of fields. Variable size. */
+}
+
+// UnmarshalBinary transforms the byte array into header data
+func (frm *OfpFlowRemovedMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 48+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &frm.Header, &frm.Cookie, &frm.Priority, &frm.Reason,
+		&frm.TableID, &frm.DurationSec, &frm.DurationNanoSec, &frm.IdleTimeout, &frm.HardTimeout,
+		&frm.PacketCount, &frm.ByteCount); err != nil {
+		return err
+	}
+	return (&frm.Match).UnmarshalBinary(data[48:])
+}
+
+// MarshalBinary converts the header fields into byte array
+func (frm *OfpFlowRemovedMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&frm.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	frm.Header.Length = uint16(48 + len(matchData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, frm.Header, frm.Cookie, frm.Priority, frm.Reason,
+		frm.TableID, frm.DurationSec, frm.DurationNanoSec, frm.IdleTimeout, frm.HardTimeout,
+		frm.PacketCount, frm.ByteCount); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package ofp13
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+func TestFlowModWireFormat(t *testing.T) {
+	msg := NewFlowModMsg(OfpFlowModCmdAdd, 0)
+	msg.Header.Xid = 1
+	msg.Cookie = 7
+	msg.IdleTimeout = 60
+	msg.Priority = 0x8000
+	msg.Flags = OfpFlowFlagSendFlowRemove
+	msg.Match.OXMFields.SetInPort(3)
+	msg.Instructions = []OfpInstruction{
+		NewInstructionMeter(3),
+		NewInstructionActions(OfpInstructionTypeApplyActions, NewActionOutput(2)),
+		NewInstructionWriteMetadata(1, 0xff),
+		NewInstructionGotoTable(2),
+	}
+	wire := "040e008000000001 0000000000000007 0000000000000000 00 00 003c 0000 8000 ffffffff ffffffff ffffffff" +
+		"0001 0000" + inPortMatchWire +
+		"0006 0008 00000003" +
+		"0004 0018 00000000 0000 0010 00000002 ffff 000000000000" +
+		"0002 0018 00000000 0000000000000001 00000000000000ff" +
+		"0001 0008 02 000000"
+	data := wiretest.Decode(t, wire)
+	wiretest.CheckEncoding(t, msg, data)
+
+	decoded := &OfpFlowModMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, data)
+	if len(decoded.Instructions) != 4 {
+		t.Fatalf("Decoded %d instructions, expected 4", len(decoded.Instructions))
+	}
+	if meter := decoded.Instructions[0].(*OfpInstructionMeter); meter.MeterID != 3 {
+		t.Errorf("Unexpected meter instruction %+v", meter)
+	}
+	if apply := decoded.Instructions[1].(*OfpInstructionActions); apply.Type != OfpInstructionTypeApplyActions ||
+		len(apply.Actions) != 1 {
+		t.Errorf("Unexpected apply actions instruction %+v", apply)
+	}
+	if metadata := decoded.Instructions[2].(*OfpInstructionWriteMetadata); metadata.Metadata != 1 ||
+		metadata.MetadataMask != 0xff {
+		t.Errorf("Unexpected write metadata instruction %+v", metadata)
+	}
+	if gotoTable := decoded.Instructions[3].(*OfpInstructionGotoTable); gotoTable.TableID != 2 {
+		t.Errorf("Unexpected goto table instruction %+v", gotoTable)
+	}
+}
+
+func TestParseInstructionsInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0001 0008 02",        // Truncated instruction
+		"0001 0004 02 000000", // Length smaller than the instruction
+		"0004 0010 00000000",  // Length beyond the data
+		"00ff 0008 00000000",  // Unknown instruction
+	} {
+		if instructions, err := ParseInstructions(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The instructions %s are decoded as %+v", wire, instructions)
+		}
+	}
+}
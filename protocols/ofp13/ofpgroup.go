@@ -0,0 +1,369 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Group commands
+// enum ofp_group_mod_command {
+const (
+	OfpGroupModCmdAdd    = iota /* New group. */
+	OfpGroupModCmdModify        /* Modify all matching groups. */
+	OfpGroupModCmdDelete        /* Delete all matching groups. */
+)
+
+// Group types. Values in the range [128, 255] are reserved for experimental
+// use.
+// enum ofp_group_type {
+const (
+	OfpGroupTypeAll      = iota /* All (multicast/broadcast) group. */
+	OfpGroupTypeSelect          /* Select group. */
+	OfpGroupTypeIndirect        /* Indirect group. */
+	OfpGroupTypeFF              /* Fast failover group. */
+)
+
+// Group configuration flags
+// enum ofp_group_capabilities {
+const (
+	OfpGroupCapSelectWeight   = 1 << iota /* Support weight for select groups */
+	OfpGroupCapSelectLiveness             /* Support liveness for select groups */
+	OfpGroupCapChaining                   /* Support chaining groups */
+	OfpGroupCapChainingChecks             /* Check chaining for loops and delete */
+)
+
+// ofp_error_msg 'code' values for OFPET_GROUP_MOD_FAILED.  'data' contains
+// at least the first 64 bytes of the failed request. */
+// enum ofp_group_mod_failed_code {
+const (
+	OfpGroupModFailedGroupExists         = iota /* Group not added because a group ADD attempted to replace an already-present group. */
+	OfpGroupModFailedInvalidGroup               /* Group not added because Group specified is invalid. */
+	OfpGroupModFailedWeightUnsupported          /* Switch does not support unequal load sharing with select groups. */
+	OfpGroupModFailedOutOfGroups                /* The group table is full. */
+	OfpGroupModFailedOutOfBuckets               /* The maximum number of action buckets for a group has been exceeded. */
+	OfpGroupModFailedChainingUnsupported        /* Switch does not support groups that forward to groups. */
+	OfpGroupModFailedWatchUnsupported           /* This group cannot watch the watch_port or watch_group specified. */
+	OfpGroupModFailedLoop                       /* Group entry would cause a loop. */
+	OfpGroupModFailedUnknownGroup               /* Group not modified because a group MODIFY attempted to modify a non-existent group. */
+	OfpGroupModFailedChainedGroup               /* Group not deleted because another group is forwarding to it. */
+	OfpGroupModFailedBadType                    /* Unsupported or unknown group type. */
+	OfpGroupModFailedBadCommand                 /* Unsupported or unknown command. */
+	OfpGroupModFailedBadBucket                  /* Error in bucket. */
+	OfpGroupModFailedBadWatch                   /* Error in watch port/group. */
+	OfpGroupModFailedErrPerm                    /* Permissions error. */
+)
+
+const (
+	bucketHeaderLen     = 16
+	groupModHeaderLen   = 16
+	groupStatsHeaderLen = 40
+	bucketCounterLen    = 16
+	groupDescHeaderLen  = 8
+	groupFeaturesLen    = 40
+)
+
+// OfpBucket represents the bucket for use in groups.
+type OfpBucket struct {
+	Length     uint16 /* Length the bucket in bytes, including this header and any padding to make it 64-bit aligned. */
+	Weight     uint16 /* Relative weight of bucket.  Only defined for select groups. */
+	WatchPort  uint32 /* Port whose state affects whether this bucket is live.  Only required for fast failover groups. */
+	WatchGroup uint32 /* Group whose state affects whether this bucket is live.  Only required for fast failover groups. */
+	Padding    [4]byte
+	Actions    []OfpAction /* 0 or more actions associated with the bucket - The action list length is inferred from the length of the bucket. */
+}
+
+// NewBucket creates the bucket with the actions, the bucket watches
+// neither port nor group
+func NewBucket(weight uint16, actions ...OfpAction) *OfpBucket {
+	return &OfpBucket{Weight: weight, WatchPort: OfpPortAny, WatchGroup: OfpGroupAny, Actions: actions}
+}
+
+// UnmarshalBinary transforms the byte array into bucket data
+func (b *OfpBucket) UnmarshalBinary(data []byte) error {
+	if len(data) < bucketHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &b.Length, &b.Weight, &b.WatchPort, &b.WatchGroup, &b.Padding); err != nil {
+		return err
+	}
+	if b.Length < bucketHeaderLen || int(b.Length) > len(data) {
+		return fmt.Errorf("Invalid bucket length %d", b.Length)
+	}
+	actions, err := ParseActions(data[bucketHeaderLen:b.Length])
+	if err != nil {
+		return err
+	}
+	b.Actions = actions
+	return nil
+}
+
+// MarshalBinary converts the bucket fields into byte array, the length
+// is set according to the actions
+func (b *OfpBucket) MarshalBinary() ([]byte, error) {
+	actionsData, err := marshalActions(b.Actions)
+	if err != nil {
+		return nil, err
+	}
+	b.Length = uint16(bucketHeaderLen + len(actionsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, b.Length, b.Weight, b.WatchPort, b.WatchGroup, b.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(actionsData)
+	return buf.Bytes(), nil
+}
+
+// parseBuckets decodes the list of buckets
+func parseBuckets(data []byte) ([]OfpBucket, error) {
+	buckets := make([]OfpBucket, 0)
+	for idx := 0; idx < len(data); {
+		bucket := OfpBucket{}
+		if err := bucket.UnmarshalBinary(data[idx:]); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+		idx += int(bucket.Length)
+	}
+	return buckets, nil
+}
+
+// marshalBuckets encodes the list of buckets
+func marshalBuckets(buckets []OfpBucket) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for idx := range buckets {
+		bucketData, err := (&buckets[idx]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bucketData)
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpGroupModMsg represents the group setup and teardown message (controller -> datapath).
+type OfpGroupModMsg struct {
+	Header    ofpgeneral.OfpHeader
+	Command   uint16      /* One of OFPGC_*. */
+	GroupType uint8       /* One of OFPGT_*. */
+	Padding   uint8       /* Pad to 64 bits. */
+	GroupID   uint32      /* Group identifier. */
+	Buckets   []OfpBucket /* The length of the bucket array is inferred from the length field in the header. */
+}
+
+// NewGroupModMsg creates the group mod of the command
+func NewGroupModMsg(command uint16, groupType uint8, groupID uint32, buckets []OfpBucket) *OfpGroupModMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeGroupMod
+	return &OfpGroupModMsg{Header: *header, Command: command, GroupType: groupType,
+		GroupID: groupID, Buckets: buckets}
+}
+
+// UnmarshalBinary transforms the byte array into group mod data
+func (gm *OfpGroupModMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < groupModHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &gm.Header, &gm.Command, &gm.GroupType,
+		&gm.Padding, &gm.GroupID); err != nil {
+		return err
+	}
+	buckets, err := parseBuckets(data[groupModHeaderLen:])
+	if err != nil {
+		return err
+	}
+	gm.Buckets = buckets
+	return nil
+}
+
+// MarshalBinary converts the group mod fields into byte array, the length
+// in the header is set according to the buckets
+func (gm *OfpGroupModMsg) MarshalBinary() ([]byte, error) {
+	bucketsData, err := marshalBuckets(gm.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	gm.Header.Length = uint16(groupModHeaderLen + len(bucketsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, gm.Header, gm.Command, gm.GroupType,
+		gm.Padding, gm.GroupID); err != nil {
+		return nil, err
+	}
+	buf.Write(bucketsData)
+	return buf.Bytes(), nil
+}
+
+// OfpGroupStatsRequest represents the body of OFPMP_GROUP request.
+type OfpGroupStatsRequest struct {
+	GroupID uint32  /* All groups if OFPG_ALL. */
+	Padding [4]byte /* Align to 64 bits. */
+}
+
+// NewGroupStatsRequestMsg creates the multipart request of the group
+// statistics, OfpGroupAll requests the statistics of all the groups
+func NewGroupStatsRequestMsg(groupID uint32) *OfpMultipartRequestMsg {
+	body := make([]byte, 8)
+	binary.BigEndian.PutUint32(body, groupID)
+	return NewMultipartRequestMsg(OfpMultipartTypeGroup, body)
+}
+
+// NewGroupDescRequestMsg creates the multipart request of the group descriptions
+func NewGroupDescRequestMsg() *OfpMultipartRequestMsg {
+	return NewMultipartRequestMsg(OfpMultipartTypeGroupDesc, nil)
+}
+
+// NewGroupFeaturesRequestMsg creates the multipart request of the group features
+func NewGroupFeaturesRequestMsg() *OfpMultipartRequestMsg {
+	return NewMultipartRequestMsg(OfpMultipartTypeGroupFeatures, nil)
+}
+
+// OfpBucketCounter represents the counters of a bucket
+type OfpBucketCounter struct {
+	PacketCount uint64 /* Number of packets processed by bucket. */
+	ByteCount   uint64 /* Number of bytes processed by bucket. */
+}
+
+// OfpGroupStats represents the body of reply to OFPMP_GROUP request.
+type OfpGroupStats struct {
+	Length          uint16  /* Length of this entry. */
+	Padding1        [2]byte /* Align to 64 bits. */
+	GroupID         uint32  /* Group identifier. */
+	RefCount        uint32  /* Number of flows or groups that directly forward to this group. */
+	Padding2        [4]byte /* Align to 64 bits. */
+	PacketCount     uint64  /* Number of packets processed by group. */
+	ByteCount       uint64  /* Number of bytes processed by group. */
+	DurationSec     uint32  /* Time group has been alive in seconds. */
+	DurationNanoSec uint32  /* Time group has been alive in nanoseconds beyond duration_sec. */
+	BucketStats     []OfpBucketCounter
+}
+
+// UnmarshalBinary transforms the byte array into group stats data
+func (gs *OfpGroupStats) UnmarshalBinary(data []byte) error {
+	if len(data) < groupStatsHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &gs.Length, &gs.Padding1, &gs.GroupID, &gs.RefCount,
+		&gs.Padding2, &gs.PacketCount, &gs.ByteCount, &gs.DurationSec, &gs.DurationNanoSec); err != nil {
+		return err
+	}
+	if gs.Length < groupStatsHeaderLen || int(gs.Length) > len(data) ||
+		(gs.Length-groupStatsHeaderLen)%bucketCounterLen != 0 {
+		return fmt.Errorf("Invalid group stats length %d", gs.Length)
+	}
+	gs.BucketStats = make([]OfpBucketCounter, (gs.Length-groupStatsHeaderLen)/bucketCounterLen)
+	return ofpgeneral.UnMarshalFields(buf, gs.BucketStats)
+}
+
+// MarshalBinary converts the group stats fields into byte array
+func (gs *OfpGroupStats) MarshalBinary() ([]byte, error) {
+	gs.Length = uint16(groupStatsHeaderLen + len(gs.BucketStats)*bucketCounterLen)
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, gs.Length, gs.Padding1, gs.GroupID, gs.RefCount,
+		gs.Padding2, gs.PacketCount, gs.ByteCount, gs.DurationSec, gs.DurationNanoSec, gs.BucketStats); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseGroupStatsBody decodes the body of the group stats reply
+func ParseGroupStatsBody(body []byte) ([]OfpGroupStats, error) {
+	stats := make([]OfpGroupStats, 0)
+	for idx := 0; idx < len(body); {
+		groupStats := OfpGroupStats{}
+		if err := groupStats.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		stats = append(stats, groupStats)
+		idx += int(groupStats.Length)
+	}
+	return stats, nil
+}
+
+// OfpGroupDesc represents the body of reply to OFPMP_GROUP_DESC request.
+type OfpGroupDesc struct {
+	Length    uint16      /* Length of this entry. */
+	GroupType uint8       /* One of OFPGT_*. */
+	Padding   uint8       /* Pad to 64 bits. */
+	GroupID   uint32      /* Group identifier. */
+	Buckets   []OfpBucket /* List of buckets - 0 or more. */
+}
+
+// UnmarshalBinary transforms the byte array into group description data
+func (gd *OfpGroupDesc) UnmarshalBinary(data []byte) error {
+	if len(data) < groupDescHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &gd.Length, &gd.GroupType, &gd.Padding, &gd.GroupID); err != nil {
+		return err
+	}
+	if gd.Length < groupDescHeaderLen || int(gd.Length) > len(data) {
+		return fmt.Errorf("Invalid group description length %d", gd.Length)
+	}
+	buckets, err := parseBuckets(data[groupDescHeaderLen:gd.Length])
+	if err != nil {
+		return err
+	}
+	gd.Buckets = buckets
+	return nil
+}
+
+// MarshalBinary converts the group description fields into byte array
+func (gd *OfpGroupDesc) MarshalBinary() ([]byte, error) {
+	bucketsData, err := marshalBuckets(gd.Buckets)
+	if err != nil {
+		return nil, err
+	}
+	gd.Length = uint16(groupDescHeaderLen + len(bucketsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, gd.Length, gd.GroupType, gd.Padding, gd.GroupID); err != nil {
+		return nil, err
+	}
+	buf.Write(bucketsData)
+	return buf.Bytes(), nil
+}
+
+// ParseGroupDescBody decodes the body of the group description reply
+func ParseGroupDescBody(body []byte) ([]OfpGroupDesc, error) {
+	descs := make([]OfpGroupDesc, 0)
+	for idx := 0; idx < len(body); {
+		desc := OfpGroupDesc{}
+		if err := desc.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		descs = append(descs, desc)
+		idx += int(desc.Length)
+	}
+	return descs, nil
+}
+
+// OfpGroupFeatures represents the body of reply to OFPMP_GROUP_FEATURES request. Group features.
+type OfpGroupFeatures struct {
+	Types        uint32    /* Bitmap of (1 << OFPGT_*) values supported. */
+	Capabilities uint32    /* Bitmap of OFPGFC_* capability supported. */
+	MaxGroups    [4]uint32 /* Maximum number of groups for each type. */
+	Actions      [4]uint32 /* Bitmaps of (1 << OFPAT_*) values supported. */
+}
+
+// UnmarshalBinary transforms the byte array into group features data
+func (gf *OfpGroupFeatures) UnmarshalBinary(data []byte) error {
+	if len(data) < groupFeaturesLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, gf)
+}
+
+// MarshalBinary converts the group features fields into byte array
+func (gf *OfpGroupFeatures) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, gf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
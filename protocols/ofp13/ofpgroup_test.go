@@ -0,0 +1,96 @@
+package ofp13
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+// bucketWire is the bucket of weight 2 outputting to the port 2
+const bucketWire = "0020 0002 ffffffff ffffffff 00000000" + "0000 0010 00000002 ffff 000000000000"
+
+func TestGroupModWireFormat(t *testing.T) {
+	msg := NewGroupModMsg(OfpGroupModCmdAdd, OfpGroupTypeSelect, 5, []OfpBucket{*NewBucket(2, NewActionOutput(2))})
+	msg.Header.Xid = 1
+	data := wiretest.Decode(t, "040f003000000001 0000 01 00 00000005"+bucketWire)
+	wiretest.CheckEncoding(t, msg, data)
+
+	decoded := &OfpGroupModMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, data)
+	if decoded.Command != OfpGroupModCmdAdd || decoded.GroupType != OfpGroupTypeSelect || decoded.GroupID != 5 ||
+		len(decoded.Buckets) != 1 {
+		t.Fatalf("Unexpected group mod %+v", decoded)
+	}
+	if bucket := decoded.Buckets[0]; bucket.Length != 32 || bucket.Weight != 2 || bucket.WatchPort != OfpPortAny ||
+		bucket.WatchGroup != OfpGroupAny || len(bucket.Actions) != 1 {
+		t.Errorf("Unexpected bucket %+v", bucket)
+	}
+}
+
+func TestParseBucketsInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0020 0002 ffffffff ffffffff",          // Truncated header
+		"0008 0002 ffffffff ffffffff 00000000", // Length smaller than the header
+		bucketWire[:len(bucketWire)-8],         // Length beyond the data
+	} {
+		if buckets, err := parseBuckets(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The buckets %s are decoded as %+v", wire, buckets)
+		}
+	}
+}
+
+func TestDecodeGroupMultipartReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, body interface{})
+	}{
+		{"group desc", "0413003800000002 0007 0000 00000000" + "0028 01 00 00000005" + bucketWire,
+			func(t *testing.T, body interface{}) {
+				descs := body.([]OfpGroupDesc)
+				if len(descs) != 1 || descs[0].Length != 40 || descs[0].GroupType != OfpGroupTypeSelect ||
+					descs[0].GroupID != 5 || len(descs[0].Buckets) != 1 || descs[0].Buckets[0].Weight != 2 {
+					t.Errorf("Unexpected group descriptions %+v", descs)
+				}
+			}},
+		{"group stats", "0413004800000003 0006 0000 00000000" +
+			"0038 0000 00000005 00000001 00000000 000000000000000a 00000000000003e8 0000000a 00000014" +
+			"0000000000000004 0000000000000190",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpGroupStats)
+				if len(stats) != 1 || stats[0].GroupID != 5 || stats[0].RefCount != 1 || stats[0].PacketCount != 10 ||
+					stats[0].ByteCount != 1000 || stats[0].DurationSec != 10 || stats[0].DurationNanoSec != 20 ||
+					len(stats[0].BucketStats) != 1 || stats[0].BucketStats[0].ByteCount != 400 {
+					t.Errorf("Unexpected group stats %+v", stats)
+				}
+			}},
+		{"group features", "0413003800000004 0008 0000 00000000 0000000f 00000005" +
+			"00000010 00000020 00000030 00000040 00000001 00000003 00000007 0000000f",
+			func(t *testing.T, body interface{}) {
+				features := body.(*OfpGroupFeatures)
+				if features.Types != 0xf || features.Capabilities != OfpGroupCapSelectWeight|OfpGroupCapChaining ||
+					features.MaxGroups[OfpGroupTypeFF] != 0x40 || features.Actions[OfpGroupTypeSelect] != 3 {
+					t.Errorf("Unexpected group features %+v", features)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := wiretest.Parse(t, &OfpMessageParser{}, tc.wire).(*OfpMultipartReplyMsg)
+			body, err := reply.DecodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, body)
+		})
+	}
+}
+
+func TestGroupStatsRequestWireFormat(t *testing.T) {
+	msg := NewGroupStatsRequestMsg(OfpGroupAll)
+	msg.Header.Xid = 5
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "0412001800000005 0006 0000 00000000 fffffffc 00000000"))
+}
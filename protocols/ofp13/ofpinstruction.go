@@ -0,0 +1,292 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// enum ofp_instruction_type {
+const (
+	OfpInstructionTypeGotoTable     = 1      /* Setup the next table in the lookup pipeline */
+	OfpInstructionTypeWriteMetadata = 2      /* Setup the metadata field for use later in pipeline */
+	OfpInstructionTypeWriteActions  = 3      /* Write the action(s) onto the datapath action set */
+	OfpInstructionTypeApplyActions  = 4      /* Applies the action(s) immediately */
+	OfpInstructionTypeClearActions  = 5      /* Clears all actions from the datapath action set */
+	OfpInstructionTypeMeter         = 6      /* Apply meter (rate limiter) */
+	OfpInstructionTypeExperimenter  = 0xFFFF /* Experimenter instruction */
+)
+
+// ofp_error_msg 'code' values for OFPET_BAD_INSTRUCTION.  'data' contains at least
+// the first 64 bytes of the failed request. */
+// enum ofp_bad_instruction_code {
+const (
+	OfpBadInstructionCodeUnknownInst       = iota /* Unknown instruction. */
+	OfpBadInstructionCodeUnsupInst                /* Switch or table does not support the instruction. */
+	OfpBadInstructionCodeBadTableID               /* Invalid Table-ID specified. */
+	OfpBadInstructionCodeUnsupMetadata            /* Metadata value unsupported by datapath. */
+	OfpBadInstructionCodeUnsupMetadataMask        /* Metadata mask value unsupported by datapath. */
+	OfpBadInstructionCodeBadExperimenter          /* Unknown experimenter id specified. */
+	OfpBadInstructionCodeBadExpType               /* Unknown instruction for experimenter id. */
+	OfpBadInstructionCodeBadLen                   /* Length problem in instructions. */
+	OfpBadInstructionCodeErrPerm                  /* Permissions error. */
+)
+
+const (
+	ofpInstructionHeaderLen = 8
+)
+
+// OfpInstruction is implemented by all the instruction structures
+type OfpInstruction interface {
+	ofpgeneral.OfpMessage
+	// Len returns the length of the instruction including the padding
+	Len() uint16
+}
+
+// OfpInstructionGotoTable represents the instruction structure for OFPIT_GOTO_TABLE
+type OfpInstructionGotoTable struct {
+	Type    uint16  /* OFPIT_GOTO_TABLE */
+	Length  uint16  /* Length of this struct in bytes. */
+	TableID uint8   /* Set next table in the lookup pipeline */
+	Padding [3]byte /* Pad to 64 bits. */
+}
+
+// NewInstructionGotoTable creates the instruction moving the packet to the table
+func NewInstructionGotoTable(tableID uint8) *OfpInstructionGotoTable {
+	return &OfpInstructionGotoTable{Type: OfpInstructionTypeGotoTable, Length: 8, TableID: tableID}
+}
+
+// Len returns the length of the instruction
+func (igt *OfpInstructionGotoTable) Len() uint16 {
+	return 8
+}
+
+// UnmarshalBinary transforms the byte array into body data
+func (igt *OfpInstructionGotoTable) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, igt.Len(), igt)
+}
+
+// MarshalBinary converts the header fields into byte array
+func (igt *OfpInstructionGotoTable) MarshalBinary() ([]byte, error) {
+	igt.Type = OfpInstructionTypeGotoTable
+	igt.Length = igt.Len()
+	return marshalFixedAction(igt)
+}
+
+// OfpInstructionWriteMetadata represents the instruction structure for OFPIT_WRITE_METADATA
+type OfpInstructionWriteMetadata struct {
+	Type         uint16  /* OFPIT_WRITE_METADATA */
+	Length       uint16  /* Length of this struct in bytes. */
+	Padding      [4]byte /* Align to 64-bits */
+	Metadata     uint64  /* Metadata value to write */
+	MetadataMask uint64  /* Metadata write bitmask */
+}
+
+// NewInstructionWriteMetadata creates the instruction writing the metadata with the mask
+func NewInstructionWriteMetadata(metadata, mask uint64) *OfpInstructionWriteMetadata {
+	return &OfpInstructionWriteMetadata{Type: OfpInstructionTypeWriteMetadata, Length: 24,
+		Metadata: metadata, MetadataMask: mask}
+}
+
+// Len returns the length of the instruction
+func (iwm *OfpInstructionWriteMetadata) Len() uint16 {
+	return 24
+}
+
+// UnmarshalBinary transforms the byte array into body data
+func (iwm *OfpInstructionWriteMetadata) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, iwm.Len(), iwm)
+}
+
+// MarshalBinary converts the header fields into byte array
+func (iwm *OfpInstructionWriteMetadata) MarshalBinary() ([]byte, error) {
+	iwm.Type = OfpInstructionTypeWriteMetadata
+	iwm.Length = iwm.Len()
+	return marshalFixedAction(iwm)
+}
+
+// OfpInstructionActions represents the instruction structure for
+// OFPIT_WRITE/APPLY/CLEAR_ACTIONS, the clear actions instruction
+// carries no action
+type OfpInstructionActions struct {
+	Type    uint16      /* One of OFPIT_*_ACTIONS */
+	Length  uint16      /* Length of this struct in bytes. */
+	Padding [4]byte     /* Align to 64-bits */
+	Actions []OfpAction /* 0 or more actions associated with OFPIT_WRITE_ACTIONS and OFPIT_APPLY_ACTIONS */
+}
+
+// NewInstructionActions creates the write, apply or clear actions
+// instruction with the actions
+func NewInstructionActions(instructionType uint16, actions ...OfpAction) *OfpInstructionActions {
+	instruction := &OfpInstructionActions{Type: instructionType, Actions: actions}
+	instruction.Length = instruction.Len()
+	return instruction
+}
+
+// Len returns the length of the instruction including the actions
+func (ia *OfpInstructionActions) Len() uint16 {
+	length := uint16(ofpInstructionHeaderLen)
+	for _, action := range ia.Actions {
+		length += action.Len()
+	}
+	return length
+}
+
+// UnmarshalBinary transforms the byte array into body data
+func (ia *OfpInstructionActions) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpInstructionHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &ia.Type, &ia.Length, &ia.Padding); err != nil {
+		return err
+	}
+	if ia.Length < ofpInstructionHeaderLen || int(ia.Length) > len(data) {
+		return fmt.Errorf("Invalid actions instruction length %d", ia.Length)
+	}
+	actions, err := ParseActions(data[ofpInstructionHeaderLen:ia.Length])
+	if err != nil {
+		return err
+	}
+	ia.Actions = actions
+	return nil
+}
+
+// MarshalBinary converts the header fields into byte array
+func (ia *OfpInstructionActions) MarshalBinary() ([]byte, error) {
+	actionsData, err := marshalActions(ia.Actions)
+	if err != nil {
+		return nil, err
+	}
+	ia.Length = uint16(ofpInstructionHeaderLen + len(actionsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ia.Type, ia.Length, ia.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(actionsData)
+	return buf.Bytes(), nil
+}
+
+// OfpInstructionMeter represents the instruction structure for OFPIT_METER
+type OfpInstructionMeter struct {
+	Type    uint16 /* OFPIT_METER */
+	Length  uint16 /* Length is 8. */
+	MeterID uint32 /* Meter instance. */
+}
+
+// NewInstructionMeter creates the instruction applying the meter
+func NewInstructionMeter(meterID uint32) *OfpInstructionMeter {
+	return &OfpInstructionMeter{Type: OfpInstructionTypeMeter, Length: 8, MeterID: meterID}
+}
+
+// Len returns the length of the instruction
+func (im *OfpInstructionMeter) Len() uint16 {
+	return 8
+}
+
+// UnmarshalBinary transforms the byte array into body data
+func (im *OfpInstructionMeter) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, im.Len(), im)
+}
+
+// MarshalBinary converts the header fields into byte array
+func (im *OfpInstructionMeter) MarshalBinary() ([]byte, error) {
+	im.Type = OfpInstructionTypeMeter
+	im.Length = im.Len()
+	return marshalFixedAction(im)
+}
+
+// OfpInstructionExperimenter represents the instruction structure for
+// experimental instructions
+type OfpInstructionExperimenter struct {
+	Type         uint16 /* OFPIT_EXPERIMENTER */
+	Length       uint16 /* Length of this struct in bytes */
+	Experimenter uint32 /* Experimenter ID which takes the same form
+	   as in struct ofp_experimenter_header. */
+	Data []byte /* Experimenter-defined arbitrary additional data. */
+}
+
+// Len returns the length of the instruction including the data
+func (ie *OfpInstructionExperimenter) Len() uint16 {
+	return uint16(ofpInstructionHeaderLen + len(ie.Data))
+}
+
+// UnmarshalBinary transforms the byte array into body data
+func (ie *OfpInstructionExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpInstructionHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &ie.Type, &ie.Length, &ie.Experimenter); err != nil {
+		return err
+	}
+	if ie.Length < ofpInstructionHeaderLen || int(ie.Length) > len(data) {
+		return fmt.Errorf("Invalid experimenter instruction length %d", ie.Length)
+	}
+	ie.Data = make([]byte, ie.Length-ofpInstructionHeaderLen)
+	copy(ie.Data, data[ofpInstructionHeaderLen:ie.Length])
+	return nil
+}
+
+// MarshalBinary converts the header fields into byte array
+func (ie *OfpInstructionExperimenter) MarshalBinary() ([]byte, error) {
+	ie.Type = OfpInstructionTypeExperimenter
+	ie.Length = ie.Len()
+	data := make([]byte, ie.Length)
+	binary.BigEndian.PutUint16(data[0:2], ie.Type)
+	binary.BigEndian.PutUint16(data[2:4], ie.Length)
+	binary.BigEndian.PutUint32(data[4:8], ie.Experimenter)
+	copy(data[ofpInstructionHeaderLen:], ie.Data)
+	return data, nil
+}
+
+// ParseInstructions decodes the list of instructions
+func ParseInstructions(data []byte) ([]OfpInstruction, error) {
+	instructions := make([]OfpInstruction, 0)
+	for idx := 0; idx < len(data); {
+		if len(data)-idx < 4 {
+			return nil, fmt.Errorf("The data size %d is not big enough to be decoded", len(data)-idx)
+		}
+		instructionType := binary.BigEndian.Uint16(data[idx : idx+2])
+		instructionLen := int(binary.BigEndian.Uint16(data[idx+2 : idx+4]))
+		if instructionLen < ofpInstructionHeaderLen || idx+instructionLen > len(data) {
+			return nil, fmt.Errorf("Invalid length %d of instruction type %d", instructionLen, instructionType)
+		}
+		var instruction OfpInstruction
+		switch instructionType {
+		case OfpInstructionTypeGotoTable:
+			instruction = &OfpInstructionGotoTable{}
+		case OfpInstructionTypeWriteMetadata:
+			instruction = &OfpInstructionWriteMetadata{}
+		case OfpInstructionTypeWriteActions, OfpInstructionTypeApplyActions, OfpInstructionTypeClearActions:
+			instruction = &OfpInstructionActions{}
+		case OfpInstructionTypeMeter:
+			instruction = &OfpInstructionMeter{}
+		case OfpInstructionTypeExperimenter:
+			instruction = &OfpInstructionExperimenter{}
+		default:
+			return nil, fmt.Errorf("Unknown instruction type %d", instructionType)
+		}
+		if err := instruction.UnmarshalBinary(data[idx : idx+instructionLen]); err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, instruction)
+		idx += instructionLen
+	}
+	return instructions, nil
+}
+
+// marshalInstructions encodes the list of instructions
+func marshalInstructions(instructions []OfpInstruction) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, instruction := range instructions {
+		instructionData, err := instruction.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(instructionData)
+	}
+	return buf.Bytes(), nil
+}
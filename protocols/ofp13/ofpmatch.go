@@ -0,0 +1,82 @@
+package ofp13
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// The match type indicates the match structure (set of fields that compose the
+// match) in use. The match type is placed in the type field at the beginning
+// of all match structures.
+// enum ofp_match_type {
+const (
+	OfpMatchTypeStandard = iota /* Deprecated. */
+	OfpMatchTypeOXM             /* OpenFlow Extensible Match */
+)
+
+const (
+	ofpMatchHeaderLen = 4
+)
+
+// OfpMatch represents the fields to match against flows
+type OfpMatch struct {
+	Type   uint16 /* One of OFPMT_* */
+	Length uint16 /* Length of ofp_match (excluding padding) */
+	/* Followed by:
+	 * - Exactly (length - 4) (possibly 0) bytes containing OXM TLVs, then
+	 * - Exactly ((length + 7)/8*8 - length) (between 0 and 7) bytes of
+	 * all-zero bytes
+	 * In summary, ofp_match is padded as needed, to make its overall size
+	 * a multiple of 8, to preserve alignement in structures using it.
+	 */
+	OXMFields ofpgeneral.OxmFields
+}
+
+// NewOfpMatch creates an empty OXM match which matches all the packets
+func NewOfpMatch() *OfpMatch {
+	return &OfpMatch{Type: OfpMatchTypeOXM, Length: ofpMatchHeaderLen}
+}
+
+// Len returns the length of the match including the padding
+func (om *OfpMatch) Len() uint16 {
+	return (om.Length + 7) / 8 * 8
+}
+
+// UnmarshalBinary transforms the byte array into match data
+func (om *OfpMatch) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &om.Type, &om.Length); err != nil {
+		return err
+	}
+	if om.Length < ofpMatchHeaderLen || len(data) < int(om.Len()) {
+		return fmt.Errorf("Invalid match length %d in data of size %d", om.Length, len(data))
+	}
+	if om.Type != OfpMatchTypeOXM {
+		return fmt.Errorf("Unsupported match type %d", om.Type)
+	}
+	return (&om.OXMFields).UnmarshalBinary(data[ofpMatchHeaderLen:om.Length])
+}
+
+// MarshalBinary converts the match fields into byte array including the
+// padding to 8 bytes, the length is set according to the OXM fields
+func (om *OfpMatch) MarshalBinary() ([]byte, error) {
+	fieldsData, err := (&om.OXMFields).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	om.Type = OfpMatchTypeOXM
+	om.Length = uint16(ofpMatchHeaderLen + len(fieldsData))
+	data := make([]byte, om.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, om.Type, om.Length); err != nil {
+		return nil, err
+	}
+	copy(data, buf.Bytes())
+	copy(data[ofpMatchHeaderLen:], fieldsData)
+	return data, nil
+}
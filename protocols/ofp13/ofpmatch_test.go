@@ -0,0 +1,52 @@
+package ofp13
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// TestOfpMatchPadding checks the match is padded to a multiple of 8 bytes
+// and the padding is skipped by the decoder
+func TestOfpMatchPadding(t *testing.T) {
+	inPort := NewOfpMatch()
+	inPort.OXMFields.SetInPort(3)
+	aligned := NewOfpMatch()
+	aligned.OXMFields.SetEthType(0x0800)
+	tests := []struct {
+		name   string
+		match  *OfpMatch
+		length uint16
+		wire   string
+	}{
+		{"empty", NewOfpMatch(), 4, "0001000400000000"},
+		{"in_port", inPort, 12, "0001000c8000000400000003" + "00000000"},
+		{"eth_type", aligned, 10, "0001000a80000a020800" + "000000000000"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.match.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(data) != tc.wire {
+				t.Fatalf("Encoded %x, expected %s", data, tc.wire)
+			}
+			if tc.match.Length != tc.length || int(tc.match.Len()) != len(data) || len(data)%8 != 0 {
+				t.Fatalf("Unexpected length %d, padded length %d", tc.match.Length, tc.match.Len())
+			}
+			// The decoder is given the following data as well
+			decoded := OfpMatch{}
+			if err := decoded.UnmarshalBinary(append(data, 0xff, 0xff)); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Length != tc.length || len(decoded.OXMFields) != len(tc.match.OXMFields) {
+				t.Fatalf("Decoded %+v, expected %+v", decoded, *tc.match)
+			}
+		})
+	}
+	// The padding must be present in the data
+	data, _ := inPort.MarshalBinary()
+	if err := (&OfpMatch{}).UnmarshalBinary(data[:12]); err == nil {
+		t.Fatal("The match without padding is decoded")
+	}
+}
@@ -0,0 +1,460 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Meter numbering. Flow meters can use any number up to OFPM_MAX.
+// enum ofp_meter {
+const (
+	/* Last usable meter. */
+	OfpMeterMax = 0xffff0000
+
+	/* Virtual meters. */
+	OfpMeterSlowPath   = 0xfffffffd /* Meter for slow datapath. */
+	OfpMeterController = 0xfffffffe /* Meter for controller connection. */
+	OfpMeterAll        = 0xffffffff /* Represents all meters for stat requests commands. */
+)
+
+// Meter commands
+// enum ofp_meter_mod_command {
+const (
+	OfpMeterModCmdAdd    = iota /* New meter. */
+	OfpMeterModCmdModify        /* Modify specified meter. */
+	OfpMeterModCmdDelete        /* Delete specified meter. */
+)
+
+// Meter configuration flags
+// enum ofp_meter_flags {
+const (
+	OfpMeterFlagKbps  = 1 << iota /* Rate value in kb/s (kilo-bit per second). */
+	OfpMeterFlagPktps             /* Rate value in packet/sec. */
+	OfpMeterFlagBurst             /* Do burst size. */
+	OfpMeterFlagStats             /* Collect statistics. */
+)
+
+// Meter band types
+// enum ofp_meter_band_type {
+const (
+	OfpMeterBandTypeDrop         = 1      /* Drop packet. */
+	OfpMeterBandTypeDscpRemark   = 2      /* Remark DSCP in the IP header. */
+	OfpMeterBandTypeExperimenter = 0xFFFF /* Experimenter meter band. */
+)
+
+// ofp_error_msg 'code' values for OFPET_METER_MOD_FAILED.  'data' contains
+// at least the first 64 bytes of the failed request. */
+// enum ofp_meter_mod_failed_code {
+const (
+	OfpMeterModFailedUnknown      = iota /* Unspecified error. */
+	OfpMeterModFailedMeterExists         /* Meter not added because a Meter ADD attempted to replace an existing Meter. */
+	OfpMeterModFailedInvalidMeter        /* Meter not added because Meter specified is invalid, or invalid meter in meter action. */
+	OfpMeterModFailedUnknownMeter        /* Meter not modified because a Meter MODIFY attempted to modify a non-existent Meter, or bad meter in meter action. */
+	OfpMeterModFailedBadCommand          /* Unsupported or unknown command. */
+	OfpMeterModFailedBadFlags            /* Flag configuration unsupported. */
+	OfpMeterModFailedBadRate             /* Rate unsupported. */
+	OfpMeterModFailedBadBurst            /* Burst size unsupported. */
+	OfpMeterModFailedBadBand             /* Band unsupported. */
+	OfpMeterModFailedBadBandValue        /* Band value unsupported. */
+	OfpMeterModFailedOutOfMeters         /* No more meters available. */
+	OfpMeterModFailedOutOfBands          /* The maximum number of properties for a meter has been exceeded. */
+)
+
+const (
+	meterBandLen         = 16
+	meterModHeaderLen    = 16
+	meterStatsHeaderLen  = 40
+	meterBandStatsLen    = 16
+	meterConfigHeaderLen = 8
+	meterFeaturesLen     = 16
+)
+
+// OfpMeterBand is implemented by all the meter band structures
+type OfpMeterBand interface {
+	ofpgeneral.OfpMessage
+	// Len returns the length of the band
+	Len() uint16
+}
+
+// OfpMeterBandDrop represents the OFPMBT_DROP band - drop packets
+type OfpMeterBandDrop struct {
+	Type      uint16 /* OFPMBT_DROP. */
+	Length    uint16 /* Length in bytes of this band. */
+	Rate      uint32 /* Rate for dropping packets. */
+	BurstSize uint32 /* Size of bursts. */
+	Padding   [4]byte
+}
+
+// NewMeterBandDrop creates the band dropping the packets above the rate
+func NewMeterBandDrop(rate, burstSize uint32) *OfpMeterBandDrop {
+	return &OfpMeterBandDrop{Type: OfpMeterBandTypeDrop, Length: meterBandLen, Rate: rate, BurstSize: burstSize}
+}
+
+// Len returns the length of the band
+func (mbd *OfpMeterBandDrop) Len() uint16 {
+	return meterBandLen
+}
+
+// UnmarshalBinary transforms the byte array into band data
+func (mbd *OfpMeterBandDrop) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, mbd.Len(), mbd)
+}
+
+// MarshalBinary converts the band fields into byte array
+func (mbd *OfpMeterBandDrop) MarshalBinary() ([]byte, error) {
+	mbd.Type = OfpMeterBandTypeDrop
+	mbd.Length = mbd.Len()
+	return marshalFixedAction(mbd)
+}
+
+// OfpMeterBandDscpRemark represents the OFPMBT_DSCP_REMARK band - Remark DSCP in the IP header
+type OfpMeterBandDscpRemark struct {
+	Type      uint16 /* OFPMBT_DSCP_REMARK. */
+	Length    uint16 /* Length in bytes of this band. */
+	Rate      uint32 /* Rate for remarking packets. */
+	BurstSize uint32 /* Size of bursts. */
+	PrecLevel uint8  /* Number of drop precedence level to add. */
+	Padding   [3]byte
+}
+
+// NewMeterBandDscpRemark creates the band increasing the drop precedence
+// of the packets above the rate
+func NewMeterBandDscpRemark(rate, burstSize uint32, precLevel uint8) *OfpMeterBandDscpRemark {
+	return &OfpMeterBandDscpRemark{Type: OfpMeterBandTypeDscpRemark, Length: meterBandLen, Rate: rate,
+		BurstSize: burstSize, PrecLevel: precLevel}
+}
+
+// Len returns the length of the band
+func (mbr *OfpMeterBandDscpRemark) Len() uint16 {
+	return meterBandLen
+}
+
+// UnmarshalBinary transforms the byte array into band data
+func (mbr *OfpMeterBandDscpRemark) UnmarshalBinary(data []byte) error {
+	return unmarshalFixedAction(data, mbr.Len(), mbr)
+}
+
+// MarshalBinary converts the band fields into byte array
+func (mbr *OfpMeterBandDscpRemark) MarshalBinary() ([]byte, error) {
+	mbr.Type = OfpMeterBandTypeDscpRemark
+	mbr.Length = mbr.Len()
+	return marshalFixedAction(mbr)
+}
+
+// OfpMeterBandExperimenter represents the OFPMBT_EXPERIMENTER band - Experimenter type.
+// The rest of the band is experimenter-defined.
+type OfpMeterBandExperimenter struct {
+	Type         uint16 /* One of OFPMBT_*. */
+	Length       uint16 /* Length in bytes of this band. */
+	Rate         uint32 /* Rate for this band. */
+	BurstSize    uint32 /* Size of bursts. */
+	Experimenter uint32 /* Experimenter ID which takes the same form as in struct ofp_experimenter_header. */
+	Data         []byte
+}
+
+// Len returns the length of the band including the data
+func (mbe *OfpMeterBandExperimenter) Len() uint16 {
+	return uint16(meterBandLen + len(mbe.Data))
+}
+
+// UnmarshalBinary transforms the byte array into band data
+func (mbe *OfpMeterBandExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < meterBandLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &mbe.Type, &mbe.Length, &mbe.Rate, &mbe.BurstSize,
+		&mbe.Experimenter); err != nil {
+		return err
+	}
+	if mbe.Length < meterBandLen || int(mbe.Length) > len(data) {
+		return fmt.Errorf("Invalid experimenter band length %d", mbe.Length)
+	}
+	mbe.Data = make([]byte, mbe.Length-meterBandLen)
+	copy(mbe.Data, data[meterBandLen:mbe.Length])
+	return nil
+}
+
+// MarshalBinary converts the band fields into byte array
+func (mbe *OfpMeterBandExperimenter) MarshalBinary() ([]byte, error) {
+	mbe.Type = OfpMeterBandTypeExperimenter
+	mbe.Length = mbe.Len()
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, mbe.Type, mbe.Length, mbe.Rate, mbe.BurstSize,
+		mbe.Experimenter); err != nil {
+		return nil, err
+	}
+	buf.Write(mbe.Data)
+	return buf.Bytes(), nil
+}
+
+// ParseMeterBands decodes the list of meter bands
+func ParseMeterBands(data []byte) ([]OfpMeterBand, error) {
+	bands := make([]OfpMeterBand, 0)
+	for idx := 0; idx < len(data); {
+		if len(data)-idx < meterBandLen {
+			return nil, fmt.Errorf("The data size %d is not big enough to be decoded", len(data)-idx)
+		}
+		bandType := binary.BigEndian.Uint16(data[idx : idx+2])
+		bandLen := int(binary.BigEndian.Uint16(data[idx+2 : idx+4]))
+		if bandLen < meterBandLen || idx+bandLen > len(data) {
+			return nil, fmt.Errorf("Invalid length %d of meter band type %d", bandLen, bandType)
+		}
+		var band OfpMeterBand
+		switch bandType {
+		case OfpMeterBandTypeDrop:
+			band = &OfpMeterBandDrop{}
+		case OfpMeterBandTypeDscpRemark:
+			band = &OfpMeterBandDscpRemark{}
+		case OfpMeterBandTypeExperimenter:
+			band = &OfpMeterBandExperimenter{}
+		default:
+			return nil, fmt.Errorf("Unknown meter band type %d", bandType)
+		}
+		if err := band.UnmarshalBinary(data[idx : idx+bandLen]); err != nil {
+			return nil, err
+		}
+		bands = append(bands, band)
+		idx += bandLen
+	}
+	return bands, nil
+}
+
+// marshalMeterBands encodes the list of meter bands
+func marshalMeterBands(bands []OfpMeterBand) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for _, band := range bands {
+		bandData, err := band.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bandData)
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpMeterModMsg represents the meter configuration message. OFPT_METER_MOD.
+type OfpMeterModMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Command uint16         /* One of OFPMC_*. */
+	Flags   uint16         /* Bitmap of OFPMF_* flags. */
+	MeterID uint32         /* Meter instance. */
+	Bands   []OfpMeterBand /* The band list length is inferred from the length field in the header. */
+}
+
+// NewMeterModMsg creates the meter mod of the command
+func NewMeterModMsg(command uint16, flags uint16, meterID uint32, bands []OfpMeterBand) *OfpMeterModMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeMeterMod
+	return &OfpMeterModMsg{Header: *header, Command: command, Flags: flags, MeterID: meterID, Bands: bands}
+}
+
+// UnmarshalBinary transforms the byte array into meter mod data
+func (mm *OfpMeterModMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < meterModHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &mm.Header, &mm.Command, &mm.Flags, &mm.MeterID); err != nil {
+		return err
+	}
+	bands, err := ParseMeterBands(data[meterModHeaderLen:])
+	if err != nil {
+		return err
+	}
+	mm.Bands = bands
+	return nil
+}
+
+// MarshalBinary converts the meter mod fields into byte array, the length
+// in the header is set according to the bands
+func (mm *OfpMeterModMsg) MarshalBinary() ([]byte, error) {
+	bandsData, err := marshalMeterBands(mm.Bands)
+	if err != nil {
+		return nil, err
+	}
+	mm.Header.Length = uint16(meterModHeaderLen + len(bandsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, mm.Header, mm.Command, mm.Flags, mm.MeterID); err != nil {
+		return nil, err
+	}
+	buf.Write(bandsData)
+	return buf.Bytes(), nil
+}
+
+// OfpMeterMultipartRequest represents the body of OFPMP_METER and OFPMP_METER_CONFIG requests.
+type OfpMeterMultipartRequest struct {
+	MeterID uint32  /* Meter instance, or OFPM_ALL. */
+	Padding [4]byte /* Align to 64 bits. */
+}
+
+// NewMeterStatsRequestMsg creates the multipart request of the meter
+// statistics, OfpMeterAll requests the statistics of all the meters
+func NewMeterStatsRequestMsg(meterID uint32) *OfpMultipartRequestMsg {
+	body := make([]byte, 8)
+	binary.BigEndian.PutUint32(body, meterID)
+	return NewMultipartRequestMsg(OfpMultipartTypeMeter, body)
+}
+
+// NewMeterConfigRequestMsg creates the multipart request of the meter
+// configuration, OfpMeterAll requests the configuration of all the meters
+func NewMeterConfigRequestMsg(meterID uint32) *OfpMultipartRequestMsg {
+	body := make([]byte, 8)
+	binary.BigEndian.PutUint32(body, meterID)
+	return NewMultipartRequestMsg(OfpMultipartTypeMeterConfig, body)
+}
+
+// NewMeterFeaturesRequestMsg creates the multipart request of the meter features
+func NewMeterFeaturesRequestMsg() *OfpMultipartRequestMsg {
+	return NewMultipartRequestMsg(OfpMultipartTypeMeterFeatures, nil)
+}
+
+// OfpMeterBandStats represents the statistics for each meter band
+type OfpMeterBandStats struct {
+	PacketBandCount uint64 /* Number of packets in band. */
+	ByteBandCount   uint64 /* Number of bytes in band. */
+}
+
+// OfpMeterStats represents the body of reply to OFPMP_METER request. Meter statistics.
+type OfpMeterStats struct {
+	MeterID         uint32 /* Meter instance. */
+	Length          uint16 /* Length in bytes of this stats. */
+	Padding         [6]byte
+	FlowCount       uint32              /* Number of flows bound to meter. */
+	PacketInCount   uint64              /* Number of packets in input. */
+	ByteInCount     uint64              /* Number of bytes in input. */
+	DurationSec     uint32              /* Time meter has been alive in seconds. */
+	DurationNanoSec uint32              /* Time meter has been alive in nanoseconds beyond duration_sec. */
+	BandStats       []OfpMeterBandStats /* The band_stats length is inferred from the length field. */
+}
+
+// UnmarshalBinary transforms the byte array into meter stats data
+func (ms *OfpMeterStats) UnmarshalBinary(data []byte) error {
+	if len(data) < meterStatsHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &ms.MeterID, &ms.Length, &ms.Padding, &ms.FlowCount,
+		&ms.PacketInCount, &ms.ByteInCount, &ms.DurationSec, &ms.DurationNanoSec); err != nil {
+		return err
+	}
+	if ms.Length < meterStatsHeaderLen || int(ms.Length) > len(data) ||
+		(ms.Length-meterStatsHeaderLen)%meterBandStatsLen != 0 {
+		return fmt.Errorf("Invalid meter stats length %d", ms.Length)
+	}
+	ms.BandStats = make([]OfpMeterBandStats, (ms.Length-meterStatsHeaderLen)/meterBandStatsLen)
+	return ofpgeneral.UnMarshalFields(buf, ms.BandStats)
+}
+
+// MarshalBinary converts the meter stats fields into byte array
+func (ms *OfpMeterStats) MarshalBinary() ([]byte, error) {
+	ms.Length = uint16(meterStatsHeaderLen + len(ms.BandStats)*meterBandStatsLen)
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ms.MeterID, ms.Length, ms.Padding, ms.FlowCount,
+		ms.PacketInCount, ms.ByteInCount, ms.DurationSec, ms.DurationNanoSec, ms.BandStats); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseMeterStatsBody decodes the body of the meter stats reply
+func ParseMeterStatsBody(body []byte) ([]OfpMeterStats, error) {
+	stats := make([]OfpMeterStats, 0)
+	for idx := 0; idx < len(body); {
+		meterStats := OfpMeterStats{}
+		if err := meterStats.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		stats = append(stats, meterStats)
+		idx += int(meterStats.Length)
+	}
+	return stats, nil
+}
+
+// OfpMeterConfig represents the body of reply to OFPMP_METER_CONFIG request. Meter configuration.
+type OfpMeterConfig struct {
+	Length  uint16         /* Length of this entry. */
+	Flags   uint16         /* All OFPMF_* that apply. */
+	MeterID uint32         /* Meter instance. */
+	Bands   []OfpMeterBand /* The bands length is inferred from the length field. */
+}
+
+// UnmarshalBinary transforms the byte array into meter config data
+func (mc *OfpMeterConfig) UnmarshalBinary(data []byte) error {
+	if len(data) < meterConfigHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &mc.Length, &mc.Flags, &mc.MeterID); err != nil {
+		return err
+	}
+	if mc.Length < meterConfigHeaderLen || int(mc.Length) > len(data) {
+		return fmt.Errorf("Invalid meter config length %d", mc.Length)
+	}
+	bands, err := ParseMeterBands(data[meterConfigHeaderLen:mc.Length])
+	if err != nil {
+		return err
+	}
+	mc.Bands = bands
+	return nil
+}
+
+// MarshalBinary converts the meter config fields into byte array
+func (mc *OfpMeterConfig) MarshalBinary() ([]byte, error) {
+	bandsData, err := marshalMeterBands(mc.Bands)
+	if err != nil {
+		return nil, err
+	}
+	mc.Length = uint16(meterConfigHeaderLen + len(bandsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, mc.Length, mc.Flags, mc.MeterID); err != nil {
+		return nil, err
+	}
+	buf.Write(bandsData)
+	return buf.Bytes(), nil
+}
+
+// ParseMeterConfigBody decodes the body of the meter config reply
+func ParseMeterConfigBody(body []byte) ([]OfpMeterConfig, error) {
+	configs := make([]OfpMeterConfig, 0)
+	for idx := 0; idx < len(body); {
+		config := OfpMeterConfig{}
+		if err := config.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+		idx += int(config.Length)
+	}
+	return configs, nil
+}
+
+// OfpMeterFeatures represents the body of reply to OFPMP_METER_FEATURES request. Meter features.
+type OfpMeterFeatures struct {
+	MaxMeter     uint32 /* Maximum number of meters. */
+	BandTypes    uint32 /* Bitmaps of (1 << OFPMBT_*) values supported. */
+	Capabilities uint32 /* Bitmaps of "ofp_meter_flags". */
+	MaxBands     uint8  /* Maximum bands per meters */
+	MaxColor     uint8  /* Maximum color value */
+	Padding      [2]byte
+}
+
+// UnmarshalBinary transforms the byte array into meter features data
+func (mf *OfpMeterFeatures) UnmarshalBinary(data []byte) error {
+	if len(data) < meterFeaturesLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, mf)
+}
+
+// MarshalBinary converts the meter features fields into byte array
+func (mf *OfpMeterFeatures) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, mf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package ofp13
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+// dropBandWire is the band dropping the packets above 1000 kb/s
+const dropBandWire = "0001 0010 000003e8 00000064 00000000"
+
+func TestMeterModWireFormat(t *testing.T) {
+	bands := []OfpMeterBand{
+		NewMeterBandDrop(1000, 100),
+		NewMeterBandDscpRemark(2000, 200, 1),
+		&OfpMeterBandExperimenter{Rate: 3000, Experimenter: 0x2320, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+	msg := NewMeterModMsg(OfpMeterModCmdAdd, OfpMeterFlagKbps|OfpMeterFlagStats, 1, bands)
+	msg.Header.Xid = 1
+	data := wiretest.Decode(t, "041d004800000001 0000 0009 00000001"+dropBandWire+
+		"0002 0010 000007d0 000000c8 01 000000"+"ffff 0018 00000bb8 00000000 00002320 0102030405060708")
+	wiretest.CheckEncoding(t, msg, data)
+
+	decoded := &OfpMeterModMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, data)
+	if decoded.Command != OfpMeterModCmdAdd || decoded.Flags != OfpMeterFlagKbps|OfpMeterFlagStats ||
+		decoded.MeterID != 1 || len(decoded.Bands) != 3 {
+		t.Fatalf("Unexpected meter mod %+v", decoded)
+	}
+	if drop := decoded.Bands[0].(*OfpMeterBandDrop); drop.Rate != 1000 || drop.BurstSize != 100 {
+		t.Errorf("Unexpected drop band %+v", drop)
+	}
+	if remark := decoded.Bands[1].(*OfpMeterBandDscpRemark); remark.Rate != 2000 || remark.PrecLevel != 1 {
+		t.Errorf("Unexpected dscp remark band %+v", remark)
+	}
+	if exp := decoded.Bands[2].(*OfpMeterBandExperimenter); exp.Experimenter != 0x2320 ||
+		hex.EncodeToString(exp.Data) != "0102030405060708" {
+		t.Errorf("Unexpected experimenter band %+v", exp)
+	}
+}
+
+func TestParseMeterBandsInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0001 0010 000003e8 00000064",          // Truncated band
+		"0001 0008 000003e8 00000064 00000000", // Length smaller than the band
+		"0001 0018 000003e8 00000064 00000000", // Length beyond the data
+		"0003 0010 000003e8 00000064 00000000", // Unknown band
+	} {
+		if bands, err := ParseMeterBands(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The bands %s are decoded as %+v", wire, bands)
+		}
+	}
+}
+
+func TestDecodeMeterMultipartReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, body interface{})
+	}{
+		{"meter stats", "0413004800000002 0009 0000 00000000" +
+			"00000001 0038 000000000000 00000002 000000000000000a 00000000000003e8 0000000a 00000014" +
+			"0000000000000004 0000000000000190",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpMeterStats)
+				if len(stats) != 1 || stats[0].MeterID != 1 || stats[0].FlowCount != 2 || stats[0].PacketInCount != 10 ||
+					stats[0].ByteInCount != 1000 || stats[0].DurationSec != 10 || len(stats[0].BandStats) != 1 ||
+					stats[0].BandStats[0].ByteBandCount != 400 {
+					t.Errorf("Unexpected meter stats %+v", stats)
+				}
+			}},
+		{"meter config", "0413002800000003 000a 0000 00000000 0018 0009 00000001" + dropBandWire,
+			func(t *testing.T, body interface{}) {
+				configs := body.([]OfpMeterConfig)
+				if len(configs) != 1 || configs[0].Length != 24 || configs[0].MeterID != 1 ||
+					configs[0].Flags != OfpMeterFlagKbps|OfpMeterFlagStats || len(configs[0].Bands) != 1 {
+					t.Errorf("Unexpected meter config %+v", configs)
+				}
+			}},
+		{"meter features", "0413002000000004 000b 0000 00000000 0000ffff 00000006 0000000f 02 08 0000",
+			func(t *testing.T, body interface{}) {
+				features := body.(*OfpMeterFeatures)
+				if features.MaxMeter != 0xffff || features.BandTypes != 6 || features.Capabilities != 0xf ||
+					features.MaxBands != 2 || features.MaxColor != 8 {
+					t.Errorf("Unexpected meter features %+v", features)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := wiretest.Parse(t, &OfpMessageParser{}, tc.wire).(*OfpMultipartReplyMsg)
+			body, err := reply.DecodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, body)
+		})
+	}
+}
+
+func TestMeterRequestsWireFormat(t *testing.T) {
+	stats := NewMeterStatsRequestMsg(OfpMeterAll)
+	stats.Header.Xid = 5
+	wiretest.CheckEncoding(t, stats, wiretest.Decode(t, "0412001800000005 0009 0000 00000000 ffffffff 00000000"))
+	config := NewMeterConfigRequestMsg(1)
+	config.Header.Xid = 6
+	wiretest.CheckEncoding(t, config, wiretest.Decode(t, "0412001800000006 000a 0000 00000000 00000001 00000000"))
+}
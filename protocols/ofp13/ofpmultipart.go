@@ -0,0 +1,247 @@
+package ofp13
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// enum ofp_multipart_types {
+const (
+	/* Description of this OpenFlow switch.
+	 * The request body is empty.
+	 * The reply body is struct ofp_desc. */
+	OfpMultipartTypeDesc = iota
+
+	/* Individual flow statistics.
+	 * The request body is struct ofp_flow_stats_request.
+	 * The reply body is an array of struct ofp_flow_stats. */
+	OfpMultipartTypeFlow
+
+	/* Aggregate flow statistics.
+	 * The request body is struct ofp_aggregate_stats_request.
+	 * The reply body is struct ofp_aggregate_stats_reply. */
+	OfpMultipartTypeAggregate
+
+	/* Flow table statistics.
+	 * The request body is empty.
+	 * The reply body is an array of struct ofp_table_stats. */
+	OfpMultipartTypeTable
+
+	/* Port statistics.
+	 * The request body is struct ofp_port_stats_request.
+	 * The reply body is an array of struct ofp_port_stats. */
+	OfpMultipartTypePortStats
+
+	/* Queue statistics for a port
+	 * The request body is struct ofp_queue_stats_request.
+	 * The reply body is an array of struct ofp_queue_stats */
+	OfpMultipartTypeQueue
+
+	/* Group counter statistics.
+	 * The request body is struct ofp_group_stats_request.
+	 * The reply is an array of struct ofp_group_stats. */
+	OfpMultipartTypeGroup
+
+	/* Group description.
+	 * The request body is empty.
+	 * The reply body is an array of struct ofp_group_desc. */
+	OfpMultipartTypeGroupDesc
+
+	/* Group features.
+	 * The request body is empty.
+	 * The reply body is struct ofp_group_features. */
+	OfpMultipartTypeGroupFeatures
+
+	/* Meter statistics.
+	 * The request body is struct ofp_meter_multipart_requests.
+	 * The reply body is an array of struct ofp_meter_stats. */
+	OfpMultipartTypeMeter
+
+	/* Meter configuration.
+	 * The request body is struct ofp_meter_multipart_requests.
+	 * The reply body is an array of struct ofp_meter_config. */
+	OfpMultipartTypeMeterConfig
+
+	/* Meter features.
+	 * The request body is empty.
+	 * The reply body is struct ofp_meter_features. */
+	OfpMultipartTypeMeterFeatures
+
+	/* Table features.
+	 * The request body is either empty or contains an array of
+	 * struct ofp_table_features containing the controller's
+	 * desired view of the switch. If the switch is unable to
+	 * set the specified view an error is returned.
+	 * The reply body is an array of struct ofp_table_features. */
+	OfpMultipartTypeTableFeatures
+
+	/* Port description.
+	 * The request body is empty.
+	 * The reply body is an array of struct ofp_port. */
+	OfpMultipartTypePortDesc
+
+	/* Experimenter extension.
+	 * The request and reply bodies begin with
+	 * struct ofp_experimenter_multipart_header.
+	 * The request and reply bodies are otherwise experimenter-defined. */
+	OfpMultipartTypeExperimenter = 0xffff
+)
+
+// enum ofp_multipart_request_flags / ofp_multipart_reply_flags {
+const (
+	OfpMultipartRequestMore = 1 << 0 /* More requests to follow. */
+	OfpMultipartReplyMore   = 1 << 0 /* More replies to follow. */
+)
+
+const (
+	multipartHeaderLen = 16
+)
+
+// OfpMultipartRequestMsg represents the structure of multipart request msg
+type OfpMultipartRequestMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Type    uint16 /* One of the OFPMP_* constants. */
+	Flags   uint16 /* OFPMPF_REQ_* flags. */
+	Padding [4]byte
+	Body    []byte /* Body of the request. 0 or more bytes. */
+}
+
+// NewMultipartRequestMsg creates the multipart request of the type
+func NewMultipartRequestMsg(mpType uint16, body []byte) *OfpMultipartRequestMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeMultiPartRequest
+	return &OfpMultipartRequestMsg{Header: *header, Type: mpType, Body: body}
+}
+
+// UnmarshalBinary transforms the byte array into multipart request data
+func (mr *OfpMultipartRequestMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < multipartHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &mr.Header, &mr.Type, &mr.Flags, &mr.Padding); err != nil {
+		return err
+	}
+	mr.Body = make([]byte, len(data)-multipartHeaderLen)
+	copy(mr.Body, data[multipartHeaderLen:])
+	return nil
+}
+
+// MarshalBinary converts the multipart request fields into byte array,
+// the length in the header is set according to the body
+func (mr *OfpMultipartRequestMsg) MarshalBinary() ([]byte, error) {
+	mr.Header.Length = uint16(multipartHeaderLen + len(mr.Body))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, mr.Header, mr.Type, mr.Flags, mr.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(mr.Body)
+	return buf.Bytes(), nil
+}
+
+// OfpMultipartReplyMsg represents the structure of multipart reply msg
+type OfpMultipartReplyMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Type    uint16 /* One of the OFPMP_* constants. */
+	Flags   uint16 /* OFPMPF_REPLY_* flags. */
+	Padding [4]byte
+	Body    []byte /* Body of the reply. 0 or more bytes. */
+}
+
+// UnmarshalBinary transforms the byte array into multipart reply data
+func (mr *OfpMultipartReplyMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < multipartHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &mr.Header, &mr.Type, &mr.Flags, &mr.Padding); err != nil {
+		return err
+	}
+	mr.Body = make([]byte, len(data)-multipartHeaderLen)
+	copy(mr.Body, data[multipartHeaderLen:])
+	return nil
+}
+
+// MarshalBinary converts the multipart reply fields into byte array,
+// the length in the header is set according to the body
+func (mr *OfpMultipartReplyMsg) MarshalBinary() ([]byte, error) {
+	mr.Header.Length = uint16(multipartHeaderLen + len(mr.Body))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, mr.Header, mr.Type, mr.Flags, mr.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(mr.Body)
+	return buf.Bytes(), nil
+}
+
+// IsMore tells whether more replies of the same request follow
+func (mr *OfpMultipartReplyMsg) IsMore() bool {
+	return mr.Flags&OfpMultipartReplyMore != 0
+}
+
+// DecodeBody decodes the body of the reply according to its type. The array
+// bodies are returned as the slice of the stats structure, e.g. []OfpFlowStats,
+// and the single ones as its pointer, e.g. *OfpDesc. The table features and
+// experimenter bodies are returned as they are
+func (mr *OfpMultipartReplyMsg) DecodeBody() (interface{}, error) {
+	var single ofpgeneral.OfpMessage
+	switch mr.Type {
+	case OfpMultipartTypeDesc:
+		single = &OfpDesc{}
+	case OfpMultipartTypeFlow:
+		return ParseFlowStatsBody(mr.Body)
+	case OfpMultipartTypeAggregate:
+		single = &OfpAggregateStatsReply{}
+	case OfpMultipartTypeTable:
+		return ParseTableStatsBody(mr.Body)
+	case OfpMultipartTypePortStats:
+		return ParsePortStatsBody(mr.Body)
+	case OfpMultipartTypeQueue:
+		return ParseQueueStatsBody(mr.Body)
+	case OfpMultipartTypeGroup:
+		return ParseGroupStatsBody(mr.Body)
+	case OfpMultipartTypeGroupDesc:
+		return ParseGroupDescBody(mr.Body)
+	case OfpMultipartTypeGroupFeatures:
+		single = &OfpGroupFeatures{}
+	case OfpMultipartTypeMeter:
+		return ParseMeterStatsBody(mr.Body)
+	case OfpMultipartTypeMeterConfig:
+		return ParseMeterConfigBody(mr.Body)
+	case OfpMultipartTypeMeterFeatures:
+		single = &OfpMeterFeatures{}
+	case OfpMultipartTypePortDesc:
+		return ParsePortDescBody(mr.Body)
+	case OfpMultipartTypeTableFeatures, OfpMultipartTypeExperimenter:
+		return mr.Body, nil
+	default:
+		return nil, fmt.Errorf("Unknown multipart type %d", mr.Type)
+	}
+	if err := single.UnmarshalBinary(mr.Body); err != nil {
+		return nil, err
+	}
+	return single, nil
+}
+
+// NewPortDescRequestMsg creates the request of the port description
+func NewPortDescRequestMsg() *OfpMultipartRequestMsg {
+	return NewMultipartRequestMsg(OfpMultipartTypePortDesc, nil)
+}
+
+// ParsePortDescBody decodes the body of the port description reply
+func ParsePortDescBody(body []byte) ([]OfpPhysPort, error) {
+	if len(body)%portLen != 0 {
+		return nil, fmt.Errorf("The port description size %d is not a multiple of %d", len(body), portLen)
+	}
+	ports := make([]OfpPhysPort, 0, len(body)/portLen)
+	for idx := 0; idx < len(body); idx += portLen {
+		port := OfpPhysPort{}
+		if err := port.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
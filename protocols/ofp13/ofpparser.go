@@ -1,6 +1,10 @@
 package ofp13
 
-import "github.com/kopwei/goof/protocols/ofpgeneral"
+import (
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
 
 // OfpMessageParser is the message parser implementation
 type OfpMessageParser struct {
@@ -8,5 +12,41 @@ type OfpMessageParser struct {
 
 // ParseMsg is used to convert bytes into ofp message
 func (p *OfpMessageParser) ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	return nil, nil
+	var message ofpgeneral.OfpMessage
+	switch b[1] {
+	case OfpTypeHello:
+		message = &ofpgeneral.OfpHelloMsg{}
+	case OfpTypeError:
+		message = &ofpgeneral.OfpErrMsg{}
+	case OfpTypeEchoRequest:
+		message = &ofpgeneral.OfpEchoMsg{}
+	case OfpTypeEchoReply:
+		message = &ofpgeneral.OfpEchoMsg{}
+	case OfpTypeExperimenter:
+		message = &OfpExperimenterMsg{}
+	case OfpTypeFeaturesReply:
+		message = &OfpSwitchFeatureMsg{}
+	case OfpTypeGetConfigReply:
+		message = &OfpSwitchConfigMsg{}
+	case OfpTypePacketIn:
+		message = &OfpPacketInMsg{}
+	case OfpTypeFlowRemoved:
+		message = &OfpFlowRemovedMsg{}
+	case OfpTypePortStatus:
+		message = &OfpPortStatusMsg{}
+	case OfpTypeMultiPartReply:
+		message = &OfpMultipartReplyMsg{}
+	case OfpTypeBarrierReply:
+		message = &ofpgeneral.OfpHeader{}
+	case OfpTypeQueueGetConfigReply:
+		message = &OfpQueueGetConfReplyMsg{}
+	case OfpTypeRoleReply:
+		message = &OfpRoleRequestMsg{}
+	case OfpTypeGetAsyncReply:
+		message = &OfpAsyncConfigMsg{}
+	default:
+		return nil, fmt.Errorf("An unknown v1.3 packet type %d was received. Parse function will discard data.", b[1])
+	}
+	err := message.UnmarshalBinary(b)
+	return message, err
 }
@@ -0,0 +1,168 @@
+package ofp13
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	// inPortMatchWire is the match of the port 3 including the padding
+	inPortMatchWire = "0001000c 80000004 00000003 00000000"
+	// portWire is the description of the port 1 named eth0
+	portWire = "00000001 00000000 020304050607 0000 6574683000000000 0000000000000000" +
+		"00000001 00000004 00002000 00000000 00000000 00000000 00989680 00989680"
+)
+
+func TestParseSwitchMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, msg ofpgeneral.OfpMessage)
+	}{
+		{"features reply", "0406002000000001 0000020304050607 00000100 fe 00 0000 0000004f 00000000",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpSwitchFeatureMsg)
+				if m.DatapathID != 0x020304050607 || m.NoOfBuffers != 256 || m.NoOfTables != 254 || m.Capabilities != 0x4f {
+					t.Errorf("Unexpected features reply %+v", m)
+				}
+			}},
+		{"packet in", "040a003000000002 ffffffff 0006 01 02 0000000000000007" + inPortMatchWire + "0000 aabbccddeeff",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPacketInMsg)
+				inPort, ok := m.Match.OXMFields.GetInPort()
+				if m.BufferID != OfpNoBuffer || m.TotalLen != 6 || m.Reason != 1 || m.TableID != 2 || m.Cookie != 7 ||
+					!ok || inPort != 3 || hex.EncodeToString(m.Data) != "aabbccddeeff" {
+					t.Errorf("Unexpected packet in %+v", m)
+				}
+			}},
+		{"flow removed", "040b004000000003 0000000000000007 8000 00 01 0000000a 00000014 003c 0000" +
+			"0000000000000005 00000000000001f4" + inPortMatchWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpFlowRemovedMsg)
+				if m.Cookie != 7 || m.Priority != 0x8000 || m.TableID != 1 || m.IdleTimeout != 60 ||
+					m.PacketCount != 5 || m.ByteCount != 500 || len(m.Match.OXMFields) != 1 {
+					t.Errorf("Unexpected flow removed %+v", m)
+				}
+			}},
+		{"port status", "040c005000000004 02 00000000000000" + portWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPortStatusMsg)
+				if m.Reason != 2 || m.Desc.PortNo != 1 || m.Desc.HwAddr.String() != "02:03:04:05:06:07" ||
+					m.Desc.State != 4 || m.Desc.CurrSpeed != 10000000 {
+					t.Errorf("Unexpected port status %+v", m)
+				}
+			}},
+		{"queue get config reply", "0417003000000005 00000001 00000000 00000001 00000001 0020 000000000000" +
+			"0001 0010 00000000 01f4 000000000000",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpQueueGetConfReplyMsg)
+				if m.Port != 1 || len(m.Queues) != 1 || m.Queues[0].Len != 32 || len(m.Queues[0].Properties) != 1 {
+					t.Fatalf("Unexpected queue config %+v", m)
+				}
+				if rate, ok := m.Queues[0].Properties[0].(*OfpQueuePropRate); !ok || rate.Rate != 500 {
+					t.Errorf("Unexpected queue property %+v", m.Queues[0].Properties[0])
+				}
+			}},
+		{"role reply", "0419001800000006 00000002 00000000 0000000000000009",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m := msg.(*OfpRoleRequestMsg); m.Role != 2 || m.GenerationID != 9 {
+					t.Errorf("Unexpected role reply %+v", m)
+				}
+			}},
+		{"experimenter", "0404001400000007 00002320 00000001 deadbeef",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m := msg.(*OfpExperimenterMsg); m.Experimenter != 0x2320 || m.ExpType != 1 || len(m.Data) != 4 {
+					t.Errorf("Unexpected experimenter message %+v", m)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, wiretest.Parse(t, &OfpMessageParser{}, tc.wire))
+		})
+	}
+}
+
+func TestDecodeMultipartReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, body interface{})
+	}{
+		{"port desc", "0413005000000008 000d 0000 00000000" + portWire,
+			func(t *testing.T, body interface{}) {
+				ports := body.([]OfpPhysPort)
+				if len(ports) != 1 || ports[0].PortNo != 1 || string(ports[0].Name[:4]) != "eth0" {
+					t.Errorf("Unexpected ports %+v", ports)
+				}
+			}},
+		{"port stats", "0413008000000009 0004 0001 00000000 00000001 00000000" +
+			"0000000000000001 0000000000000002 0000000000000003 0000000000000004" +
+			"0000000000000005 0000000000000006 0000000000000007 0000000000000008" +
+			"0000000000000009 000000000000000a 000000000000000b 000000000000000c 0000000a 00000014",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpPortStats)
+				if len(stats) != 1 || stats[0].PortNo != 1 || stats[0].RxPackets != 1 || stats[0].Collisions != 12 ||
+					stats[0].DurationSec != 10 || stats[0].DurationNanoSec != 20 {
+					t.Errorf("Unexpected port stats %+v", stats)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := wiretest.Parse(t, &OfpMessageParser{}, tc.wire).(*OfpMultipartReplyMsg)
+			body, err := reply.DecodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, body)
+		})
+	}
+}
+
+func TestActionsWireFormat(t *testing.T) {
+	vid := ofpgeneral.OxmFields{}
+	vid.SetVlanVID(ofpgeneral.OfpVIDPresent | 5)
+	actions := []OfpAction{
+		NewActionOutput(2),
+		NewActionPush(OfpActionPushVlan, 0x8100),
+		NewActionSetField(vid[0]),
+		NewActionGroup(1),
+		NewActionSetQueue(7),
+		NewActionHeader(OfpActionDecNWTTL),
+	}
+	wire := "0000 0010 00000002 ffff 000000000000" + "0011 0008 8100 0000" +
+		"0019 0010 80000c02 1005 000000000000" + "0016 0008 00000001" + "0015 0008 00000007" +
+		"0018 0008 00000000"
+	data, err := marshalActions(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := wiretest.Decode(t, wire)
+	if hex.EncodeToString(data) != hex.EncodeToString(expected) {
+		t.Fatalf("Encoded %x, expected %x", data, expected)
+	}
+	decoded, err := ParseActions(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(actions) {
+		t.Fatalf("Decoded %d actions, expected %d", len(decoded), len(actions))
+	}
+	if output := decoded[0].(*OfpActionOutput); output.Port != 2 || output.MaxLen != 0xffff {
+		t.Errorf("Unexpected output action %+v", output)
+	}
+	if setField := decoded[2].(*OfpActionSetFieldInfo); setField.Field.Field != ofpgeneral.OxmFieldVlanVID {
+		t.Errorf("Unexpected set field action %+v", setField)
+	}
+	redone, err := marshalActions(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(redone) != hex.EncodeToString(expected) {
+		t.Fatalf("Decoded actions are encoded as %x, expected %x", redone, expected)
+	}
+}
@@ -8,40 +8,50 @@ import (
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
+// Port numbering. Ports are numbered starting from 1.
+// enum ofp_port_no {
+const (
+	/* Maximum number of physical and logical switch ports. */
+	OfpPortMax = 0xffffff00
+
+	/* Reserved OpenFlow Port (fake output "ports"). */
+	OfpPortInPort = 0xfffffff8 /* Send the packet out the input port.  This
+	   reserved port must be explicitly used
+	   in order to send back out of the input
+	   port. */
+	OfpPortTable = 0xfffffff9 /* Submit the packet to the first flow table
+	   NB: This destination port can only be
+	   used in packet-out messages. */
+	OfpPortNormal     = 0xfffffffa /* Process with normal L2/L3 switching. */
+	OfpPortFlood      = 0xfffffffb /* All physical ports in VLAN, except input port and those blocked or link down. */
+	OfpPortAll        = 0xfffffffc /* All physical ports except input port. */
+	OfpPortController = 0xfffffffd /* Send to controller. */
+	OfpPortLocal      = 0xfffffffe /* Local openflow "port". */
+	OfpPortAny        = 0xffffffff /* Wildcard port used only for flow mod (delete) and flow stats requests. Selects all flows regardless of output port (including flows with no output port). */
+)
+
 // OFP Port Config
 // Flags to indicate behavior of the physical port.  These flags are
-// used in ofp_phy_port to describe the current configuration.  They are
+// used in ofp_port to describe the current configuration.  They are
 // used in the ofp_port_mod message to configure the port's behavior.
 const (
-	OfpPortConfPortDown = 1 << iota // Port is administratively down.
-	OfpPortConfNoSTP                // Disable 802.1D spanning tree on port.
-	OfpPortConfNoRecv               /* Drop all packets except 802.1D spanning
-	   tree packets. */
-	OfpPortConfNoRecvSTP  /* Drop received 802.1D STP packets. */
-	OfpPortConfNoFlood    /* Do not include this port when flooding. */
-	OfpPortConfNoFwd      /* Drop packets forwarded to port. */
-	OfpPortConfNoPacketIn /* Do not send packet-in msgs for port. */
+	OfpPortConfPortDown   = 1 << 0 // Port is administratively down.
+	OfpPortConfNoRecv     = 1 << 2 /* Drop all packets received by port. */
+	OfpPortConfNoFwd      = 1 << 5 /* Drop packets forwarded to port. */
+	OfpPortConfNoPacketIn = 1 << 6 /* Do not send packet-in msgs for port. */
 )
 
 // OFP Port State
 // Current state of the physical port.  These are not configurable from
 // the controller.
 const (
-	OfpPortStateLinkDown = 1 << 0 /* No physical link present. */
-
-	/* The OfpPortStateSTP* bits have no effect on switch operation.  The
-	 * controller must adjust OFPPC_NO_RECV, OFPPC_NO_FWD, and
-	 * OFPPC_NO_PACKET_IN appropriately to fully implement an 802.1D spanning
-	 * tree. */
-	OfpPortStateSTPListen  = iota << 8       /* Not learning or relaying frames. */
-	OfpPortStateSTPLearn                     /* Learning but not relaying frames. */
-	OfpPortStateSTPForward                   /* Learning and relaying frames. */
-	OfpPortStateSTPBlock                     /* Not part of spanning tree. */
-	OfpPortStateSTPMask    = (iota - 1) << 8 /* Bit mask for OFPPS_STP_* values. */
+	OfpPortStateLinkDown = 1 << iota /* No physical link present. */
+	OfpPortStateBlocked              /* Port is blocked */
+	OfpPortStateLive                 /* Live for Fast Failover Group. */
 )
 
 // OfpPortFeatures
-// Features of physical ports available in a datapath.
+// Features of ports available in a datapath.
 const (
 	OfpPortFeature10MbHD    = 1 << iota /* 10 Mb half-duplex rate support. */
 	OfpPortFeature10MbFD                /* 10 Mb full-duplex rate support. */
@@ -50,6 +60,10 @@ const (
 	OfpPortFeature1GbHD                 /* 1 Gb half-duplex rate support. */
 	OfpPortFeature1GbFD                 /* 1 Gb full-duplex rate support. */
 	OfpPortFeature10GbFD                /* 10 Gb full-duplex rate support. */
+	OfpPortFeature40GbFD                /* 40 Gb full-duplex rate support. */
+	OfpPortFeature100GbFD               /* 100 Gb full-duplex rate support. */
+	OfpPortFeature1TbFD                 /* 1 Tb full-duplex rate support. */
+	OfpPortFeatureOther                 /* Other rate, not in the list. */
 	OfpPortFeatureCopper                /* Copper medium. */
 	OfpPortFeatureFiber                 /* Fiber medium. */
 	OfpPortFeatureAutoNeg               /* Auto-negotiation. */
@@ -69,17 +83,29 @@ const (
 // at least the first 64 bytes of the failed request. */
 // enum ofp_port_mod_failed_code {
 const (
-	OfpPortModFailedCodeBadPort   = iota /* Specified port does not exist. */
-	OfpPortModFailedCodeBadHwAddr        /* Specified hardware address is wrong. */
+	OfpPortModFailedCodeBadPort      = iota /* Specified port number does not exist. */
+	OfpPortModFailedCodeBadHwAddr           /* Specified hardware address does not match the port number. */
+	OfpPortModFailedCodeBadConfig           /* Specified config is invalid. */
+	OfpPortModFailedCodeBadAdvertise        /* Specified advertise is invalid. */
+	OfpPortModFailedCodeErrPerm             /* Permissions error. */
+)
+
+const (
+	portLen = 64
+	// ofpMaxPortNameLen is the size of the port name including the
+	// terminating zero
+	ofpMaxPortNameLen = 16
 )
 
-// OfpPhysPort represents the physical port structure
+// OfpPhysPort represents the port structure (ofp_port)
 type OfpPhysPort struct {
-	PortNo uint16
-	HwAddr net.HardwareAddr
-	Name   []byte
-	config uint32 /* Bitmap of OFPPC_* flags. */
-	state  uint32 /* Bitmap of OFPPS_* flags. *
+	PortNo   uint32
+	Padding1 [4]byte
+	HwAddr   net.HardwareAddr
+	Padding2 [2]byte
+	Name     []byte /* Null-terminated */
+	Config   uint32 /* Bitmap of OFPPC_* flags. */
+	State    uint32 /* Bitmap of OFPPS_* flags. */
 
 	/* Bitmaps of OpfPortFeature* that describe features.  All bits zeroed if
 	 * unsupported or unavailable. */
@@ -87,25 +113,34 @@ type OfpPhysPort struct {
 	Advertised uint32 /* Features being advertised by the port. */
 	Supported  uint32 /* Features supported by the port. */
 	Peer       uint32 /* Features advertised by peer. */
+
+	CurrSpeed uint32 /* Current port bitrate in kbps. */
+	MaxSpeed  uint32 /* Max port bitrate in kbps */
 }
 
 // UnmarshalBinary transforms the byte array into body data
 func (pp *OfpPhysPort) UnmarshalBinary(data []byte) error {
-	if len(data) < 64 {
+	if len(data) < portLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
 	pp.HwAddr = make([]byte, 6)
-	pp.Name = make([]byte, 16)
-	return ofpgeneral.UnMarshalFields(buf, &pp.PortNo, &pp.HwAddr, &pp.Name, &pp.config,
-		&pp.state, &pp.Curr, &pp.Advertised, &pp.Supported, &pp.Peer)
+	pp.Name = make([]byte, ofpMaxPortNameLen)
+	return ofpgeneral.UnMarshalFields(buf, &pp.PortNo, &pp.Padding1, &pp.HwAddr, &pp.Padding2,
+		&pp.Name, &pp.Config, &pp.State, &pp.Curr, &pp.Advertised, &pp.Supported, &pp.Peer,
+		&pp.CurrSpeed, &pp.MaxSpeed)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (pp *OfpPhysPort) MarshalBinary() ([]byte, error) {
+	hwAddr := make([]byte, 6)
+	copy(hwAddr, pp.HwAddr)
+	name := make([]byte, ofpMaxPortNameLen)
+	copy(name[:ofpMaxPortNameLen-1], pp.Name)
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, pp.PortNo, pp.HwAddr, pp.Name, pp.config,
-		pp.state, pp.Curr, pp.Advertised, pp.Supported, pp.Peer); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, pp.PortNo, pp.Padding1, hwAddr, pp.Padding2,
+		name, pp.Config, pp.State, pp.Curr, pp.Advertised, pp.Supported, pp.Peer,
+		pp.CurrSpeed, pp.MaxSpeed); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -113,37 +148,43 @@ func (pp *OfpPhysPort) MarshalBinary() ([]byte, error) {
 
 // OfpPortModMsg represents the message layout of a port modification message
 type OfpPortModMsg struct {
-	Header ofpgeneral.OfpHeader
-	PortNo uint16
-	HwAddr net.HardwareAddr /* The hardware address is not
+	Header   ofpgeneral.OfpHeader
+	PortNo   uint32
+	Padding1 [4]byte
+	HwAddr   net.HardwareAddr /* The hardware address is not
 	   configurable.  This is used to
 	   sanity-check the request, so it must
 	   be the same as returned in an
-	   OfpPhyPort struct. */
+	   ofp_port struct. */
+	Padding2 [2]byte
 
 	Config uint32 /* Bitmap of OFPPC_* flags. */
 	Mask   uint32 /* Bitmap of OFPPC_* flags to be changed. */
 
-	Advertise uint32 /* Bitmap of "ofp_port_features"s.  Zero all
-	   bits to prevent any action taking place. */
-	Padding [4]byte /* Pad to 64-bits. */
+	Advertise uint32 /* Bitmap of OFPPF_*.  Zero all bits to prevent
+	   any action taking place. */
+	Padding3 [4]byte /* Pad to 64-bits. */
 }
 
 // UnmarshalBinary transforms the byte array into header data
 func (pmm *OfpPortModMsg) UnmarshalBinary(data []byte) error {
-	if len(data) < 28 {
+	if len(data) < 40 {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &pmm.Header, &pmm.PortNo, pmm.HwAddr,
-		&pmm.Config, &pmm.Mask, &pmm.Advertise)
+	pmm.HwAddr = make([]byte, 6)
+	return ofpgeneral.UnMarshalFields(buf, &pmm.Header, &pmm.PortNo, &pmm.Padding1, &pmm.HwAddr,
+		&pmm.Padding2, &pmm.Config, &pmm.Mask, &pmm.Advertise, &pmm.Padding3)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (pmm *OfpPortModMsg) MarshalBinary() ([]byte, error) {
+	pmm.Header.Length = 40
+	hwAddr := make([]byte, 6)
+	copy(hwAddr, pmm.HwAddr)
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, pmm.Header, pmm.PortNo, pmm.HwAddr,
-		pmm.Config, pmm.Mask, pmm.Advertise); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, pmm.Header, pmm.PortNo, pmm.Padding1, hwAddr,
+		pmm.Padding2, pmm.Config, pmm.Mask, pmm.Advertise, pmm.Padding3); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -160,19 +201,27 @@ type OfpPortStatusMsg struct {
 
 // UnmarshalBinary transforms the byte array into header data
 func (psm *OfpPortStatusMsg) UnmarshalBinary(data []byte) error {
-	if len(data) < 40 {
+	if len(data) < 16+portLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &psm.Header, &psm.Reason, &psm.Padding,
-		&psm.Desc)
+	if err := ofpgeneral.UnMarshalFields(buf, &psm.Header, &psm.Reason, &psm.Padding); err != nil {
+		return err
+	}
+	return (&psm.Desc).UnmarshalBinary(data[16:])
 }
 
 // MarshalBinary converts the header fields into byte array
 func (psm *OfpPortStatusMsg) MarshalBinary() ([]byte, error) {
+	psm.Header.Length = 16 + portLen
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, psm.Header, psm.Reason, psm.Padding, psm.Desc); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, psm.Header, psm.Reason, psm.Padding); err != nil {
+		return nil, err
+	}
+	descData, err := (&psm.Desc).MarshalBinary()
+	if err != nil {
 		return nil, err
 	}
+	buf.Write(descData)
 	return buf.Bytes(), nil
 }
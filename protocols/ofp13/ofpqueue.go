@@ -0,0 +1,256 @@
+package ofp13
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// enum ofp_queue_properties {
+const (
+	OfpQueMinRate      = 1      /* Minimum datarate guaranteed. */
+	OfpQueMaxRate      = 2      /* Maximum datarate. */
+	OfpQueExperimenter = 0xffff /* Experimenter defined property. */
+)
+
+// OfpQueueAll is the queue id which selects all the queues of a port
+const OfpQueueAll = 0xffffffff
+
+const (
+	queuePropHeaderLen = 8
+	packetQueueLen     = 16
+)
+
+// OfpQueuePropHeader represents the header structure of common description for a queue.
+type OfpQueuePropHeader struct {
+	Property uint16  /* One of OFPQT_. */
+	Len      uint16  /* Length of property, including this header. */
+	Padding  [4]byte /* 64-bit alignemnt. */
+}
+
+// UnmarshalBinary transforms the byte array into property header data
+func (qph *OfpQueuePropHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < queuePropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qph)
+}
+
+// MarshalBinary converts the property header fields into byte array
+func (qph *OfpQueuePropHeader) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qph); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpQueuePropRate represents the min-rate and max-rate queue property description.
+type OfpQueuePropRate struct {
+	PropHeader OfpQueuePropHeader /* prop: OFPQT_MIN_RATE/OFPQT_MAX_RATE, len: 16. */
+	Rate       uint16             /* In 1/10 of a percent; >1000 -> disabled. */
+	Padding    [6]byte            /* 64-bit alignment */
+}
+
+// UnmarshalBinary transforms the byte array into rate property data
+func (qpr *OfpQueuePropRate) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qpr)
+}
+
+// MarshalBinary converts the rate property fields into byte array
+func (qpr *OfpQueuePropRate) MarshalBinary() ([]byte, error) {
+	qpr.PropHeader.Len = 16
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qpr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpQueuePropExperimenter represents the experimenter queue property description.
+type OfpQueuePropExperimenter struct {
+	PropHeader   OfpQueuePropHeader /* prop: OFPQT_EXPERIMENTER, len: 16. */
+	Experimenter uint32             /* Experimenter ID which takes the same form as in struct ofp_experimenter_header. */
+	Padding      [4]byte            /* 64-bit alignment */
+	Data         []byte             /* Experimenter defined data. */
+}
+
+// UnmarshalBinary transforms the byte array into experimenter property data
+func (qpe *OfpQueuePropExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &qpe.PropHeader, &qpe.Experimenter, &qpe.Padding); err != nil {
+		return err
+	}
+	if int(qpe.PropHeader.Len) < 16 || int(qpe.PropHeader.Len) > len(data) {
+		return fmt.Errorf("Invalid queue property length %d", qpe.PropHeader.Len)
+	}
+	qpe.Data = make([]byte, qpe.PropHeader.Len-16)
+	copy(qpe.Data, data[16:qpe.PropHeader.Len])
+	return nil
+}
+
+// MarshalBinary converts the experimenter property fields into byte array
+func (qpe *OfpQueuePropExperimenter) MarshalBinary() ([]byte, error) {
+	qpe.PropHeader.Len = uint16(16 + len(qpe.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qpe.PropHeader, qpe.Experimenter, qpe.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(qpe.Data)
+	return buf.Bytes(), nil
+}
+
+// OfpPacketQueue represents the full description for a queue.
+type OfpPacketQueue struct {
+	QueueID uint32  /* id for the specific queue. */
+	Port    uint32  /* Port this queue is attached to. */
+	Len     uint16  /* Length in bytes of this queue desc. */
+	Padding [6]byte /* 64-bit alignment. */
+	// Properties holds *OfpQueuePropRate for the min and max rate
+	// properties, *OfpQueuePropExperimenter for the experimenter
+	// property and *OfpQueuePropHeader for the unknown properties
+	Properties []ofpgeneral.OfpMessage /* List of properties. */
+}
+
+// UnmarshalBinary transforms the byte array into queue description data
+func (pq *OfpPacketQueue) UnmarshalBinary(data []byte) error {
+	if len(data) < packetQueueLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &pq.QueueID, &pq.Port, &pq.Len, &pq.Padding); err != nil {
+		return err
+	}
+	if int(pq.Len) < packetQueueLen || int(pq.Len) > len(data) {
+		return fmt.Errorf("Invalid queue description length %d", pq.Len)
+	}
+	pq.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := packetQueueLen; idx+queuePropHeaderLen <= int(pq.Len); {
+		propHeader := OfpQueuePropHeader{}
+		if err := propHeader.UnmarshalBinary(data[idx:pq.Len]); err != nil {
+			return err
+		}
+		if propHeader.Len < queuePropHeaderLen || idx+int(propHeader.Len) > int(pq.Len) {
+			return fmt.Errorf("Invalid queue property length %d", propHeader.Len)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch propHeader.Property {
+		case OfpQueMinRate, OfpQueMaxRate:
+			prop = &OfpQueuePropRate{}
+		case OfpQueExperimenter:
+			prop = &OfpQueuePropExperimenter{}
+		default:
+			prop = &OfpQueuePropHeader{}
+		}
+		if err := prop.UnmarshalBinary(data[idx : idx+int(propHeader.Len)]); err != nil {
+			return err
+		}
+		pq.Properties = append(pq.Properties, prop)
+		idx += int(propHeader.Len)
+	}
+	return nil
+}
+
+// MarshalBinary converts the queue description fields into byte array,
+// the length is set according to the properties
+func (pq *OfpPacketQueue) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range pq.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	pq.Len = uint16(packetQueueLen + propBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, pq.QueueID, pq.Port, pq.Len, pq.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// OfpQueueGetConfReqMsg represents the query msg for port queue configuration.
+type OfpQueueGetConfReqMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Port    uint32  /* Port to be queried. Should refer to a valid physical port (i.e. <= OFPP_MAX), or OFPP_ANY to request all configured queues.*/
+	Padding [4]byte /* 64-bit alignment. */
+}
+
+// UnmarshalBinary transforms the byte array into queue config request data
+func (qcr *OfpQueueGetConfReqMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, qcr)
+}
+
+// MarshalBinary converts the queue config request fields into byte array
+func (qcr *OfpQueueGetConfReqMsg) MarshalBinary() ([]byte, error) {
+	qcr.Header.Length = 16
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qcr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpQueueGetConfReplyMsg represents queue configuration for a given port.
+type OfpQueueGetConfReplyMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Port    uint32
+	Padding [4]byte
+	Queues  []OfpPacketQueue /* List of configured queues. */
+}
+
+// UnmarshalBinary transforms the byte array into queue config reply data
+func (qcr *OfpQueueGetConfReplyMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &qcr.Header, &qcr.Port, &qcr.Padding); err != nil {
+		return err
+	}
+	qcr.Queues = make([]OfpPacketQueue, 0)
+	for idx := 16; idx+packetQueueLen <= len(data); {
+		queue := OfpPacketQueue{}
+		if err := queue.UnmarshalBinary(data[idx:]); err != nil {
+			return err
+		}
+		qcr.Queues = append(qcr.Queues, queue)
+		idx += int(queue.Len)
+	}
+	return nil
+}
+
+// MarshalBinary converts the queue config reply fields into byte array,
+// the length in the header is set according to the queues
+func (qcr *OfpQueueGetConfReplyMsg) MarshalBinary() ([]byte, error) {
+	queueBuf := new(bytes.Buffer)
+	for i := range qcr.Queues {
+		queueData, err := (&qcr.Queues[i]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		queueBuf.Write(queueData)
+	}
+	qcr.Header.Length = uint16(16 + queueBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, qcr.Header, qcr.Port, qcr.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(queueBuf.Bytes())
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package ofp13
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Controller roles.
+// enum ofp_controller_role {
+const (
+	OfpControllerRoleNoChange = iota /* Don't change current role. */
+	OfpControllerRoleEqual           /* Default role, full access. */
+	OfpControllerRoleMaster          /* Full access, at most one master. */
+	OfpControllerRoleSlave           /* Read-only access. */
+)
+
+// ofp_error_msg 'code' values for OFPET_ROLE_REQUEST_FAILED. 'data' contains
+// at least the first 64 bytes of the failed request.
+// enum ofp_role_request_failed_code {
+const (
+	OfpRoleRequestFailedStale   = iota /* Stale Message: old generation_id. */
+	OfpRoleRequestFailedUnsup          /* Controller role change unsupported. */
+	OfpRoleRequestFailedBadRole        /* Invalid role. */
+)
+
+const (
+	roleRequestLen = 24
+)
+
+// OfpRoleRequestMsg represents the role request and reply message
+type OfpRoleRequestMsg struct {
+	Header       ofpgeneral.OfpHeader
+	Role         uint32 /* One of OFPCR_ROLE_*. */
+	Padding      [4]byte
+	GenerationID uint64 /* Master Election Generation Id */
+}
+
+// NewRoleRequestMsg creates the request of the controller role, the
+// generation id is ignored by the switch for the equal role
+func NewRoleRequestMsg(role uint32, generationID uint64) *OfpRoleRequestMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeRoleRequest
+	return &OfpRoleRequestMsg{Header: *header, Role: role, GenerationID: generationID}
+}
+
+// UnmarshalBinary transforms the byte array into role request data
+func (rr *OfpRoleRequestMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < roleRequestLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, &rr.Header, &rr.Role, &rr.Padding, &rr.GenerationID)
+}
+
+// MarshalBinary converts the role request fields into byte array
+func (rr *OfpRoleRequestMsg) MarshalBinary() ([]byte, error) {
+	rr.Header.Length = roleRequestLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, rr.Header, rr.Role, rr.Padding, rr.GenerationID); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
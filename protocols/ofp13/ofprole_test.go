@@ -0,0 +1,21 @@
+package ofp13
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+func TestRoleRequestWireFormat(t *testing.T) {
+	msg := NewRoleRequestMsg(OfpControllerRoleMaster, 9)
+	msg.Header.Xid = 1
+	data := wiretest.Decode(t, "0418001800000001 00000002 00000000 0000000000000009")
+	wiretest.CheckEncoding(t, msg, data)
+	decoded := &OfpRoleRequestMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Role != OfpControllerRoleMaster || decoded.GenerationID != 9 {
+		t.Errorf("Unexpected role request %+v", decoded)
+	}
+}
@@ -0,0 +1,356 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	descStrLen   = 256
+	serialNumLen = 32
+
+	descLen                = 4*descStrLen + serialNumLen
+	flowStatsRequestLen    = 32
+	flowStatsHeaderLen     = 48
+	aggregateStatsReplyLen = 24
+	tableStatsLen          = 24
+	portStatsRequestLen    = 8
+	portStatsLen           = 112
+	queueStatsRequestLen   = 8
+	queueStatsLen          = 40
+)
+
+// OfpDesc represents the body of reply to OFPMP_DESC request. Each entry is a
+// null-terminated ASCII string.
+type OfpDesc struct {
+	ManufacurerDesc [descStrLen]byte   /* Manufacturer description. */
+	HwDesc          [descStrLen]byte   /* Hardware description. */
+	SwDesc          [descStrLen]byte   /* Software description. */
+	SerialNum       [serialNumLen]byte /* Serial number. */
+	DatapathDesc    [descStrLen]byte   /* Human readable description of datapath. */
+}
+
+// NewDescRequestMsg creates the request of the switch description
+func NewDescRequestMsg() *OfpMultipartRequestMsg {
+	return NewMultipartRequestMsg(OfpMultipartTypeDesc, nil)
+}
+
+// UnmarshalBinary transforms the byte array into desc data
+func (d *OfpDesc) UnmarshalBinary(data []byte) error {
+	if len(data) < descLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, d)
+}
+
+// MarshalBinary converts the desc fields into byte array
+func (d *OfpDesc) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpFlowStatsRequest represents the body for ofp_multipart_request of type
+// OFPMP_FLOW, the body of OFPMP_AGGREGATE request shares the same layout.
+type OfpFlowStatsRequest struct {
+	TableID  uint8 /* ID of table to read (from ofp_table_stats), OFPTT_ALL for all tables. */
+	Padding1 [3]byte
+	OutPort  uint32 /* Require matching entries to include this
+	   as an output port.  A value of OFPP_ANY
+	   indicates no restriction. */
+	OutGroup uint32 /* Require matching entries to include this
+	   as an output group.  A value of OFPG_ANY
+	   indicates no restriction. */
+	Padding2   [4]byte
+	Cookie     uint64 /* Require matching entries to contain this cookie value */
+	CookieMask uint64 /* Mask used to restrict the cookie bits that
+	   must match. A value of 0 indicates
+	   no restriction. */
+	Match OfpMatch /* Fields to match. Variable size. */
+}
+
+// NewFlowStatsRequestMsg creates the request of the individual flow stats
+// in the table, a nil match selects all the flows
+func NewFlowStatsRequestMsg(tableID uint8, match *OfpMatch) (*OfpMultipartRequestMsg, error) {
+	return newFlowStatsRequestMsg(OfpMultipartTypeFlow, tableID, match)
+}
+
+// NewAggregateStatsRequestMsg creates the request of the aggregate flow stats
+// in the table, a nil match selects all the flows
+func NewAggregateStatsRequestMsg(tableID uint8, match *OfpMatch) (*OfpMultipartRequestMsg, error) {
+	return newFlowStatsRequestMsg(OfpMultipartTypeAggregate, tableID, match)
+}
+
+func newFlowStatsRequestMsg(mpType uint16, tableID uint8, match *OfpMatch) (*OfpMultipartRequestMsg, error) {
+	if match == nil {
+		match = NewOfpMatch()
+	}
+	req := &OfpFlowStatsRequest{TableID: tableID, OutPort: OfpPortAny, OutGroup: OfpGroupAny, Match: *match}
+	body, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return NewMultipartRequestMsg(mpType, body), nil
+}
+
+// UnmarshalBinary transforms the byte array into flow stats request data
+func (fsr *OfpFlowStatsRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsRequestLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fsr.TableID, &fsr.Padding1, &fsr.OutPort, &fsr.OutGroup,
+		&fsr.Padding2, &fsr.Cookie, &fsr.CookieMask); err != nil {
+		return err
+	}
+	return (&fsr.Match).UnmarshalBinary(data[flowStatsRequestLen:])
+}
+
+// MarshalBinary converts the flow stats request fields into byte array
+func (fsr *OfpFlowStatsRequest) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fsr.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fsr.TableID, fsr.Padding1, fsr.OutPort, fsr.OutGroup,
+		fsr.Padding2, fsr.Cookie, fsr.CookieMask); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	return buf.Bytes(), nil
+}
+
+// OfpFlowStats represents the body of reply to OFPMP_FLOW request.
+type OfpFlowStats struct {
+	Length          uint16 /* Length of this entry. */
+	TableID         uint8  /* ID of table flow came from. */
+	Padding1        byte
+	DurationSec     uint32 /* Time flow has been alive in seconds. */
+	DurationNanoSec uint32 /* Time flow has been alive in nanoseconds beyond
+	   duration_sec. */
+	Priority     uint16 /* Priority of the entry. */
+	IdleTimeout  uint16 /* Number of seconds idle before expiration. */
+	HardTimeout  uint16 /* Number of seconds before expiration. */
+	Flags        uint16 /* One of OFPFF_*. */
+	Padding2     [4]byte
+	Cookie       uint64           /* Opaque controller-issued identifier. */
+	PacketCount  uint64           /* Number of packets in flow. */
+	ByteCount    uint64           /* Number of bytes in flow. */
+	Match        OfpMatch         /* Description of fields. Variable size. */
+	Instructions []OfpInstruction /* Instruction set - 0 or more. */
+}
+
+// UnmarshalBinary transforms the byte array into flow stats data
+func (fs *OfpFlowStats) UnmarshalBinary(data []byte) error {
+	if len(data) < flowStatsHeaderLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fs.Length, &fs.TableID, &fs.Padding1, &fs.DurationSec,
+		&fs.DurationNanoSec, &fs.Priority, &fs.IdleTimeout, &fs.HardTimeout, &fs.Flags, &fs.Padding2,
+		&fs.Cookie, &fs.PacketCount, &fs.ByteCount); err != nil {
+		return err
+	}
+	if fs.Length < flowStatsHeaderLen+ofpMatchHeaderLen || int(fs.Length) > len(data) {
+		return fmt.Errorf("Invalid flow stats length %d", fs.Length)
+	}
+	if err := (&fs.Match).UnmarshalBinary(data[flowStatsHeaderLen:fs.Length]); err != nil {
+		return err
+	}
+	instructionIdx := flowStatsHeaderLen + int(fs.Match.Len())
+	if instructionIdx > int(fs.Length) {
+		return fmt.Errorf("Invalid flow stats length %d", fs.Length)
+	}
+	instructions, err := ParseInstructions(data[instructionIdx:fs.Length])
+	if err != nil {
+		return err
+	}
+	fs.Instructions = instructions
+	return nil
+}
+
+// MarshalBinary converts the flow stats fields into byte array, the length
+// is set according to the match and the instructions
+func (fs *OfpFlowStats) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fs.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	instructionsData, err := marshalInstructions(fs.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	fs.Length = uint16(flowStatsHeaderLen + len(matchData) + len(instructionsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fs.Length, fs.TableID, fs.Padding1, fs.DurationSec,
+		fs.DurationNanoSec, fs.Priority, fs.IdleTimeout, fs.HardTimeout, fs.Flags, fs.Padding2,
+		fs.Cookie, fs.PacketCount, fs.ByteCount); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(instructionsData)
+	return buf.Bytes(), nil
+}
+
+// ParseFlowStatsBody decodes the body of the flow stats reply
+func ParseFlowStatsBody(body []byte) ([]OfpFlowStats, error) {
+	stats := make([]OfpFlowStats, 0)
+	for idx := 0; idx < len(body); {
+		flowStats := OfpFlowStats{}
+		if err := flowStats.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		stats = append(stats, flowStats)
+		idx += int(flowStats.Length)
+	}
+	return stats, nil
+}
+
+// OfpAggregateStatsReply represents the body of reply to OFPMP_AGGREGATE request.
+type OfpAggregateStatsReply struct {
+	PacketCount uint64 /* Number of packets in flows. */
+	ByteCount   uint64 /* Number of bytes in flows. */
+	FlowCount   uint32 /* Number of flows. */
+	Padding     [4]byte
+}
+
+// UnmarshalBinary transforms the byte array into aggregate stats reply data
+func (asr *OfpAggregateStatsReply) UnmarshalBinary(data []byte) error {
+	if len(data) < aggregateStatsReplyLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, asr)
+}
+
+// MarshalBinary converts the aggregate stats reply fields into byte array
+func (asr *OfpAggregateStatsReply) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, asr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpTableStats represents the body of reply to OFPMP_TABLE request.
+type OfpTableStats struct {
+	TableID      uint8 /* Identifier of table.  Lower numbered tables are consulted first. */
+	Padding      [3]byte
+	ActiveCount  uint32 /* Number of active entries. */
+	LookupCount  uint64 /* Number of packets looked up in table. */
+	MatchedCount uint64 /* Number of packets that hit table. */
+}
+
+// NewTableStatsRequestMsg creates the request of the flow table stats
+func NewTableStatsRequestMsg() *OfpMultipartRequestMsg {
+	return NewMultipartRequestMsg(OfpMultipartTypeTable, nil)
+}
+
+// ParseTableStatsBody decodes the body of the table stats reply
+func ParseTableStatsBody(body []byte) ([]OfpTableStats, error) {
+	if len(body)%tableStatsLen != 0 {
+		return nil, fmt.Errorf("The table stats size %d is not a multiple of %d", len(body), tableStatsLen)
+	}
+	stats := make([]OfpTableStats, len(body)/tableStatsLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// OfpPortStatsRequest represents the body for ofp_multipart_request of type OFPMP_PORT.
+type OfpPortStatsRequest struct {
+	PortNo uint32 /* OFPMP_PORT message must request statistics
+	 * either for a single port (specified in
+	 * port_no) or for all ports (if port_no ==
+	 * OFPP_ANY). */
+	Padding [4]byte
+}
+
+// NewPortStatsRequestMsg creates the request of the port stats, OFPP_ANY
+// selects all the ports
+func NewPortStatsRequestMsg(portNo uint32) *OfpMultipartRequestMsg {
+	body := make([]byte, portStatsRequestLen)
+	binary.BigEndian.PutUint32(body, portNo)
+	return NewMultipartRequestMsg(OfpMultipartTypePortStats, body)
+}
+
+// OfpPortStats represents the body of reply to OFPMP_PORT request. If a counter is
+// unsupported, set the field to all ones.
+type OfpPortStats struct {
+	PortNo          uint32
+	Padding         [4]byte
+	RxPackets       uint64 /* Number of received packets. */
+	TxPackets       uint64 /* Number of transmitted packets. */
+	RxBytes         uint64 /* Number of received bytes. */
+	TxBytes         uint64 /* Number of transmitted bytes. */
+	RxDropped       uint64 /* Number of packets dropped by RX. */
+	TxDropped       uint64 /* Number of packets dropped by TX. */
+	RxErrors        uint64 /* Number of receive errors. */
+	TxErrors        uint64 /* Number of transmit errors. */
+	RxFrameErr      uint64 /* Number of frame alignment errors. */
+	RxOverErr       uint64 /* Number of packets with RX overrun. */
+	RxCrcErr        uint64 /* Number of CRC errors. */
+	Collisions      uint64 /* Number of collisions. */
+	DurationSec     uint32 /* Time port has been alive in seconds. */
+	DurationNanoSec uint32 /* Time port has been alive in nanoseconds beyond
+	   duration_sec. */
+}
+
+// ParsePortStatsBody decodes the body of the port stats reply
+func ParsePortStatsBody(body []byte) ([]OfpPortStats, error) {
+	if len(body)%portStatsLen != 0 {
+		return nil, fmt.Errorf("The port stats size %d is not a multiple of %d", len(body), portStatsLen)
+	}
+	stats := make([]OfpPortStats, len(body)/portStatsLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// OfpQueueStatsRequest represents the body for ofp_multipart_request of type OFPMP_QUEUE.
+type OfpQueueStatsRequest struct {
+	PortNo  uint32 /* All ports if OFPP_ANY. */
+	QueueID uint32 /* All queues if OFPQ_ALL. */
+}
+
+// NewQueueStatsRequestMsg creates the request of the queue stats, OFPP_ANY
+// and OFPQ_ALL select all the ports and queues
+func NewQueueStatsRequestMsg(portNo uint32, queueID uint32) *OfpMultipartRequestMsg {
+	body := make([]byte, queueStatsRequestLen)
+	binary.BigEndian.PutUint32(body, portNo)
+	binary.BigEndian.PutUint32(body[4:], queueID)
+	return NewMultipartRequestMsg(OfpMultipartTypeQueue, body)
+}
+
+// OfpQueueStats represents the body of reply to OFPMP_QUEUE request.
+type OfpQueueStats struct {
+	PortNo          uint32
+	QueueID         uint32 /* Queue i.d */
+	TxBytes         uint64 /* Number of transmitted bytes. */
+	TxPackets       uint64 /* Number of transmitted packets. */
+	TxErrors        uint64 /* Number of packets dropped due to overrun. */
+	DurationSec     uint32 /* Time queue has been alive in seconds. */
+	DurationNanoSec uint32 /* Time queue has been alive in nanoseconds beyond
+	   duration_sec. */
+}
+
+// ParseQueueStatsBody decodes the body of the queue stats reply
+func ParseQueueStatsBody(body []byte) ([]OfpQueueStats, error) {
+	if len(body)%queueStatsLen != 0 {
+		return nil, fmt.Errorf("The queue stats size %d is not a multiple of %d", len(body), queueStatsLen)
+	}
+	stats := make([]OfpQueueStats, len(body)/queueStatsLen)
+	if err := ofpgeneral.UnMarshalFields(bytes.NewReader(body), stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
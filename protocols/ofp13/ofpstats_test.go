@@ -0,0 +1,136 @@
+package ofp13
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// emptyMatchWire is the match of all the packets including the padding
+const emptyMatchWire = "0001 0004 00000000"
+
+func TestStatsRequestsWireFormat(t *testing.T) {
+	flowStats, err := NewFlowStatsRequestMsg(OfpTableAll, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggregate, err := NewAggregateStatsRequestMsg(1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		msg  *OfpMultipartRequestMsg
+		wire string
+	}{
+		{"desc", NewDescRequestMsg(), "0412001000000001 0000 0000 00000000"},
+		{"flow stats", flowStats, "0412003800000001 0001 0000 00000000" +
+			"ff 000000 ffffffff ffffffff 00000000 0000000000000000 0000000000000000" + emptyMatchWire},
+		{"aggregate stats", aggregate, "0412003800000001 0002 0000 00000000" +
+			"01 000000 ffffffff ffffffff 00000000 0000000000000000 0000000000000000" + emptyMatchWire},
+		{"table stats", NewTableStatsRequestMsg(), "0412001000000001 0003 0000 00000000"},
+		{"port stats", NewPortStatsRequestMsg(OfpPortAny), "0412001800000001 0004 0000 00000000 ffffffff 00000000"},
+		{"queue stats", NewQueueStatsRequestMsg(1, OfpQueueAll),
+			"0412001800000001 0005 0000 00000000 00000001 ffffffff"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.msg.Header.Xid = 1
+			wiretest.CheckEncoding(t, tc.msg, wiretest.Decode(t, tc.wire))
+		})
+	}
+}
+
+func TestDecodeStatsReply(t *testing.T) {
+	descWire := func(s string, size int) string {
+		return strings.Repeat("61", len(s)) + strings.Repeat("00", size-len(s))
+	}
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, body interface{})
+	}{
+		{"desc", "0413043000000001 0000 0000 00000000" + descWire("aaaa", 256) + descWire("aa", 256) +
+			descWire("a", 256) + descWire("aaa", 32) + descWire("", 256),
+			func(t *testing.T, body interface{}) {
+				desc := body.(*OfpDesc)
+				if string(desc.ManufacurerDesc[:5]) != "aaaa\x00" || string(desc.SerialNum[:4]) != "aaa\x00" {
+					t.Errorf("Unexpected desc %+v", desc)
+				}
+			}},
+		{"flow stats", "0413006800000002 0001 0000 00000000" +
+			"0058 01 00 0000000a 00000014 8000 003c 0000 0001 00000000" +
+			"0000000000000007 0000000000000005 00000000000001f4" + inPortMatchWire +
+			"0004 0018 00000000 0000 0010 00000002 ffff 000000000000",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpFlowStats)
+				if len(stats) != 1 || stats[0].Length != 88 || stats[0].TableID != 1 || stats[0].Priority != 0x8000 ||
+					stats[0].IdleTimeout != 60 || stats[0].Flags != OfpFlowFlagSendFlowRemove || stats[0].Cookie != 7 ||
+					stats[0].PacketCount != 5 || stats[0].ByteCount != 500 || len(stats[0].Instructions) != 1 {
+					t.Fatalf("Unexpected flow stats %+v", stats)
+				}
+				if inPort, ok := stats[0].Match.OXMFields.GetInPort(); !ok || inPort != 3 {
+					t.Errorf("Unexpected match %+v", stats[0].Match)
+				}
+			}},
+		{"aggregate stats", "0413002800000003 0002 0000 00000000 000000000000000a 00000000000003e8 00000002 00000000",
+			func(t *testing.T, body interface{}) {
+				if agg := body.(*OfpAggregateStatsReply); agg.PacketCount != 10 || agg.ByteCount != 1000 ||
+					agg.FlowCount != 2 {
+					t.Errorf("Unexpected aggregate stats %+v", agg)
+				}
+			}},
+		{"table stats", "0413002800000004 0003 0000 00000000 00 000000 00000005 0000000000000064 0000000000000032",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpTableStats)
+				if len(stats) != 1 || stats[0].ActiveCount != 5 || stats[0].LookupCount != 100 ||
+					stats[0].MatchedCount != 50 {
+					t.Errorf("Unexpected table stats %+v", stats)
+				}
+			}},
+		{"queue stats", "0413003800000005 0005 0000 00000000 00000001 00000002" +
+			"0000000000000064 000000000000000a 0000000000000001 0000000a 00000014",
+			func(t *testing.T, body interface{}) {
+				stats := body.([]OfpQueueStats)
+				if len(stats) != 1 || stats[0].PortNo != 1 || stats[0].QueueID != 2 || stats[0].TxBytes != 100 ||
+					stats[0].TxPackets != 10 || stats[0].TxErrors != 1 || stats[0].DurationSec != 10 {
+					t.Errorf("Unexpected queue stats %+v", stats)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := wiretest.Parse(t, &OfpMessageParser{}, tc.wire).(*OfpMultipartReplyMsg)
+			body, err := reply.DecodeBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, body)
+		})
+	}
+}
+
+func TestDecodeStatsReplyInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		mpType uint16
+		body   string
+	}{
+		{"truncated desc", OfpMultipartTypeDesc, "61616161"},
+		{"flow stats beyond the body", OfpMultipartTypeFlow, "0068 01 00 0000000a 00000014 8000 003c 0000 0001 00000000" +
+			"0000000000000007 0000000000000005 00000000000001f4" + inPortMatchWire},
+		{"partial table stats", OfpMultipartTypeTable, "00 000000 00000005 0000000000000064"},
+		{"partial queue stats", OfpMultipartTypeQueue, "00000001 00000002 0000000000000064"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reply := &OfpMultipartReplyMsg{Header: *ofpgeneral.NewOfpHeader(Version), Type: tc.mpType,
+				Body: wiretest.Decode(t, tc.body)}
+			if body, err := reply.DecodeBody(); err == nil {
+				t.Fatalf("The body is decoded as %+v", body)
+			}
+		})
+	}
+}
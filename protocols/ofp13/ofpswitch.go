@@ -16,35 +16,30 @@ type OfpSwitchFeatureMsg struct {
 
 	NoOfBuffers uint32 /* Max packets buffered at once. */
 
-	NoOfTables uint8 /* Number of tables supported by datapath. */
-
-	Padding [3]byte /* Align to 64-bits. */
+	NoOfTables  uint8   /* Number of tables supported by datapath. */
+	AuxiliaryID uint8   /* Identify auxiliary connections */
+	Padding     [2]byte /* Align to 64-bits. */
 
 	/* Features. */
 	Capabilities uint32 /* Bitmap of support "ofp_capabilities". */
-	Actions      uint32 /* Bitmap of supported "ofp_action_type"s. */
-
-	/* Port info.*/
-	Ports []OfpPhysPort /* Port definitions.  The number of ports
-	   is inferred from the length field in
-	   the header. */
+	Reserved     uint32
 }
 
 // UnmarshalBinary transforms the byte array into header data
 func (sf *OfpSwitchFeatureMsg) UnmarshalBinary(data []byte) error {
-	if len(data) < 28 {
+	if len(data) < 32 {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
 	return ofpgeneral.UnMarshalFields(buf, &sf.Header, &sf.DatapathID, &sf.NoOfBuffers,
-		&sf.NoOfTables, &sf.Padding, &sf.Capabilities, &sf.Actions, &sf.Ports)
+		&sf.NoOfTables, &sf.AuxiliaryID, &sf.Padding, &sf.Capabilities, &sf.Reserved)
 }
 
 // MarshalBinary converts the header fields into byte array
 func (sf *OfpSwitchFeatureMsg) MarshalBinary() ([]byte, error) {
 	buf := new(bytes.Buffer)
 	if err := ofpgeneral.MarshalFields(buf, sf.Header, sf.DatapathID, sf.NoOfBuffers,
-		sf.NoOfTables, sf.Padding, sf.Capabilities, sf.Actions, sf.Ports); err != nil {
+		sf.NoOfTables, sf.AuxiliaryID, sf.Padding, sf.Capabilities, sf.Reserved); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
@@ -60,7 +55,7 @@ type OfpSwitchConfigMsg struct {
 
 // UnmarshalBinary transforms the byte array into header data
 func (sc *OfpSwitchConfigMsg) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+	if len(data) < 12 {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
@@ -69,9 +64,43 @@ func (sc *OfpSwitchConfigMsg) UnmarshalBinary(data []byte) error {
 
 // MarshalBinary converts the header fields into byte array
 func (sc *OfpSwitchConfigMsg) MarshalBinary() ([]byte, error) {
+	sc.Header.Length = 12
 	buf := new(bytes.Buffer)
 	if err := ofpgeneral.MarshalFields(buf, sc.Header, sc.Flags, sc.MissSendLen); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
+
+// Flags to configure the table. Reserved for future use.
+// enum ofp_table_config {
+const (
+	OfpTableConfigDeprecatedMask = 3 /* Deprecated bits */
+)
+
+// OfpTableModMsg represents the configure/modify behavior of a flow table
+type OfpTableModMsg struct {
+	Header  ofpgeneral.OfpHeader
+	TableID uint8   /* ID of the table, OFPTT_ALL indicates all tables */
+	Padding [3]byte /* Pad to 32 bits */
+	Config  uint32  /* Bitmap of OFPTC_* flags */
+}
+
+// UnmarshalBinary transforms the byte array into table mod data
+func (tm *OfpTableModMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, tm)
+}
+
+// MarshalBinary converts the table mod fields into byte array
+func (tm *OfpTableModMsg) MarshalBinary() ([]byte, error) {
+	tm.Header.Length = 16
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, tm); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
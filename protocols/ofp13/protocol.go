@@ -2,6 +2,7 @@ package ofp13
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
@@ -23,14 +24,13 @@ const (
 // Ofp Capability flags
 // Capabilities supported by the datapath.
 const (
-	OfpCapFlowStats  = 1 << iota /* Flow statistics. */
-	OfpCapTableStats             /* Table statistics. */
-	OfpCapPortStats              /* Port statistics. */
-	OfpCapSTP                    /* 802.1d spanning tree. */
-	OfpCapReserved               /* Reserved, must be zero. */
-	OfpCapIPReAsm                /* Can reassemble IP fragments. */
-	OfpQueueStats                /* Queue statistics. */
-	OfpArpMatchIP                /* Match IP addresses in ARP pkts. */
+	OfpCapFlowStats   = 1 << 0 /* Flow statistics. */
+	OfpCapTableStats  = 1 << 1 /* Table statistics. */
+	OfpCapPortStats   = 1 << 2 /* Port statistics. */
+	OfpCapGroupStats  = 1 << 3 /* Group statistics. */
+	OfpCapIPReAsm     = 1 << 5 /* Can reassemble IP fragments. */
+	OfpCapQueueStats  = 1 << 6 /* Queue statistics. */
+	OfpCapPortBlocked = 1 << 8 /* Switch will block looping ports. */
 )
 
 // The OFP Type constants
@@ -89,19 +89,28 @@ const (
 // Values for 'type' in ofp_error_message.  These values are immutable: they
 // will not change in future versions of the protocol (although new values may
 // be added).
-//enum ofp_error_type {
+// enum ofp_error_type {
 const (
-	OfpErrTypeHelloFailed   = iota /* Hello protocol failed. */
-	OfpErrTypeBadRequest           /* Request was not understood. */
-	OfpErrTypeBadAction            /* Error in action description. */
-	OfpErrTypeFlowModFailed        /* Problem modifying flow entry. */
-	OfpErrTypePortModFailed        /* Port mod request failed. */
-	OfpErrTypeQueueOpFailed        /* Queue operation failed. */
+	OfpErrTypeHelloFailed         = iota   /* Hello protocol failed. */
+	OfpErrTypeBadRequest                   /* Request was not understood. */
+	OfpErrTypeBadAction                    /* Error in action description. */
+	OfpErrTypeBadInstruction               /* Error in instruction list. */
+	OfpErrTypeBadMatch                     /* Error in match. */
+	OfpErrTypeFlowModFailed                /* Problem modifying flow entry. */
+	OfpErrTypeGroupModFailed               /* Problem modifying group entry. */
+	OfpErrTypePortModFailed                /* Port mod request failed. */
+	OfpErrTypeTableModFailed               /* Table mod request failed. */
+	OfpErrTypeQueueOpFailed                /* Queue operation failed. */
+	OfpErrTypeSwitchConfigFailed           /* Switch config request failed. */
+	OfpErrTypeRoleRequestFailed            /* Controller Role request failed. */
+	OfpErrTypeMeterModFailed               /* Error in meter. */
+	OfpErrTypeTableFeaturesFailed          /* Setting table features failed. */
+	OfpErrTypeExperimenter        = 0xffff /* Experimenter error messages. */
 )
 
 // ofp_error_msg 'code' values for OFPET_HELLO_FAILED.  'data' contains an
 // ASCII text string that may give failure details. */
-//enum ofp_hello_failed_code {
+// enum ofp_hello_failed_code {
 const (
 	OfpHelloFaildCodeIncompatioble = iota /* No compatible version. */
 	OfpHelloFaildCodeErrPerm              /* Permissions error. */
@@ -109,17 +118,22 @@ const (
 
 // ofp_error_msg 'code' values for OFPET_BAD_REQUEST.  'data' contains at least
 // the first 64 bytes of the failed request.
-//enum ofp_bad_request_code {
+// enum ofp_bad_request_code {
 const (
-	OfpBadReqCodeBadVersion    = iota /* ofp_header.version not supported. */
-	OfpBadReqCodeBadType              /* ofp_header.type not supported. */
-	OfpBadReqCodeBadStat              /* ofp_stats_request.type not supported. */
-	OfpBadReqCodeBadVendor            /* Vendor not supported (in ofp_vendor_header or ofp_stats_request or ofp_stats_reply). */
-	OfpBadReqCodeBadSubType           /* Vendor subtype not supported. */
-	OfpBadReqCodeErrPerm              /* Permissions error. */
-	OfpBadReqCodeBadLen               /* Wrong request length for type. */
-	OfpBadReqCodeBufferEmpty          /* Specified buffer has already been used. */
-	OfpBadReqCodeBufferUnknown        /* Specified buffer does not exist. */
+	OfpBadReqCodeBadVersion           = iota /* ofp_header.version not supported. */
+	OfpBadReqCodeBadType                     /* ofp_header.type not supported. */
+	OfpBadReqCodeBadMultipart                /* ofp_multipart_request.type not supported. */
+	OfpBadReqCodeBadExperimenter             /* Experimenter id not supported (in ofp_experimenter_header or ofp_multipart_request or ofp_multipart_reply). */
+	OfpBadReqCodeBadExpType                  /* Experimenter type not supported. */
+	OfpBadReqCodeErrPerm                     /* Permissions error. */
+	OfpBadReqCodeBadLen                      /* Wrong request length for type. */
+	OfpBadReqCodeBufferEmpty                 /* Specified buffer has already been used. */
+	OfpBadReqCodeBufferUnknown               /* Specified buffer does not exist. */
+	OfpBadReqCodeBadTableID                  /* Specified table-id invalid or does not exist. */
+	OfpBadReqCodeIsSlave                     /* Denied because controller is slave. */
+	OfpBadReqCodeBadPort                     /* Invalid port. */
+	OfpBadReqCodeBadPacket                   /* Invalid packet in packet-out. */
+	OfpBadReqCodeMultipartBufOverflow        /* ofp_multipart_request overflowed the assigned buffer. */
 )
 
 // ofp_error msg 'code' values for OFPET_QUEUE_OP_FAILED. 'data' contains
@@ -131,48 +145,84 @@ const (
 	OfpQueFailedCodeErrPerm        /* Permissions error. */
 )
 
+// ofp_error_msg 'code' values for OFPET_SWITCH_CONFIG_FAILED.  'data' contains
+// at least the first 64 bytes of the failed request.
+// enum ofp_switch_config_failed_code {
+const (
+	OfpSwitchConfigFailedCodeBadFlags = iota /* Specified flags is invalid. */
+	OfpSwitchConfigFailedCodeBadLen          /* Specified len is invalid. */
+	OfpSwitchConfigFailedCodeErrPerm         /* Permissions error. */
+)
+
+// Why is this packet being sent to the controller?
+// enum ofp_packet_in_reason {
+const (
+	OfpPacketInReasonNoMatch    = iota /* No matching flow (table-miss flow entry). */
+	OfpPacketInReasonAction            /* Action explicitly output to controller. */
+	OfpPacketInReasonInvalidTTL        /* Packet has invalid TTL */
+)
+
+// Table numbering. Tables can use any number up to OfpTableMax.
+// enum ofp_table {
+const (
+	OfpTableMax = 0xfe /* Last usable table number. */
+	OfpTableAll = 0xff /* Wildcard table used for table config, flow stats and flow deletes. */
+)
+
+// OfpNoBuffer is the buffer id which indicates the packet isn't buffered
+const OfpNoBuffer = 0xffffffff
+
 // OfpPacketInMsg reprensents the packet_in message received by controller
 /* Packet received on port (datapath -> controller). */
 type OfpPacketInMsg struct {
 	Header   ofpgeneral.OfpHeader
-	BufferID uint32 /* ID assigned by datapath. */
-	TotalLen uint16 /* Full length of frame. */
-	InPort   uint16 /* Port on which frame was received. */
-	Reason   uint8  /* Reason packet is being sent (one of OFPR_*) */
-	Padding  uint8
-	Data     []byte /* Ethernet frame, halfway through 32-bit word,
-	   so the IP header is 32-bit aligned.  The
-	   amount of data is inferred from the length
-	   field in the header.  Because of padding,
-	   offsetof(struct ofp_packet_in, data) ==
-	   sizeof(struct ofp_packet_in) - 2. */
+	BufferID uint32   /* ID assigned by datapath. */
+	TotalLen uint16   /* Full length of frame. */
+	Reason   uint8    /* Reason packet is being sent (one of OFPR_*) */
+	TableID  uint8    /* ID of the table that was looked up */
+	Cookie   uint64   /* Cookie of the flow entry that was looked up. */
+	Match    OfpMatch /* Packet metadata. Variable size. */
+	Padding  [2]byte  /* Align to 64 bit + 16 bit */
+	Data     []byte   /* Ethernet frame */
 }
 
 // MarshalBinary converts the packet in msg fields into byte array
 func (in *OfpPacketInMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, in.Header.Length)
-	headerData, err := (&in.Header).MarshalBinary()
-	copy(data, headerData)
-	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, in.BufferID, in.TotalLen, in.InPort, in.Reason, in.Padding)
+	matchData, err := (&in.Match).MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
-	copy(data[4:16], buf.Bytes())
-	copy(data[16:], in.Data)
-	return data, err
+	in.Header.Length = uint16(24 + len(matchData) + 2 + len(in.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, in.Header, in.BufferID, in.TotalLen, in.Reason,
+		in.TableID, in.Cookie); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(in.Padding[:])
+	buf.Write(in.Data)
+	return buf.Bytes(), nil
 }
 
 // UnmarshalBinary transforms the byte array into packet in message data
 func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
-	if err := (&in.Header).UnmarshalBinary(data); err != nil {
+	if len(data) < 24+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &in.Header, &in.BufferID, &in.TotalLen, &in.Reason,
+		&in.TableID, &in.Cookie); err != nil {
 		return err
 	}
-	buf := bytes.NewReader(data[4:14])
-	if err := ofpgeneral.UnMarshalFields(buf, &in.BufferID, &in.TotalLen, &in.InPort, &in.Reason, &in.Padding); err != nil {
+	if err := (&in.Match).UnmarshalBinary(data[24:]); err != nil {
 		return err
 	}
-	copy(in.Data, data[14:])
+	dataIdx := 24 + int(in.Match.Len()) + 2
+	if len(data) < dataIdx {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	in.Data = make([]byte, len(data)-dataIdx)
+	copy(in.Data, data[dataIdx:])
 	return nil
 }
 
@@ -180,70 +230,110 @@ func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
 /* Send packet (controller -> datapath). */
 type OfpPacketOutMsg struct {
 	Header     ofpgeneral.OfpHeader
-	BufferID   uint32         /* ID assigned by datapath (-1 if none). */
-	InPort     uint16         /* Packet's input port (OFPP_NONE if none). */
-	ActionsLen uint16         /* Size of action array in bytes. */
-	Actions    []OfpActionMsg /* Actions. */
-	/* uint8_t data[0]; */ /* Packet data.  The length is inferred
+	BufferID   uint32      /* ID assigned by datapath (OFP_NO_BUFFER if none). */
+	InPort     uint32      /* Packet's input port or OFPP_CONTROLLER. */
+	ActionsLen uint16      /* Size of action array in bytes. */
+	Padding    [6]byte     /* Align to 64 bits. */
+	Actions    []OfpAction /* Action list. */
+	Data       []byte      /* Packet data. The length is inferred
 	   from the length field in the header.
 	   (Only meaningful if buffer_id == -1.) */
 }
 
-// MarshalBinary converts the packet out msg fields into byte array
+// NewPacketOutMsg creates the packet out of the unbuffered packet data
+// received on the port, OFPP_CONTROLLER is used if there is none
+func NewPacketOutMsg(inPort uint32, actions []OfpAction, data []byte) *OfpPacketOutMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypePacketOut
+	return &OfpPacketOutMsg{
+		Header:   *header,
+		BufferID: OfpNoBuffer,
+		InPort:   inPort,
+		Actions:  actions,
+		Data:     data,
+	}
+}
+
+// MarshalBinary converts the packet out msg fields into byte array,
+// the lengths are set according to the actions and the data
 func (out *OfpPacketOutMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, out.Header.Length)
-	headerData, err := (&out.Header).MarshalBinary()
+	actionData, err := marshalActions(out.Actions)
 	if err != nil {
 		return nil, err
 	}
-	copy(data, headerData)
+	out.ActionsLen = uint16(len(actionData))
+	out.Header.Length = uint16(24 + len(actionData) + len(out.Data))
 	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, out.BufferID, out.InPort, out.ActionsLen)
-	if err != nil {
+	if err := ofpgeneral.MarshalFields(buf, out.Header, out.BufferID, out.InPort,
+		out.ActionsLen, out.Padding); err != nil {
 		return nil, err
 	}
-	copy(data[4:16], buf.Bytes())
-	actionByteIdx := uint16(16)
-	for _, action := range out.Actions {
-		actionData, err := action.MarshalBinary()
-		if err != nil {
-			return nil, err
-		}
-		copy(data[actionByteIdx:actionByteIdx+action.Header.Len], actionData)
-		actionByteIdx = actionByteIdx + action.Header.Len
-	}
-	return data, err
+	buf.Write(actionData)
+	buf.Write(out.Data)
+	return buf.Bytes(), nil
 }
 
 // UnmarshalBinary transforms the byte array into packet out message data
 func (out *OfpPacketOutMsg) UnmarshalBinary(data []byte) error {
-	if err := (&out.Header).UnmarshalBinary(data); err != nil {
-		return err
+	if len(data) < 24 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
-	buf := bytes.NewReader(data[4:16])
-	if err := ofpgeneral.UnMarshalFields(buf, &out.BufferID, &out.InPort, &out.ActionsLen); err != nil {
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &out.Header, &out.BufferID, &out.InPort,
+		&out.ActionsLen, &out.Padding); err != nil {
 		return err
 	}
-	actionByteIdx := uint16(16)
-	for i := uint16(0); i < out.ActionsLen; i++ {
-		if err := out.Actions[i].UnmarshalBinary(data[actionByteIdx:]); err != nil {
-			return err
-		}
-		actionByteIdx += out.Actions[i].Header.Len
+	actionEnd := 24 + int(out.ActionsLen)
+	if len(data) < actionEnd {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	actions, err := ParseActions(data[24:actionEnd])
+	if err != nil {
+		return err
 	}
+	out.Actions = actions
+	out.Data = make([]byte, len(data)-actionEnd)
+	copy(out.Data, data[actionEnd:])
 	return nil
 }
 
-// ParseMsg is the function which parses the message
-func ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	var msg ofpgeneral.OfpMessage
-	var err error
-	switch b[1] {
-	case OfpTypeHello:
-		msg = &ofpgeneral.OfpHelloMsg{}
-		err = msg.UnmarshalBinary(b)
+// OfpExperimenterMsg represents the experimenter extension message
+type OfpExperimenterMsg struct {
+	Header       ofpgeneral.OfpHeader
+	Experimenter uint32 /* Experimenter ID:
+	 * - MSB 0: low-order bytes are IEEE OUI.
+	 * - MSB != 0: defined by ONF. */
+	ExpType uint32 /* Experimenter defined. */
+	Data    []byte /* Experimenter-defined arbitrary additional data. */
+}
 
+// UnmarshalBinary transforms the byte array into experimenter message data
+func (em *OfpExperimenterMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &em.Header, &em.Experimenter, &em.ExpType); err != nil {
+		return err
 	}
+	em.Data = make([]byte, len(data)-16)
+	copy(em.Data, data[16:])
+	return nil
+}
 
-	return msg, err
+// MarshalBinary converts the experimenter message fields into byte array,
+// the length in the header is set according to the data
+func (em *OfpExperimenterMsg) MarshalBinary() ([]byte, error) {
+	em.Header.Length = uint16(16 + len(em.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, em.Header, em.Experimenter, em.ExpType); err != nil {
+		return nil, err
+	}
+	buf.Write(em.Data)
+	return buf.Bytes(), nil
+}
+
+// ParseMsg is the function which parses the message
+func ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
+	return (&OfpMessageParser{}).ParseMsg(b)
 }
@@ -0,0 +1,31 @@
+package ofp14
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+func TestActionMsg(t *testing.T) {
+	data := wiretest.Decode(t, "0000 0010 00000002 ffff 000000000000")
+	msg := &OfpActionMsg{}
+	if err := msg.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	output, ok := msg.Body.(*OfpActionOutput)
+	if !ok || msg.Header.Type != OfpActionOutputToPort || msg.Header.Length != 16 || output.Port != 2 ||
+		output.MaxLen != ofp13.OfpControllerMaxLenNoBuf {
+		t.Fatalf("Unexpected action %+v %+v", msg.Header, msg.Body)
+	}
+	wiretest.CheckEncoding(t, msg, data)
+
+	for _, wire := range []string{
+		"0000 0010 00000002",                    // Truncated action
+		"0012 0008 00000000 0012 0008 00000000", // Two actions
+	} {
+		if err := (&OfpActionMsg{}).UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The action %s is decoded", wire)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package ofp14
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Async Config property types.
+// Low order bit cleared indicates a property for the slave role.
+// Low order bit set indicates a property for the master/equal role.
+// enum ofp_async_config_prop_type {
+const (
+	OfpAsyncConfigPropTypePacketInSlave        = 0      /* Packet-in mask for slave. */
+	OfpAsyncConfigPropTypePacketInMaster       = 1      /* Packet-in mask for master. */
+	OfpAsyncConfigPropTypePortStatusSlave      = 2      /* Port-status mask for slave. */
+	OfpAsyncConfigPropTypePortStatusMaster     = 3      /* Port-status mask for master. */
+	OfpAsyncConfigPropTypeFlowRemovedSlave     = 4      /* Flow removed mask for slave. */
+	OfpAsyncConfigPropTypeFlowRemovedMaster    = 5      /* Flow removed mask for master. */
+	OfpAsyncConfigPropTypeRoleStatusSlave      = 6      /* Role status mask for slave. */
+	OfpAsyncConfigPropTypeRoleStatusMaster     = 7      /* Role status mask for master. */
+	OfpAsyncConfigPropTypeTableStatusSlave     = 8      /* Table status mask for slave. */
+	OfpAsyncConfigPropTypeTableStatusMaster    = 9      /* Table status mask for master. */
+	OfpAsyncConfigPropTypeRequestForwardSlave  = 10     /* RequestForward mask for slave. */
+	OfpAsyncConfigPropTypeRequestForwardMaster = 11     /* RequestForward mask for master. */
+	OfpAsyncConfigPropTypeExperimenterSlave    = 0xfffe /* Experimenter for slave. */
+	OfpAsyncConfigPropTypeExperimenterMaster   = 0xffff /* Experimenter for master. */
+)
+
+const (
+	asyncConfigHeaderLen           = 8
+	asyncConfigPropHeaderLen       = 4
+	asyncConfigPropReasonsLen      = 8
+	asyncConfigPropExperimenterLen = 12
+)
+
+// OfpAsyncConfigPropHeader represents the common header of all async config
+// properties
+type OfpAsyncConfigPropHeader struct {
+	Type   uint16 /* One of OFPACPT_*. */
+	Length uint16 /* Length in bytes of this property. */
+}
+
+// UnmarshalBinary transforms the byte array into property header data
+func (aph *OfpAsyncConfigPropHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < asyncConfigPropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, aph)
+}
+
+// MarshalBinary converts the property header fields into byte array
+func (aph *OfpAsyncConfigPropHeader) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, aph); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpAsyncConfigPropReasons represents the async config property carrying
+// the bitmask of the reasons of one message type for one role
+type OfpAsyncConfigPropReasons struct {
+	Type   uint16 /* One of OFPACPT_PACKET_IN_*, OFPACPT_PORT_STATUS_*, OFPACPT_FLOW_REMOVED_*, OFPACPT_ROLE_STATUS_*, OFPACPT_TABLE_STATUS_*, OFPACPT_REQUESTFORWARD_*. */
+	Length uint16 /* Length in bytes of this property. */
+	Mask   uint32 /* Bitmasks of reason values. */
+}
+
+// NewAsyncConfigPropReasons creates the reasons property of the type
+func NewAsyncConfigPropReasons(propType uint16, mask uint32) *OfpAsyncConfigPropReasons {
+	return &OfpAsyncConfigPropReasons{Type: propType, Length: asyncConfigPropReasonsLen, Mask: mask}
+}
+
+// UnmarshalBinary transforms the byte array into reasons property data
+func (apr *OfpAsyncConfigPropReasons) UnmarshalBinary(data []byte) error {
+	if len(data) < asyncConfigPropReasonsLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, apr)
+}
+
+// MarshalBinary converts the reasons property fields into byte array
+func (apr *OfpAsyncConfigPropReasons) MarshalBinary() ([]byte, error) {
+	apr.Length = asyncConfigPropReasonsLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, apr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpAsyncConfigPropExperimenter represents the experimenter async config
+// property
+type OfpAsyncConfigPropExperimenter struct {
+	Type         uint16 /* One of OFPACPT_EXPERIMENTER_SLAVE, OFPACPT_EXPERIMENTER_MASTER. */
+	Length       uint16 /* Length in bytes of this property. */
+	Experimenter uint32 /* Experimenter ID which takes the same
+	   form as in struct
+	   ofp_experimenter_header. */
+	ExpType uint32 /* Experimenter defined. */
+	Data    []byte /* Experimenter defined data, the padding to
+	   the multiple of 8 bytes is not included. */
+}
+
+// UnmarshalBinary transforms the byte array into experimenter property data
+func (ape *OfpAsyncConfigPropExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < asyncConfigPropExperimenterLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &ape.Type, &ape.Length, &ape.Experimenter, &ape.ExpType); err != nil {
+		return err
+	}
+	if ape.Length < asyncConfigPropExperimenterLen || int(ape.Length) > len(data) {
+		return fmt.Errorf("Invalid async config property length %d", ape.Length)
+	}
+	ape.Data = make([]byte, ape.Length-asyncConfigPropExperimenterLen)
+	copy(ape.Data, data[asyncConfigPropExperimenterLen:ape.Length])
+	return nil
+}
+
+// MarshalBinary converts the experimenter property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (ape *OfpAsyncConfigPropExperimenter) MarshalBinary() ([]byte, error) {
+	if ape.Type != OfpAsyncConfigPropTypeExperimenterSlave {
+		ape.Type = OfpAsyncConfigPropTypeExperimenterMaster
+	}
+	ape.Length = uint16(asyncConfigPropExperimenterLen + len(ape.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ape.Type, ape.Length, ape.Experimenter, ape.ExpType); err != nil {
+		return nil, err
+	}
+	buf.Write(ape.Data)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpAsyncConfigMsg represents the asynchronous message configuration of
+// openflow 1.4 used by the set async message and the get async reply, the
+// masks are carried by the properties. The set async message only changes
+// the configuration of the properties it carries.
+type OfpAsyncConfigMsg struct {
+	Header ofpgeneral.OfpHeader
+	// Properties holds *OfpAsyncConfigPropReasons and
+	// *OfpAsyncConfigPropExperimenter, *OfpAsyncConfigPropHeader is kept
+	// for the unknown properties
+	Properties []ofpgeneral.OfpMessage /* Async config property list. */
+}
+
+// NewSetAsyncMsg creates the set async message with no property
+func NewSetAsyncMsg() *OfpAsyncConfigMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeSetAsync
+	return &OfpAsyncConfigMsg{Header: *header, Properties: make([]ofpgeneral.OfpMessage, 0)}
+}
+
+// NewGetAsyncRequestMsg creates the request of the asynchronous message
+// configuration, the request has no body
+func NewGetAsyncRequestMsg() *ofpgeneral.OfpHeader {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeGetAsyncRequest
+	return header
+}
+
+// GetReasonsMask returns the mask of the reasons property of the type, the
+// second result tells whether the property is present
+func (ac *OfpAsyncConfigMsg) GetReasonsMask(propType uint16) (uint32, bool) {
+	for _, prop := range ac.Properties {
+		if reasons, ok := prop.(*OfpAsyncConfigPropReasons); ok && reasons.Type == propType {
+			return reasons.Mask, true
+		}
+	}
+	return 0, false
+}
+
+// UnmarshalBinary transforms the byte array into async config data
+func (ac *OfpAsyncConfigMsg) UnmarshalBinary(data []byte) error {
+	if err := ac.Header.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if int(ac.Header.Length) < asyncConfigHeaderLen || int(ac.Header.Length) > len(data) {
+		return fmt.Errorf("Invalid async config length %d", ac.Header.Length)
+	}
+	ac.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := asyncConfigHeaderLen; idx+asyncConfigPropHeaderLen <= int(ac.Header.Length); {
+		propHeader := OfpAsyncConfigPropHeader{}
+		if err := propHeader.UnmarshalBinary(data[idx:ac.Header.Length]); err != nil {
+			return err
+		}
+		if propHeader.Length < asyncConfigPropHeaderLen || idx+int(propHeader.Length) > int(ac.Header.Length) {
+			return fmt.Errorf("Invalid async config property length %d", propHeader.Length)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch {
+		case propHeader.Type >= OfpAsyncConfigPropTypeExperimenterSlave:
+			prop = &OfpAsyncConfigPropExperimenter{}
+		case propHeader.Length == asyncConfigPropReasonsLen:
+			// The reasons layout is shared by the masks introduced by
+			// the later versions such as the openflow 1.5 controller
+			// status masks
+			prop = &OfpAsyncConfigPropReasons{}
+		default:
+			prop = &OfpAsyncConfigPropHeader{}
+		}
+		if err := prop.UnmarshalBinary(data[idx : idx+int(propHeader.Length)]); err != nil {
+			return err
+		}
+		ac.Properties = append(ac.Properties, prop)
+		// The experimenter properties are padded to 8 bytes
+		idx += (int(propHeader.Length) + 7) / 8 * 8
+	}
+	return nil
+}
+
+// MarshalBinary converts the async config fields into byte array
+func (ac *OfpAsyncConfigMsg) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range ac.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	ac.Header.Length = uint16(asyncConfigHeaderLen + propBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ac.Header); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
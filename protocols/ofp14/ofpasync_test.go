@@ -0,0 +1,53 @@
+package ofp14
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+func TestParseAsyncConfig(t *testing.T) {
+	// The reasons, an experimenter property and the controller status
+	// mask of openflow 1.5
+	m := wiretest.Parse(t, &OfpMessageParser{}, "051b003000000001 0000 0008 00000001 0001 0008 00000007"+
+		"ffff 000e 00002320 00000001 abcd 0000 000d 0008 00000003").(*OfpAsyncConfigMsg)
+	if len(m.Properties) != 4 {
+		t.Fatalf("Unexpected async config %+v", m)
+	}
+	for propType, expected := range map[uint16]uint32{OfpAsyncConfigPropTypePacketInSlave: 1,
+		OfpAsyncConfigPropTypePacketInMaster: 7, 13: 3} {
+		if mask, ok := m.GetReasonsMask(propType); !ok || mask != expected {
+			t.Errorf("Unexpected mask %#x %v of property type %d", mask, ok, propType)
+		}
+	}
+	if _, ok := m.GetReasonsMask(OfpAsyncConfigPropTypeFlowRemovedMaster); ok {
+		t.Error("The absent flow removed mask is found")
+	}
+	if exp, ok := m.Properties[2].(*OfpAsyncConfigPropExperimenter); !ok || exp.Experimenter != 0x2320 ||
+		exp.ExpType != 1 || hex.EncodeToString(exp.Data) != "abcd" {
+		t.Errorf("Unexpected experimenter property %+v", m.Properties[2])
+	}
+}
+
+func TestSetAsyncWireFormat(t *testing.T) {
+	msg := NewSetAsyncMsg()
+	msg.Header.Xid = 1
+	msg.Properties = append(msg.Properties, NewAsyncConfigPropReasons(OfpAsyncConfigPropTypeRoleStatusMaster, 1),
+		&OfpAsyncConfigPropExperimenter{Type: OfpAsyncConfigPropTypeExperimenterSlave, Experimenter: 0x2320,
+			ExpType: 1, Data: []byte{0xab}})
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "051c002000000001 0007 0008 00000001 fffe 000d 00002320 00000001 ab 000000"))
+}
+
+func TestParseAsyncConfigInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"051b001800000001 0000 0008 00000001", // Length beyond the data
+		"051b001000000001 0000 0002 00000001", // Property shorter than its header
+		"051b001000000001 0000 0010 00000001", // Property beyond the message
+	} {
+		m := &OfpAsyncConfigMsg{}
+		if err := m.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The async config %s is decoded as %+v", wire, m)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package ofp14
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Bundle control message types
+// enum ofp_bundle_ctrl_type {
+const (
+	OfpBundleCtrlTypeOpenRequest    = iota /* Open the bundle. */
+	OfpBundleCtrlTypeOpenReply             /* The bundle is opened. */
+	OfpBundleCtrlTypeCloseRequest          /* Close the bundle. */
+	OfpBundleCtrlTypeCloseReply            /* The bundle is closed. */
+	OfpBundleCtrlTypeCommitRequest         /* Commit the bundle. */
+	OfpBundleCtrlTypeCommitReply           /* The bundle is committed. */
+	OfpBundleCtrlTypeDiscardRequest        /* Discard the bundle. */
+	OfpBundleCtrlTypeDiscardReply          /* The bundle is discarded. */
+)
+
+// Bundle configuration flags.
+// enum ofp_bundle_flags {
+const (
+	OfpBundleFlagAtomic  = 1 << 0 /* Execute atomically. */
+	OfpBundleFlagOrdered = 1 << 1 /* Execute in specified order. */
+)
+
+// Bundle property types.
+// enum ofp_bundle_prop_type {
+const (
+	OfpBundlePropTypeExperimenter = 0xffff /* Experimenter property. */
+)
+
+// OfpErrTypeBundleFailed is the error type of the bundle errors
+const OfpErrTypeBundleFailed = 17
+
+// ofp_error_msg 'code' values for OFPET_BUNDLE_FAILED.  'data' contains
+// at least the first 64 bytes of the failed request.
+// enum ofp_bundle_failed_code {
+const (
+	OfpBundleFailedCodeUnknown          = iota /* Unspecified error. */
+	OfpBundleFailedCodeErrPerm                 /* Permissions error. */
+	OfpBundleFailedCodeBadID                   /* Bundle ID doesn't exist. */
+	OfpBundleFailedCodeBundleExist             /* Bundle ID already exist. */
+	OfpBundleFailedCodeBundleClosed            /* Bundle ID is closed. */
+	OfpBundleFailedCodeOutOfBundles            /* Too many bundles IDs. */
+	OfpBundleFailedCodeBadType                 /* Unsupported or unknown message control type. */
+	OfpBundleFailedCodeBadFlags                /* Unsupported, unknown, or inconsistent flags. */
+	OfpBundleFailedCodeMsgBadLen               /* Length problem in included message. */
+	OfpBundleFailedCodeMsgBadXid               /* Inconsistent or duplicate XID. */
+	OfpBundleFailedCodeMsgUnsup                /* Unsupported message in this bundle. */
+	OfpBundleFailedCodeMsgConflict             /* Unsupported message combination in this bundle. */
+	OfpBundleFailedCodeMsgTooMany              /* Can't handle this many messages in bundle. */
+	OfpBundleFailedCodeMsgFailed               /* One message in bundle failed. */
+	OfpBundleFailedCodeTimeout                 /* Bundle is taking too long. */
+	OfpBundleFailedCodeBundleInProgress        /* Bundle is locking the resource. */
+)
+
+const (
+	bundleCtrlLen             = 16
+	bundleAddHeaderLen        = 16
+	bundlePropExperimenterLen = 12
+)
+
+// OfpBundlePropExperimenter represents the experimenter bundle property
+type OfpBundlePropExperimenter struct {
+	Type         uint16 /* OFPBPT_EXPERIMENTER. */
+	Length       uint16 /* Length in bytes of this property. */
+	Experimenter uint32 /* Experimenter ID which takes the same
+	   form as in struct
+	   ofp_experimenter_header. */
+	ExpType uint32 /* Experimenter defined. */
+	Data    []byte /* Experimenter defined data, the padding to
+	   the multiple of 8 bytes is not included. */
+}
+
+// UnmarshalBinary transforms the byte array into experimenter property data
+func (bpe *OfpBundlePropExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < bundlePropExperimenterLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &bpe.Type, &bpe.Length, &bpe.Experimenter, &bpe.ExpType); err != nil {
+		return err
+	}
+	if bpe.Length < bundlePropExperimenterLen || int(bpe.Length) > len(data) {
+		return fmt.Errorf("Invalid bundle property length %d", bpe.Length)
+	}
+	bpe.Data = make([]byte, bpe.Length-bundlePropExperimenterLen)
+	copy(bpe.Data, data[bundlePropExperimenterLen:bpe.Length])
+	return nil
+}
+
+// MarshalBinary converts the experimenter property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (bpe *OfpBundlePropExperimenter) MarshalBinary() ([]byte, error) {
+	bpe.Type = OfpBundlePropTypeExperimenter
+	bpe.Length = uint16(bundlePropExperimenterLen + len(bpe.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, bpe.Type, bpe.Length, bpe.Experimenter, bpe.ExpType); err != nil {
+		return nil, err
+	}
+	buf.Write(bpe.Data)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// unmarshalBundleProps decodes the bundle property list
+func unmarshalBundleProps(data []byte) ([]OfpBundlePropExperimenter, error) {
+	props := make([]OfpBundlePropExperimenter, 0)
+	for idx := 0; idx < len(data); {
+		prop := OfpBundlePropExperimenter{}
+		if err := prop.UnmarshalBinary(data[idx:]); err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+		idx += (int(prop.Length) + 7) / 8 * 8
+	}
+	return props, nil
+}
+
+// marshalBundleProps encodes the bundle property list
+func marshalBundleProps(props []OfpBundlePropExperimenter) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for idx := range props {
+		propData, err := props[idx].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(propData)
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpBundleCtrlMsg represents the bundle control message, which is used to
+// open, close, commit and discard the bundles and to reply to them
+type OfpBundleCtrlMsg struct {
+	Header     ofpgeneral.OfpHeader
+	BundleID   uint32                      /* Identify the bundle. */
+	Type       uint16                      /* OFPBCT_*. */
+	Flags      uint16                      /* Bitmap of OFPBF_* flags. */
+	Properties []OfpBundlePropExperimenter /* Bundle Property list. */
+}
+
+// NewBundleCtrlMsg creates the bundle control message of the type
+func NewBundleCtrlMsg(bundleID uint32, ctrlType uint16, flags uint16) *OfpBundleCtrlMsg {
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeBundleControl
+	return &OfpBundleCtrlMsg{
+		Header:     *header,
+		BundleID:   bundleID,
+		Type:       ctrlType,
+		Flags:      flags,
+		Properties: make([]OfpBundlePropExperimenter, 0),
+	}
+}
+
+// UnmarshalBinary transforms the byte array into bundle control data
+func (bc *OfpBundleCtrlMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < bundleCtrlLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &bc.Header, &bc.BundleID, &bc.Type, &bc.Flags); err != nil {
+		return err
+	}
+	if int(bc.Header.Length) < bundleCtrlLen || int(bc.Header.Length) > len(data) {
+		return fmt.Errorf("Invalid bundle control length %d", bc.Header.Length)
+	}
+	props, err := unmarshalBundleProps(data[bundleCtrlLen:bc.Header.Length])
+	if err != nil {
+		return err
+	}
+	bc.Properties = props
+	return nil
+}
+
+// MarshalBinary converts the bundle control fields into byte array
+func (bc *OfpBundleCtrlMsg) MarshalBinary() ([]byte, error) {
+	propData, err := marshalBundleProps(bc.Properties)
+	if err != nil {
+		return nil, err
+	}
+	bc.Header.Length = uint16(bundleCtrlLen + len(propData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, bc.Header, bc.BundleID, bc.Type, bc.Flags); err != nil {
+		return nil, err
+	}
+	buf.Write(propData)
+	return buf.Bytes(), nil
+}
+
+// OfpBundleAddMsg represents the message which adds a message to the bundle
+type OfpBundleAddMsg struct {
+	Header   ofpgeneral.OfpHeader
+	BundleID uint32 /* Identify the bundle. */
+	Padding  [2]byte
+	Flags    uint16 /* Bitmap of OFPBF_* flags. */
+	// Message is the encoded message added to the bundle, its version and
+	// xid are set to the ones of the bundle add message when encoded
+	Message    []byte
+	Properties []OfpBundlePropExperimenter /* Bundle Property list. */
+}
+
+// NewBundleAddMsg creates the message which adds the message to the bundle
+func NewBundleAddMsg(bundleID uint32, flags uint16, msg ofpgeneral.OfpMessage) (*OfpBundleAddMsg, error) {
+	msgData, err := msg.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypeBundleAddMessage
+	return &OfpBundleAddMsg{
+		Header:     *header,
+		BundleID:   bundleID,
+		Flags:      flags,
+		Message:    msgData,
+		Properties: make([]OfpBundlePropExperimenter, 0),
+	}, nil
+}
+
+// UnmarshalBinary transforms the byte array into bundle add data
+func (ba *OfpBundleAddMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < bundleAddHeaderLen+8 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &ba.Header, &ba.BundleID, &ba.Padding, &ba.Flags); err != nil {
+		return err
+	}
+	if int(ba.Header.Length) < bundleAddHeaderLen+8 || int(ba.Header.Length) > len(data) {
+		return fmt.Errorf("Invalid bundle add length %d", ba.Header.Length)
+	}
+	msgHeader := ofpgeneral.OfpHeader{}
+	if err := msgHeader.UnmarshalBinary(data[bundleAddHeaderLen:]); err != nil {
+		return err
+	}
+	msgEnd := bundleAddHeaderLen + int(msgHeader.Length)
+	if msgHeader.Length < 8 || msgEnd > int(ba.Header.Length) {
+		return fmt.Errorf("Invalid bundled message length %d", msgHeader.Length)
+	}
+	ba.Message = make([]byte, msgHeader.Length)
+	copy(ba.Message, data[bundleAddHeaderLen:msgEnd])
+	// The properties follow the message padded to the multiple of 8 bytes
+	propStart := (msgEnd + 7) / 8 * 8
+	if propStart > int(ba.Header.Length) {
+		propStart = int(ba.Header.Length)
+	}
+	props, err := unmarshalBundleProps(data[propStart:ba.Header.Length])
+	if err != nil {
+		return err
+	}
+	ba.Properties = props
+	return nil
+}
+
+// MarshalBinary converts the bundle add fields into byte array
+func (ba *OfpBundleAddMsg) MarshalBinary() ([]byte, error) {
+	if len(ba.Message) < 8 {
+		return nil, fmt.Errorf("Invalid bundled message length %d", len(ba.Message))
+	}
+	propData, err := marshalBundleProps(ba.Properties)
+	if err != nil {
+		return nil, err
+	}
+	// The properties follow the message padded to the multiple of 8 bytes
+	msgLen := bundleAddHeaderLen + len(ba.Message)
+	padLen := 0
+	if len(propData) > 0 {
+		padLen = (8 - msgLen%8) % 8
+	}
+	ba.Header.Length = uint16(msgLen + padLen + len(propData))
+	// The bundled message must carry the version and the xid of the
+	// bundle add message
+	msgHeader := ofpgeneral.OfpHeader{}
+	if err := msgHeader.UnmarshalBinary(ba.Message); err != nil {
+		return nil, err
+	}
+	msgHeader.Version = ba.Header.Version
+	msgHeader.Xid = ba.Header.Xid
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ba.Header, ba.BundleID, ba.Padding, ba.Flags, msgHeader); err != nil {
+		return nil, err
+	}
+	buf.Write(ba.Message[8:])
+	buf.Write(make([]byte, padLen))
+	buf.Write(propData)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package ofp14
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+func TestBundleCtrlWireFormat(t *testing.T) {
+	msg := NewBundleCtrlMsg(1, OfpBundleCtrlTypeOpenRequest, OfpBundleFlagAtomic|OfpBundleFlagOrdered)
+	msg.Header.Xid = 1
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "0521001000000001 00000001 0000 0003"))
+
+	reply := wiretest.Parse(t, &OfpMessageParser{}, "0521002000000002 00000001 0001 0003 ffff 000e 00002320 00000001 abcd 0000").(*OfpBundleCtrlMsg)
+	if reply.BundleID != 1 || reply.Type != OfpBundleCtrlTypeOpenReply || reply.Flags != 3 ||
+		len(reply.Properties) != 1 {
+		t.Fatalf("Unexpected bundle control reply %+v", reply)
+	}
+	if prop := reply.Properties[0]; prop.Length != 14 || prop.Experimenter != 0x2320 ||
+		hex.EncodeToString(prop.Data) != "abcd" {
+		t.Errorf("Unexpected bundle property %+v", prop)
+	}
+}
+
+func TestBundleAddWireFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   ofpgeneral.OfpMessage
+		props []OfpBundlePropExperimenter
+		wire  string
+	}{
+		// The version and the xid of the bundled message are replaced
+		{"aligned message", ofpgeneral.NewOfpHeader(4), nil, "0522001800000007 00000001 0000 0001 0500000800000007"},
+		{"padded message with property", ofpgeneral.NewEchoRequestMsg(4, []byte{0xde, 0xad, 0xbe, 0xef}),
+			[]OfpBundlePropExperimenter{{Experimenter: 0x2320, ExpType: 1, Data: []byte{0xab, 0xcd}}},
+			"0522003000000007 00000001 0000 0001 0502000c00000007 deadbeef 00000000" +
+				"ffff 000e 00002320 00000001 abcd 0000"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := NewBundleAddMsg(1, OfpBundleFlagAtomic, tc.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg.Header.Xid = 7
+			msg.Properties = append(msg.Properties, tc.props...)
+			data := wiretest.Decode(t, tc.wire)
+			wiretest.CheckEncoding(t, msg, data)
+
+			decoded := &OfpBundleAddMsg{}
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			wiretest.CheckEncoding(t, decoded, data)
+			if decoded.BundleID != 1 || decoded.Flags != OfpBundleFlagAtomic || len(decoded.Properties) != len(tc.props) {
+				t.Fatalf("Unexpected bundle add %+v", decoded)
+			}
+		})
+	}
+}
+
+func TestBundleAddInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0522001000000007 00000001 0000 0001",                                 // No bundled message
+		"0522001800000007 00000001 0000 0001 0500001000000007",                // Bundled message beyond the length
+		"0522001800000007 00000001 0000 0001 0500000400000007",                // Bundled message shorter than its header
+		"0522002000000007 00000001 0000 0001 0500000800000007 ffff 000c 0000", // Truncated property
+	} {
+		msg := &OfpBundleAddMsg{}
+		if err := msg.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The bundle add %s is decoded as %+v", wire, msg)
+		}
+	}
+}
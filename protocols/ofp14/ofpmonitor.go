@@ -0,0 +1,295 @@
+package ofp14
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Flow monitor commands
+// enum ofp_flow_monitor_command {
+const (
+	OfpFlowMonitorCmdAdd    = iota /* New flow monitor. */
+	OfpFlowMonitorCmdModify        /* Modify existing flow monitor. */
+	OfpFlowMonitorCmdDelete        /* Delete/cancel existing flow monitor. */
+)
+
+// 'flags' bits in struct of_flow_monitor_request.
+// enum ofp_flow_monitor_flags {
+const (
+	/* When to send updates. */
+	OfpFlowMonitorFlagInitial = 1 << 0 /* Initially matching flows. */
+	OfpFlowMonitorFlagAdd     = 1 << 1 /* New matching flows as they are added. */
+	OfpFlowMonitorFlagRemoved = 1 << 2 /* Old matching flows as they are removed. */
+	OfpFlowMonitorFlagModify  = 1 << 3 /* Matching flows as they are changed. */
+
+	/* What to include in updates */
+	OfpFlowMonitorFlagInstructions = 1 << 4 /* If set, instructions are included. */
+	OfpFlowMonitorFlagNoAbbrev     = 1 << 5 /* If set, include own changes in full. */
+	OfpFlowMonitorFlagOnlyOwn      = 1 << 6 /* If set, don't include other controllers. */
+)
+
+// 'event' values in struct ofp_flow_update_header.
+// enum ofp_flow_update_event {
+const (
+	/* struct ofp_flow_update_full. */
+	OfpFlowUpdateEventInitial  = iota /* Flow present when flow monitor created. */
+	OfpFlowUpdateEventAdded           /* Flow was added. */
+	OfpFlowUpdateEventRemoved         /* Flow was removed. */
+	OfpFlowUpdateEventModified        /* Flow instructions were changed. */
+
+	/* struct ofp_flow_update_abbrev. */
+	OfpFlowUpdateEventAbbrev /* Abbreviated reply. */
+
+	/* struct ofp_flow_update_header. */
+	OfpFlowUpdateEventPaused  /* Monitoring paused (out of buffer space). */
+	OfpFlowUpdateEventResumed /* Monitoring resumed. */
+)
+
+const (
+	flowMonitorRequestLen = 16
+	flowUpdateHeaderLen   = 4
+	flowUpdateFullLen     = 24
+	flowUpdateAbbrevLen   = 8
+	flowUpdatePausedLen   = 8
+	ofpMatchHeaderLen     = 4
+)
+
+// OfpFlowMonitorRequest represents the body of the OFPMP_FLOW_MONITOR
+// request
+type OfpFlowMonitorRequest struct {
+	MonitorID uint32         /* Controller-assigned ID for this monitor. */
+	OutPort   uint32         /* Required output port, if not OFPP_ANY. */
+	OutGroup  uint32         /* Required group, if not OFPG_ANY. */
+	Flags     uint16         /* OFPFMF_*. */
+	TableID   uint8          /* One table's ID or OFPTT_ALL (all tables). */
+	Command   uint8          /* One of OFPFMC_*. */
+	Match     ofp13.OfpMatch /* Fields to match. Variable size. */
+}
+
+// NewFlowMonitorRequestMsg creates the flow monitor multipart request
+func NewFlowMonitorRequestMsg(req *OfpFlowMonitorRequest) (*ofp13.OfpMultipartRequestMsg, error) {
+	body, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	msg := ofp13.NewMultipartRequestMsg(OfpMultipartTypeFlowMonitor, body)
+	msg.Header.Version = Version
+	return msg, nil
+}
+
+// UnmarshalBinary transforms the byte array into flow monitor request data
+func (fmr *OfpFlowMonitorRequest) UnmarshalBinary(data []byte) error {
+	if len(data) < flowMonitorRequestLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fmr.MonitorID, &fmr.OutPort, &fmr.OutGroup, &fmr.Flags,
+		&fmr.TableID, &fmr.Command); err != nil {
+		return err
+	}
+	return (&fmr.Match).UnmarshalBinary(data[flowMonitorRequestLen:])
+}
+
+// MarshalBinary converts the flow monitor request fields into byte array
+func (fmr *OfpFlowMonitorRequest) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fmr.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fmr.MonitorID, fmr.OutPort, fmr.OutGroup, fmr.Flags,
+		fmr.TableID, fmr.Command); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	return buf.Bytes(), nil
+}
+
+// OfpFlowUpdateHeader represents the common header of all flow updates, it
+// is also used for the paused and resumed events and the unknown events
+type OfpFlowUpdateHeader struct {
+	Length uint16 /* Length of this entry. */
+	Event  uint16 /* One of OFPFME_*. */
+}
+
+// UnmarshalBinary transforms the byte array into flow update header data
+func (fuh *OfpFlowUpdateHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < flowUpdateHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, fuh)
+}
+
+// MarshalBinary converts the flow update header fields into byte array
+func (fuh *OfpFlowUpdateHeader) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fuh); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpFlowUpdateFull represents the flow update of the initial, added,
+// removed and modified events
+type OfpFlowUpdateFull struct {
+	Length       uint16 /* Length is 32 + match + instructions. */
+	Event        uint16 /* One of OFPFME_*. */
+	TableID      uint8  /* ID of flow's table. */
+	Reason       uint8  /* OFPRR_* for OFPFME_REMOVED, else zero. */
+	IdleTimeout  uint16 /* Number of seconds idle before expiration. */
+	HardTimeout  uint16 /* Number of seconds before expiration. */
+	Priority     uint16 /* Priority of the entry. */
+	Padding      [4]byte
+	Cookie       uint64                 /* Opaque controller-issued identifier. */
+	Match        ofp13.OfpMatch         /* Fields to match. Variable size. */
+	Instructions []ofp13.OfpInstruction /* Instruction set.
+	   If OFPFMF_INSTRUCTIONS is not set
+	   in the monitor request, the list is
+	   empty. */
+}
+
+// UnmarshalBinary transforms the byte array into full flow update data
+func (fuf *OfpFlowUpdateFull) UnmarshalBinary(data []byte) error {
+	if len(data) < flowUpdateFullLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &fuf.Length, &fuf.Event, &fuf.TableID, &fuf.Reason,
+		&fuf.IdleTimeout, &fuf.HardTimeout, &fuf.Priority, &fuf.Padding, &fuf.Cookie); err != nil {
+		return err
+	}
+	if fuf.Length < flowUpdateFullLen+ofpMatchHeaderLen || int(fuf.Length) > len(data) {
+		return fmt.Errorf("Invalid flow update length %d", fuf.Length)
+	}
+	if err := (&fuf.Match).UnmarshalBinary(data[flowUpdateFullLen:fuf.Length]); err != nil {
+		return err
+	}
+	instructionIdx := flowUpdateFullLen + int(fuf.Match.Len())
+	if instructionIdx > int(fuf.Length) {
+		return fmt.Errorf("Invalid flow update length %d", fuf.Length)
+	}
+	instructions, err := ofp13.ParseInstructions(data[instructionIdx:fuf.Length])
+	if err != nil {
+		return err
+	}
+	fuf.Instructions = instructions
+	return nil
+}
+
+// MarshalBinary converts the full flow update fields into byte array, the
+// length is set according to the match and the instructions
+func (fuf *OfpFlowUpdateFull) MarshalBinary() ([]byte, error) {
+	matchData, err := (&fuf.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	instructionsBuf := new(bytes.Buffer)
+	for _, instruction := range fuf.Instructions {
+		instructionData, err := instruction.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		instructionsBuf.Write(instructionData)
+	}
+	fuf.Length = uint16(flowUpdateFullLen + len(matchData) + instructionsBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fuf.Length, fuf.Event, fuf.TableID, fuf.Reason,
+		fuf.IdleTimeout, fuf.HardTimeout, fuf.Priority, fuf.Padding, fuf.Cookie); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(instructionsBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// OfpFlowUpdateAbbrev represents the abbreviated flow update, which is sent
+// for the changes made by the monitoring controller itself
+type OfpFlowUpdateAbbrev struct {
+	Length uint16 /* Length is 8. */
+	Event  uint16 /* OFPFME_ABBREV. */
+	Xid    uint32 /* Controller-specified xid from flow_mod. */
+}
+
+// UnmarshalBinary transforms the byte array into abbreviated flow update data
+func (fua *OfpFlowUpdateAbbrev) UnmarshalBinary(data []byte) error {
+	if len(data) < flowUpdateAbbrevLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, fua)
+}
+
+// MarshalBinary converts the abbreviated flow update fields into byte array
+func (fua *OfpFlowUpdateAbbrev) MarshalBinary() ([]byte, error) {
+	fua.Length = flowUpdateAbbrevLen
+	fua.Event = OfpFlowUpdateEventAbbrev
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fua); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpFlowUpdatePaused represents the flow update of the paused and resumed
+// events
+type OfpFlowUpdatePaused struct {
+	Length  uint16 /* Length is 8. */
+	Event   uint16 /* One of OFPFME_*. */
+	Padding [4]byte
+}
+
+// UnmarshalBinary transforms the byte array into paused flow update data
+func (fup *OfpFlowUpdatePaused) UnmarshalBinary(data []byte) error {
+	if len(data) < flowUpdatePausedLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, fup)
+}
+
+// MarshalBinary converts the paused flow update fields into byte array
+func (fup *OfpFlowUpdatePaused) MarshalBinary() ([]byte, error) {
+	fup.Length = flowUpdatePausedLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, fup); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseFlowUpdates decodes the body of the flow monitor reply into
+// *OfpFlowUpdateFull, *OfpFlowUpdateAbbrev and *OfpFlowUpdatePaused,
+// *OfpFlowUpdateHeader is kept for the unknown events
+func ParseFlowUpdates(body []byte) ([]ofpgeneral.OfpMessage, error) {
+	updates := make([]ofpgeneral.OfpMessage, 0)
+	for idx := 0; idx < len(body); {
+		updateHeader := OfpFlowUpdateHeader{}
+		if err := updateHeader.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		if updateHeader.Length < flowUpdateHeaderLen || idx+int(updateHeader.Length) > len(body) {
+			return nil, fmt.Errorf("Invalid flow update length %d", updateHeader.Length)
+		}
+		var update ofpgeneral.OfpMessage
+		switch updateHeader.Event {
+		case OfpFlowUpdateEventInitial, OfpFlowUpdateEventAdded, OfpFlowUpdateEventRemoved, OfpFlowUpdateEventModified:
+			update = &OfpFlowUpdateFull{}
+		case OfpFlowUpdateEventAbbrev:
+			update = &OfpFlowUpdateAbbrev{}
+		case OfpFlowUpdateEventPaused, OfpFlowUpdateEventResumed:
+			update = &OfpFlowUpdatePaused{}
+		default:
+			update = &OfpFlowUpdateHeader{}
+		}
+		if err := update.UnmarshalBinary(body[idx : idx+int(updateHeader.Length)]); err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+		idx += int(updateHeader.Length)
+	}
+	return updates, nil
+}
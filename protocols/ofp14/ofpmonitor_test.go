@@ -0,0 +1,106 @@
+package ofp14
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	// fullUpdateWire is the added flow of the table 0 matching the in_port
+	// 3 whose instruction goes to the table 1
+	fullUpdateWire = "0030 0001 00 00 000a 0000 8000 00000000 0000000000000001" +
+		"0001000c 80000004 00000003 00000000 0001 0008 01000000"
+	abbrevUpdateWire = "0008 0004 00000007"
+	pausedUpdateWire = "0008 0005 00000000"
+	// unknownUpdateWire is the update of an undefined event
+	unknownUpdateWire = "0004 0009"
+)
+
+func TestFlowMonitorRequestWireFormat(t *testing.T) {
+	msg, err := NewFlowMonitorRequestMsg(&OfpFlowMonitorRequest{
+		MonitorID: 1,
+		OutPort:   ofp13.OfpPortAny,
+		OutGroup:  ofp13.OfpGroupAny,
+		Flags:     OfpFlowMonitorFlagInitial | OfpFlowMonitorFlagAdd | OfpFlowMonitorFlagRemoved | OfpFlowMonitorFlagModify,
+		TableID:   ofp13.OfpTableAll,
+		Command:   OfpFlowMonitorCmdAdd,
+		Match:     *ofp13.NewOfpMatch(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Header.Xid = 1
+	data := wiretest.Decode(t, "0512002800000001 0010 0000 00000000"+
+		"00000001 ffffffff ffffffff 000f ff 00 00010004 00000000")
+	wiretest.CheckEncoding(t, msg, data)
+
+	request := &ofp13.OfpMultipartRequestMsg{}
+	if err := request.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	body := &OfpFlowMonitorRequest{}
+	if err := body.UnmarshalBinary(request.Body); err != nil {
+		t.Fatal(err)
+	}
+	if body.MonitorID != 1 || body.OutPort != ofp13.OfpPortAny || body.OutGroup != ofp13.OfpGroupAny ||
+		body.Flags != 0x0f || body.TableID != ofp13.OfpTableAll || body.Command != OfpFlowMonitorCmdAdd {
+		t.Errorf("Unexpected flow monitor request %+v", body)
+	}
+}
+
+func TestDecodeFlowMonitorReply(t *testing.T) {
+	reply := wiretest.Parse(t, &OfpMessageParser{}, "0513005400000002 0010 0000 00000000"+
+		fullUpdateWire+abbrevUpdateWire+pausedUpdateWire+unknownUpdateWire).(*ofp13.OfpMultipartReplyMsg)
+	body, err := DecodeMultipartBody(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := body.([]ofpgeneral.OfpMessage)
+	if len(updates) != 4 {
+		t.Fatalf("Decoded %d flow updates, expected 4", len(updates))
+	}
+
+	full, ok := updates[0].(*OfpFlowUpdateFull)
+	if !ok || full.Length != 48 || full.Event != OfpFlowUpdateEventAdded || full.IdleTimeout != 10 ||
+		full.Priority != 0x8000 || full.Cookie != 1 || len(full.Instructions) != 1 {
+		t.Fatalf("Unexpected full flow update %+v", updates[0])
+	}
+	if inPort, ok := full.Match.OXMFields.GetInPort(); !ok || inPort != 3 {
+		t.Errorf("Unexpected match %+v", full.Match)
+	}
+	if gotoTable, ok := full.Instructions[0].(*ofp13.OfpInstructionGotoTable); !ok || gotoTable.TableID != 1 {
+		t.Errorf("Unexpected instruction %+v", full.Instructions[0])
+	}
+	wiretest.CheckEncoding(t, full, wiretest.Decode(t, fullUpdateWire))
+
+	if abbrev, ok := updates[1].(*OfpFlowUpdateAbbrev); !ok || abbrev.Xid != 7 {
+		t.Errorf("Unexpected abbreviated flow update %+v", updates[1])
+	}
+	wiretest.CheckEncoding(t, updates[1], wiretest.Decode(t, abbrevUpdateWire))
+	if paused, ok := updates[2].(*OfpFlowUpdatePaused); !ok || paused.Event != OfpFlowUpdateEventPaused {
+		t.Errorf("Unexpected paused flow update %+v", updates[2])
+	}
+	wiretest.CheckEncoding(t, updates[2], wiretest.Decode(t, pausedUpdateWire))
+	if unknown, ok := updates[3].(*OfpFlowUpdateHeader); !ok || unknown.Event != 9 {
+		t.Errorf("Unexpected flow update %+v", updates[3])
+	}
+	wiretest.CheckEncoding(t, updates[3], wiretest.Decode(t, unknownUpdateWire))
+}
+
+func TestParseFlowUpdatesInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0008",                      // Truncated header
+		"0002 0004",                 // Length shorter than the header
+		"0010 0004 00000007",        // Length beyond the body
+		"0008 0001 00 00 000a 0000", // Full update shorter than its fixed part
+		"0020 0001 00 00 000a 0000 8000 00000000 0000000000000001 0001000c 80000004",           // Match beyond the update
+		"0024 0001 00 00 000a 0000 8000 00000000 0000000000000001 00010004 00000000 0001 0008", // Truncated instruction
+	} {
+		if updates, err := ParseFlowUpdates(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The flow updates %s are decoded as %+v", wire, updates)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ofp14
+
+import (
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// Multipart types introduced by openflow 1.4, the other types are the
+// ofp13.OfpMultipartType* constants
+// enum ofp_multipart_type {
+const (
+	/* Table description.
+	 * The request body is empty.
+	 * The reply body is an array of struct ofp_table_desc. */
+	OfpMultipartTypeTableDesc = 14
+
+	/* Queue description.
+	 * The request body is struct ofp_queue_desc_request.
+	 * The reply body is an array of struct ofp_queue_desc. */
+	OfpMultipartTypeQueueDesc = 15
+
+	/* Flow monitors. Reply may be an asynchronous message.
+	 * The request body is an array of struct ofp_flow_monitor_request.
+	 * The reply body is an array of struct ofp_flow_update_header. */
+	OfpMultipartTypeFlowMonitor = 16
+)
+
+// DecodeMultipartBody decodes the body of the openflow 1.4 multipart reply,
+// the bodies whose layout is unchanged since openflow 1.3 are decoded by
+// ofp13.OfpMultipartReplyMsg.DecodeBody
+func DecodeMultipartBody(mr *ofp13.OfpMultipartReplyMsg) (interface{}, error) {
+	switch mr.Type {
+	case ofp13.OfpMultipartTypePortDesc:
+		return ParsePortDescBody(mr.Body)
+	case OfpMultipartTypeFlowMonitor:
+		return ParseFlowUpdates(mr.Body)
+	case ofp13.OfpMultipartTypePortStats, ofp13.OfpMultipartTypeQueue,
+		OfpMultipartTypeTableDesc, OfpMultipartTypeQueueDesc:
+		return nil, fmt.Errorf("Decoding of the v1.4 multipart type %d is not supported", mr.Type)
+	}
+	return mr.DecodeBody()
+}
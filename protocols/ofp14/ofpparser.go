@@ -1,12 +1,32 @@
 package ofp14
 
-import "github.com/kopwei/goof/protocols/ofpgeneral"
+import (
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
 
 // OfpMessageParser is the message parser implementation
 type OfpMessageParser struct {
+	// The messages whose layout is unchanged since openflow 1.3 are
+	// decoded into the ofp13 structures
+	ofp13Parser ofp13.OfpMessageParser
 }
 
 // ParseMsg is used to convert bytes into ofp message
 func (p *OfpMessageParser) ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	return nil, nil
+	var message ofpgeneral.OfpMessage
+	switch b[1] {
+	case OfpTypePortStatus:
+		message = &OfpPortStatusMsg{}
+	case OfpTypeRoleStatus:
+		message = &OfpRoleStatusMsg{}
+	case OfpTypeGetAsyncReply:
+		message = &OfpAsyncConfigMsg{}
+	case OfpTypeBundleControl:
+		message = &OfpBundleCtrlMsg{}
+	default:
+		return p.ofp13Parser.ParseMsg(b)
+	}
+	err := message.UnmarshalBinary(b)
+	return message, err
 }
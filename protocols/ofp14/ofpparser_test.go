@@ -0,0 +1,101 @@
+package ofp14
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	// portWire is the description of the ethernet port 1 named eth0 with
+	// an experimenter property
+	portWire = "00000001 0058 0000 020304050607 0000 6574683000000000 0000000000000000 00000000 00000004" +
+		"0000 0020 00000000 00002000 00000000 00000000 00000000 00989680 00989680" +
+		"ffff 000e 00002320 00000001 abcd 0000"
+)
+
+// checkPort checks the port is the one of portWire
+func checkPort(t *testing.T, port *OfpPort) {
+	if port.PortNo != 1 || port.Length != 88 || port.HwAddr.String() != "02:03:04:05:06:07" ||
+		string(port.Name[:5]) != "eth0\x00" || port.State != 4 || len(port.Properties) != 2 {
+		t.Fatalf("Unexpected port %+v", port)
+	}
+	if ethernet := port.GetEthernetProp(); ethernet == nil || ethernet.Curr != 0x2000 || ethernet.CurrSpeed != 10000000 {
+		t.Errorf("Unexpected ethernet property %+v", ethernet)
+	}
+	if exp, ok := port.Properties[1].(*OfpPortDescPropExperimenter); !ok || exp.Experimenter != 0x2320 ||
+		hex.EncodeToString(exp.Data) != "abcd" {
+		t.Errorf("Unexpected experimenter property %+v", port.Properties[1])
+	}
+}
+
+func TestParseSwitchMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, msg ofpgeneral.OfpMessage)
+	}{
+		{"port status", "050c006800000001 02 00000000000000" + portWire,
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpPortStatusMsg)
+				if m.Reason != 2 {
+					t.Errorf("Unexpected port status %+v", m)
+				}
+				checkPort(t, &m.Desc)
+			}},
+		{"role status", "051e002800000002 00000002 00 000000 0000000000000009" +
+			"ffff 000e 00002320 00000001 abcd 0000",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				m := msg.(*OfpRoleStatusMsg)
+				if m.Role != ofp13.OfpControllerRoleMaster || m.Reason != OfpCRReasonMasterRequest ||
+					m.GenerationID != 9 || len(m.Properties) != 1 {
+					t.Fatalf("Unexpected role status %+v", m)
+				}
+				if prop := m.Properties[0]; prop.Length != 14 || prop.Experimenter != 0x2320 || prop.ExpType != 1 ||
+					hex.EncodeToString(prop.Data) != "abcd" {
+					t.Errorf("Unexpected role property %+v", prop)
+				}
+			}},
+		{"role reply", "0519001800000003 00000003 00000000 0000000000000009",
+			func(t *testing.T, msg ofpgeneral.OfpMessage) {
+				if m := msg.(*ofp13.OfpRoleRequestMsg); m.Role != ofp13.OfpControllerRoleSlave || m.GenerationID != 9 {
+					t.Errorf("Unexpected role reply %+v", m)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, wiretest.Parse(t, &OfpMessageParser{}, tc.wire))
+		})
+	}
+}
+
+func TestDecodePortDescBody(t *testing.T) {
+	reply := wiretest.Parse(t, &OfpMessageParser{}, "0513006800000004 000d 0000 00000000"+portWire).(*ofp13.OfpMultipartReplyMsg)
+	body, err := DecodeMultipartBody(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ports := body.([]OfpPort)
+	if len(ports) != 1 {
+		t.Fatalf("Decoded %d ports, expected 1", len(ports))
+	}
+	checkPort(t, &ports[0])
+}
+
+func TestParsePortInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"00000001 0028 0000 020304050607 0000 6574683000000000",                                    // Truncated port
+		"00000001 0020 0000 020304050607 0000 6574683000000000 0000000000000000 00000000 00000004", // Length smaller than the port
+		"00000001 0030 0000 020304050607 0000 6574683000000000 0000000000000000 00000000 00000004" +
+			"0000 0020 00000000", // Property beyond the port
+	} {
+		port := OfpPort{}
+		if err := port.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The port %s is decoded as %+v", wire, port)
+		}
+	}
+}
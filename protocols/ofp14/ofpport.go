@@ -0,0 +1,316 @@
+package ofp14
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Port description property types.
+// enum ofp_port_desc_prop_type {
+const (
+	OfpPortDescPropTypeEthernet     = 0      /* Ethernet property. */
+	OfpPortDescPropTypeOptical      = 1      /* Optical property. */
+	OfpPortDescPropTypeExperimenter = 0xffff /* Experimenter property. */
+)
+
+const (
+	portHeaderLen               = 40
+	portDescPropHeaderLen       = 4
+	portDescPropEthernetLen     = 32
+	portDescPropOpticalLen      = 40
+	portDescPropExperimenterLen = 12
+	portStatusHeaderLen         = 16
+	// ofpMaxPortNameLen is the size of the port name including the
+	// terminating zero
+	ofpMaxPortNameLen = 16
+)
+
+// OfpPortDescPropHeader represents the common header of all port description properties
+type OfpPortDescPropHeader struct {
+	Type   uint16 /* One of OFPPDPT_*. */
+	Length uint16 /* Length in bytes of this property. */
+}
+
+// UnmarshalBinary transforms the byte array into property header data
+func (pph *OfpPortDescPropHeader) UnmarshalBinary(data []byte) error {
+	if len(data) < portDescPropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, pph)
+}
+
+// MarshalBinary converts the property header fields into byte array
+func (pph *OfpPortDescPropHeader) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, pph); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpPortDescPropEthernet represents the ethernet port description property
+type OfpPortDescPropEthernet struct {
+	Type    uint16 /* OFPPDPT_ETHERNET. */
+	Length  uint16 /* Length in bytes of this property. */
+	Padding [4]byte
+	/* Bitmaps of OFPPF_* that describe features.  All bits zeroed if
+	 * unsupported or unavailable. */
+	Curr       uint32 /* Current features. */
+	Advertised uint32 /* Features being advertised by the port. */
+	Supported  uint32 /* Features supported by the port. */
+	Peer       uint32 /* Features advertised by peer. */
+
+	CurrSpeed uint32 /* Current port bitrate in kbps. */
+	MaxSpeed  uint32 /* Max port bitrate in kbps */
+}
+
+// UnmarshalBinary transforms the byte array into ethernet property data
+func (ppe *OfpPortDescPropEthernet) UnmarshalBinary(data []byte) error {
+	if len(data) < portDescPropEthernetLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, ppe)
+}
+
+// MarshalBinary converts the ethernet property fields into byte array
+func (ppe *OfpPortDescPropEthernet) MarshalBinary() ([]byte, error) {
+	ppe.Type = OfpPortDescPropTypeEthernet
+	ppe.Length = portDescPropEthernetLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ppe); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpPortDescPropOptical represents the optical port description property
+type OfpPortDescPropOptical struct {
+	Type           uint16 /* OFPPDPT_OPTICAL. */
+	Length         uint16 /* Length in bytes of this property. */
+	Padding        [4]byte
+	Supported      uint32 /* Features supported by the port. */
+	TxMinFreqLmda  uint32 /* Minimum TX Frequency/Wavelength */
+	TxMaxFreqLmda  uint32 /* Maximum TX Frequency/Wavelength */
+	TxGridFreqLmda uint32 /* TX Grid Spacing Frequency/Wavelength */
+	RxMinFreqLmda  uint32 /* Minimum RX Frequency/Wavelength */
+	RxMaxFreqLmda  uint32 /* Maximum RX Frequency/Wavelength */
+	RxGridFreqLmda uint32 /* RX Grid Spacing Frequency/Wavelength */
+	TxPwrMin       uint16 /* Minimum TX power */
+	TxPwrMax       uint16 /* Maximum TX power */
+}
+
+// UnmarshalBinary transforms the byte array into optical property data
+func (ppo *OfpPortDescPropOptical) UnmarshalBinary(data []byte) error {
+	if len(data) < portDescPropOpticalLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	return ofpgeneral.UnMarshalFields(buf, ppo)
+}
+
+// MarshalBinary converts the optical property fields into byte array
+func (ppo *OfpPortDescPropOptical) MarshalBinary() ([]byte, error) {
+	ppo.Type = OfpPortDescPropTypeOptical
+	ppo.Length = portDescPropOpticalLen
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ppo); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// OfpPortDescPropExperimenter represents the experimenter port description property
+type OfpPortDescPropExperimenter struct {
+	Type         uint16 /* OFPPDPT_EXPERIMENTER. */
+	Length       uint16 /* Length in bytes of this property. */
+	Experimenter uint32 /* Experimenter ID which takes the same
+	   form as in struct
+	   ofp_experimenter_header. */
+	ExpType uint32 /* Experimenter defined. */
+	Data    []byte /* Experimenter defined data, the padding to
+	   the multiple of 8 bytes is not included. */
+}
+
+// UnmarshalBinary transforms the byte array into experimenter property data
+func (ppe *OfpPortDescPropExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < portDescPropExperimenterLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &ppe.Type, &ppe.Length, &ppe.Experimenter, &ppe.ExpType); err != nil {
+		return err
+	}
+	if ppe.Length < portDescPropExperimenterLen || int(ppe.Length) > len(data) {
+		return fmt.Errorf("Invalid port property length %d", ppe.Length)
+	}
+	ppe.Data = make([]byte, ppe.Length-portDescPropExperimenterLen)
+	copy(ppe.Data, data[portDescPropExperimenterLen:ppe.Length])
+	return nil
+}
+
+// MarshalBinary converts the experimenter property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (ppe *OfpPortDescPropExperimenter) MarshalBinary() ([]byte, error) {
+	ppe.Type = OfpPortDescPropTypeExperimenter
+	ppe.Length = uint16(portDescPropExperimenterLen + len(ppe.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, ppe.Type, ppe.Length, ppe.Experimenter, ppe.ExpType); err != nil {
+		return nil, err
+	}
+	buf.Write(ppe.Data)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpPort represents the port structure (ofp_port) of openflow 1.4, the
+// features and the speeds moved to the properties
+type OfpPort struct {
+	PortNo   uint32
+	Length   uint16
+	Padding1 [2]byte
+	HwAddr   net.HardwareAddr
+	Padding2 [2]byte
+	Name     []byte /* Null-terminated */
+	Config   uint32 /* Bitmap of OFPPC_* flags. */
+	State    uint32 /* Bitmap of OFPPS_* flags. */
+	// Properties holds *OfpPortDescPropEthernet, *OfpPortDescPropOptical
+	// and *OfpPortDescPropExperimenter, *OfpPortDescPropHeader is kept
+	// for the unknown properties
+	Properties []ofpgeneral.OfpMessage /* Port description property list. */
+}
+
+// GetEthernetProp returns the ethernet property of the port, nil is
+// returned if the port isn't an ethernet port
+func (p *OfpPort) GetEthernetProp() *OfpPortDescPropEthernet {
+	for _, prop := range p.Properties {
+		if ethernet, ok := prop.(*OfpPortDescPropEthernet); ok {
+			return ethernet
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary transforms the byte array into port data
+func (p *OfpPort) UnmarshalBinary(data []byte) error {
+	if len(data) < portHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	p.HwAddr = make([]byte, 6)
+	p.Name = make([]byte, ofpMaxPortNameLen)
+	if err := ofpgeneral.UnMarshalFields(buf, &p.PortNo, &p.Length, &p.Padding1, &p.HwAddr, &p.Padding2,
+		&p.Name, &p.Config, &p.State); err != nil {
+		return err
+	}
+	if p.Length < portHeaderLen || int(p.Length) > len(data) {
+		return fmt.Errorf("Invalid port length %d", p.Length)
+	}
+	p.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := portHeaderLen; idx+portDescPropHeaderLen <= int(p.Length); {
+		propHeader := OfpPortDescPropHeader{}
+		if err := propHeader.UnmarshalBinary(data[idx:p.Length]); err != nil {
+			return err
+		}
+		if propHeader.Length < portDescPropHeaderLen || idx+int(propHeader.Length) > int(p.Length) {
+			return fmt.Errorf("Invalid port property length %d", propHeader.Length)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch propHeader.Type {
+		case OfpPortDescPropTypeEthernet:
+			prop = &OfpPortDescPropEthernet{}
+		case OfpPortDescPropTypeOptical:
+			prop = &OfpPortDescPropOptical{}
+		case OfpPortDescPropTypeExperimenter:
+			prop = &OfpPortDescPropExperimenter{}
+		default:
+			prop = &OfpPortDescPropHeader{}
+		}
+		if err := prop.UnmarshalBinary(data[idx : idx+int(propHeader.Length)]); err != nil {
+			return err
+		}
+		p.Properties = append(p.Properties, prop)
+		// The experimenter properties are padded to 8 bytes
+		idx += (int(propHeader.Length) + 7) / 8 * 8
+	}
+	return nil
+}
+
+// MarshalBinary converts the port fields into byte array, the length is
+// set according to the properties
+func (p *OfpPort) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range p.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	p.Length = uint16(portHeaderLen + propBuf.Len())
+	hwAddr := make([]byte, 6)
+	copy(hwAddr, p.HwAddr)
+	name := make([]byte, ofpMaxPortNameLen)
+	copy(name[:ofpMaxPortNameLen-1], p.Name)
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, p.PortNo, p.Length, p.Padding1, hwAddr, p.Padding2,
+		name, p.Config, p.State); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// ParsePortDescBody decodes the body of the openflow 1.4 port description reply
+func ParsePortDescBody(body []byte) ([]OfpPort, error) {
+	ports := make([]OfpPort, 0)
+	for idx := 0; idx < len(body); {
+		port := OfpPort{}
+		if err := port.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+		idx += int(port.Length)
+	}
+	return ports, nil
+}
+
+// OfpPortStatusMsg represents the port status msg structure
+/* A physical port has changed in the datapath */
+type OfpPortStatusMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Reason  uint8   /* One of OFPPR_*. */
+	Padding [7]byte /* Align to 64-bits. */
+	Desc    OfpPort
+}
+
+// UnmarshalBinary transforms the byte array into port status data
+func (psm *OfpPortStatusMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < portStatusHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &psm.Header, &psm.Reason, &psm.Padding); err != nil {
+		return err
+	}
+	return (&psm.Desc).UnmarshalBinary(data[portStatusHeaderLen:])
+}
+
+// MarshalBinary converts the port status fields into byte array
+func (psm *OfpPortStatusMsg) MarshalBinary() ([]byte, error) {
+	descData, err := (&psm.Desc).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	psm.Header.Length = uint16(portStatusHeaderLen + len(descData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, psm.Header, psm.Reason, psm.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(descData)
+	return buf.Bytes(), nil
+}
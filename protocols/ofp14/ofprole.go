@@ -0,0 +1,122 @@
+package ofp14
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// What changed about the controller role
+// enum ofp_controller_role_reason {
+const (
+	OfpCRReasonMasterRequest = iota /* Another controller asked to be master. */
+	OfpCRReasonConfig               /* Configuration changed on the switch. */
+	OfpCRReasonExperimenter         /* Experimenter data changed. */
+)
+
+// Role property types.
+// enum ofp_role_prop_type {
+const (
+	OfpRolePropTypeExperimenter = 0xffff /* Experimenter property. */
+)
+
+const (
+	roleStatusHeaderLen     = 24
+	rolePropExperimenterLen = 12
+)
+
+// OfpRolePropExperimenter represents the experimenter role property
+type OfpRolePropExperimenter struct {
+	Type         uint16 /* OFPRPT_EXPERIMENTER. */
+	Length       uint16 /* Length in bytes of this property. */
+	Experimenter uint32 /* Experimenter ID which takes the same
+	   form as in struct
+	   ofp_experimenter_header. */
+	ExpType uint32 /* Experimenter defined. */
+	Data    []byte /* Experimenter defined data. */
+}
+
+// UnmarshalBinary transforms the byte array into experimenter property data
+func (rpe *OfpRolePropExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < rolePropExperimenterLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &rpe.Type, &rpe.Length, &rpe.Experimenter, &rpe.ExpType); err != nil {
+		return err
+	}
+	if rpe.Length < rolePropExperimenterLen || int(rpe.Length) > len(data) {
+		return fmt.Errorf("Invalid role property length %d", rpe.Length)
+	}
+	rpe.Data = make([]byte, rpe.Length-rolePropExperimenterLen)
+	copy(rpe.Data, data[rolePropExperimenterLen:rpe.Length])
+	return nil
+}
+
+// MarshalBinary converts the experimenter property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (rpe *OfpRolePropExperimenter) MarshalBinary() ([]byte, error) {
+	rpe.Type = OfpRolePropTypeExperimenter
+	rpe.Length = uint16(rolePropExperimenterLen + len(rpe.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, rpe.Type, rpe.Length, rpe.Experimenter, rpe.ExpType); err != nil {
+		return nil, err
+	}
+	buf.Write(rpe.Data)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpRoleStatusMsg represents the role status message, which informs the
+// controller that its role has changed
+type OfpRoleStatusMsg struct {
+	Header       ofpgeneral.OfpHeader
+	Role         uint32 /* One of OFPCR_ROLE_*. */
+	Reason       uint8  /* One of OFPCRR_*. */
+	Padding      [3]byte
+	GenerationID uint64                    /* Master Election Generation Id */
+	Properties   []OfpRolePropExperimenter /* Role Property list */
+}
+
+// UnmarshalBinary transforms the byte array into role status data
+func (rs *OfpRoleStatusMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < roleStatusHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &rs.Header, &rs.Role, &rs.Reason, &rs.Padding,
+		&rs.GenerationID); err != nil {
+		return err
+	}
+	rs.Properties = make([]OfpRolePropExperimenter, 0)
+	for idx := roleStatusHeaderLen; idx < len(data); {
+		prop := OfpRolePropExperimenter{}
+		if err := prop.UnmarshalBinary(data[idx:]); err != nil {
+			return err
+		}
+		rs.Properties = append(rs.Properties, prop)
+		idx += (int(prop.Length) + 7) / 8 * 8
+	}
+	return nil
+}
+
+// MarshalBinary converts the role status fields into byte array
+func (rs *OfpRoleStatusMsg) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for idx := range rs.Properties {
+		propData, err := rs.Properties[idx].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	rs.Header.Length = uint16(roleStatusHeaderLen + propBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, rs.Header, rs.Role, rs.Reason, rs.Padding,
+		rs.GenerationID); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
@@ -1,8 +1,7 @@
 package ofp14
 
 import (
-	"bytes"
-
+	"github.com/kopwei/goof/protocols/ofp13"
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
@@ -144,119 +143,19 @@ const (
 	OfpQueFailedCodeErrPerm        /* Permissions error. */
 )
 
-// OfpPacketInMsg reprensents the packet_in message received by controller
-/* Packet received on port (datapath -> controller). */
-type OfpPacketInMsg struct {
-	Header   ofpgeneral.OfpHeader
-	BufferID uint32 /* ID assigned by datapath. */
-	TotalLen uint16 /* Full length of frame. */
-	InPort   uint16 /* Port on which frame was received. */
-	Reason   uint8  /* Reason packet is being sent (one of OFPR_*) */
-	Padding  uint8
-	Data     []byte /* Ethernet frame, halfway through 32-bit word,
-	   so the IP header is 32-bit aligned.  The
-	   amount of data is inferred from the length
-	   field in the header.  Because of padding,
-	   offsetof(struct ofp_packet_in, data) ==
-	   sizeof(struct ofp_packet_in) - 2. */
-}
+// The packet in and packet out messages are unchanged since openflow 1.3
 
-// MarshalBinary converts the packet in msg fields into byte array
-func (in *OfpPacketInMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, in.Header.Length)
-	headerData, err := (&in.Header).MarshalBinary()
-	copy(data, headerData)
-	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, in.BufferID, in.TotalLen, in.InPort, in.Reason, in.Padding)
-	if err != nil {
-		return nil, err
-	}
-	copy(data[4:16], buf.Bytes())
-	copy(data[16:], in.Data)
-	return data, err
-}
-
-// UnmarshalBinary transforms the byte array into packet in message data
-func (in *OfpPacketInMsg) UnmarshalBinary(data []byte) error {
-	if err := (&in.Header).UnmarshalBinary(data); err != nil {
-		return err
-	}
-	buf := bytes.NewReader(data[4:14])
-	if err := ofpgeneral.UnMarshalFields(buf, &in.BufferID, &in.TotalLen, &in.InPort, &in.Reason, &in.Padding); err != nil {
-		return err
-	}
-	copy(in.Data, data[14:])
-	return nil
-}
+// OfpPacketInMsg reprensents the packet_in message received by controller
+//
+// Deprecated: use ofp13.OfpPacketInMsg
+type OfpPacketInMsg = ofp13.OfpPacketInMsg
 
 // OfpPacketOutMsg reprensents the packet_out message sent by controller
-/* Send packet (controller -> datapath). */
-type OfpPacketOutMsg struct {
-	Header     ofpgeneral.OfpHeader
-	BufferID   uint32         /* ID assigned by datapath (-1 if none). */
-	InPort     uint16         /* Packet's input port (OFPP_NONE if none). */
-	ActionsLen uint16         /* Size of action array in bytes. */
-	Actions    []OfpActionMsg /* Actions. */
-	/* uint8_t data[0]; */ /* Packet data.  The length is inferred
-	   from the length field in the header.
-	   (Only meaningful if buffer_id == -1.) */
-}
-
-// MarshalBinary converts the packet out msg fields into byte array
-func (out *OfpPacketOutMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, out.Header.Length)
-	headerData, err := (&out.Header).MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	copy(data, headerData)
-	buf := new(bytes.Buffer)
-	err = ofpgeneral.MarshalFields(buf, out.BufferID, out.InPort, out.ActionsLen)
-	if err != nil {
-		return nil, err
-	}
-	copy(data[4:16], buf.Bytes())
-	actionByteIdx := uint16(16)
-	for _, action := range out.Actions {
-		actionData, err := action.MarshalBinary()
-		if err != nil {
-			return nil, err
-		}
-		copy(data[actionByteIdx:actionByteIdx+action.Header.Len], actionData)
-		actionByteIdx = actionByteIdx + action.Header.Len
-	}
-	return data, err
-}
-
-// UnmarshalBinary transforms the byte array into packet out message data
-func (out *OfpPacketOutMsg) UnmarshalBinary(data []byte) error {
-	if err := (&out.Header).UnmarshalBinary(data); err != nil {
-		return err
-	}
-	buf := bytes.NewReader(data[4:16])
-	if err := ofpgeneral.UnMarshalFields(buf, &out.BufferID, &out.InPort, &out.ActionsLen); err != nil {
-		return err
-	}
-	actionByteIdx := uint16(16)
-	for i := uint16(0); i < out.ActionsLen; i++ {
-		if err := out.Actions[i].UnmarshalBinary(data[actionByteIdx:]); err != nil {
-			return err
-		}
-		actionByteIdx += out.Actions[i].Header.Len
-	}
-	return nil
-}
+//
+// Deprecated: use ofp13.OfpPacketOutMsg
+type OfpPacketOutMsg = ofp13.OfpPacketOutMsg
 
 // ParseMsg is the function which parses the message
 func ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	var msg ofpgeneral.OfpMessage
-	var err error
-	switch b[1] {
-	case OfpTypeHello:
-		msg = &ofpgeneral.OfpHelloMsg{}
-		err = msg.UnmarshalBinary(b)
-
-	}
-
-	return msg, err
+	return (&OfpMessageParser{}).ParseMsg(b)
 }
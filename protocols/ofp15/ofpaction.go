@@ -4,368 +4,156 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
-	"net"
 
+	"github.com/kopwei/goof/protocols/ofp13"
 	"github.com/kopwei/goof/protocols/ofpgeneral"
 )
 
-// OFP Action Type
+// Action types introduced by openflow 1.5, the other types are the
+// ofp13.OfpAction* constants whose layouts are unchanged
+// enum ofp_action_type {
 const (
-	OfpActionOutputToPort = iota /* Output to switch port. */
-	OfpActionSetVlanVID          /* Set the 802.1q VLAN id. */
-	OfpActionSetVlanPCP          /* Set the 802.1q priority. */
-	OfpActionStripVlan           /* Strip the 802.1q header. */
-	OfpActionSetDLSrc            /* Ethernet source address. */
-	OfpActionSetDLDst            /* Ethernet destination address. */
-	OfpActionSetNWSrc            /* IP source address. */
-	OfpActionSetNWDst            /* IP destination address. */
-	OfpActionSetNWToS            /* IP ToS (DSCP field, 6 bits). */
-	OfpActionSetTPSrc            /* TCP/UDP source port. */
-	OfpActionSetTPDst            /* TCP/UDP destination port. */
-	OfpActionEnqueue             /* Output to queue.  */
-	OfpActionVendor       = 0xffff
+	OfpActionCopyField = 28 /* Copy value between header and register. */
+	OfpActionMeter     = 29 /* Apply meter (rate limiter) */
 )
 
-// ofp_error_msg 'code' values for OFPET_BAD_ACTION.  'data' contains at least
-// the first 64 bytes of the failed request. */
-//enum ofp_bad_action_code {
 const (
-	OfpBadActionCodeBadType       = iota /* Unknown action type. */
-	OfpBadActionCodeBadLen               /* Length problem in actions. */
-	OfpBadActionCodeBadVendor            /* Unknown vendor id specified. */
-	OfpBadActionCodeBadVendorType        /* Unknown action type for vendor id. */
-	OfpBadActionCodeBadOutPort           /* Problem validating output action. */
-	OfpBadActionCodeBadArgument          /* Bad action argument. */
-	OfpBadActionCodeErrPerm              /* Permissions error. */
-	OfpBadActionCodeTooMany              /* Can't handle this many actions. */
-	OfpBadActionCodeBadQueue             /* Problem validating output queue. */
+	ofpActionHeaderLen  = 8
+	actionCopyFieldLen  = 24
+	actionMeterLen      = 8
+	actionCopyFieldSize = 20 /* Size of the copy field action without padding. */
 )
 
-// OfpActionOutput represents the ofp action output
-// Action structure for OFPAT_OUTPUT, which sends packets out 'port'.
-// When the 'port' is the OFPP_CONTROLLER, 'max_len' indicates the max
-// number of bytes to send.  A 'max_len' of zero means no bytes of the
-// packet should be sent
-type OfpActionOutput struct {
-	Type   uint16 /* OFPAT_OUTPUT. */
-	Len    uint16 /* Length is 8. */
-	Port   uint16 /* Output port. */
-	MaxLen uint16 /* Max length to send to controller. */
+// OfpActionCopyFieldInfo represents action structure for OFPAT_COPY_FIELD,
+// which copies the bits of a header field or a packet register into
+// another one. The fields are given by their OXM ids, see
+// ofpgeneral.NewOxmID.
+type OfpActionCopyFieldInfo struct {
+	Type      uint16 /* OFPAT_COPY_FIELD. */
+	Length    uint16 /* Length is padded to 64 bits. */
+	NBits     uint16 /* Number of bits to copy. */
+	SrcOffset uint16 /* Starting bit offset in source. */
+	DstOffset uint16 /* Starting bit offset in destination. */
+	Padding   [2]byte
+	SrcOxmID  uint32 /* Source OXM header. */
+	DstOxmID  uint32 /* Destination OXM header. */
 }
 
-// UnmarshalBinary transforms the byte array into body data
-func (ao *OfpActionOutput) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ao.Type, &ao.Len, &ao.Port, &ao.MaxLen)
-}
-
-// MarshalBinary converts the header fields into byte array
-func (ao *OfpActionOutput) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ao.Type, ao.Len, ao.Port, ao.MaxLen); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+// NewActionCopyField creates the action copying nBits bits of the source
+// field to the destination field
+func NewActionCopyField(nBits, srcOffset, dstOffset uint16, srcOxmID, dstOxmID uint32) *OfpActionCopyFieldInfo {
+	return &OfpActionCopyFieldInfo{Type: OfpActionCopyField, Length: actionCopyFieldLen, NBits: nBits,
+		SrcOffset: srcOffset, DstOffset: dstOffset, SrcOxmID: srcOxmID, DstOxmID: dstOxmID}
 }
 
-// OfpActionVlanVID represents action structure for OFPAT_SET_VLAN_VID.
-// The VLAN id is 12 bits, so we can use the entire 16 bits to indicate
-// special conditions.  All ones is used to match that no VLAN id was
-// set.
-type OfpActionVlanVID struct {
-	Type    uint16 /* OFPAT_SET_VLAN_VID. */
-	Len     uint16 /* Length is 8. */
-	VlanVID uint16 /* VLAN id. */
-	Padding [2]byte
+// Len returns the length of the action including the padding
+func (acf *OfpActionCopyFieldInfo) Len() uint16 {
+	return actionCopyFieldLen
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (avv *OfpActionVlanVID) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
+func (acf *OfpActionCopyFieldInfo) UnmarshalBinary(data []byte) error {
+	if len(data) < actionCopyFieldSize {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &avv.Type, &avv.Len, &avv.VlanVID)
+	return ofpgeneral.UnMarshalFields(buf, acf)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (avv *OfpActionVlanVID) MarshalBinary() ([]byte, error) {
+func (acf *OfpActionCopyFieldInfo) MarshalBinary() ([]byte, error) {
+	acf.Length = actionCopyFieldLen
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, avv.Type, avv.Len, avv.VlanVID, avv.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, acf); err != nil {
 		return nil, err
 	}
+	buf.Write(make([]byte, actionCopyFieldLen-actionCopyFieldSize))
 	return buf.Bytes(), nil
 }
 
-// OfpActionVlanPCP represents action structure for OFPAT_SET_VLAN_PCP.
-type OfpActionVlanPCP struct {
-	Type    uint16 /* OFPAT_SET_VLAN_PCP. */
-	Len     uint16 /* Length is 8. */
-	VlanPCP uint8  /* VLAN priority. */
-	Padding [3]byte
+// OfpActionMeterInfo represents action structure for OFPAT_METER, which
+// replaces the meter instruction of the previous versions
+type OfpActionMeterInfo struct {
+	Type    uint16 /* OFPAT_METER. */
+	Length  uint16 /* Length is 8. */
+	MeterID uint32 /* Meter instance. */
 }
 
-// UnmarshalBinary transforms the byte array into body data
-func (avp *OfpActionVlanPCP) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &avp.Type, &avp.Len, &avp.VlanPCP)
+// NewActionMeter creates the action applying the meter
+func NewActionMeter(meterID uint32) *OfpActionMeterInfo {
+	return &OfpActionMeterInfo{Type: OfpActionMeter, Length: actionMeterLen, MeterID: meterID}
 }
 
-// MarshalBinary converts the header fields into byte array
-func (avp *OfpActionVlanPCP) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, avp.Type, avp.Len, avp.VlanPCP, avp.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-// OfpActionDLAddt represents action structure for OFPAT_SET_DL_SRC/DST.
-type OfpActionDLAddt struct {
-	Type    uint16 /* OFPAT_SET_DL_SRC/DST. */
-	Len     uint16 /* Length is 16. */
-	DLAddr  net.HardwareAddr
-	Padding [6]byte
+// Len returns the length of the action
+func (am *OfpActionMeterInfo) Len() uint16 {
+	return actionMeterLen
 }
 
 // UnmarshalBinary transforms the byte array into body data
-func (ada *OfpActionDLAddt) UnmarshalBinary(data []byte) error {
-	if len(data) < 16 {
+func (am *OfpActionMeterInfo) UnmarshalBinary(data []byte) error {
+	if len(data) < actionMeterLen {
 		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
 	}
 	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &ada.Type, &ada.Len); err != nil {
-		return err
-	}
-	ada.DLAddr = make([]byte, 6)
-	copy(ada.DLAddr, data[4:])
-	return nil
+	return ofpgeneral.UnMarshalFields(buf, am)
 }
 
 // MarshalBinary converts the header fields into byte array
-func (ada *OfpActionDLAddt) MarshalBinary() ([]byte, error) {
-	data := make([]byte, 16)
+func (am *OfpActionMeterInfo) MarshalBinary() ([]byte, error) {
+	am.Length = actionMeterLen
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ada.Type, ada.Len); err != nil {
-		return nil, err
-	}
-	copy(data, buf.Bytes())
-	copy(data[4:], ada.DLAddr)
-	return data, nil
-}
-
-// OfpActionNWAddt represents Action structure for OFPAT_SET_NW_SRC/DST.
-type OfpActionNWAddt struct {
-	Type   uint16 /* OFPAT_SET_TW_SRC/DST. */
-	Len    uint16 /* Length is 8. */
-	NWAddr net.IP /* IP address. */
-}
-
-// UnmarshalBinary transforms the byte array into body data
-func (ana *OfpActionNWAddt) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	if err := ofpgeneral.UnMarshalFields(buf, &ana.Type, &ana.Len); err != nil {
-		return err
-	}
-	ana.NWAddr = make([]byte, 4)
-	copy(ana.NWAddr, data[4:])
-	return nil
-}
-
-// MarshalBinary converts the header fields into byte array
-func (ana *OfpActionNWAddt) MarshalBinary() ([]byte, error) {
-	data := make([]byte, 8)
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ana.Type, ana.Len); err != nil {
-		return nil, err
-	}
-	copy(data, buf.Bytes())
-	copy(data[4:], ana.NWAddr)
-	return data, nil
-}
-
-// OfpActionTPPort represents action structure for OFPAT_SET_TP_SRC/DST.
-type OfpActionTPPort struct {
-	Type    uint16 /* OFPAT_SET_TP_SRC/DST. */
-	Len     uint16 /* Length is 8. */
-	TPPort  uint16 /* TCP/UDP port. */
-	Padding [2]byte
-}
-
-// UnmarshalBinary transforms the byte array into body data
-func (atp *OfpActionTPPort) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &atp.Type, &atp.Len, &atp.TPPort)
-}
-
-// MarshalBinary converts the header fields into byte array
-func (atp *OfpActionTPPort) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, atp.Type, atp.Len, atp.TPPort, atp.Padding); err != nil {
+	if err := ofpgeneral.MarshalFields(buf, am); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// OfpActionNWToS represents action structure for OFPAT_SET_NW_TOS.
-type OfpActionNWToS struct {
-	Type    uint16 /* OFPAT_SET_TW_SRC/DST. */
-	Len     uint16 /* Length is 8. */
-	NWTos   uint8  /* IP ToS (DSCP field, 6 bits). */
-	Padding [3]byte
-}
-
-// UnmarshalBinary transforms the byte array into body data
-func (ant *OfpActionNWToS) UnmarshalBinary(data []byte) error {
-	if len(data) < 8 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ant.Type, &ant.Len, &ant.NWTos)
-}
-
-// MarshalBinary converts the header fields into byte array
-func (ant *OfpActionNWToS) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ant.Type, ant.Len, ant.NWTos, ant.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-// OfpActionVendorHeader represents action header for OFPAT_VENDOR.
-// The rest of the body is vendor-defined.
-type OfpActionVendorHeader struct {
-	Type   uint16 /* OFPAT_VENDOR. */
-	Len    uint16 /* Length is a multiple of 8. */
-	Vendor uint32 /* Vendor ID, which takes the same form
-	   as in "struct ofp_vendor_header". */
-}
-
-// OfpActionHeader represents the header structure that is common to all actions.
-// The length includes the header and any padding used to make the action 64-bit aligned.
-// NB: The length of an action *must* always be a multiple of eight.
-type OfpActionHeader struct {
-	Type uint16 /* One of OFPAT_*. */
-	Len  uint16 /* Length of action, including this
-	   header.  This is the length of action,
-	   including any padding to make it
-	   64-bit aligned. */
-	Padding [4]byte
-}
-
-// UnmarshalBinary transforms the byte array into header data
-func (ah *OfpActionHeader) UnmarshalBinary(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &ah.Type, &ah.Len)
-}
-
-// MarshalBinary converts the header fields into byte array
-func (ah *OfpActionHeader) MarshalBinary() ([]byte, error) {
-	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, ah.Type, ah.Len, ah.Padding); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-// OfpActionEnqueueInfo represents the OFPAT_ENQUEUE action struct: send packets to given queue on port.
-type OfpActionEnqueueInfo struct {
-	Type uint16 /* OFPAT_ENQUEUE. */
-	Len  uint16 /* Len is 16. */
-	Port uint16 /* Port that queue belongs. Should
-	   refer to a valid physical port
-	   (i.e. < OFPP_MAX) or OFPP_IN_PORT. */
-	Padding [6]byte /* Pad for 64-bit alignment. */
-	QueueID uint32  /* Where to enqueue the packets. */
-}
-
-// UnmarshalBinary transforms the byte array into body data
-func (aei *OfpActionEnqueueInfo) UnmarshalBinary(data []byte) error {
-	if len(data) < 16 {
-		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
-	}
-	buf := bytes.NewReader(data)
-	return ofpgeneral.UnMarshalFields(buf, &aei.Type, &aei.Len, &aei.Port, &aei.Padding, &aei.QueueID)
-}
-
-// MarshalBinary converts the header fields into byte array
-func (aei *OfpActionEnqueueInfo) MarshalBinary() ([]byte, error) {
+// ParseActions decodes the list of openflow 1.5 actions, the actions
+// unchanged since openflow 1.3 are decoded into the ofp13 structures
+func ParseActions(data []byte) ([]ofp13.OfpAction, error) {
+	actions := make([]ofp13.OfpAction, 0)
+	for idx := 0; idx < len(data); {
+		if len(data)-idx < ofpActionHeaderLen {
+			return nil, fmt.Errorf("The data size %d is not big enough to be decoded", len(data)-idx)
+		}
+		actionType := binary.BigEndian.Uint16(data[idx : idx+2])
+		actionLen := int(binary.BigEndian.Uint16(data[idx+2 : idx+4]))
+		if actionLen < ofpActionHeaderLen || idx+actionLen > len(data) {
+			return nil, fmt.Errorf("Invalid length %d of action type %d", actionLen, actionType)
+		}
+		var action ofp13.OfpAction
+		switch actionType {
+		case OfpActionCopyField:
+			action = &OfpActionCopyFieldInfo{}
+		case OfpActionMeter:
+			action = &OfpActionMeterInfo{}
+		default:
+			parsed, err := ofp13.ParseActions(data[idx : idx+actionLen])
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, parsed...)
+			idx += actionLen
+			continue
+		}
+		if err := action.UnmarshalBinary(data[idx : idx+actionLen]); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+		idx += actionLen
+	}
+	return actions, nil
+}
+
+// marshalActions encodes the list of actions
+func marshalActions(actions []ofp13.OfpAction) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	if err := ofpgeneral.MarshalFields(buf, aei.Type, aei.Len, aei.Port, aei.Padding, aei.QueueID); err != nil {
-		return nil, err
+	for _, action := range actions {
+		actionData, err := action.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(actionData)
 	}
 	return buf.Bytes(), nil
 }
-
-// OfpActionMsg represents the body structure of the action msg sent to datapath
-type OfpActionMsg struct {
-	Header OfpActionHeader
-	Body   ofpgeneral.OfpMessage
-}
-
-// UnmarshalBinary transforms the byte array into msg data
-func (oam *OfpActionMsg) UnmarshalBinary(data []byte) error {
-	if err := (&oam.Header).UnmarshalBinary(data); err != nil {
-		return err
-	}
-	dataStartIdx := 4
-	actionType := binary.BigEndian.Uint16(data[dataStartIdx : dataStartIdx+2])
-	switch actionType {
-	case OfpActionOutputToPort:
-		oam.Body = &OfpActionOutput{}
-	case OfpActionSetVlanVID:
-		oam.Body = &OfpActionVlanVID{}
-	case OfpActionSetVlanPCP:
-		oam.Body = &OfpActionVlanPCP{}
-	case OfpActionSetDLSrc:
-		oam.Body = &OfpActionDLAddt{}
-	case OfpActionSetDLDst:
-		oam.Body = &OfpActionDLAddt{}
-	case OfpActionSetNWSrc:
-		oam.Body = &OfpActionNWAddt{}
-	case OfpActionSetNWDst:
-		oam.Body = &OfpActionNWAddt{}
-	case OfpActionSetNWToS:
-		oam.Body = &OfpActionNWToS{}
-	case OfpActionSetTPSrc:
-		oam.Body = &OfpActionTPPort{}
-	case OfpActionSetTPDst:
-		oam.Body = &OfpActionTPPort{}
-	case OfpActionEnqueue:
-		oam.Body = &OfpActionEnqueueInfo{}
-	}
-	oam.Body.UnmarshalBinary(data[dataStartIdx:])
-	return nil
-}
-
-// MarshalBinary transforms the msg data into byte array
-func (oam *OfpActionMsg) MarshalBinary() ([]byte, error) {
-	data := make([]byte, oam.Header.Len)
-	headerData, err := oam.Header.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	copy(data, headerData)
-	bodyData, err := oam.Body.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	copy(data[4:], bodyData)
-	return data, nil
-}
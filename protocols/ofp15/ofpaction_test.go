@@ -0,0 +1,66 @@
+package ofp15
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+func TestActionsWireFormat(t *testing.T) {
+	// The ipv4_src field is copied into the packet_reg1 register, then the
+	// meter 5 is applied and the packet is output to the port 1
+	srcID := ofpgeneral.NewOxmID(ofpgeneral.OxmClassOpenflowBasic, ofpgeneral.OxmFieldIPv4Src)
+	dstID := ofpgeneral.NewOxmID(ofpgeneral.OxmClassPacketRegs, 1)
+	actions := []ofp13.OfpAction{NewActionCopyField(32, 0, 16, srcID, dstID), NewActionMeter(5), ofp13.NewActionOutput(1)}
+	data := wiretest.Decode(t, "001c 0018 0020 0000 0010 0000 80001604 80010208 00000000"+
+		"001d 0008 00000005"+
+		"0000 0010 00000001 ffff 000000000000")
+	encoded, err := marshalActions(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != string(data) {
+		t.Fatalf("The actions are encoded as %x, expected %x", encoded, data)
+	}
+
+	decoded, err := ParseActions(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("Decoded %d actions, expected 3", len(decoded))
+	}
+	if copyField, ok := decoded[0].(*OfpActionCopyFieldInfo); !ok || copyField.NBits != 32 || copyField.SrcOffset != 0 ||
+		copyField.DstOffset != 16 || copyField.SrcOxmID != srcID || copyField.DstOxmID != dstID || copyField.Len() != 24 {
+		t.Errorf("Unexpected copy field action %+v", decoded[0])
+	}
+	if meter, ok := decoded[1].(*OfpActionMeterInfo); !ok || meter.MeterID != 5 {
+		t.Errorf("Unexpected meter action %+v", decoded[1])
+	}
+	if output, ok := decoded[2].(*ofp13.OfpActionOutput); !ok || output.Port != 1 {
+		t.Errorf("Unexpected output action %+v", decoded[2])
+	}
+	reencoded, err := marshalActions(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reencoded) != string(data) {
+		t.Errorf("The decoded actions are encoded as %x, expected %x", reencoded, data)
+	}
+}
+
+func TestParseActionsInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"001c 0018 0020", // Truncated header
+		"001c 0018 0020 0000 0010 0000 80001604 80010208", // Copy field beyond the data
+		"001c 0010 0020 0000 0010 0000 80001604",          // Copy field shorter than its fields
+		"001d 0004 00000005",                              // Meter shorter than the action header
+		"001d 0008 00000005 0000 0010 00000001",           // Truncated output
+	} {
+		if actions, err := ParseActions(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The actions %s are decoded as %+v", wire, actions)
+		}
+	}
+}
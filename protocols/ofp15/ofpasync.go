@@ -0,0 +1,10 @@
+package ofp15
+
+// Async Config property types introduced by openflow 1.5, the other types
+// are the ofp14.OfpAsyncConfigPropType* constants whose layouts are
+// unchanged
+// enum ofp_async_config_prop_type {
+const (
+	OfpAsyncConfigPropTypeContStatusSlave  = 12 /* Controller status mask for slave. */
+	OfpAsyncConfigPropTypeContStatusMaster = 13 /* Controller status mask for master. */
+)
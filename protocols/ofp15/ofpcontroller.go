@@ -0,0 +1,256 @@
+package ofp15
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Why is the controller status being sent?
+// enum ofp_controller_status_reason {
+const (
+	OfpCSReasonRequest           = iota /* Controller requested status. */
+	OfpCSReasonChannelStatus            /* Oper status of channel changed. */
+	OfpCSReasonRole                     /* Controller role changed. */
+	OfpCSReasonControllerAdded          /* New controller added. */
+	OfpCSReasonControllerRemoved        /* Controller removed from config. */
+	OfpCSReasonShortID                  /* Controller ID changed. */
+	OfpCSReasonExperimenter             /* Experimenter data changed. */
+)
+
+// Control channel status.
+// enum ofp_control_channel_status {
+const (
+	OfpCTStatusUp   = 0 /* Control channel is operational. */
+	OfpCTStatusDown = 1 /* Control channel is not operational. */
+)
+
+// Controller status property types.
+// enum ofp_controller_status_prop_type {
+const (
+	OfpCSPropTypeURI          = 0      /* Connection URI property. */
+	OfpCSPropTypeExperimenter = 0xffff /* Experimenter property. */
+)
+
+const (
+	controllerStatusLen               = 16
+	controllerStatusPropHeaderLen     = 4
+	controllerStatusPropExperimentLen = 12
+)
+
+// OfpControllerStatusPropURI represents the connection URI property of the
+// controller status
+type OfpControllerStatusPropURI struct {
+	Type   uint16 /* OFPCSPT_URI. */
+	Length uint16 /* Length in bytes of this property. */
+	URI    string /* Controller connection URI, the padding isn't included. */
+}
+
+// UnmarshalBinary transforms the byte array into URI property data
+func (csu *OfpControllerStatusPropURI) UnmarshalBinary(data []byte) error {
+	if len(data) < controllerStatusPropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &csu.Type, &csu.Length); err != nil {
+		return err
+	}
+	if csu.Length < controllerStatusPropHeaderLen || int(csu.Length) > len(data) {
+		return fmt.Errorf("Invalid controller status property length %d", csu.Length)
+	}
+	csu.URI = string(bytes.TrimRight(data[controllerStatusPropHeaderLen:csu.Length], "\x00"))
+	return nil
+}
+
+// MarshalBinary converts the URI property fields into byte array, the
+// property is padded to the multiple of 8 bytes
+func (csu *OfpControllerStatusPropURI) MarshalBinary() ([]byte, error) {
+	csu.Type = OfpCSPropTypeURI
+	csu.Length = uint16(controllerStatusPropHeaderLen + len(csu.URI))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, csu.Type, csu.Length); err != nil {
+		return nil, err
+	}
+	buf.WriteString(csu.URI)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpControllerStatusPropExperimenter represents the experimenter property
+// of the controller status
+type OfpControllerStatusPropExperimenter struct {
+	Type         uint16 /* OFPCSPT_EXPERIMENTER. */
+	Length       uint16 /* Length in bytes of this property. */
+	Experimenter uint32 /* Experimenter ID which takes the same
+	   form as in struct
+	   ofp_experimenter_header. */
+	ExpType uint32 /* Experimenter defined. */
+	Data    []byte /* Experimenter defined data, the padding to
+	   the multiple of 8 bytes is not included. */
+}
+
+// UnmarshalBinary transforms the byte array into experimenter property data
+func (cse *OfpControllerStatusPropExperimenter) UnmarshalBinary(data []byte) error {
+	if len(data) < controllerStatusPropExperimentLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &cse.Type, &cse.Length, &cse.Experimenter, &cse.ExpType); err != nil {
+		return err
+	}
+	if cse.Length < controllerStatusPropExperimentLen || int(cse.Length) > len(data) {
+		return fmt.Errorf("Invalid controller status property length %d", cse.Length)
+	}
+	cse.Data = make([]byte, cse.Length-controllerStatusPropExperimentLen)
+	copy(cse.Data, data[controllerStatusPropExperimentLen:cse.Length])
+	return nil
+}
+
+// MarshalBinary converts the experimenter property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (cse *OfpControllerStatusPropExperimenter) MarshalBinary() ([]byte, error) {
+	cse.Type = OfpCSPropTypeExperimenter
+	cse.Length = uint16(controllerStatusPropExperimentLen + len(cse.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, cse.Type, cse.Length, cse.Experimenter, cse.ExpType); err != nil {
+		return nil, err
+	}
+	buf.Write(cse.Data)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpControllerStatus represents the status of a controller connected to
+// the switch, it is the body of the controller status message and an entry
+// of the controller status multipart reply
+type OfpControllerStatus struct {
+	Length        uint16 /* Length of this entry. */
+	ShortID       uint16 /* ID number which identifies the controller. */
+	Role          uint32 /* One of OFPCR_ROLE_*. */
+	Reason        uint8  /* One of OFPCSR_*. */
+	ChannelStatus uint8  /* One of OFPCT_STATUS_*. */
+	Padding       [6]byte
+	// Properties holds *OfpControllerStatusPropURI and
+	// *OfpControllerStatusPropExperimenter, the unknown properties are
+	// skipped
+	Properties []ofpgeneral.OfpMessage /* Controller Status Property list. */
+}
+
+// GetURI returns the connection URI of the controller, an empty string is
+// returned if the switch doesn't report it
+func (cs *OfpControllerStatus) GetURI() string {
+	for _, prop := range cs.Properties {
+		if uri, ok := prop.(*OfpControllerStatusPropURI); ok {
+			return uri.URI
+		}
+	}
+	return ""
+}
+
+// UnmarshalBinary transforms the byte array into controller status data
+func (cs *OfpControllerStatus) UnmarshalBinary(data []byte) error {
+	if len(data) < controllerStatusLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &cs.Length, &cs.ShortID, &cs.Role, &cs.Reason,
+		&cs.ChannelStatus, &cs.Padding); err != nil {
+		return err
+	}
+	if cs.Length < controllerStatusLen || int(cs.Length) > len(data) {
+		return fmt.Errorf("Invalid controller status length %d", cs.Length)
+	}
+	cs.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := controllerStatusLen; idx+controllerStatusPropHeaderLen <= int(cs.Length); {
+		var propType, propLen uint16
+		propBuf := bytes.NewReader(data[idx:cs.Length])
+		if err := ofpgeneral.UnMarshalFields(propBuf, &propType, &propLen); err != nil {
+			return err
+		}
+		if propLen < controllerStatusPropHeaderLen || idx+int(propLen) > int(cs.Length) {
+			return fmt.Errorf("Invalid controller status property length %d", propLen)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch propType {
+		case OfpCSPropTypeURI:
+			prop = &OfpControllerStatusPropURI{}
+		case OfpCSPropTypeExperimenter:
+			prop = &OfpControllerStatusPropExperimenter{}
+		}
+		if prop != nil {
+			if err := prop.UnmarshalBinary(data[idx : idx+int(propLen)]); err != nil {
+				return err
+			}
+			cs.Properties = append(cs.Properties, prop)
+		}
+		idx += (int(propLen) + 7) / 8 * 8
+	}
+	return nil
+}
+
+// MarshalBinary converts the controller status fields into byte array, the
+// length is set according to the properties
+func (cs *OfpControllerStatus) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range cs.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	cs.Length = uint16(controllerStatusLen + propBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, cs.Length, cs.ShortID, cs.Role, cs.Reason,
+		cs.ChannelStatus, cs.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// OfpControllerStatusMsg represents the controller status message, which
+// informs the controllers that the status of a controller of the switch
+// has changed
+type OfpControllerStatusMsg struct {
+	Header ofpgeneral.OfpHeader
+	Status OfpControllerStatus /* Controller status */
+}
+
+// UnmarshalBinary transforms the byte array into controller status message data
+func (csm *OfpControllerStatusMsg) UnmarshalBinary(data []byte) error {
+	if err := csm.Header.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	return (&csm.Status).UnmarshalBinary(data[8:])
+}
+
+// MarshalBinary converts the controller status message fields into byte array
+func (csm *OfpControllerStatusMsg) MarshalBinary() ([]byte, error) {
+	statusData, err := (&csm.Status).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	csm.Header.Length = uint16(8 + len(statusData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, csm.Header); err != nil {
+		return nil, err
+	}
+	buf.Write(statusData)
+	return buf.Bytes(), nil
+}
+
+// ParseControllerStatusBody decodes the body of the controller status reply
+func ParseControllerStatusBody(body []byte) ([]OfpControllerStatus, error) {
+	statuses := make([]OfpControllerStatus, 0)
+	for idx := 0; idx < len(body); {
+		status := OfpControllerStatus{}
+		if err := status.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+		idx += int(status.Length)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,218 @@
+package ofp15
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Why was this flow removed?
+// enum ofp_flow_removed_reason {
+const (
+	OfpFlowRemoveReasonIdleTimeout = iota /* Flow idle time exceeded idle_timeout. */
+	OfpFlowRemoveReasonHardTimeout        /* Time exceeded hard_timeout. */
+	OfpFlowRemoveReasonDelete             /* Evicted by a DELETE flow mod. */
+	OfpFlowRemoveReasonGroupDelete        /* Group was removed. */
+	OfpFlowRemoveReasonMeterDelete        /* Meter was removed. */
+	OfpFlowRemoveReasonEviction           /* Switch eviction to free resources. */
+)
+
+// OXS Class IDs.
+// enum ofp_oxs_class {
+const (
+	OxsClassOpenflowBasic = 0x8002 /* Basic stats class for OpenFlow */
+	OxsClassExperimenter  = 0xffff /* Experimenter class */
+)
+
+// OXS flow stat field types for OpenFlow basic class.
+// enum oxs_ofb_stat_fields {
+const (
+	OxsFieldDuration    = 0 /* Time flow entry has been alive. */
+	OxsFieldIdleTime    = 1 /* Time flow entry has been idle. */
+	OxsFieldFlowCount   = 3 /* Number of aggregated flow entries. */
+	OxsFieldPacketCount = 4 /* Number of packets in flow entry. */
+	OxsFieldByteCount   = 5 /* Number of bytes in flow entry. */
+)
+
+const (
+	flowRemovedHeaderLen = 24
+	ofpStatsHeaderLen    = 4
+	oxsHeaderLen         = 4
+)
+
+// OfpOxsField represents a stat field of the flow statistics, the value of
+// the durations carries the seconds in the upper 32 bits and the
+// nanoseconds in the lower ones
+type OfpOxsField struct {
+	Class uint16 /* One of OFPXSC_*. */
+	Field uint8  /* One of OFPXST_*, the reserved bit is dropped. */
+	Value []byte /* The length of the value is the oxs_length. */
+}
+
+// OfpStats represents the flow statistics made of OXS fields
+type OfpStats struct {
+	Reserved uint16 /* Reserved for future use, currently zeroed. */
+	Length   uint16 /* Length of ofp_stats (excluding padding) */
+	Fields   []OfpOxsField
+}
+
+// getField returns the value of the openflow basic field, nil is returned
+// if the field of the size isn't present
+func (s *OfpStats) getField(field uint8, size int) []byte {
+	for _, f := range s.Fields {
+		if f.Class == OxsClassOpenflowBasic && f.Field == field && len(f.Value) == size {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// GetDuration returns the time the flow has been alive, the second return
+// value is false if the switch doesn't report it
+func (s *OfpStats) GetDuration() (uint32, uint32, bool) {
+	value := s.getField(OxsFieldDuration, 8)
+	if value == nil {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(value), binary.BigEndian.Uint32(value[4:]), true
+}
+
+// GetPacketCount returns the number of the packets matched by the flow,
+// the second return value is false if the switch doesn't report it
+func (s *OfpStats) GetPacketCount() (uint64, bool) {
+	value := s.getField(OxsFieldPacketCount, 8)
+	if value == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(value), true
+}
+
+// GetByteCount returns the number of the bytes matched by the flow, the
+// second return value is false if the switch doesn't report it
+func (s *OfpStats) GetByteCount() (uint64, bool) {
+	value := s.getField(OxsFieldByteCount, 8)
+	if value == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(value), true
+}
+
+// Len returns the length of the statistics including the padding
+func (s *OfpStats) Len() uint16 {
+	return (s.Length + 7) / 8 * 8
+}
+
+// UnmarshalBinary transforms the byte array into statistics data
+func (s *OfpStats) UnmarshalBinary(data []byte) error {
+	if len(data) < ofpStatsHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &s.Reserved, &s.Length); err != nil {
+		return err
+	}
+	if s.Length < ofpStatsHeaderLen || int(s.Length) > len(data) {
+		return fmt.Errorf("Invalid stats length %d", s.Length)
+	}
+	s.Fields = make([]OfpOxsField, 0)
+	for idx := ofpStatsHeaderLen; idx < int(s.Length); {
+		if idx+oxsHeaderLen > int(s.Length) {
+			return fmt.Errorf("The stat field at %d is truncated", idx)
+		}
+		header := binary.BigEndian.Uint32(data[idx:])
+		valueLen := int(header & 0xff)
+		if idx+oxsHeaderLen+valueLen > int(s.Length) {
+			return fmt.Errorf("Invalid stat field length %d", valueLen)
+		}
+		field := OfpOxsField{Class: uint16(header >> 16), Field: uint8(header>>9) & 0x7f,
+			Value: make([]byte, valueLen)}
+		copy(field.Value, data[idx+oxsHeaderLen:])
+		s.Fields = append(s.Fields, field)
+		idx += oxsHeaderLen + valueLen
+	}
+	return nil
+}
+
+// MarshalBinary converts the statistics fields into byte array, the length
+// is set according to the fields and the statistics are padded to the
+// multiple of 8 bytes
+func (s *OfpStats) MarshalBinary() ([]byte, error) {
+	fieldBuf := new(bytes.Buffer)
+	for _, field := range s.Fields {
+		if len(field.Value) > 0xff {
+			return nil, fmt.Errorf("The value of the stat field %d is too long", field.Field)
+		}
+		header := uint32(field.Class)<<16 | uint32(field.Field&0x7f)<<9 | uint32(len(field.Value))
+		if err := binary.Write(fieldBuf, binary.BigEndian, header); err != nil {
+			return nil, err
+		}
+		fieldBuf.Write(field.Value)
+	}
+	s.Length = uint16(ofpStatsHeaderLen + fieldBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, s.Reserved, s.Length); err != nil {
+		return nil, err
+	}
+	buf.Write(fieldBuf.Bytes())
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpFlowRemovedMsg represents the msg structure of flow removed (datapath ->
+// controller), since openflow 1.5 the statistics of the flow are OXS fields
+// following the match
+type OfpFlowRemovedMsg struct {
+	Header      ofpgeneral.OfpHeader
+	TableID     uint8          /* ID of the table */
+	Reason      uint8          /* One of OFPRR_*. */
+	Priority    uint16         /* Priority level of flow entry. */
+	IdleTimeout uint16         /* Idle timeout from original flow mod. */
+	HardTimeout uint16         /* Hard timeout from original flow mod. */
+	Cookie      uint64         /* Opaque controller-issued identifier. */
+	Match       ofp13.OfpMatch /* Description of fields. Variable size. */
+	Stats       OfpStats       /* Statistics list. Variable size. */
+}
+
+// UnmarshalBinary transforms the byte array into flow removed data
+func (frm *OfpFlowRemovedMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < flowRemovedHeaderLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &frm.Header, &frm.TableID, &frm.Reason, &frm.Priority,
+		&frm.IdleTimeout, &frm.HardTimeout, &frm.Cookie); err != nil {
+		return err
+	}
+	if err := (&frm.Match).UnmarshalBinary(data[flowRemovedHeaderLen:]); err != nil {
+		return err
+	}
+	statsIdx := flowRemovedHeaderLen + int(frm.Match.Len())
+	if statsIdx > len(data) {
+		return fmt.Errorf("The match length %d exceeds the message", frm.Match.Length)
+	}
+	return (&frm.Stats).UnmarshalBinary(data[statsIdx:])
+}
+
+// MarshalBinary converts the flow removed fields into byte array
+func (frm *OfpFlowRemovedMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&frm.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	statsData, err := (&frm.Stats).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	frm.Header.Length = uint16(flowRemovedHeaderLen + len(matchData) + len(statsData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, frm.Header, frm.TableID, frm.Reason, frm.Priority,
+		frm.IdleTimeout, frm.HardTimeout, frm.Cookie); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(statsData)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package ofp15
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+)
+
+func TestParseFlowRemoved(t *testing.T) {
+	// The flow of the in_port 3 in the table 1 deleted after 10.5 seconds,
+	// it matched 100 packets of 6000 bytes
+	msg := wiretest.Parse(t, &OfpMessageParser{}, "060b0050 00000001 01 02 8000 000a 0000 0000000000000007"+
+		"0001 000c 80000004 00000003 00000000"+
+		"0000 0028 80020008 0000000a1dcd6500 80020808 0000000000000064 80020a08 0000000000001770")
+	removed, ok := msg.(*OfpFlowRemovedMsg)
+	if !ok {
+		t.Fatalf("Unexpected message %T", msg)
+	}
+	if removed.TableID != 1 || removed.Reason != OfpFlowRemoveReasonDelete || removed.Priority != 0x8000 ||
+		removed.IdleTimeout != 10 || removed.Cookie != 7 {
+		t.Errorf("Unexpected flow removed %+v", removed)
+	}
+	if inPort, ok := removed.Match.OXMFields.GetInPort(); !ok || inPort != 3 {
+		t.Errorf("Unexpected flow removed match %+v", removed.Match)
+	}
+	sec, nsec, ok := removed.Stats.GetDuration()
+	if !ok || sec != 10 || nsec != 500000000 {
+		t.Errorf("Unexpected duration %d.%09d", sec, nsec)
+	}
+	if packets, ok := removed.Stats.GetPacketCount(); !ok || packets != 100 {
+		t.Errorf("Unexpected packet count %d", packets)
+	}
+	if bytes, ok := removed.Stats.GetByteCount(); !ok || bytes != 6000 {
+		t.Errorf("Unexpected byte count %d", bytes)
+	}
+}
+
+func TestFlowStatsPadding(t *testing.T) {
+	// The flow count and the experimenter stat field are padded to the
+	// multiple of 8 bytes
+	stats := &OfpStats{Fields: []OfpOxsField{
+		{Class: OxsClassOpenflowBasic, Field: OxsFieldFlowCount, Value: []byte{0, 0, 0, 2}},
+		{Class: OxsClassExperimenter, Field: 1, Value: []byte{0, 0, 0x23, 0x20, 0xab}},
+	}}
+	data, err := stats.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wire := wiretest.Decode(t, "0000 0015 80020604 00000002 ffff0205 00002320ab 000000")
+	wiretest.CheckEncoding(t, stats, wire)
+	decoded := &OfpStats{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, wire)
+	if _, ok := decoded.GetPacketCount(); ok || decoded.Len() != 24 {
+		t.Errorf("Unexpected stats %+v", decoded)
+	}
+
+	for _, wire := range []string{
+		"0000 0008 80020804 00000000", // Truncated packet count
+		"0000 0006 80020808 00000000", // Truncated field header
+		"0000 0010 80020008",          // Stats exceeding the data
+	} {
+		if err := (&OfpStats{}).UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The stats %s are decoded", wire)
+		}
+	}
+}
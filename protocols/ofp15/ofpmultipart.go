@@ -0,0 +1,55 @@
+package ofp15
+
+import (
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+)
+
+// Multipart types introduced by openflow 1.5, the other types are the
+// ofp13.OfpMultipartType* and ofp14.OfpMultipartType* constants
+// enum ofp_multipart_type {
+const (
+	/* Flow description.
+	 * The request body is struct ofp_flow_stats_request.
+	 * The reply body is an array of struct ofp_flow_desc. */
+	OfpMultipartTypeFlowDesc = 17
+
+	/* Controller status.
+	 * The request body is empty.
+	 * The reply body is an array of struct ofp_controller_status. */
+	OfpMultipartTypeControllerStatus = 18
+
+	/* Bundle features.
+	 * The request body is ofp_bundle_features_request.
+	 * The reply body is struct ofp_bundle_features. */
+	OfpMultipartTypeBundleFeatures = 19
+)
+
+// DecodeMultipartBody decodes the body of the openflow 1.5 multipart reply,
+// the bodies whose layout is unchanged since openflow 1.4 are decoded by
+// ofp14.DecodeMultipartBody. The group stats and features are among them,
+// the group descriptions carrying the bucket ids and properties are not
+func DecodeMultipartBody(mr *ofp13.OfpMultipartReplyMsg) (interface{}, error) {
+	switch mr.Type {
+	case ofp13.OfpMultipartTypePortDesc:
+		return ParsePortDescBody(mr.Body)
+	case ofp13.OfpMultipartTypeTableFeatures:
+		return ParseTableFeaturesBody(mr.Body)
+	case OfpMultipartTypeControllerStatus:
+		return ParseControllerStatusBody(mr.Body)
+	case ofp13.OfpMultipartTypeFlow, ofp13.OfpMultipartTypeAggregate, ofp13.OfpMultipartTypeGroupDesc,
+		OfpMultipartTypeFlowDesc, OfpMultipartTypeBundleFeatures:
+		return nil, fmt.Errorf("Decoding of the v1.5 multipart type %d is not supported", mr.Type)
+	}
+	return ofp14.DecodeMultipartBody(mr)
+}
+
+// NewControllerStatusRequestMsg creates the request of the status of the
+// controllers connected to the switch
+func NewControllerStatusRequestMsg() *ofp13.OfpMultipartRequestMsg {
+	msg := ofp13.NewMultipartRequestMsg(OfpMultipartTypeControllerStatus, nil)
+	msg.Header.Version = Version
+	return msg
+}
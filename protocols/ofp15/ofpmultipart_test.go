@@ -0,0 +1,54 @@
+package ofp15
+
+import (
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+)
+
+// TestDecodeGroupMultipartBody checks the group stats, unchanged since
+// openflow 1.3, are decoded and the group descriptions are not
+func TestDecodeGroupMultipartBody(t *testing.T) {
+	stats := &ofp13.OfpMultipartReplyMsg{Type: ofp13.OfpMultipartTypeGroup, Body: wiretest.Decode(t,
+		"0038 0000 00000005 00000001 00000000 000000000000000a 00000000000003e8 0000000a 00000014"+
+			"0000000000000004 0000000000000190")}
+	body, err := DecodeMultipartBody(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groupStats := body.([]ofp13.OfpGroupStats); len(groupStats) != 1 || groupStats[0].GroupID != 5 ||
+		len(groupStats[0].BucketStats) != 1 {
+		t.Errorf("Unexpected group stats %+v", groupStats)
+	}
+
+	// The bucket of the openflow 1.5 group description with the output
+	// action and the weight property
+	desc := &ofp13.OfpMultipartReplyMsg{Type: ofp13.OfpMultipartTypeGroupDesc, Body: wiretest.Decode(t,
+		"0030 01 00 00000005 0020 000000000000 0020 0010 00000000 0000 0010 00000002 ffff 000000000000"+
+			"0000 0008 0002 0000")}
+	if body, err := DecodeMultipartBody(desc); err == nil {
+		t.Errorf("The openflow 1.5 group descriptions are decoded as %+v", body)
+	}
+}
+
+func TestControllerStatusWireFormat(t *testing.T) {
+	msg := NewControllerStatusRequestMsg()
+	msg.Header.Xid = 1
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "0612001000000001 0012 0000 00000000"))
+
+	reply := &ofp13.OfpMultipartReplyMsg{Type: OfpMultipartTypeControllerStatus,
+		Body: wiretest.Decode(t, controllerStatusWire+controllerStatusWire)}
+	body, err := DecodeMultipartBody(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := body.([]OfpControllerStatus)
+	if len(statuses) != 2 {
+		t.Fatalf("Decoded %d controller statuses, expected 2", len(statuses))
+	}
+	for idx := range statuses {
+		checkControllerStatus(t, &statuses[idx])
+		wiretest.CheckEncoding(t, &statuses[idx], wiretest.Decode(t, controllerStatusWire))
+	}
+}
@@ -1,12 +1,30 @@
 package ofp15
 
-import "github.com/kopwei/goof/protocols/ofpgeneral"
+import (
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
 
 // OfpMessageParser is the message parser implementation
 type OfpMessageParser struct {
+	// The messages whose layout is unchanged since openflow 1.4 are
+	// decoded into the ofp14 and ofp13 structures
+	ofp14Parser ofp14.OfpMessageParser
 }
 
 // ParseMsg is used to convert bytes into ofp message
 func (p *OfpMessageParser) ParseMsg(b []byte) (ofpgeneral.OfpMessage, error) {
-	return nil, nil
+	var message ofpgeneral.OfpMessage
+	switch b[1] {
+	case OfpTypePortStatus:
+		message = &OfpPortStatusMsg{}
+	case OfpTypeControllerStatus:
+		message = &OfpControllerStatusMsg{}
+	case OfpTypeFlowRemoved:
+		message = &OfpFlowRemovedMsg{}
+	default:
+		return p.ofp14Parser.ParseMsg(b)
+	}
+	err := message.UnmarshalBinary(b)
+	return message, err
 }
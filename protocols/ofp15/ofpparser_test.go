@@ -0,0 +1,160 @@
+package ofp15
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+const (
+	// portWire is the description of the port 1 named eth0 whose packets
+	// are received with the in_port and packet_reg0 pipeline fields and
+	// recirculated on the port 5
+	portWire = "00000001 0040 0000 020304050607 0000 6574683000000000 0000000000000000 00000000 00000004" +
+		"0002 000c 80000004 80010008 00000000 0004 0008 00000005"
+	// controllerStatusWire is the status of the master controller whose
+	// role changed, with the URI and an experimenter properties
+	controllerStatusWire = "0038 0001 00000002 02 00 000000000000" +
+		"0000 0015 7463703a31302e302e302e313a36363533 000000" +
+		"ffff 000e 00002320 00000001 abcd 0000"
+)
+
+// checkPort checks the port is the one of portWire
+func checkPort(t *testing.T, port *OfpPort) {
+	if port.PortNo != 1 || port.Length != 64 || port.HwAddr.String() != "02:03:04:05:06:07" ||
+		string(port.Name[:5]) != "eth0\x00" || port.State != 4 || len(port.Properties) != 2 {
+		t.Fatalf("Unexpected port %+v", port)
+	}
+	inPortID := ofpgeneral.NewOxmID(ofpgeneral.OxmClassOpenflowBasic, ofpgeneral.OxmFieldInPort)
+	regID := ofpgeneral.NewOxmID(ofpgeneral.OxmClassPacketRegs, 0)
+	if pipeline, ok := port.Properties[0].(*OfpPortDescPropOxm); !ok || pipeline.Type != OfpPortDescPropTypePipelineInput ||
+		len(pipeline.OxmIDs) != 2 || pipeline.OxmIDs[0] != inPortID || pipeline.OxmIDs[1] != regID {
+		t.Errorf("Unexpected pipeline input property %+v", port.Properties[0])
+	}
+	if recirculate, ok := port.Properties[1].(*OfpPortDescPropRecirculate); !ok || len(recirculate.PortNo) != 1 ||
+		recirculate.PortNo[0] != 5 {
+		t.Errorf("Unexpected recirculate property %+v", port.Properties[1])
+	}
+}
+
+// checkControllerStatus checks the status is the one of
+// controllerStatusWire
+func checkControllerStatus(t *testing.T, status *OfpControllerStatus) {
+	if status.Length != 56 || status.ShortID != 1 || status.Role != ofp13.OfpControllerRoleMaster ||
+		status.Reason != OfpCSReasonRole || status.ChannelStatus != OfpCTStatusUp || len(status.Properties) != 2 {
+		t.Fatalf("Unexpected controller status %+v", status)
+	}
+	if uri := status.GetURI(); uri != "tcp:10.0.0.1:6653" {
+		t.Errorf("Unexpected controller URI %q", uri)
+	}
+	if exp, ok := status.Properties[1].(*OfpControllerStatusPropExperimenter); !ok || exp.Experimenter != 0x2320 ||
+		exp.ExpType != 1 || hex.EncodeToString(exp.Data) != "abcd" {
+		t.Errorf("Unexpected experimenter property %+v", status.Properties[1])
+	}
+}
+
+func TestParseSwitchMessages(t *testing.T) {
+	tests := []struct {
+		name  string
+		wire  string
+		check func(t *testing.T, msg ofpgeneral.OfpMessage)
+	}{
+		{"port status", "060c005000000000 02 00000000000000" + portWire, func(t *testing.T, msg ofpgeneral.OfpMessage) {
+			m := msg.(*OfpPortStatusMsg)
+			if m.Reason != ofp13.OfpPortReasonModify {
+				t.Errorf("Unexpected port status reason %d", m.Reason)
+			}
+			checkPort(t, &m.Desc)
+		}},
+		{"controller status", "0623004000000000" + controllerStatusWire, func(t *testing.T, msg ofpgeneral.OfpMessage) {
+			checkControllerStatus(t, &msg.(*OfpControllerStatusMsg).Status)
+		}},
+		// The messages unchanged since openflow 1.4 are decoded by the
+		// previous versions
+		{"role status", "061e001800000000 00000002 00 000000 0000000000000005", func(t *testing.T, msg ofpgeneral.OfpMessage) {
+			if _, ok := msg.(*ofp14.OfpRoleStatusMsg); !ok {
+				t.Errorf("Unexpected role status %+v", msg)
+			}
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, wiretest.Parse(t, &OfpMessageParser{}, tc.wire))
+		})
+	}
+}
+
+func TestPacketOutWireFormat(t *testing.T) {
+	match := ofp13.NewOfpMatch()
+	match.OXMFields.SetInPort(3)
+	match.OXMFields.SetPacketReg(0, 7)
+	msg := NewPacketOutMsg(match, []ofp13.OfpAction{ofp13.NewActionOutput(ofp13.OfpPortFlood)}, []byte{0xaa, 0xbb, 0xcc})
+	msg.Header.Xid = 1
+	data := wiretest.Decode(t, "060d003b00000001 ffffffff 0010 0000"+
+		"0001 0018 80000004 00000003 80010008 0000000000000007"+
+		"0000 0010 fffffffb ffff 000000000000 aabbcc")
+	wiretest.CheckEncoding(t, msg, data)
+
+	decoded := &OfpPacketOutMsg{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	wiretest.CheckEncoding(t, decoded, data)
+	inPort, ok := decoded.Match.OXMFields.GetInPort()
+	reg, _, regOk := decoded.Match.OXMFields.GetPacketReg(0)
+	if !ok || inPort != 3 || !regOk || reg != 7 || decoded.BufferID != ofp13.OfpNoBuffer ||
+		len(decoded.Actions) != 1 || hex.EncodeToString(decoded.Data) != "aabbcc" {
+		t.Errorf("Unexpected packet out %+v", decoded)
+	}
+
+	// A nil match sets no pipeline field
+	msg = NewPacketOutMsg(nil, nil, nil)
+	msg.Header.Xid = 1
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "060d001800000001 ffffffff 0000 0000 00010004 00000000"))
+}
+
+func TestPacketOutInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"060d001000000001 ffffffff 0000 0000",                                      // No match
+		"060d001800000001 ffffffff 0010 0000 00010004 00000000",                    // Actions beyond the message
+		"060d002000000001 ffffffff 0008 0000 00010004 00000000 0000 0010 00000000", // Truncated action
+	} {
+		msg := &OfpPacketOutMsg{}
+		if err := msg.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The packet out %s is decoded as %+v", wire, msg)
+		}
+	}
+}
+
+func TestControllerStatusInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"0038 0001 00000002 02 00 0000",                            // Truncated status
+		"0038 0001 00000002 02 00 000000000000",                    // Length beyond the data
+		"0018 0001 00000002 02 00 000000000000 0000 0010 74637000", // URI beyond the status
+		"0018 0001 00000002 02 00 000000000000 ffff 0008 00002320", // Experimenter shorter than its header
+	} {
+		status := &OfpControllerStatus{}
+		if err := status.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The controller status %s is decoded as %+v", wire, status)
+		}
+	}
+}
+
+func TestParsePortInvalid(t *testing.T) {
+	for _, wire := range []string{
+		"00000001 0040 0000 020304050607 0000 6574683000000000 0000000000000000 00000000", // Truncated port
+		"00000001 0030 0000 020304050607 0000 6574683000000000 0000000000000000 00000000 00000004" +
+			"0002 000c 80000004 80010008", // Property beyond the port
+		"00000001 0030 0000 020304050607 0000 6574683000000000 0000000000000000 00000000 00000004" +
+			"0004 0006 00000005", // Unaligned recirculated ports
+	} {
+		port := &OfpPort{}
+		if err := port.UnmarshalBinary(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The port %s is decoded as %+v", wire, port)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package ofp15
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/kopwei/goof/protocols/ofp14"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Port description property types.
+// enum ofp_port_desc_prop_type {
+const (
+	OfpPortDescPropTypeEthernet       = 0      /* Ethernet property. */
+	OfpPortDescPropTypeOptical        = 1      /* Optical property. */
+	OfpPortDescPropTypePipelineInput  = 2      /* Ingress pipeline fields. */
+	OfpPortDescPropTypePipelineOutput = 3      /* Egress pipeline fields. */
+	OfpPortDescPropTypeRecirculate    = 4      /* Recirculation property. */
+	OfpPortDescPropTypeExperimenter   = 0xffff /* Experimenter property. */
+)
+
+const (
+	portHeaderLen         = 40
+	portDescPropHeaderLen = 4
+	portStatusHeaderLen   = 16
+	// ofpMaxPortNameLen is the size of the port name including the
+	// terminating zero
+	ofpMaxPortNameLen = 16
+)
+
+// OfpPortDescPropOxm represents the pipeline input and pipeline output port
+// description properties, which list the pipeline fields set on the
+// packets received on the port or available to the egress processing
+type OfpPortDescPropOxm struct {
+	Type   uint16   /* One of OFPPDPT_PIPELINE_INPUT or OFPPDPT_PIPELINE_OUTPUT. */
+	Length uint16   /* Length in bytes of this property. */
+	OxmIDs []uint32 /* Array of OXM headers */
+}
+
+// UnmarshalBinary transforms the byte array into pipeline property data
+func (ppo *OfpPortDescPropOxm) UnmarshalBinary(data []byte) error {
+	if len(data) < portDescPropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	ppo.Type = binary.BigEndian.Uint16(data)
+	ppo.Length = binary.BigEndian.Uint16(data[2:])
+	ids, err := unmarshalUint32List(data, ppo.Length, portDescPropHeaderLen)
+	if err != nil {
+		return err
+	}
+	ppo.OxmIDs = ids
+	return nil
+}
+
+// MarshalBinary converts the pipeline property fields into byte array, the
+// property is padded to the multiple of 8 bytes
+func (ppo *OfpPortDescPropOxm) MarshalBinary() ([]byte, error) {
+	ppo.Length = uint16(portDescPropHeaderLen + 4*len(ppo.OxmIDs))
+	return marshalUint32List(ppo.Type, ppo.Length, ppo.OxmIDs)
+}
+
+// OfpPortDescPropRecirculate represents the recirculate port description
+// property, which lists the port numbers the packets output to the port
+// can be received on
+type OfpPortDescPropRecirculate struct {
+	Type   uint16   /* OFPPDPT_RECIRCULATE. */
+	Length uint16   /* Length in bytes of this property. */
+	PortNo []uint32 /* List of recirculated ports. */
+}
+
+// UnmarshalBinary transforms the byte array into recirculate property data
+func (ppr *OfpPortDescPropRecirculate) UnmarshalBinary(data []byte) error {
+	if len(data) < portDescPropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	ppr.Type = binary.BigEndian.Uint16(data)
+	ppr.Length = binary.BigEndian.Uint16(data[2:])
+	ports, err := unmarshalUint32List(data, ppr.Length, portDescPropHeaderLen)
+	if err != nil {
+		return err
+	}
+	ppr.PortNo = ports
+	return nil
+}
+
+// MarshalBinary converts the recirculate property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (ppr *OfpPortDescPropRecirculate) MarshalBinary() ([]byte, error) {
+	ppr.Type = OfpPortDescPropTypeRecirculate
+	ppr.Length = uint16(portDescPropHeaderLen + 4*len(ppr.PortNo))
+	return marshalUint32List(ppr.Type, ppr.Length, ppr.PortNo)
+}
+
+// OfpPort represents the port structure (ofp_port) of openflow 1.5, the
+// layout is the one of openflow 1.4 with more property types
+type OfpPort struct {
+	PortNo   uint32
+	Length   uint16
+	Padding1 [2]byte
+	HwAddr   net.HardwareAddr
+	Padding2 [2]byte
+	Name     []byte /* Null-terminated */
+	Config   uint32 /* Bitmap of OFPPC_* flags. */
+	State    uint32 /* Bitmap of OFPPS_* flags. */
+	// Properties holds *ofp14.OfpPortDescPropEthernet,
+	// *ofp14.OfpPortDescPropOptical, *OfpPortDescPropOxm,
+	// *OfpPortDescPropRecirculate and *ofp14.OfpPortDescPropExperimenter,
+	// *ofp14.OfpPortDescPropHeader is kept for the unknown properties
+	Properties []ofpgeneral.OfpMessage /* Port description property list. */
+}
+
+// GetEthernetProp returns the ethernet property of the port, nil is
+// returned if the port isn't an ethernet port
+func (p *OfpPort) GetEthernetProp() *ofp14.OfpPortDescPropEthernet {
+	for _, prop := range p.Properties {
+		if ethernet, ok := prop.(*ofp14.OfpPortDescPropEthernet); ok {
+			return ethernet
+		}
+	}
+	return nil
+}
+
+// UnmarshalBinary transforms the byte array into port data
+func (p *OfpPort) UnmarshalBinary(data []byte) error {
+	if len(data) < portHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	p.HwAddr = make([]byte, 6)
+	p.Name = make([]byte, ofpMaxPortNameLen)
+	if err := ofpgeneral.UnMarshalFields(buf, &p.PortNo, &p.Length, &p.Padding1, &p.HwAddr, &p.Padding2,
+		&p.Name, &p.Config, &p.State); err != nil {
+		return err
+	}
+	if p.Length < portHeaderLen || int(p.Length) > len(data) {
+		return fmt.Errorf("Invalid port length %d", p.Length)
+	}
+	p.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := portHeaderLen; idx+portDescPropHeaderLen <= int(p.Length); {
+		propHeader := ofp14.OfpPortDescPropHeader{}
+		if err := propHeader.UnmarshalBinary(data[idx:p.Length]); err != nil {
+			return err
+		}
+		if propHeader.Length < portDescPropHeaderLen || idx+int(propHeader.Length) > int(p.Length) {
+			return fmt.Errorf("Invalid port property length %d", propHeader.Length)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch propHeader.Type {
+		case OfpPortDescPropTypeEthernet:
+			prop = &ofp14.OfpPortDescPropEthernet{}
+		case OfpPortDescPropTypeOptical:
+			prop = &ofp14.OfpPortDescPropOptical{}
+		case OfpPortDescPropTypePipelineInput, OfpPortDescPropTypePipelineOutput:
+			prop = &OfpPortDescPropOxm{}
+		case OfpPortDescPropTypeRecirculate:
+			prop = &OfpPortDescPropRecirculate{}
+		case OfpPortDescPropTypeExperimenter:
+			prop = &ofp14.OfpPortDescPropExperimenter{}
+		default:
+			prop = &ofp14.OfpPortDescPropHeader{}
+		}
+		if err := prop.UnmarshalBinary(data[idx : idx+int(propHeader.Length)]); err != nil {
+			return err
+		}
+		p.Properties = append(p.Properties, prop)
+		// The variable size properties are padded to 8 bytes
+		idx += (int(propHeader.Length) + 7) / 8 * 8
+	}
+	return nil
+}
+
+// MarshalBinary converts the port fields into byte array, the length is
+// set according to the properties
+func (p *OfpPort) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range p.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	p.Length = uint16(portHeaderLen + propBuf.Len())
+	hwAddr := make([]byte, 6)
+	copy(hwAddr, p.HwAddr)
+	name := make([]byte, ofpMaxPortNameLen)
+	copy(name[:ofpMaxPortNameLen-1], p.Name)
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, p.PortNo, p.Length, p.Padding1, hwAddr, p.Padding2,
+		name, p.Config, p.State); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// ParsePortDescBody decodes the body of the openflow 1.5 port description reply
+func ParsePortDescBody(body []byte) ([]OfpPort, error) {
+	ports := make([]OfpPort, 0)
+	for idx := 0; idx < len(body); {
+		port := OfpPort{}
+		if err := port.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+		idx += int(port.Length)
+	}
+	return ports, nil
+}
+
+// OfpPortStatusMsg represents the port status msg structure
+/* A physical port has changed in the datapath */
+type OfpPortStatusMsg struct {
+	Header  ofpgeneral.OfpHeader
+	Reason  uint8   /* One of OFPPR_*. */
+	Padding [7]byte /* Align to 64-bits. */
+	Desc    OfpPort
+}
+
+// UnmarshalBinary transforms the byte array into port status data
+func (psm *OfpPortStatusMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < portStatusHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &psm.Header, &psm.Reason, &psm.Padding); err != nil {
+		return err
+	}
+	return (&psm.Desc).UnmarshalBinary(data[portStatusHeaderLen:])
+}
+
+// MarshalBinary converts the port status fields into byte array
+func (psm *OfpPortStatusMsg) MarshalBinary() ([]byte, error) {
+	descData, err := (&psm.Desc).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	psm.Header.Length = uint16(portStatusHeaderLen + len(descData))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, psm.Header, psm.Reason, psm.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(descData)
+	return buf.Bytes(), nil
+}
+
+// unmarshalUint32List decodes the list of 32 bits values following the
+// header of the property of the length
+func unmarshalUint32List(data []byte, length uint16, headerLen int) ([]uint32, error) {
+	if int(length) < headerLen || int(length) > len(data) || (int(length)-headerLen)%4 != 0 {
+		return nil, fmt.Errorf("Invalid property length %d", length)
+	}
+	values := make([]uint32, 0, (int(length)-headerLen)/4)
+	for idx := headerLen; idx < int(length); idx += 4 {
+		values = append(values, binary.BigEndian.Uint32(data[idx:]))
+	}
+	return values, nil
+}
+
+// marshalUint32List encodes the property made of the type, the length and
+// the list of 32 bits values, the property is padded to the multiple of 8
+// bytes
+func marshalUint32List(propType, length uint16, values []uint32) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, propType, length, values); err != nil {
+		return nil, err
+	}
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,284 @@
+package ofp15
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// Table features commands.
+// enum ofp_table_features_command {
+const (
+	OfpTableFeaturesCmdReplace = 0 /* Replace full pipeline. */
+	OfpTableFeaturesCmdModify  = 1 /* Modify flow tables capabilities. */
+	OfpTableFeaturesCmdEnable  = 2 /* Enable flow tables in the pipeline. */
+	OfpTableFeaturesCmdDisable = 3 /* Disable flow tables in pipeline. */
+)
+
+// Table Feature Flags.
+// enum ofp_table_feature_flag {
+const (
+	OfpTableFeatureFlagIngressTable = 1 << 0 /* Can be configured as ingress table. */
+	OfpTableFeatureFlagEgressTable  = 1 << 1 /* Can be configured as egress table. */
+	OfpTableFeatureFlagFirstEgress  = 1 << 4 /* Is the first egress table. */
+)
+
+// Table Feature property types.
+// Low order bit cleared indicates a property for a regular Flow Entry.
+// Low order bit set indicates a property for the Table-Miss Flow Entry.
+// enum ofp_table_feature_prop_type {
+const (
+	OfpTableFeaturePropTypeInstructions       = 0      /* Instructions property. */
+	OfpTableFeaturePropTypeInstructionsMiss   = 1      /* Instructions for table-miss. */
+	OfpTableFeaturePropTypeNextTables         = 2      /* Next Table property. */
+	OfpTableFeaturePropTypeNextTablesMiss     = 3      /* Next Table for table-miss. */
+	OfpTableFeaturePropTypeWriteActions       = 4      /* Write Actions property. */
+	OfpTableFeaturePropTypeWriteActionsMiss   = 5      /* Write Actions for table-miss. */
+	OfpTableFeaturePropTypeApplyActions       = 6      /* Apply Actions property. */
+	OfpTableFeaturePropTypeApplyActionsMiss   = 7      /* Apply Actions for table-miss. */
+	OfpTableFeaturePropTypeMatch              = 8      /* Match property. */
+	OfpTableFeaturePropTypeWildcards          = 10     /* Wildcards property. */
+	OfpTableFeaturePropTypeWriteSetField      = 12     /* Write Set-Field property. */
+	OfpTableFeaturePropTypeWriteSetFieldMiss  = 13     /* Write Set-Field for table-miss. */
+	OfpTableFeaturePropTypeApplySetField      = 14     /* Apply Set-Field property. */
+	OfpTableFeaturePropTypeApplySetFieldMiss  = 15     /* Apply Set-Field for table-miss. */
+	OfpTableFeaturePropTypeTableSyncFrom      = 16     /* Table synchronisation property. */
+	OfpTableFeaturePropTypeWriteCopyField     = 18     /* Write Copy-Field property. */
+	OfpTableFeaturePropTypeWriteCopyFieldMiss = 19     /* Write Copy-Field for table-miss. */
+	OfpTableFeaturePropTypeApplyCopyField     = 20     /* Apply Copy-Field property. */
+	OfpTableFeaturePropTypeApplyCopyFieldMiss = 21     /* Apply Copy-Field for table-miss. */
+	OfpTableFeaturePropTypePacketTypes        = 22     /* Packet types property. */
+	OfpTableFeaturePropTypeExperimenter       = 0xfffe /* Experimenter property. */
+	OfpTableFeaturePropTypeExperimenterMiss   = 0xffff /* Experimenter for table-miss. */
+)
+
+const (
+	tableFeaturesHeaderLen    = 64
+	tableFeaturePropHeaderLen = 4
+	ofpMaxTableNameLen        = 32
+)
+
+// OfpTableFeaturePropNextTables represents the next tables and the table
+// sync from properties, which list table ids
+type OfpTableFeaturePropNextTables struct {
+	Type         uint16  /* One of OFPTFPT_NEXT_TABLES, OFPTFPT_NEXT_TABLES_MISS, OFPTFPT_TABLE_SYNC_FROM. */
+	Length       uint16  /* Length in bytes of this property. */
+	NextTableIDs []uint8 /* List of table ids. */
+}
+
+// UnmarshalBinary transforms the byte array into next tables property data
+func (tfn *OfpTableFeaturePropNextTables) UnmarshalBinary(data []byte) error {
+	if len(data) < tableFeaturePropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	tfn.Type = binary.BigEndian.Uint16(data)
+	tfn.Length = binary.BigEndian.Uint16(data[2:])
+	if tfn.Length < tableFeaturePropHeaderLen || int(tfn.Length) > len(data) {
+		return fmt.Errorf("Invalid property length %d", tfn.Length)
+	}
+	tfn.NextTableIDs = make([]uint8, tfn.Length-tableFeaturePropHeaderLen)
+	copy(tfn.NextTableIDs, data[tableFeaturePropHeaderLen:tfn.Length])
+	return nil
+}
+
+// MarshalBinary converts the next tables property fields into byte array,
+// the property is padded to the multiple of 8 bytes
+func (tfn *OfpTableFeaturePropNextTables) MarshalBinary() ([]byte, error) {
+	tfn.Length = uint16(tableFeaturePropHeaderLen + len(tfn.NextTableIDs))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, tfn.Type, tfn.Length); err != nil {
+		return nil, err
+	}
+	buf.Write(tfn.NextTableIDs)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpTableFeaturePropOxm represents the properties which list the OXM
+// fields, such as the match, the wildcards, the set-field, the copy-field
+// and the packet types properties
+type OfpTableFeaturePropOxm struct {
+	Type   uint16   /* One of OFPTFPT_MATCH, OFPTFPT_WILDCARDS, OFPTFPT_*_SETFIELD*, OFPTFPT_*_COPYFIELD*, OFPTFPT_PACKET_TYPES. */
+	Length uint16   /* Length in bytes of this property. */
+	OxmIDs []uint32 /* Array of OXM headers */
+}
+
+// UnmarshalBinary transforms the byte array into OXM property data
+func (tfo *OfpTableFeaturePropOxm) UnmarshalBinary(data []byte) error {
+	if len(data) < tableFeaturePropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	tfo.Type = binary.BigEndian.Uint16(data)
+	tfo.Length = binary.BigEndian.Uint16(data[2:])
+	ids, err := unmarshalUint32List(data, tfo.Length, tableFeaturePropHeaderLen)
+	if err != nil {
+		return err
+	}
+	tfo.OxmIDs = ids
+	return nil
+}
+
+// MarshalBinary converts the OXM property fields into byte array, the
+// property is padded to the multiple of 8 bytes
+func (tfo *OfpTableFeaturePropOxm) MarshalBinary() ([]byte, error) {
+	tfo.Length = uint16(tableFeaturePropHeaderLen + 4*len(tfo.OxmIDs))
+	return marshalUint32List(tfo.Type, tfo.Length, tfo.OxmIDs)
+}
+
+// OfpTableFeaturePropRaw represents the instructions, the actions and the
+// experimenter properties, whose content is kept undecoded
+type OfpTableFeaturePropRaw struct {
+	Type   uint16 /* One of OFPTFPT_*. */
+	Length uint16 /* Length in bytes of this property. */
+	Data   []byte /* Property content, the padding is not included. */
+}
+
+// UnmarshalBinary transforms the byte array into property data
+func (tfr *OfpTableFeaturePropRaw) UnmarshalBinary(data []byte) error {
+	if len(data) < tableFeaturePropHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	tfr.Type = binary.BigEndian.Uint16(data)
+	tfr.Length = binary.BigEndian.Uint16(data[2:])
+	if tfr.Length < tableFeaturePropHeaderLen || int(tfr.Length) > len(data) {
+		return fmt.Errorf("Invalid property length %d", tfr.Length)
+	}
+	tfr.Data = make([]byte, tfr.Length-tableFeaturePropHeaderLen)
+	copy(tfr.Data, data[tableFeaturePropHeaderLen:tfr.Length])
+	return nil
+}
+
+// MarshalBinary converts the property fields into byte array, the property
+// is padded to the multiple of 8 bytes
+func (tfr *OfpTableFeaturePropRaw) MarshalBinary() ([]byte, error) {
+	tfr.Length = uint16(tableFeaturePropHeaderLen + len(tfr.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, tfr.Type, tfr.Length); err != nil {
+		return nil, err
+	}
+	buf.Write(tfr.Data)
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	return buf.Bytes(), nil
+}
+
+// OfpTableFeatures represents the features of a flow table, it is the
+// entry of both the table features request and reply
+type OfpTableFeatures struct {
+	Length  uint16 /* Length is padded to 64 bits. */
+	TableID uint8  /* Identifier of table. Lower numbered tables
+	   are consulted first. */
+	Command       uint8  /* One of OFPTFC_*. */
+	Features      uint32 /* Bitmap of OFPTFF_* values. */
+	Name          [ofpMaxTableNameLen]byte
+	MetadataMatch uint64 /* Bits of metadata table can match. */
+	MetadataWrite uint64 /* Bits of metadata table can write. */
+	Capabilities  uint32 /* Bitmap of OFPTC_* values. */
+	MaxEntries    uint32 /* Max number of entries supported. */
+	// Properties holds *OfpTableFeaturePropNextTables,
+	// *OfpTableFeaturePropOxm and *OfpTableFeaturePropRaw
+	Properties []ofpgeneral.OfpMessage /* Table Feature Property list */
+}
+
+// GetName returns the name of the table
+func (tf *OfpTableFeatures) GetName() string {
+	return string(bytes.TrimRight(tf.Name[:], "\x00"))
+}
+
+// UnmarshalBinary transforms the byte array into table features data
+func (tf *OfpTableFeatures) UnmarshalBinary(data []byte) error {
+	if len(data) < tableFeaturesHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &tf.Length, &tf.TableID, &tf.Command, &tf.Features,
+		&tf.Name, &tf.MetadataMatch, &tf.MetadataWrite, &tf.Capabilities, &tf.MaxEntries); err != nil {
+		return err
+	}
+	if tf.Length < tableFeaturesHeaderLen || int(tf.Length) > len(data) {
+		return fmt.Errorf("Invalid table features length %d", tf.Length)
+	}
+	tf.Properties = make([]ofpgeneral.OfpMessage, 0)
+	for idx := tableFeaturesHeaderLen; idx+tableFeaturePropHeaderLen <= int(tf.Length); {
+		propType := binary.BigEndian.Uint16(data[idx:])
+		propLen := binary.BigEndian.Uint16(data[idx+2:])
+		if propLen < tableFeaturePropHeaderLen || idx+int(propLen) > int(tf.Length) {
+			return fmt.Errorf("Invalid table feature property length %d", propLen)
+		}
+		var prop ofpgeneral.OfpMessage
+		switch propType {
+		case OfpTableFeaturePropTypeNextTables, OfpTableFeaturePropTypeNextTablesMiss,
+			OfpTableFeaturePropTypeTableSyncFrom:
+			prop = &OfpTableFeaturePropNextTables{}
+		case OfpTableFeaturePropTypeMatch, OfpTableFeaturePropTypeWildcards,
+			OfpTableFeaturePropTypeWriteSetField, OfpTableFeaturePropTypeWriteSetFieldMiss,
+			OfpTableFeaturePropTypeApplySetField, OfpTableFeaturePropTypeApplySetFieldMiss,
+			OfpTableFeaturePropTypeWriteCopyField, OfpTableFeaturePropTypeWriteCopyFieldMiss,
+			OfpTableFeaturePropTypeApplyCopyField, OfpTableFeaturePropTypeApplyCopyFieldMiss,
+			OfpTableFeaturePropTypePacketTypes:
+			prop = &OfpTableFeaturePropOxm{}
+		default:
+			prop = &OfpTableFeaturePropRaw{}
+		}
+		if err := prop.UnmarshalBinary(data[idx : idx+int(propLen)]); err != nil {
+			return err
+		}
+		tf.Properties = append(tf.Properties, prop)
+		idx += (int(propLen) + 7) / 8 * 8
+	}
+	return nil
+}
+
+// MarshalBinary converts the table features fields into byte array, the
+// length is set according to the properties
+func (tf *OfpTableFeatures) MarshalBinary() ([]byte, error) {
+	propBuf := new(bytes.Buffer)
+	for _, prop := range tf.Properties {
+		propData, err := prop.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		propBuf.Write(propData)
+	}
+	tf.Length = uint16(tableFeaturesHeaderLen + propBuf.Len())
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, tf.Length, tf.TableID, tf.Command, tf.Features,
+		tf.Name, tf.MetadataMatch, tf.MetadataWrite, tf.Capabilities, tf.MaxEntries); err != nil {
+		return nil, err
+	}
+	buf.Write(propBuf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// NewTableFeaturesRequestMsg creates the table features request, an empty
+// list of features queries the features of all the tables, otherwise the
+// features are applied according to their commands
+func NewTableFeaturesRequestMsg(features []OfpTableFeatures) (*ofp13.OfpMultipartRequestMsg, error) {
+	body := new(bytes.Buffer)
+	for idx := range features {
+		data, err := (&features[idx]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		body.Write(data)
+	}
+	msg := ofp13.NewMultipartRequestMsg(ofp13.OfpMultipartTypeTableFeatures, body.Bytes())
+	msg.Header.Version = Version
+	return msg, nil
+}
+
+// ParseTableFeaturesBody decodes the body of the table features reply
+func ParseTableFeaturesBody(body []byte) ([]OfpTableFeatures, error) {
+	features := make([]OfpTableFeatures, 0)
+	for idx := 0; idx < len(body); {
+		feature := OfpTableFeatures{}
+		if err := feature.UnmarshalBinary(body[idx:]); err != nil {
+			return nil, err
+		}
+		features = append(features, feature)
+		idx += int(feature.Length)
+	}
+	return features, nil
+}
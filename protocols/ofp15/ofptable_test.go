@@ -0,0 +1,77 @@
+package ofp15
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/kopwei/goof/protocols/internal/wiretest"
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
+// egressTableWire is the features of the table 1 configured as the first
+// egress table, which goes to the tables 2 and 3, may copy the ipv4_src
+// field and may go to another table
+const egressTableWire = "0058 01 01 00000012 6567726573730000000000000000000000000000000000000000000000000000" +
+	"ffffffffffffffff 0000000000000000 00000000 00000100" +
+	"0002 0006 0203 0000 0014 0008 80001604 0000 0008 00010004"
+
+func TestTableFeaturesWireFormat(t *testing.T) {
+	features := OfpTableFeatures{TableID: 1, Command: OfpTableFeaturesCmdModify,
+		Features: OfpTableFeatureFlagEgressTable | OfpTableFeatureFlagFirstEgress, MetadataMatch: 0xffffffffffffffff,
+		MaxEntries: 256, Properties: []ofpgeneral.OfpMessage{
+			&OfpTableFeaturePropNextTables{Type: OfpTableFeaturePropTypeNextTables, NextTableIDs: []uint8{2, 3}},
+			&OfpTableFeaturePropOxm{Type: OfpTableFeaturePropTypeApplyCopyField,
+				OxmIDs: []uint32{ofpgeneral.NewOxmID(ofpgeneral.OxmClassOpenflowBasic, ofpgeneral.OxmFieldIPv4Src)}},
+			&OfpTableFeaturePropRaw{Type: OfpTableFeaturePropTypeInstructions, Data: []byte{0x00, 0x01, 0x00, 0x04}},
+		}}
+	copy(features.Name[:], "egress")
+	msg, err := NewTableFeaturesRequestMsg([]OfpTableFeatures{features})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Header.Xid = 1
+	wiretest.CheckEncoding(t, msg, wiretest.Decode(t, "0612006800000001 000c 0000 00000000"+egressTableWire))
+
+	// The switch replies the features in the same layout
+	reply := &ofp13.OfpMultipartReplyMsg{Type: ofp13.OfpMultipartTypeTableFeatures, Body: wiretest.Decode(t, egressTableWire)}
+	body, err := DecodeMultipartBody(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := body.([]OfpTableFeatures)
+	if len(decoded) != 1 {
+		t.Fatalf("Decoded %d table features, expected 1", len(decoded))
+	}
+	table := &decoded[0]
+	if table.Length != 88 || table.TableID != 1 || table.Features != 0x12 || table.GetName() != "egress" ||
+		table.MaxEntries != 256 || len(table.Properties) != 3 {
+		t.Fatalf("Unexpected table features %+v", table)
+	}
+	if next, ok := table.Properties[0].(*OfpTableFeaturePropNextTables); !ok || hex.EncodeToString(next.NextTableIDs) != "0203" {
+		t.Errorf("Unexpected next tables property %+v", table.Properties[0])
+	}
+	if copyField, ok := table.Properties[1].(*OfpTableFeaturePropOxm); !ok || copyField.Type != OfpTableFeaturePropTypeApplyCopyField ||
+		len(copyField.OxmIDs) != 1 || copyField.OxmIDs[0] != 0x80001604 {
+		t.Errorf("Unexpected copy field property %+v", table.Properties[1])
+	}
+	if raw, ok := table.Properties[2].(*OfpTableFeaturePropRaw); !ok || hex.EncodeToString(raw.Data) != "00010004" {
+		t.Errorf("Unexpected instructions property %+v", table.Properties[2])
+	}
+	wiretest.CheckEncoding(t, table, wiretest.Decode(t, egressTableWire))
+}
+
+func TestParseTableFeaturesInvalid(t *testing.T) {
+	header := "01 01 00000012 6567726573730000000000000000000000000000000000000000000000000000" +
+		"ffffffffffffffff 0000000000000000 00000000 00000100"
+	for _, wire := range []string{
+		"0058 01 01 00000012",                    // Truncated features
+		"0058 " + header,                         // Length beyond the data
+		"0048 " + header + "0002 000c 0203 0000", // Property beyond the features
+		"0048 " + header + "0008 0006 8000 0000", // Unaligned OXM ids
+	} {
+		if features, err := ParseTableFeaturesBody(wiretest.Decode(t, wire)); err == nil {
+			t.Errorf("The table features %s are decoded as %+v", wire, features)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package ofp15
 
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kopwei/goof/protocols/ofp13"
+	"github.com/kopwei/goof/protocols/ofpgeneral"
+)
+
 const (
 	// Version is the value of version byte in ofp header
 	Version = uint8(0x06)
@@ -69,3 +77,94 @@ const (
 	/* Controller Status async message */
 	OfpTypeControllerStatus
 )
+
+const (
+	packetOutHeaderLen = 16
+	ofpMatchHeaderLen  = 4
+)
+
+// OfpPacketOutMsg reprensents the packet_out message sent by controller,
+// since openflow 1.5 the in_port is replaced by a match which carries the
+// pipeline fields of the packet
+/* Send packet (controller -> datapath). */
+type OfpPacketOutMsg struct {
+	Header     ofpgeneral.OfpHeader
+	BufferID   uint32 /* ID assigned by datapath (OFP_NO_BUFFER if none). */
+	ActionsLen uint16 /* Size of action array in bytes. */
+	Padding    [2]byte
+	Match      ofp13.OfpMatch    /* Packet pipeline fields. Variable size. */
+	Actions    []ofp13.OfpAction /* Action list. */
+	Data       []byte            /* Packet data. The length is inferred
+	   from the length field in the header.
+	   (Only meaningful if buffer_id == -1.) */
+}
+
+// NewPacketOutMsg creates a packet out message which sends the unbuffered
+// data, the match is used to set the pipeline fields such as the in_port,
+// a nil match means no pipeline field is set
+func NewPacketOutMsg(match *ofp13.OfpMatch, actions []ofp13.OfpAction, data []byte) *OfpPacketOutMsg {
+	if match == nil {
+		match = ofp13.NewOfpMatch()
+	}
+	header := ofpgeneral.NewOfpHeader(Version)
+	header.Type = OfpTypePacketOut
+	return &OfpPacketOutMsg{
+		Header:   *header,
+		BufferID: ofp13.OfpNoBuffer,
+		Match:    *match,
+		Actions:  actions,
+		Data:     data,
+	}
+}
+
+// MarshalBinary converts the packet out msg fields into byte array,
+// the lengths are set according to the match, the actions and the data
+func (out *OfpPacketOutMsg) MarshalBinary() ([]byte, error) {
+	matchData, err := (&out.Match).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	actionData, err := marshalActions(out.Actions)
+	if err != nil {
+		return nil, err
+	}
+	out.ActionsLen = uint16(len(actionData))
+	out.Header.Length = uint16(packetOutHeaderLen + len(matchData) + len(actionData) + len(out.Data))
+	buf := new(bytes.Buffer)
+	if err := ofpgeneral.MarshalFields(buf, out.Header, out.BufferID, out.ActionsLen,
+		out.Padding); err != nil {
+		return nil, err
+	}
+	buf.Write(matchData)
+	buf.Write(actionData)
+	buf.Write(out.Data)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary transforms the byte array into packet out message data
+func (out *OfpPacketOutMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < packetOutHeaderLen+ofpMatchHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := ofpgeneral.UnMarshalFields(buf, &out.Header, &out.BufferID, &out.ActionsLen,
+		&out.Padding); err != nil {
+		return err
+	}
+	if err := (&out.Match).UnmarshalBinary(data[packetOutHeaderLen:]); err != nil {
+		return err
+	}
+	actionIdx := packetOutHeaderLen + int(out.Match.Len())
+	actionEnd := actionIdx + int(out.ActionsLen)
+	if len(data) < actionEnd {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	actions, err := ParseActions(data[actionIdx:actionEnd])
+	if err != nil {
+		return err
+	}
+	out.Actions = actions
+	out.Data = make([]byte, len(data)-actionEnd)
+	copy(out.Data, data[actionEnd:])
+	return nil
+}
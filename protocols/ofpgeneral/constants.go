@@ -4,3 +4,23 @@ const (
 	// OfpEthALen is the length of ethA header
 	OfpEthALen = 8
 )
+
+// The OFP Type constants which are immutable across the versions
+const (
+	OfpTypeHello = iota /* Symmetric message */
+	OfpTypeError        /* Symmetric message */
+	OfpTypeEchoRequest
+	OfpTypeEchoReply
+)
+
+// Values for 'type' in ofp_error_message which are immutable across the versions
+const (
+	OfpErrTypeHelloFailed = 0 /* Hello protocol failed. */
+)
+
+// ofp_error_msg 'code' values for OFPET_HELLO_FAILED.  'data' contains an
+// ASCII text string that may give failure details. */
+const (
+	OfpHelloFailedCodeIncompatible = iota /* No compatible version. */
+	OfpHelloFailedCodeErrPerm             /* Permissions error. */
+)
@@ -29,7 +29,7 @@ func NewOfpHeader(version uint8) *OfpHeader {
 	xidLock.Lock()
 	defer xidLock.Unlock()
 	messageXid++
-	return &OfpHeader{Version: version, Xid: messageXid}
+	return &OfpHeader{Version: version, Length: 8, Xid: messageXid}
 }
 
 // UnmarshalBinary transforms the byte array into header data
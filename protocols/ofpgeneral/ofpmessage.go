@@ -1,6 +1,10 @@
 package ofpgeneral
 
-import "bytes"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
 
 // OfpMessage is the general representation of the messages
 // transferred between controller and switch
@@ -9,19 +13,136 @@ type OfpMessage interface {
 	UnmarshalBinary(data []byte) error
 }
 
+// Hello element types
+// enum ofp_hello_elem_type {
+const (
+	OfpHelloElemTypeVersionBitmap = 1 /* Bitmap of version supported. */
+)
+
+// OfpHelloElem represents the common header of all hello elements
+// followed by the element payload
+type OfpHelloElem struct {
+	Type   uint16 /* One of OFPHET_*. */
+	Length uint16 /* Length in bytes of element, including this header,
+	   excluding padding. */
+	Data []byte /* Element payload, e.g. the version bitmaps. */
+}
+
+// Len returns the length of the element including the padding
+func (he *OfpHelloElem) Len() uint16 {
+	return (he.Length + 7) / 8 * 8
+}
+
+// MarshalBinary converts the hello element into byte array
+func (he *OfpHelloElem) MarshalBinary() ([]byte, error) {
+	he.Length = uint16(4 + len(he.Data))
+	data := make([]byte, he.Len())
+	buf := new(bytes.Buffer)
+	if err := MarshalFields(buf, he.Type, he.Length); err != nil {
+		return nil, err
+	}
+	copy(data, buf.Bytes())
+	copy(data[4:], he.Data)
+	return data, nil
+}
+
+// UnmarshalBinary transforms the byte array into hello element
+func (he *OfpHelloElem) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	buf := bytes.NewReader(data)
+	if err := UnMarshalFields(buf, &he.Type, &he.Length); err != nil {
+		return err
+	}
+	if he.Length < 4 || int(he.Length) > len(data) {
+		return fmt.Errorf("Invalid hello element length %d", he.Length)
+	}
+	he.Data = make([]byte, he.Length-4)
+	copy(he.Data, data[4:he.Length])
+	return nil
+}
+
+// NewVersionBitmapElem creates the hello element advertising the versions
+func NewVersionBitmapElem(versions ...uint8) OfpHelloElem {
+	bitmaps := make([]uint32, 0)
+	for _, version := range versions {
+		for int(version)/32 >= len(bitmaps) {
+			bitmaps = append(bitmaps, 0)
+		}
+		bitmaps[version/32] |= 1 << (version % 32)
+	}
+	data := make([]byte, 4*len(bitmaps))
+	for i, bitmap := range bitmaps {
+		binary.BigEndian.PutUint32(data[4*i:], bitmap)
+	}
+	return OfpHelloElem{Type: OfpHelloElemTypeVersionBitmap, Data: data}
+}
+
 // OfpHelloMsg represents the hello message structure
 type OfpHelloMsg struct {
-	Header OfpHeader
+	Header   OfpHeader
+	Elements []OfpHelloElem /* Hello element list */
 }
 
 // MarshalBinary converts the hello msg fields into byte array
 func (hello *OfpHelloMsg) MarshalBinary() ([]byte, error) {
-	return (&hello.Header).MarshalBinary()
+	buf := new(bytes.Buffer)
+	for _, elem := range hello.Elements {
+		elemData, err := elem.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(elemData)
+	}
+	hello.Header.Length = uint16(8 + buf.Len())
+	headerData, err := (&hello.Header).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(headerData, buf.Bytes()...), nil
 }
 
 // UnmarshalBinary transforms the byte array into hello message data
 func (hello *OfpHelloMsg) UnmarshalBinary(data []byte) error {
-	return (&hello.Header).UnmarshalBinary(data)
+	if err := (&hello.Header).UnmarshalBinary(data); err != nil {
+		return err
+	}
+	hello.Elements = make([]OfpHelloElem, 0)
+	end := int(hello.Header.Length)
+	if end > len(data) {
+		end = len(data)
+	}
+	for idx := 8; idx+4 <= end; {
+		elem := OfpHelloElem{}
+		if err := elem.UnmarshalBinary(data[idx:end]); err != nil {
+			return err
+		}
+		hello.Elements = append(hello.Elements, elem)
+		idx += int(elem.Len())
+	}
+	return nil
+}
+
+// GetVersions returns the versions advertised in the version bitmap
+// element, the second return value is false if there is no such element
+func (hello *OfpHelloMsg) GetVersions() ([]uint8, bool) {
+	for _, elem := range hello.Elements {
+		if elem.Type != OfpHelloElemTypeVersionBitmap {
+			continue
+		}
+		versions := make([]uint8, 0)
+		for i := 0; i+4 <= len(elem.Data) && i < 32; i += 4 {
+			bitmap := binary.BigEndian.Uint32(elem.Data[i:])
+			for bit := uint(0); bit < 32; bit++ {
+				if bitmap&(1<<bit) != 0 {
+					versions = append(versions, uint8(uint(i)*8+bit))
+				}
+			}
+		}
+		return versions, true
+	}
+	return nil, false
 }
 
 // NewHelloMsg creates a hello message
@@ -30,6 +151,20 @@ func NewHelloMsg(version uint8) *OfpHelloMsg {
 	return &OfpHelloMsg{Header: *header}
 }
 
+// NewHelloMsgWithVersions creates a hello message carrying the highest
+// version in its header and all the versions in a version bitmap element
+func NewHelloMsgWithVersions(versions ...uint8) *OfpHelloMsg {
+	highest := uint8(0)
+	for _, version := range versions {
+		if version > highest {
+			highest = version
+		}
+	}
+	hello := NewHelloMsg(highest)
+	hello.Elements = []OfpHelloElem{NewVersionBitmapElem(versions...)}
+	return hello
+}
+
 // OfpErrMsg represents the msg structure of OFPT_ERROR: Error message (datapath -> controller).
 type OfpErrMsg struct {
 	Header OfpHeader
@@ -39,6 +174,14 @@ type OfpErrMsg struct {
 	Data []byte /* Variable-length data.  Interpreted based on the type and code. */
 }
 
+// NewErrMsg creates an error message
+func NewErrMsg(version uint8, errType, code uint16, data []byte) *OfpErrMsg {
+	header := NewOfpHeader(version)
+	header.Type = OfpTypeError
+	header.Length = uint16(12 + len(data))
+	return &OfpErrMsg{Header: *header, Type: errType, Code: code, Data: data}
+}
+
 // MarshalBinary converts the packet in msg fields into byte array
 func (em *OfpErrMsg) MarshalBinary() ([]byte, error) {
 	data := make([]byte, em.Header.Length)
@@ -56,6 +199,9 @@ func (em *OfpErrMsg) MarshalBinary() ([]byte, error) {
 
 // UnmarshalBinary transforms the byte array into packet in message data
 func (em *OfpErrMsg) UnmarshalBinary(data []byte) error {
+	if len(data) < 12 {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
 	if err := (&em.Header).UnmarshalBinary(data); err != nil {
 		return err
 	}
@@ -63,6 +209,50 @@ func (em *OfpErrMsg) UnmarshalBinary(data []byte) error {
 	if err := UnMarshalFields(buf, &em.Type, &em.Code); err != nil {
 		return err
 	}
+	em.Data = make([]byte, len(data)-12)
 	copy(em.Data, data[12:])
 	return nil
 }
+
+// OfpEchoMsg represents the echo request and echo reply message structure
+type OfpEchoMsg struct {
+	Header OfpHeader
+	Data   []byte /* Arbitrary-length data. */
+}
+
+// NewEchoRequestMsg creates an echo request message
+func NewEchoRequestMsg(version uint8, data []byte) *OfpEchoMsg {
+	header := NewOfpHeader(version)
+	header.Type = OfpTypeEchoRequest
+	header.Length = uint16(8 + len(data))
+	return &OfpEchoMsg{Header: *header, Data: data}
+}
+
+// NewEchoReplyMsg creates the echo reply of an echo request, the reply
+// carries the same xid and payload as the request
+func NewEchoReplyMsg(request *OfpEchoMsg) *OfpEchoMsg {
+	header := request.Header
+	header.Type = OfpTypeEchoReply
+	header.Length = uint16(8 + len(request.Data))
+	return &OfpEchoMsg{Header: header, Data: request.Data}
+}
+
+// MarshalBinary converts the echo msg fields into byte array
+func (echo *OfpEchoMsg) MarshalBinary() ([]byte, error) {
+	echo.Header.Length = uint16(8 + len(echo.Data))
+	headerData, err := (&echo.Header).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(headerData, echo.Data...), nil
+}
+
+// UnmarshalBinary transforms the byte array into echo message data
+func (echo *OfpEchoMsg) UnmarshalBinary(data []byte) error {
+	if err := (&echo.Header).UnmarshalBinary(data); err != nil {
+		return err
+	}
+	echo.Data = make([]byte, len(data)-8)
+	copy(echo.Data, data[8:])
+	return nil
+}
@@ -0,0 +1,459 @@
+package ofpgeneral
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// OXM Class IDs.
+// The high order bit differentiate reserved classes from member classes.
+// Classes 0x0000 to 0x7FFF are member classes, allocated by ONF.
+// Classes 0x8000 to 0xFFFE are reserved classes, reserved for standardisation.
+// enum ofp_oxm_class {
+const (
+	OxmClassNxm0          = 0x0000 /* Backward compatibility with NXM */
+	OxmClassNxm1          = 0x0001 /* Backward compatibility with NXM */
+	OxmClassOpenflowBasic = 0x8000 /* Basic class for OpenFlow */
+	OxmClassPacketRegs    = 0x8001 /* Packet registers (pipeline fields). */
+	OxmClassExperimenter  = 0xFFFF /* Experimenter class */
+)
+
+// OXM Flow field types for OpenFlow basic class.
+// enum oxm_ofb_match_fields {
+const (
+	OxmFieldInPort       = 0  /* Switch input port. */
+	OxmFieldInPhyPort    = 1  /* Switch physical input port. */
+	OxmFieldMetadata     = 2  /* Metadata passed between tables. */
+	OxmFieldEthDst       = 3  /* Ethernet destination address. */
+	OxmFieldEthSrc       = 4  /* Ethernet source address. */
+	OxmFieldEthType      = 5  /* Ethernet frame type. */
+	OxmFieldVlanVID      = 6  /* VLAN id. */
+	OxmFieldVlanPCP      = 7  /* VLAN priority. */
+	OxmFieldIPDSCP       = 8  /* IP DSCP (6 bits in ToS field). */
+	OxmFieldIPECN        = 9  /* IP ECN (2 bits in ToS field). */
+	OxmFieldIPProto      = 10 /* IP protocol. */
+	OxmFieldIPv4Src      = 11 /* IPv4 source address. */
+	OxmFieldIPv4Dst      = 12 /* IPv4 destination address. */
+	OxmFieldTCPSrc       = 13 /* TCP source port. */
+	OxmFieldTCPDst       = 14 /* TCP destination port. */
+	OxmFieldUDPSrc       = 15 /* UDP source port. */
+	OxmFieldUDPDst       = 16 /* UDP destination port. */
+	OxmFieldSCTPSrc      = 17 /* SCTP source port. */
+	OxmFieldSCTPDst      = 18 /* SCTP destination port. */
+	OxmFieldICMPv4Type   = 19 /* ICMP type. */
+	OxmFieldICMPv4Code   = 20 /* ICMP code. */
+	OxmFieldARPOp        = 21 /* ARP opcode. */
+	OxmFieldARPSpa       = 22 /* ARP source IPv4 address. */
+	OxmFieldARPTpa       = 23 /* ARP target IPv4 address. */
+	OxmFieldARPSha       = 24 /* ARP source hardware address. */
+	OxmFieldARPTha       = 25 /* ARP target hardware address. */
+	OxmFieldIPv6Src      = 26 /* IPv6 source address. */
+	OxmFieldIPv6Dst      = 27 /* IPv6 destination address. */
+	OxmFieldIPv6FLabel   = 28 /* IPv6 Flow Label */
+	OxmFieldICMPv6Type   = 29 /* ICMPv6 type. */
+	OxmFieldICMPv6Code   = 30 /* ICMPv6 code. */
+	OxmFieldIPv6NDTarget = 31 /* Target address for ND. */
+	OxmFieldIPv6NDSLL    = 32 /* Source link-layer for ND. */
+	OxmFieldIPv6NDTLL    = 33 /* Target link-layer for ND. */
+	OxmFieldMplsLabel    = 34 /* MPLS label. */
+	OxmFieldMplsTC       = 35 /* MPLS TC. */
+	OxmFieldMplsBOS      = 36 /* MPLS BoS bit. */
+	OxmFieldPbbISID      = 37 /* PBB I-SID. */
+	OxmFieldTunnelID     = 38 /* Logical Port Metadata. */
+	OxmFieldIPv6ExtHdr   = 39 /* IPv6 Extension Header pseudo-field */
+	OxmFieldPbbUCA       = 41 /* PBB UCA header field. */
+	OxmFieldTCPFlags     = 42 /* TCP flags. */
+	OxmFieldActsetOutput = 43 /* Output port from action set metadata. */
+	OxmFieldPacketType   = 44 /* Packet type value. */
+)
+
+// The VLAN id is 12-bits, so we can use the entire 16 bits to indicate
+// special conditions.
+// enum ofp_vlan_id {
+const (
+	OfpVIDPresent = 0x1000 /* Bit that indicate that a VLAN id is set */
+	OfpVIDNone    = 0x0000 /* No VLAN id was set. */
+)
+
+// Bit definitions for IPv6 Extension Header pseudo-field.
+// enum ofp_ipv6exthdr_flags {
+const (
+	OfpIEHNoNext = 1 << iota /* "No next header" encountered. */
+	OfpIEHEsp                /* Encrypted Sec Payload header present. */
+	OfpIEHAuth               /* Authentication header present. */
+	OfpIEHDest               /* 1 or 2 dest headers present. */
+	OfpIEHFrag               /* Fragment header present. */
+	OfpIEHRouter             /* Router header present. */
+	OfpIEHHop                /* Hop-by-hop header present. */
+	OfpIEHUnRep              /* Unexpected repeats encountered. */
+	OfpIEHUnSeq              /* Unexpected sequencing encountered. */
+)
+
+const (
+	oxmHeaderLen = 4
+	// oxmExperimenterLen is the size of the experimenter id following
+	// the header of the experimenter fields
+	oxmExperimenterLen = 4
+	// oxmPacketRegLen is the size of the value of the packet registers
+	oxmPacketRegLen = 8
+)
+
+// oxmBasicFieldLen holds the size of the value of the openflow basic fields
+var oxmBasicFieldLen = map[uint8]uint8{
+	OxmFieldInPort:       4,
+	OxmFieldInPhyPort:    4,
+	OxmFieldMetadata:     8,
+	OxmFieldEthDst:       6,
+	OxmFieldEthSrc:       6,
+	OxmFieldEthType:      2,
+	OxmFieldVlanVID:      2,
+	OxmFieldVlanPCP:      1,
+	OxmFieldIPDSCP:       1,
+	OxmFieldIPECN:        1,
+	OxmFieldIPProto:      1,
+	OxmFieldIPv4Src:      4,
+	OxmFieldIPv4Dst:      4,
+	OxmFieldTCPSrc:       2,
+	OxmFieldTCPDst:       2,
+	OxmFieldUDPSrc:       2,
+	OxmFieldUDPDst:       2,
+	OxmFieldSCTPSrc:      2,
+	OxmFieldSCTPDst:      2,
+	OxmFieldICMPv4Type:   1,
+	OxmFieldICMPv4Code:   1,
+	OxmFieldARPOp:        2,
+	OxmFieldARPSpa:       4,
+	OxmFieldARPTpa:       4,
+	OxmFieldARPSha:       6,
+	OxmFieldARPTha:       6,
+	OxmFieldIPv6Src:      16,
+	OxmFieldIPv6Dst:      16,
+	OxmFieldIPv6FLabel:   4,
+	OxmFieldICMPv6Type:   1,
+	OxmFieldICMPv6Code:   1,
+	OxmFieldIPv6NDTarget: 16,
+	OxmFieldIPv6NDSLL:    6,
+	OxmFieldIPv6NDTLL:    6,
+	OxmFieldMplsLabel:    4,
+	OxmFieldMplsTC:       1,
+	OxmFieldMplsBOS:      1,
+	OxmFieldPbbISID:      3,
+	OxmFieldTunnelID:     8,
+	OxmFieldIPv6ExtHdr:   2,
+	OxmFieldPbbUCA:       1,
+	OxmFieldTCPFlags:     2,
+	OxmFieldActsetOutput: 4,
+	OxmFieldPacketType:   4,
+}
+
+// oxmBasicFieldNames holds the names of the openflow basic fields
+var oxmBasicFieldNames = map[uint8]string{
+	OxmFieldInPort:       "in_port",
+	OxmFieldInPhyPort:    "in_phy_port",
+	OxmFieldMetadata:     "metadata",
+	OxmFieldEthDst:       "eth_dst",
+	OxmFieldEthSrc:       "eth_src",
+	OxmFieldEthType:      "eth_type",
+	OxmFieldVlanVID:      "vlan_vid",
+	OxmFieldVlanPCP:      "vlan_pcp",
+	OxmFieldIPDSCP:       "ip_dscp",
+	OxmFieldIPECN:        "ip_ecn",
+	OxmFieldIPProto:      "ip_proto",
+	OxmFieldIPv4Src:      "ipv4_src",
+	OxmFieldIPv4Dst:      "ipv4_dst",
+	OxmFieldTCPSrc:       "tcp_src",
+	OxmFieldTCPDst:       "tcp_dst",
+	OxmFieldUDPSrc:       "udp_src",
+	OxmFieldUDPDst:       "udp_dst",
+	OxmFieldSCTPSrc:      "sctp_src",
+	OxmFieldSCTPDst:      "sctp_dst",
+	OxmFieldICMPv4Type:   "icmpv4_type",
+	OxmFieldICMPv4Code:   "icmpv4_code",
+	OxmFieldARPOp:        "arp_op",
+	OxmFieldARPSpa:       "arp_spa",
+	OxmFieldARPTpa:       "arp_tpa",
+	OxmFieldARPSha:       "arp_sha",
+	OxmFieldARPTha:       "arp_tha",
+	OxmFieldIPv6Src:      "ipv6_src",
+	OxmFieldIPv6Dst:      "ipv6_dst",
+	OxmFieldIPv6FLabel:   "ipv6_flabel",
+	OxmFieldICMPv6Type:   "icmpv6_type",
+	OxmFieldICMPv6Code:   "icmpv6_code",
+	OxmFieldIPv6NDTarget: "ipv6_nd_target",
+	OxmFieldIPv6NDSLL:    "ipv6_nd_sll",
+	OxmFieldIPv6NDTLL:    "ipv6_nd_tll",
+	OxmFieldMplsLabel:    "mpls_label",
+	OxmFieldMplsTC:       "mpls_tc",
+	OxmFieldMplsBOS:      "mpls_bos",
+	OxmFieldPbbISID:      "pbb_isid",
+	OxmFieldTunnelID:     "tunnel_id",
+	OxmFieldIPv6ExtHdr:   "ipv6_exthdr",
+	OxmFieldPbbUCA:       "pbb_uca",
+	OxmFieldTCPFlags:     "tcp_flags",
+	OxmFieldActsetOutput: "actset_output",
+	OxmFieldPacketType:   "packet_type",
+}
+
+// OxmField represents a single OXM TLV, the header is made of the class,
+// the field, the has mask bit and the length of the payload
+type OxmField struct {
+	Class        uint16 /* One of OFPXMC_* */
+	Field        uint8  /* Field type within the class, 7 bits. */
+	HasMask      bool   /* Whether the payload contains the mask. */
+	Length       uint8  /* Length of the payload in bytes. */
+	Experimenter uint32 /* Experimenter ID, only present in the experimenter class. */
+	Value        []byte
+	Mask         []byte /* Only present when HasMask is set. */
+}
+
+// NewOxmField creates the field of the class, the mask is optional
+func NewOxmField(class uint16, field uint8, value []byte, mask []byte) *OxmField {
+	return &OxmField{Class: class, Field: field, HasMask: mask != nil, Value: value, Mask: mask}
+}
+
+// NewOxmID returns the OXM header identifying the unmasked field, which is
+// used by the actions and the properties referring to fields rather than
+// carrying them. The length of the experimenter fields is unknown and left
+// zero.
+func NewOxmID(class uint16, field uint8) uint32 {
+	of := OxmField{Class: class, Field: field}
+	switch class {
+	case OxmClassOpenflowBasic:
+		of.Length = oxmBasicFieldLen[field]
+	case OxmClassPacketRegs:
+		of.Length = oxmPacketRegLen
+	}
+	return of.Header()
+}
+
+// Name returns the name of the field used by the openflow specification,
+// e.g. eth_dst, or the class and the field numbers if the field isn't an
+// openflow basic one
+func (of *OxmField) Name() string {
+	switch of.Class {
+	case OxmClassOpenflowBasic:
+		if name, ok := oxmBasicFieldNames[of.Field]; ok {
+			return name
+		}
+	case OxmClassPacketRegs:
+		return fmt.Sprintf("packet_reg%d", of.Field)
+	}
+	return fmt.Sprintf("oxm field %#04x:%d", of.Class, of.Field)
+}
+
+// Header returns the 32 bits OXM header of the field
+func (of *OxmField) Header() uint32 {
+	header := uint32(of.Class)<<16 | uint32(of.Field&0x7f)<<9 | uint32(of.Length)
+	if of.HasMask {
+		header |= 1 << 8
+	}
+	return header
+}
+
+// Len returns the length of the TLV including the header
+func (of *OxmField) Len() uint16 {
+	return oxmHeaderLen + uint16(of.experimenterLen()+len(of.Value)+len(of.Mask))
+}
+
+// experimenterLen returns the size of the experimenter id in the payload
+func (of *OxmField) experimenterLen() int {
+	if of.Class == OxmClassExperimenter {
+		return oxmExperimenterLen
+	}
+	return 0
+}
+
+// UnmarshalBinary transforms the byte array into field data
+func (of *OxmField) UnmarshalBinary(data []byte) error {
+	if len(data) < oxmHeaderLen {
+		return fmt.Errorf("The data size %d is not big enough to be decoded", len(data))
+	}
+	header := binary.BigEndian.Uint32(data)
+	of.Class = uint16(header >> 16)
+	of.Field = uint8(header>>9) & 0x7f
+	of.HasMask = header&(1<<8) != 0
+	of.Length = uint8(header)
+	if len(data) < oxmHeaderLen+int(of.Length) {
+		return fmt.Errorf("Invalid OXM field length %d in data of size %d", of.Length, len(data))
+	}
+	// The experimenter id isn't part of the value and the mask
+	valueLen := int(of.Length) - of.experimenterLen()
+	if valueLen < 0 {
+		return fmt.Errorf("Invalid length %d of the experimenter OXM field %d", of.Length, of.Field)
+	}
+	if of.HasMask {
+		if valueLen%2 != 0 {
+			return fmt.Errorf("Invalid masked OXM field length %d", of.Length)
+		}
+		valueLen /= 2
+	}
+	switch of.Class {
+	case OxmClassOpenflowBasic:
+		if size, ok := oxmBasicFieldLen[of.Field]; ok && int(size) != valueLen {
+			return fmt.Errorf("Invalid length %d of the OXM field %d", of.Length, of.Field)
+		}
+	case OxmClassPacketRegs:
+		if valueLen != oxmPacketRegLen {
+			return fmt.Errorf("Invalid length %d of the packet register %d", of.Length, of.Field)
+		}
+	}
+	payload := data[oxmHeaderLen:]
+	of.Experimenter = 0
+	if of.Class == OxmClassExperimenter {
+		of.Experimenter = binary.BigEndian.Uint32(payload)
+		payload = payload[oxmExperimenterLen:]
+	}
+	of.Value = make([]byte, valueLen)
+	copy(of.Value, payload)
+	of.Mask = nil
+	if of.HasMask {
+		of.Mask = make([]byte, valueLen)
+		copy(of.Mask, payload[valueLen:])
+	}
+	return nil
+}
+
+// MarshalBinary converts the field into byte array, the length is set
+// according to the value and the mask
+func (of *OxmField) MarshalBinary() ([]byte, error) {
+	if of.HasMask && len(of.Mask) != len(of.Value) {
+		return nil, fmt.Errorf("The mask size %d doesn't match the value size %d of the OXM field %d",
+			len(of.Mask), len(of.Value), of.Field)
+	}
+	if !of.HasMask {
+		of.Mask = nil
+	}
+	of.Length = uint8(of.experimenterLen() + len(of.Value) + len(of.Mask))
+	data := make([]byte, of.Len())
+	binary.BigEndian.PutUint32(data, of.Header())
+	payload := data[oxmHeaderLen:]
+	if of.Class == OxmClassExperimenter {
+		binary.BigEndian.PutUint32(payload, of.Experimenter)
+		payload = payload[oxmExperimenterLen:]
+	}
+	copy(payload, of.Value)
+	copy(payload[len(of.Value):], of.Mask)
+	return data, nil
+}
+
+// OxmFields is the list of OXM TLVs composing a match, the typed
+// accessors of the openflow basic fields are provided on it
+type OxmFields []OxmField
+
+// Len returns the length of the TLVs excluding any padding
+func (fields *OxmFields) Len() uint16 {
+	length := uint16(0)
+	for idx := range *fields {
+		length += (*fields)[idx].Len()
+	}
+	return length
+}
+
+// Get returns the field of the class, nil is returned if the field
+// is absent
+func (fields *OxmFields) Get(class uint16, field uint8) *OxmField {
+	for idx := range *fields {
+		if (*fields)[idx].Class == class && (*fields)[idx].Field == field {
+			return &(*fields)[idx]
+		}
+	}
+	return nil
+}
+
+// Set adds the field to the list, the field of the same class and type
+// is replaced if already present
+func (fields *OxmFields) Set(field OxmField) {
+	if existing := fields.Get(field.Class, field.Field); existing != nil {
+		*existing = field
+		return
+	}
+	*fields = append(*fields, field)
+}
+
+// Delete removes the field of the class from the list
+func (fields *OxmFields) Delete(class uint16, field uint8) {
+	for idx := range *fields {
+		if (*fields)[idx].Class == class && (*fields)[idx].Field == field {
+			*fields = append((*fields)[:idx], (*fields)[idx+1:]...)
+			return
+		}
+	}
+}
+
+// UnmarshalBinary transforms the byte array into the list of TLVs, the
+// whole data is expected to be made of TLVs
+func (fields *OxmFields) UnmarshalBinary(data []byte) error {
+	result := make(OxmFields, 0)
+	for idx := 0; idx < len(data); {
+		field := OxmField{}
+		if err := field.UnmarshalBinary(data[idx:]); err != nil {
+			return err
+		}
+		result = append(result, field)
+		idx += int(field.Len())
+	}
+	*fields = result
+	return nil
+}
+
+// MarshalBinary converts the list of TLVs into byte array without padding
+func (fields *OxmFields) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	for idx := range *fields {
+		fieldData, err := (&(*fields)[idx]).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(fieldData)
+	}
+	return buf.Bytes(), nil
+}
+
+// setBasic sets the openflow basic field with the value and the optional mask
+func (fields *OxmFields) setBasic(field uint8, value []byte, mask []byte) {
+	fields.Set(*NewOxmField(OxmClassOpenflowBasic, field, value, mask))
+}
+
+// getBasic returns the value and the mask of the openflow basic field,
+// the mask is nil if the field isn't masked
+func (fields *OxmFields) getBasic(field uint8) ([]byte, []byte, bool) {
+	f := fields.Get(OxmClassOpenflowBasic, field)
+	if f == nil {
+		return nil, nil, false
+	}
+	return f.Value, f.Mask, true
+}
+
+// uintBytes encodes the lowest size bytes of the value in network order
+func uintBytes(value uint64, size int) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	return data[8-size:]
+}
+
+// bytesUint decodes the value in network order of up to 8 bytes
+func bytesUint(data []byte) uint64 {
+	value := uint64(0)
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// maskUint decodes the mask of the field of size bytes, the mask is all
+// ones if the field isn't masked
+func maskUint(mask []byte, size int) uint64 {
+	if mask == nil {
+		return bytesUint(bytes.Repeat([]byte{0xff}, size))
+	}
+	return bytesUint(mask)
+}
+
+// fixedBytes copies the data into a slice of the size, the data is
+// truncated or zero filled if its size is different
+func fixedBytes(data []byte, size int) []byte {
+	result := make([]byte, size)
+	copy(result, data)
+	return result
+}
@@ -0,0 +1,152 @@
+package ofpgeneral
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestOxmFieldWireFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *OxmField
+		wire  string
+	}{
+		{"in_port", NewOxmField(OxmClassOpenflowBasic, OxmFieldInPort, []byte{0, 0, 0, 3}, nil),
+			"8000000400000003"},
+		{"masked eth_dst", NewOxmField(OxmClassOpenflowBasic, OxmFieldEthDst,
+			[]byte{1, 2, 3, 4, 5, 6}, []byte{0xff, 0xff, 0xff, 0, 0, 0}),
+			"8000070c010203040506ffffff000000"},
+		{"masked ipv4_src", NewOxmField(OxmClassOpenflowBasic, OxmFieldIPv4Src,
+			[]byte{10, 0, 0, 0}, []byte{255, 0, 0, 0}),
+			"800017080a000000ff000000"},
+		{"packet register", NewOxmField(OxmClassPacketRegs, 2, []byte{0, 0, 0, 0, 0, 0, 0, 7}, nil),
+			"800104080000000000000007"},
+		{"experimenter", &OxmField{Class: OxmClassExperimenter, Field: 5, Experimenter: 0x2320,
+			Value: []byte{0, 1}},
+			"ffff0a06000023200001"},
+		{"masked experimenter", &OxmField{Class: OxmClassExperimenter, Field: 5, HasMask: true,
+			Experimenter: 0x2320, Value: []byte{0, 1}, Mask: []byte{0, 0xff}},
+			"ffff0b0800002320000100ff"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.field.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(data) != tc.wire {
+				t.Fatalf("Encoded %x, expected %s", data, tc.wire)
+			}
+			if int(tc.field.Len()) != len(data) {
+				t.Fatalf("Length %d doesn't match the encoded size %d", tc.field.Len(), len(data))
+			}
+			decoded := OxmField{}
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&decoded, tc.field) {
+				t.Fatalf("Decoded %+v, expected %+v", decoded, *tc.field)
+			}
+		})
+	}
+}
+
+// TestOxmBasicFieldsRoundTrip encodes and decodes every openflow basic
+// field with and without mask
+func TestOxmBasicFieldsRoundTrip(t *testing.T) {
+	for field, size := range oxmBasicFieldLen {
+		value := make([]byte, size)
+		mask := make([]byte, size)
+		for i := range value {
+			value[i] = byte(i + 1)
+			mask[i] = 0xf0
+		}
+		for _, masked := range []bool{false, true} {
+			of := NewOxmField(OxmClassOpenflowBasic, field, value, nil)
+			if masked {
+				of = NewOxmField(OxmClassOpenflowBasic, field, value, mask)
+			}
+			data, err := of.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%s: %v", of.Name(), err)
+			}
+			payloadLen := int(size)
+			if masked {
+				payloadLen *= 2
+			}
+			if len(data) != oxmHeaderLen+payloadLen || int(data[3]) != payloadLen {
+				t.Fatalf("%s masked %v: unexpected encoding %x", of.Name(), masked, data)
+			}
+			if (data[2]&1 != 0) != masked || data[2]>>1 != field {
+				t.Fatalf("%s masked %v: unexpected header %x", of.Name(), masked, data[:4])
+			}
+			decoded := OxmField{}
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("%s masked %v: %v", of.Name(), masked, err)
+			}
+			if !reflect.DeepEqual(&decoded, of) {
+				t.Fatalf("%s masked %v: decoded %+v, expected %+v", of.Name(), masked, decoded, *of)
+			}
+		}
+	}
+}
+
+func TestOxmFieldInvalidLength(t *testing.T) {
+	for _, wire := range []string{
+		"800000",           // Truncated header
+		"80000004000003",   // Truncated value
+		"8000000200000003", // Wrong size of in_port
+		"8000010500000003", // Odd size of the masked field
+		"ffff0a0200002320", // Experimenter id beyond the length
+	} {
+		data, _ := hex.DecodeString(wire)
+		of := OxmField{}
+		if err := of.UnmarshalBinary(data); err == nil {
+			t.Errorf("The field %s is decoded as %+v", wire, of)
+		}
+	}
+}
+
+func TestOxmFieldsAccessors(t *testing.T) {
+	fields := OxmFields{}
+	fields.SetInPort(3)
+	fields.SetEthType(0x0800)
+	fields.SetIPv4SrcMasked(net.IP{10, 1, 0, 0}, net.CIDRMask(16, 32))
+	fields.SetVlanVIDMasked(OfpVIDPresent, OfpVIDPresent)
+	fields.SetMetadataMasked(0x1234, 0xff00)
+	data, err := fields.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "8000000400000003" + "80000a020800" + "800017080a010000ffff0000" +
+		"80000d0410001000" + "800005100000000000001234000000000000ff00"
+	if hex.EncodeToString(data) != expected {
+		t.Fatalf("Encoded %x, expected %s", data, expected)
+	}
+	decoded := OxmFields{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if port, ok := decoded.GetInPort(); !ok || port != 3 {
+		t.Errorf("Unexpected in_port %d %v", port, ok)
+	}
+	if ethType, ok := decoded.GetEthType(); !ok || ethType != 0x0800 {
+		t.Errorf("Unexpected eth_type %#x %v", ethType, ok)
+	}
+	if addr, mask, ok := decoded.GetIPv4Src(); !ok || !addr.Equal(net.IP{10, 1, 0, 0}) ||
+		!bytes.Equal(mask, net.CIDRMask(16, 32)) {
+		t.Errorf("Unexpected ipv4_src %v/%v %v", addr, mask, ok)
+	}
+	if vid, mask, ok := decoded.GetVlanVID(); !ok || vid != OfpVIDPresent || mask != OfpVIDPresent {
+		t.Errorf("Unexpected vlan_vid %#x/%#x %v", vid, mask, ok)
+	}
+	if metadata, mask, ok := decoded.GetMetadata(); !ok || metadata != 0x1234 || mask != 0xff00 {
+		t.Errorf("Unexpected metadata %#x/%#x %v", metadata, mask, ok)
+	}
+	if _, ok := decoded.GetTCPDst(); ok {
+		t.Error("The absent tcp_dst is found")
+	}
+}
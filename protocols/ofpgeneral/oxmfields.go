@@ -0,0 +1,615 @@
+package ofpgeneral
+
+import (
+	"net"
+)
+
+// This file holds the typed accessors of the openflow basic fields. The
+// getters return false if the field is absent from the match. When the
+// field isn't masked, the getters of the maskable fields return an all
+// ones mask for the integer fields and a nil mask for the addresses.
+
+// SetInPort sets the switch input port to match
+func (fields *OxmFields) SetInPort(port uint32) {
+	fields.setBasic(OxmFieldInPort, uintBytes(uint64(port), 4), nil)
+}
+
+// GetInPort returns the matched switch input port
+func (fields *OxmFields) GetInPort() (uint32, bool) {
+	value, _, ok := fields.getBasic(OxmFieldInPort)
+	return uint32(bytesUint(value)), ok
+}
+
+// SetInPhyPort sets the switch physical input port to match
+func (fields *OxmFields) SetInPhyPort(port uint32) {
+	fields.setBasic(OxmFieldInPhyPort, uintBytes(uint64(port), 4), nil)
+}
+
+// GetInPhyPort returns the matched switch physical input port
+func (fields *OxmFields) GetInPhyPort() (uint32, bool) {
+	value, _, ok := fields.getBasic(OxmFieldInPhyPort)
+	return uint32(bytesUint(value)), ok
+}
+
+// SetMetadata sets the metadata passed between tables to match
+func (fields *OxmFields) SetMetadata(metadata uint64) {
+	fields.setBasic(OxmFieldMetadata, uintBytes(metadata, 8), nil)
+}
+
+// SetMetadataMasked sets the metadata passed between tables to match with the mask
+func (fields *OxmFields) SetMetadataMasked(metadata, mask uint64) {
+	fields.setBasic(OxmFieldMetadata, uintBytes(metadata, 8), uintBytes(mask, 8))
+}
+
+// GetMetadata returns the matched metadata passed between tables and its mask
+func (fields *OxmFields) GetMetadata() (uint64, uint64, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldMetadata)
+	return bytesUint(value), maskUint(mask, 8), ok
+}
+
+// SetEthDst sets the ethernet destination address to match
+func (fields *OxmFields) SetEthDst(addr net.HardwareAddr) {
+	fields.setBasic(OxmFieldEthDst, fixedBytes(addr, 6), nil)
+}
+
+// SetEthDstMasked sets the ethernet destination address to match with the mask
+func (fields *OxmFields) SetEthDstMasked(addr net.HardwareAddr, mask net.HardwareAddr) {
+	fields.setBasic(OxmFieldEthDst, fixedBytes(addr, 6), fixedBytes(mask, 6))
+}
+
+// GetEthDst returns the matched ethernet destination address and its mask
+func (fields *OxmFields) GetEthDst() (net.HardwareAddr, net.HardwareAddr, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldEthDst)
+	return net.HardwareAddr(value), net.HardwareAddr(mask), ok
+}
+
+// SetEthSrc sets the ethernet source address to match
+func (fields *OxmFields) SetEthSrc(addr net.HardwareAddr) {
+	fields.setBasic(OxmFieldEthSrc, fixedBytes(addr, 6), nil)
+}
+
+// SetEthSrcMasked sets the ethernet source address to match with the mask
+func (fields *OxmFields) SetEthSrcMasked(addr net.HardwareAddr, mask net.HardwareAddr) {
+	fields.setBasic(OxmFieldEthSrc, fixedBytes(addr, 6), fixedBytes(mask, 6))
+}
+
+// GetEthSrc returns the matched ethernet source address and its mask
+func (fields *OxmFields) GetEthSrc() (net.HardwareAddr, net.HardwareAddr, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldEthSrc)
+	return net.HardwareAddr(value), net.HardwareAddr(mask), ok
+}
+
+// SetEthType sets the ethernet frame type to match
+func (fields *OxmFields) SetEthType(ethType uint16) {
+	fields.setBasic(OxmFieldEthType, uintBytes(uint64(ethType), 2), nil)
+}
+
+// GetEthType returns the matched ethernet frame type
+func (fields *OxmFields) GetEthType() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldEthType)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetVlanVID sets the vlan id, OfpVIDPresent is set for tagged packets to match
+func (fields *OxmFields) SetVlanVID(vid uint16) {
+	fields.setBasic(OxmFieldVlanVID, uintBytes(uint64(vid), 2), nil)
+}
+
+// SetVlanVIDMasked sets the vlan id, OfpVIDPresent is set for tagged packets to match with the mask
+func (fields *OxmFields) SetVlanVIDMasked(vid, mask uint16) {
+	fields.setBasic(OxmFieldVlanVID, uintBytes(uint64(vid), 2), uintBytes(uint64(mask), 2))
+}
+
+// GetVlanVID returns the matched vlan id, OfpVIDPresent is set for tagged packets and its mask
+func (fields *OxmFields) GetVlanVID() (uint16, uint16, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldVlanVID)
+	return uint16(bytesUint(value)), uint16(maskUint(mask, 2)), ok
+}
+
+// SetVlanPCP sets the vlan priority to match
+func (fields *OxmFields) SetVlanPCP(pcp uint8) {
+	fields.setBasic(OxmFieldVlanPCP, uintBytes(uint64(pcp), 1), nil)
+}
+
+// GetVlanPCP returns the matched vlan priority
+func (fields *OxmFields) GetVlanPCP() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldVlanPCP)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetIPDSCP sets the ip dscp (6 bits in ToS field) to match
+func (fields *OxmFields) SetIPDSCP(dscp uint8) {
+	fields.setBasic(OxmFieldIPDSCP, uintBytes(uint64(dscp), 1), nil)
+}
+
+// GetIPDSCP returns the matched ip dscp (6 bits in ToS field)
+func (fields *OxmFields) GetIPDSCP() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldIPDSCP)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetIPECN sets the ip ecn (2 bits in ToS field) to match
+func (fields *OxmFields) SetIPECN(ecn uint8) {
+	fields.setBasic(OxmFieldIPECN, uintBytes(uint64(ecn), 1), nil)
+}
+
+// GetIPECN returns the matched ip ecn (2 bits in ToS field)
+func (fields *OxmFields) GetIPECN() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldIPECN)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetIPProto sets the ip protocol to match
+func (fields *OxmFields) SetIPProto(proto uint8) {
+	fields.setBasic(OxmFieldIPProto, uintBytes(uint64(proto), 1), nil)
+}
+
+// GetIPProto returns the matched ip protocol
+func (fields *OxmFields) GetIPProto() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldIPProto)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetIPv4Src sets the ipv4 source address to match
+func (fields *OxmFields) SetIPv4Src(addr net.IP) {
+	fields.setBasic(OxmFieldIPv4Src, ipv4Bytes(addr), nil)
+}
+
+// SetIPv4SrcMasked sets the ipv4 source address to match with the mask
+func (fields *OxmFields) SetIPv4SrcMasked(addr net.IP, mask net.IPMask) {
+	fields.setBasic(OxmFieldIPv4Src, ipv4Bytes(addr), ipv4MaskBytes(mask))
+}
+
+// GetIPv4Src returns the matched ipv4 source address and its mask
+func (fields *OxmFields) GetIPv4Src() (net.IP, net.IPMask, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldIPv4Src)
+	return net.IP(value), net.IPMask(mask), ok
+}
+
+// SetIPv4Dst sets the ipv4 destination address to match
+func (fields *OxmFields) SetIPv4Dst(addr net.IP) {
+	fields.setBasic(OxmFieldIPv4Dst, ipv4Bytes(addr), nil)
+}
+
+// SetIPv4DstMasked sets the ipv4 destination address to match with the mask
+func (fields *OxmFields) SetIPv4DstMasked(addr net.IP, mask net.IPMask) {
+	fields.setBasic(OxmFieldIPv4Dst, ipv4Bytes(addr), ipv4MaskBytes(mask))
+}
+
+// GetIPv4Dst returns the matched ipv4 destination address and its mask
+func (fields *OxmFields) GetIPv4Dst() (net.IP, net.IPMask, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldIPv4Dst)
+	return net.IP(value), net.IPMask(mask), ok
+}
+
+// SetTCPSrc sets the tcp source port to match
+func (fields *OxmFields) SetTCPSrc(port uint16) {
+	fields.setBasic(OxmFieldTCPSrc, uintBytes(uint64(port), 2), nil)
+}
+
+// GetTCPSrc returns the matched tcp source port
+func (fields *OxmFields) GetTCPSrc() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldTCPSrc)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetTCPDst sets the tcp destination port to match
+func (fields *OxmFields) SetTCPDst(port uint16) {
+	fields.setBasic(OxmFieldTCPDst, uintBytes(uint64(port), 2), nil)
+}
+
+// GetTCPDst returns the matched tcp destination port
+func (fields *OxmFields) GetTCPDst() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldTCPDst)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetUDPSrc sets the udp source port to match
+func (fields *OxmFields) SetUDPSrc(port uint16) {
+	fields.setBasic(OxmFieldUDPSrc, uintBytes(uint64(port), 2), nil)
+}
+
+// GetUDPSrc returns the matched udp source port
+func (fields *OxmFields) GetUDPSrc() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldUDPSrc)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetUDPDst sets the udp destination port to match
+func (fields *OxmFields) SetUDPDst(port uint16) {
+	fields.setBasic(OxmFieldUDPDst, uintBytes(uint64(port), 2), nil)
+}
+
+// GetUDPDst returns the matched udp destination port
+func (fields *OxmFields) GetUDPDst() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldUDPDst)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetSCTPSrc sets the sctp source port to match
+func (fields *OxmFields) SetSCTPSrc(port uint16) {
+	fields.setBasic(OxmFieldSCTPSrc, uintBytes(uint64(port), 2), nil)
+}
+
+// GetSCTPSrc returns the matched sctp source port
+func (fields *OxmFields) GetSCTPSrc() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldSCTPSrc)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetSCTPDst sets the sctp destination port to match
+func (fields *OxmFields) SetSCTPDst(port uint16) {
+	fields.setBasic(OxmFieldSCTPDst, uintBytes(uint64(port), 2), nil)
+}
+
+// GetSCTPDst returns the matched sctp destination port
+func (fields *OxmFields) GetSCTPDst() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldSCTPDst)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetICMPv4Type sets the icmp type to match
+func (fields *OxmFields) SetICMPv4Type(icmpType uint8) {
+	fields.setBasic(OxmFieldICMPv4Type, uintBytes(uint64(icmpType), 1), nil)
+}
+
+// GetICMPv4Type returns the matched icmp type
+func (fields *OxmFields) GetICMPv4Type() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldICMPv4Type)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetICMPv4Code sets the icmp code to match
+func (fields *OxmFields) SetICMPv4Code(icmpCode uint8) {
+	fields.setBasic(OxmFieldICMPv4Code, uintBytes(uint64(icmpCode), 1), nil)
+}
+
+// GetICMPv4Code returns the matched icmp code
+func (fields *OxmFields) GetICMPv4Code() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldICMPv4Code)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetARPOp sets the arp opcode to match
+func (fields *OxmFields) SetARPOp(op uint16) {
+	fields.setBasic(OxmFieldARPOp, uintBytes(uint64(op), 2), nil)
+}
+
+// GetARPOp returns the matched arp opcode
+func (fields *OxmFields) GetARPOp() (uint16, bool) {
+	value, _, ok := fields.getBasic(OxmFieldARPOp)
+	return uint16(bytesUint(value)), ok
+}
+
+// SetARPSpa sets the arp source ipv4 address to match
+func (fields *OxmFields) SetARPSpa(addr net.IP) {
+	fields.setBasic(OxmFieldARPSpa, ipv4Bytes(addr), nil)
+}
+
+// SetARPSpaMasked sets the arp source ipv4 address to match with the mask
+func (fields *OxmFields) SetARPSpaMasked(addr net.IP, mask net.IPMask) {
+	fields.setBasic(OxmFieldARPSpa, ipv4Bytes(addr), ipv4MaskBytes(mask))
+}
+
+// GetARPSpa returns the matched arp source ipv4 address and its mask
+func (fields *OxmFields) GetARPSpa() (net.IP, net.IPMask, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldARPSpa)
+	return net.IP(value), net.IPMask(mask), ok
+}
+
+// SetARPTpa sets the arp target ipv4 address to match
+func (fields *OxmFields) SetARPTpa(addr net.IP) {
+	fields.setBasic(OxmFieldARPTpa, ipv4Bytes(addr), nil)
+}
+
+// SetARPTpaMasked sets the arp target ipv4 address to match with the mask
+func (fields *OxmFields) SetARPTpaMasked(addr net.IP, mask net.IPMask) {
+	fields.setBasic(OxmFieldARPTpa, ipv4Bytes(addr), ipv4MaskBytes(mask))
+}
+
+// GetARPTpa returns the matched arp target ipv4 address and its mask
+func (fields *OxmFields) GetARPTpa() (net.IP, net.IPMask, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldARPTpa)
+	return net.IP(value), net.IPMask(mask), ok
+}
+
+// SetARPSha sets the arp source hardware address to match
+func (fields *OxmFields) SetARPSha(addr net.HardwareAddr) {
+	fields.setBasic(OxmFieldARPSha, fixedBytes(addr, 6), nil)
+}
+
+// SetARPShaMasked sets the arp source hardware address to match with the mask
+func (fields *OxmFields) SetARPShaMasked(addr net.HardwareAddr, mask net.HardwareAddr) {
+	fields.setBasic(OxmFieldARPSha, fixedBytes(addr, 6), fixedBytes(mask, 6))
+}
+
+// GetARPSha returns the matched arp source hardware address and its mask
+func (fields *OxmFields) GetARPSha() (net.HardwareAddr, net.HardwareAddr, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldARPSha)
+	return net.HardwareAddr(value), net.HardwareAddr(mask), ok
+}
+
+// SetARPTha sets the arp target hardware address to match
+func (fields *OxmFields) SetARPTha(addr net.HardwareAddr) {
+	fields.setBasic(OxmFieldARPTha, fixedBytes(addr, 6), nil)
+}
+
+// SetARPThaMasked sets the arp target hardware address to match with the mask
+func (fields *OxmFields) SetARPThaMasked(addr net.HardwareAddr, mask net.HardwareAddr) {
+	fields.setBasic(OxmFieldARPTha, fixedBytes(addr, 6), fixedBytes(mask, 6))
+}
+
+// GetARPTha returns the matched arp target hardware address and its mask
+func (fields *OxmFields) GetARPTha() (net.HardwareAddr, net.HardwareAddr, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldARPTha)
+	return net.HardwareAddr(value), net.HardwareAddr(mask), ok
+}
+
+// SetIPv6Src sets the ipv6 source address to match
+func (fields *OxmFields) SetIPv6Src(addr net.IP) {
+	fields.setBasic(OxmFieldIPv6Src, fixedBytes(addr.To16(), 16), nil)
+}
+
+// SetIPv6SrcMasked sets the ipv6 source address to match with the mask
+func (fields *OxmFields) SetIPv6SrcMasked(addr net.IP, mask net.IPMask) {
+	fields.setBasic(OxmFieldIPv6Src, fixedBytes(addr.To16(), 16), fixedBytes(mask, 16))
+}
+
+// GetIPv6Src returns the matched ipv6 source address and its mask
+func (fields *OxmFields) GetIPv6Src() (net.IP, net.IPMask, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldIPv6Src)
+	return net.IP(value), net.IPMask(mask), ok
+}
+
+// SetIPv6Dst sets the ipv6 destination address to match
+func (fields *OxmFields) SetIPv6Dst(addr net.IP) {
+	fields.setBasic(OxmFieldIPv6Dst, fixedBytes(addr.To16(), 16), nil)
+}
+
+// SetIPv6DstMasked sets the ipv6 destination address to match with the mask
+func (fields *OxmFields) SetIPv6DstMasked(addr net.IP, mask net.IPMask) {
+	fields.setBasic(OxmFieldIPv6Dst, fixedBytes(addr.To16(), 16), fixedBytes(mask, 16))
+}
+
+// GetIPv6Dst returns the matched ipv6 destination address and its mask
+func (fields *OxmFields) GetIPv6Dst() (net.IP, net.IPMask, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldIPv6Dst)
+	return net.IP(value), net.IPMask(mask), ok
+}
+
+// SetIPv6FLabel sets the ipv6 flow label to match
+func (fields *OxmFields) SetIPv6FLabel(label uint32) {
+	fields.setBasic(OxmFieldIPv6FLabel, uintBytes(uint64(label), 4), nil)
+}
+
+// SetIPv6FLabelMasked sets the ipv6 flow label to match with the mask
+func (fields *OxmFields) SetIPv6FLabelMasked(label, mask uint32) {
+	fields.setBasic(OxmFieldIPv6FLabel, uintBytes(uint64(label), 4), uintBytes(uint64(mask), 4))
+}
+
+// GetIPv6FLabel returns the matched ipv6 flow label and its mask
+func (fields *OxmFields) GetIPv6FLabel() (uint32, uint32, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldIPv6FLabel)
+	return uint32(bytesUint(value)), uint32(maskUint(mask, 4)), ok
+}
+
+// SetICMPv6Type sets the icmpv6 type to match
+func (fields *OxmFields) SetICMPv6Type(icmpType uint8) {
+	fields.setBasic(OxmFieldICMPv6Type, uintBytes(uint64(icmpType), 1), nil)
+}
+
+// GetICMPv6Type returns the matched icmpv6 type
+func (fields *OxmFields) GetICMPv6Type() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldICMPv6Type)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetICMPv6Code sets the icmpv6 code to match
+func (fields *OxmFields) SetICMPv6Code(icmpCode uint8) {
+	fields.setBasic(OxmFieldICMPv6Code, uintBytes(uint64(icmpCode), 1), nil)
+}
+
+// GetICMPv6Code returns the matched icmpv6 code
+func (fields *OxmFields) GetICMPv6Code() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldICMPv6Code)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetIPv6NDTarget sets the target address for neighbor discovery to match
+func (fields *OxmFields) SetIPv6NDTarget(addr net.IP) {
+	fields.setBasic(OxmFieldIPv6NDTarget, fixedBytes(addr.To16(), 16), nil)
+}
+
+// GetIPv6NDTarget returns the matched target address for neighbor discovery
+func (fields *OxmFields) GetIPv6NDTarget() (net.IP, bool) {
+	value, _, ok := fields.getBasic(OxmFieldIPv6NDTarget)
+	return net.IP(value), ok
+}
+
+// SetIPv6NDSLL sets the source link-layer address for neighbor discovery to match
+func (fields *OxmFields) SetIPv6NDSLL(addr net.HardwareAddr) {
+	fields.setBasic(OxmFieldIPv6NDSLL, fixedBytes(addr, 6), nil)
+}
+
+// GetIPv6NDSLL returns the matched source link-layer address for neighbor discovery
+func (fields *OxmFields) GetIPv6NDSLL() (net.HardwareAddr, bool) {
+	value, _, ok := fields.getBasic(OxmFieldIPv6NDSLL)
+	return net.HardwareAddr(value), ok
+}
+
+// SetIPv6NDTLL sets the target link-layer address for neighbor discovery to match
+func (fields *OxmFields) SetIPv6NDTLL(addr net.HardwareAddr) {
+	fields.setBasic(OxmFieldIPv6NDTLL, fixedBytes(addr, 6), nil)
+}
+
+// GetIPv6NDTLL returns the matched target link-layer address for neighbor discovery
+func (fields *OxmFields) GetIPv6NDTLL() (net.HardwareAddr, bool) {
+	value, _, ok := fields.getBasic(OxmFieldIPv6NDTLL)
+	return net.HardwareAddr(value), ok
+}
+
+// SetMplsLabel sets the mpls label to match
+func (fields *OxmFields) SetMplsLabel(label uint32) {
+	fields.setBasic(OxmFieldMplsLabel, uintBytes(uint64(label), 4), nil)
+}
+
+// GetMplsLabel returns the matched mpls label
+func (fields *OxmFields) GetMplsLabel() (uint32, bool) {
+	value, _, ok := fields.getBasic(OxmFieldMplsLabel)
+	return uint32(bytesUint(value)), ok
+}
+
+// SetMplsTC sets the mpls traffic class to match
+func (fields *OxmFields) SetMplsTC(tc uint8) {
+	fields.setBasic(OxmFieldMplsTC, uintBytes(uint64(tc), 1), nil)
+}
+
+// GetMplsTC returns the matched mpls traffic class
+func (fields *OxmFields) GetMplsTC() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldMplsTC)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetMplsBOS sets the mpls bottom of stack bit to match
+func (fields *OxmFields) SetMplsBOS(bos uint8) {
+	fields.setBasic(OxmFieldMplsBOS, uintBytes(uint64(bos), 1), nil)
+}
+
+// GetMplsBOS returns the matched mpls bottom of stack bit
+func (fields *OxmFields) GetMplsBOS() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldMplsBOS)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetPbbISID sets the pbb i-sid to match
+func (fields *OxmFields) SetPbbISID(isid uint32) {
+	fields.setBasic(OxmFieldPbbISID, uintBytes(uint64(isid), 3), nil)
+}
+
+// SetPbbISIDMasked sets the pbb i-sid to match with the mask
+func (fields *OxmFields) SetPbbISIDMasked(isid, mask uint32) {
+	fields.setBasic(OxmFieldPbbISID, uintBytes(uint64(isid), 3), uintBytes(uint64(mask), 3))
+}
+
+// GetPbbISID returns the matched pbb i-sid and its mask
+func (fields *OxmFields) GetPbbISID() (uint32, uint32, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldPbbISID)
+	return uint32(bytesUint(value)), uint32(maskUint(mask, 3)), ok
+}
+
+// SetTunnelID sets the logical port metadata to match
+func (fields *OxmFields) SetTunnelID(tunnelID uint64) {
+	fields.setBasic(OxmFieldTunnelID, uintBytes(tunnelID, 8), nil)
+}
+
+// SetTunnelIDMasked sets the logical port metadata to match with the mask
+func (fields *OxmFields) SetTunnelIDMasked(tunnelID, mask uint64) {
+	fields.setBasic(OxmFieldTunnelID, uintBytes(tunnelID, 8), uintBytes(mask, 8))
+}
+
+// GetTunnelID returns the matched logical port metadata and its mask
+func (fields *OxmFields) GetTunnelID() (uint64, uint64, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldTunnelID)
+	return bytesUint(value), maskUint(mask, 8), ok
+}
+
+// SetIPv6ExtHdr sets the ipv6 extension header pseudo-field, a bitmap of OfpIEH* to match
+func (fields *OxmFields) SetIPv6ExtHdr(flags uint16) {
+	fields.setBasic(OxmFieldIPv6ExtHdr, uintBytes(uint64(flags), 2), nil)
+}
+
+// SetIPv6ExtHdrMasked sets the ipv6 extension header pseudo-field, a bitmap of OfpIEH* to match with the mask
+func (fields *OxmFields) SetIPv6ExtHdrMasked(flags, mask uint16) {
+	fields.setBasic(OxmFieldIPv6ExtHdr, uintBytes(uint64(flags), 2), uintBytes(uint64(mask), 2))
+}
+
+// GetIPv6ExtHdr returns the matched ipv6 extension header pseudo-field, a bitmap of OfpIEH* and its mask
+func (fields *OxmFields) GetIPv6ExtHdr() (uint16, uint16, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldIPv6ExtHdr)
+	return uint16(bytesUint(value)), uint16(maskUint(mask, 2)), ok
+}
+
+// SetPbbUCA sets the pbb uca header field to match
+func (fields *OxmFields) SetPbbUCA(uca uint8) {
+	fields.setBasic(OxmFieldPbbUCA, uintBytes(uint64(uca), 1), nil)
+}
+
+// GetPbbUCA returns the matched pbb uca header field
+func (fields *OxmFields) GetPbbUCA() (uint8, bool) {
+	value, _, ok := fields.getBasic(OxmFieldPbbUCA)
+	return uint8(bytesUint(value)), ok
+}
+
+// SetTCPFlags sets the tcp flags to match
+func (fields *OxmFields) SetTCPFlags(flags uint16) {
+	fields.setBasic(OxmFieldTCPFlags, uintBytes(uint64(flags), 2), nil)
+}
+
+// SetTCPFlagsMasked sets the tcp flags to match with the mask
+func (fields *OxmFields) SetTCPFlagsMasked(flags, mask uint16) {
+	fields.setBasic(OxmFieldTCPFlags, uintBytes(uint64(flags), 2), uintBytes(uint64(mask), 2))
+}
+
+// GetTCPFlags returns the matched tcp flags and its mask
+func (fields *OxmFields) GetTCPFlags() (uint16, uint16, bool) {
+	value, mask, ok := fields.getBasic(OxmFieldTCPFlags)
+	return uint16(bytesUint(value)), uint16(maskUint(mask, 2)), ok
+}
+
+// SetActsetOutput sets the output port from the action set metadata to match
+func (fields *OxmFields) SetActsetOutput(port uint32) {
+	fields.setBasic(OxmFieldActsetOutput, uintBytes(uint64(port), 4), nil)
+}
+
+// GetActsetOutput returns the matched output port from the action set metadata
+func (fields *OxmFields) GetActsetOutput() (uint32, bool) {
+	value, _, ok := fields.getBasic(OxmFieldActsetOutput)
+	return uint32(bytesUint(value)), ok
+}
+
+// SetPacketType sets the packet type value to match
+func (fields *OxmFields) SetPacketType(packetType uint32) {
+	fields.setBasic(OxmFieldPacketType, uintBytes(uint64(packetType), 4), nil)
+}
+
+// GetPacketType returns the matched packet type value
+func (fields *OxmFields) GetPacketType() (uint32, bool) {
+	value, _, ok := fields.getBasic(OxmFieldPacketType)
+	return uint32(bytesUint(value)), ok
+}
+
+// SetPacketReg sets the 64 bits packet register of the index to match, the
+// packet registers are introduced by openflow 1.5 in the OxmClassPacketRegs
+// class
+func (fields *OxmFields) SetPacketReg(reg uint8, value uint64) {
+	fields.Set(*NewOxmField(OxmClassPacketRegs, reg, uintBytes(value, oxmPacketRegLen), nil))
+}
+
+// SetPacketRegMasked sets the packet register of the index to match with the mask
+func (fields *OxmFields) SetPacketRegMasked(reg uint8, value, mask uint64) {
+	fields.Set(*NewOxmField(OxmClassPacketRegs, reg, uintBytes(value, oxmPacketRegLen),
+		uintBytes(mask, oxmPacketRegLen)))
+}
+
+// GetPacketReg returns the matched packet register of the index and its mask
+func (fields *OxmFields) GetPacketReg(reg uint8) (uint64, uint64, bool) {
+	f := fields.Get(OxmClassPacketRegs, reg)
+	if f == nil {
+		return 0, 0, false
+	}
+	return bytesUint(f.Value), maskUint(f.Mask, oxmPacketRegLen), true
+}
+
+// ipv4Bytes returns the 4 bytes form of the ipv4 address, the address
+// is zero if it isn't an ipv4 one
+func ipv4Bytes(addr net.IP) []byte {
+	return fixedBytes(addr.To4(), 4)
+}
+
+// ipv4MaskBytes returns the 4 bytes form of the ipv4 mask
+func ipv4MaskBytes(mask net.IPMask) []byte {
+	if len(mask) == 16 {
+		return fixedBytes(mask[12:], 4)
+	}
+	return fixedBytes(mask, 4)
+}
@@ -27,6 +27,23 @@ func GetOfpMsgVersion(msg OfpMessage) (uint8, error) {
 	return GetMessageVersion(b)
 }
 
+// GetMessageXid is used to retrive the transaction id of the msg from byte slice
+func GetMessageXid(msg []byte) (uint32, error) {
+	if len(msg) < 8 {
+		return 0, fmt.Errorf("The message length %d is smaller than minimum length", len(msg))
+	}
+	return binary.BigEndian.Uint32(msg[4:8]), nil
+}
+
+// GetOfpMsgXid is used to retrieve the transaction id of the ofp messge
+func GetOfpMsgXid(msg OfpMessage) (uint32, error) {
+	b, err := msg.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	return GetMessageXid(b)
+}
+
 // UnMarshalFields is used to read the fields value from reader
 func UnMarshalFields(reader io.Reader, fields ...interface{}) error {
 	for _, f := range fields {